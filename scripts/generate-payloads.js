@@ -18,6 +18,13 @@
  *   --cartridge-addr <addr> Cartridge address (NQ..., required)
  *   --schema <version>     Schema version (default: 1)
  *   --chunk-size <size>    Chunk size in bytes (default: 51)
+ *
+ * Test vectors:
+ *   node generate-payloads.js --vectors <file>
+ *
+ *   Checks the encoders below and the decoders in web/src/utils/payloads.js
+ *   against the shared test vectors written by `nimiq-uploader testvectors`
+ *   (uploader/testdata/payload-vectors.json).
  */
 
 const fs = require('fs')
@@ -26,6 +33,18 @@ const path = require('path')
 
 // Parse command line arguments
 const args = process.argv.slice(2)
+
+if (args[0] === '--vectors') {
+  checkVectors(args[1]).then(
+    failed => process.exit(failed > 0 ? 1 : 0),
+    err => {
+      console.error(`Error: ${err.message}`)
+      process.exit(1)
+    }
+  )
+  return
+}
+
 const zipFile = args[0]
 
 if (!zipFile) {
@@ -100,47 +119,93 @@ const totalSize = zipData.length
 const sha256 = crypto.createHash('sha256').update(zipData).digest('hex')
 const sha256Bytes = Buffer.from(sha256, 'hex')
 
-// Convert cartridge address to bytes
+// Convert a user-friendly cartridge address (NQxx XXXX ...) to bytes. The
+// older "NQ" + 40 hex characters form is still accepted.
 function addressNQToBytes(address) {
-  if (!address.startsWith('NQ')) {
-    throw new Error('Invalid address format')
+  // Nimiq base32 alphabet (no I, O, W, Z)
+  const NIMIQ_BASE32_ALPHABET = '0123456789ABCDEFGHJKLMNPQRSTUVXY'
+  const normalized = address.replace(/\s/g, '').toUpperCase()
+  if (!normalized.startsWith('NQ')) {
+    throw new Error(`Invalid address format: ${address} (expected NQ...)`)
+  }
+  if (normalized.length === 42 && /^[0-9A-F]{40}$/.test(normalized.slice(2))) {
+    return Buffer.from(normalized.slice(2), 'hex')
+  }
+  if (normalized.length !== 36) {
+    throw new Error(`Invalid address format: ${address} (expected NQ + 34 base32 characters, or NQ + 40 hex characters)`)
+  }
+  let bits = 0
+  let bitCount = 0
+  const bytes = []
+  for (const char of normalized.slice(4)) {
+    const value = NIMIQ_BASE32_ALPHABET.indexOf(char)
+    if (value < 0) {
+      throw new Error(`Invalid address character: ${char}`)
+    }
+    bits = (bits << 5) | value
+    bitCount += 5
+    if (bitCount >= 8) {
+      bytes.push((bits >> (bitCount - 8)) & 0xff)
+      bitCount -= 8
+      bits &= (1 << bitCount) - 1
+    }
   }
-  const hex = address.slice(2)
-  if (hex.length !== 40) {
-    throw new Error('Invalid address length')
+  const checksum = addressChecksum(normalized.slice(4))
+  if (normalized.slice(2, 4) !== checksum) {
+    throw new Error(`Invalid address checksum: ${address} (expected NQ${checksum}...)`)
   }
-  return Buffer.from(hex, 'hex')
+  return Buffer.from(bytes)
 }
 
-const cartridgeAddrBytes = addressNQToBytes(cartridgeAddr)
+// IBAN check digits (ISO 13616) of an address body, as used by Nimiq
+function addressChecksum(body) {
+  const digits = (body + 'NQ00').split('').map(c => parseInt(c, 36).toString()).join('')
+  let remainder = 0
+  for (const digit of digits) {
+    remainder = (remainder * 10 + Number(digit)) % 97
+  }
+  return String(98 - remainder).padStart(2, '0')
+}
+
+let cartridgeAddrBytes
+try {
+  cartridgeAddrBytes = addressNQToBytes(cartridgeAddr)
+} catch (err) {
+  console.error(`Error: --cartridge-addr: ${err.message}`)
+  process.exit(1)
+}
 
 // Generate CART header (64 bytes)
 function generateCART() {
+  return encodeCART({ schema, platform, chunkSize, flags: 0, cartridgeId, totalSize, sha256 })
+}
+
+function encodeCART(fields) {
   const buffer = Buffer.alloc(64)
   
   // MAGIC "CART" (4 bytes)
   buffer.write('CART', 0)
   
   // schema (1 byte)
-  buffer[4] = schema
+  buffer[4] = fields.schema
   
   // platform (1 byte)
-  buffer[5] = platform
+  buffer[5] = fields.platform
   
   // chunk_size (1 byte)
-  buffer[6] = chunkSize
+  buffer[6] = fields.chunkSize
   
   // flags (1 byte)
-  buffer[7] = 0
+  buffer[7] = fields.flags
   
   // cartridge_id (u32, little-endian)
-  buffer.writeUInt32LE(cartridgeId, 8)
+  buffer.writeUInt32LE(fields.cartridgeId, 8)
   
   // total_size (u64, little-endian)
-  buffer.writeBigUInt64LE(BigInt(totalSize), 12)
+  buffer.writeBigUInt64LE(BigInt(fields.totalSize), 12)
   
   // sha256 (32 bytes)
-  sha256Bytes.copy(buffer, 20)
+  Buffer.from(fields.sha256, 'hex').copy(buffer, 20)
   
   // reserved (12 bytes) - already zero
   
@@ -158,22 +223,7 @@ function generateDATAChunks() {
     const chunkDataLen = Math.min(chunkSize, remaining)
     const chunkData = zipData.slice(offset, offset + chunkDataLen)
     
-    const buffer = Buffer.alloc(64)
-    
-    // MAGIC "DATA" (4 bytes)
-    buffer.write('DATA', 0)
-    
-    // cartridge_id (u32, little-endian)
-    buffer.writeUInt32LE(cartridgeId, 4)
-    
-    // chunk_index (u32, little-endian)
-    buffer.writeUInt32LE(i, 8)
-    
-    // len (1 byte)
-    buffer[12] = chunkDataLen
-    
-    // bytes (51 bytes)
-    chunkData.copy(buffer, 13)
+    const buffer = encodeDATA({ cartridgeId, chunkIndex: i, len: chunkDataLen, data: chunkData })
     
     chunks.push({
       index: i,
@@ -185,35 +235,60 @@ function generateDATAChunks() {
   return chunks
 }
 
+function encodeDATA(fields) {
+  const buffer = Buffer.alloc(64)
+  
+  // MAGIC "DATA" (4 bytes)
+  buffer.write('DATA', 0)
+  
+  // cartridge_id (u32, little-endian)
+  buffer.writeUInt32LE(fields.cartridgeId, 4)
+  
+  // chunk_index (u32, little-endian)
+  buffer.writeUInt32LE(fields.chunkIndex, 8)
+  
+  // len (1 byte)
+  buffer[12] = fields.len
+  
+  // bytes (51 bytes)
+  Buffer.from(fields.data).copy(buffer, 13)
+  
+  return buffer
+}
+
 // Generate CENT entry (64 bytes)
 function generateCENT() {
+  return encodeCENT({ schema, platform, flags: 0, appId, semver: semverParts, cartridgeAddrBytes, title })
+}
+
+function encodeCENT(fields) {
   const buffer = Buffer.alloc(64)
   
   // MAGIC "CENT" (4 bytes)
   buffer.write('CENT', 0)
   
   // schema (1 byte)
-  buffer[4] = schema
+  buffer[4] = fields.schema
   
   // platform (1 byte)
-  buffer[5] = platform
+  buffer[5] = fields.platform
   
   // flags (1 byte)
-  buffer[6] = 0
+  buffer[6] = fields.flags
   
   // app_id (u32, little-endian)
-  buffer.writeUInt32LE(appId, 7)
+  buffer.writeUInt32LE(fields.appId, 7)
   
   // semver (3 bytes: major, minor, patch)
-  buffer[11] = semverParts[0]
-  buffer[12] = semverParts[1]
-  buffer[13] = semverParts[2]
+  buffer[11] = fields.semver[0]
+  buffer[12] = fields.semver[1]
+  buffer[13] = fields.semver[2]
   
   // cartridge_address (20 bytes)
-  cartridgeAddrBytes.copy(buffer, 14)
+  fields.cartridgeAddrBytes.copy(buffer, 14)
   
   // title_short (16 bytes, null-terminated)
-  const titleBytes = Buffer.from(title, 'utf8').slice(0, 15)
+  const titleBytes = Buffer.from(fields.title, 'utf8').slice(0, 15)
   titleBytes.copy(buffer, 34)
   buffer[34 + titleBytes.length] = 0 // null terminator
   
@@ -338,3 +413,106 @@ console.log(`  - CENT.hex`)
 console.log(`  - CART.hex`)
 console.log(`  - DATA.chunks`)
 
+// Legacy DOOM chunk (upload command), only used to check the test vectors:
+// MAGIC "DOOM"(4), game_id(u32), index(u32), len(u8), bytes(51)
+function encodeDOOM(fields) {
+  const buffer = Buffer.alloc(64)
+  buffer.write('DOOM', 0)
+  buffer.writeUInt32LE(fields.gameId, 4)
+  buffer.writeUInt32LE(fields.index, 8)
+  buffer[12] = fields.len
+  Buffer.from(fields.data).copy(buffer, 13)
+  return buffer
+}
+
+function decodeDOOM(data) {
+  if (!data || data.length < 64) return null
+  const buffer = Buffer.from(data)
+  if (buffer.toString('latin1', 0, 4) !== 'DOOM') return null
+  const len = buffer[12]
+  if (len > 51) return null
+  return {
+    gameId: buffer.readUInt32LE(4),
+    index: buffer.readUInt32LE(8),
+    len,
+    data: buffer.subarray(13, 13 + len)
+  }
+}
+
+// Check the encoders above and the web decoders against the shared test
+// vectors; returns the number of failed checks
+async function checkVectors(vectorsFile) {
+  if (!vectorsFile) {
+    throw new Error('Usage: node generate-payloads.js --vectors <file>')
+  }
+  const { vectors } = JSON.parse(fs.readFileSync(vectorsFile, 'utf8'))
+  const payloads = await import(path.join(__dirname, '..', 'web', 'src', 'utils', 'payloads.js'))
+  const decoders = {
+    CART: payloads.parseCART,
+    DATA: payloads.parseDATA,
+    CENT: payloads.parseCENT,
+    CSGN: payloads.parseCSGN,
+    DOOM: decodeDOOM
+  }
+  const toHex = bytes => Buffer.from(bytes).toString('hex')
+  const encoders = {
+    CART: f => encodeCART(f),
+    DATA: f => encodeDATA({ ...f, data: Buffer.from(f.data, 'hex') }),
+    CENT: f => encodeCENT({
+      ...f,
+      semver: f.semver.split('.').map(v => parseInt(v)),
+      cartridgeAddrBytes: addressNQToBytes(f.cartridgeAddress)
+    }),
+    DOOM: f => encodeDOOM({ ...f, data: Buffer.from(f.data, 'hex') })
+  }
+  // Bring decoded objects into the vector field format
+  const fieldsOf = {
+    CART: r => r,
+    DATA: r => ({ ...r, data: toHex(r.data) }),
+    CENT: r => ({ ...r, semver: r.semver.string, title: r.title || '' }),
+    CSGN: r => r,
+    DOOM: r => ({ ...r, data: toHex(r.data) })
+  }
+
+  let failed = 0
+  let checked = 0
+  for (const vector of vectors) {
+    const decode = decoders[vector.type]
+    checked++
+    if (!decode) {
+      failed++
+      console.log(`❌ ${vector.name} (${vector.type}): no JS decoder for this record type`)
+      continue
+    }
+    const errors = []
+
+    const payload = new Uint8Array(Buffer.from(vector.hex, 'hex'))
+    const decoded = decode(payload)
+    if (!vector.valid) {
+      if (decoded) errors.push('decoded, but the vector is invalid')
+    } else if (!decoded) {
+      errors.push('failed to decode')
+    } else {
+      const fields = fieldsOf[vector.type](decoded)
+      for (const [key, want] of Object.entries(vector.fields)) {
+        if (JSON.stringify(fields[key]) !== JSON.stringify(want)) {
+          errors.push(`${key} decoded as ${JSON.stringify(fields[key])}, expected ${JSON.stringify(want)}`)
+        }
+      }
+      if (encoders[vector.type]) {
+        const encoded = encoders[vector.type](vector.fields).toString('hex')
+        if (encoded !== vector.hex) errors.push(`encodes to ${encoded}`)
+      }
+    }
+
+    if (errors.length > 0) {
+      failed++
+      console.log(`❌ ${vector.name} (${vector.type}): ${errors.join('; ')}`)
+    }
+  }
+
+  if (failed === 0) {
+    console.log(`✅ ${checked} test vectors match the JS encoders and web decoders`)
+  }
+  return failed
+}
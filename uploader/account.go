@@ -21,6 +21,8 @@ func newAccountCmd() *cobra.Command {
 	accountCmd.AddCommand(newAccountStatusCmd())
 	accountCmd.AddCommand(newAccountBalanceCmd())
 	accountCmd.AddCommand(newAccountWaitFundsCmd())
+	accountCmd.AddCommand(newAccountFundWorkersCmd())
+	accountCmd.AddCommand(newAccountSweepCmd())
 	accountCmd.AddCommand(newAccountConsensusCmd())
 	accountCmd.AddCommand(newAccountUnlockCmd())
 	accountCmd.AddCommand(newAccountLockCmd())
@@ -33,6 +35,8 @@ func newAccountCreateCmd() *cobra.Command {
 		rpcURL       string
 		saveFile     string
 		saveToConfig bool
+		noEncrypt    bool
+		showSecrets  bool
 	)
 
 	cmd := &cobra.Command{
@@ -44,6 +48,16 @@ func newAccountCreateCmd() *cobra.Command {
 				rpcURL = GetDefaultRPCURL()
 			}
 
+			// Ask for the keystore passphrase before anything is created
+			var keystorePassphrase string
+			if !noEncrypt {
+				var err error
+				keystorePassphrase, err = resolveKeystorePassphrase(true)
+				if err != nil {
+					return fmt.Errorf("%w (or use --no-encrypt to save the private key in plaintext)", err)
+				}
+			}
+
 			rpc := NewNimiqRPC(rpcURL)
 			account, err := rpc.CreateAccount()
 			if err != nil {
@@ -84,6 +98,11 @@ func newAccountCreateCmd() *cobra.Command {
 				RPCURL:     rpcURL,
 				CreatedAt:  time.Now().Format(time.RFC3339),
 			}
+			if !noEncrypt {
+				if err := EncryptCredentials(creds, keystorePassphrase); err != nil {
+					return fmt.Errorf("failed to encrypt credentials: %w", err)
+				}
+			}
 
 			// Determine save location
 			var savePath string
@@ -107,13 +126,19 @@ func newAccountCreateCmd() *cobra.Command {
 			fmt.Println("✅ Account created and imported successfully!")
 			fmt.Printf("Address:    %s\n", account.Address)
 			fmt.Printf("Public Key: %s\n", account.PublicKey)
-			fmt.Printf("Private Key: %s\n", account.PrivateKey)
-			fmt.Printf("Passphrase: %s\n", passphrase)
+			if showSecrets {
+				fmt.Printf("Private Key: %s\n", account.PrivateKey)
+				fmt.Printf("Passphrase: %s\n", passphrase)
+			}
 			fmt.Printf("\n📝 Credentials saved to: %s\n", savePath)
-			fmt.Println("\n⚠️  IMPORTANT: Keep this file secure! It contains your private key and passphrase.")
+			if noEncrypt {
+				fmt.Println("\n⚠️  IMPORTANT: Keep this file secure! It contains your private key and passphrase in plaintext.")
+			} else {
+				fmt.Println("\n🔒 Private key and passphrase are encrypted in the keystore. Don't lose the keystore passphrase.")
+			}
 			fmt.Printf("\n💡 Next steps:\n")
 			fmt.Printf("   1. Fund this address with some NIM (mainnet)\n")
-			fmt.Printf("   2. Unlock account: nimiq-uploader account unlock --passphrase \"%s\"\n", passphrase)
+			fmt.Printf("   2. Unlock account: nimiq-uploader account unlock\n")
 			fmt.Printf("   3. Check balance: nimiq-uploader account balance\n")
 			fmt.Printf("   4. Wait for funds: nimiq-uploader account wait-funds\n")
 
@@ -124,6 +149,8 @@ func newAccountCreateCmd() *cobra.Command {
 	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
 	cmd.Flags().StringVar(&saveFile, "save", "", "File to save credentials to (default: ./credentials.json)")
 	cmd.Flags().BoolVar(&saveToConfig, "global", false, "Save credentials to config directory (~/.config/nimiq-uploader/)")
+	cmd.Flags().BoolVar(&noEncrypt, "no-encrypt", false, "Save the private key and passphrase in plaintext instead of an encrypted keystore")
+	cmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Print the private key and passphrase")
 
 	return cmd
 }
@@ -148,7 +175,7 @@ func newAccountImportCmd() *cobra.Command {
 
 			// Load from credentials file if requested
 			if fromFile {
-				creds, err := LoadCredentials("")
+				creds, err := LoadCredentialSecrets("")
 				if err != nil {
 					return fmt.Errorf("failed to load credentials: %w", err)
 				}
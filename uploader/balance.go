@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -109,29 +110,7 @@ func newAccountWaitFundsCmd() *cobra.Command {
 				return fmt.Errorf("node does not have consensus with the network - wait for sync")
 			}
 			
-			fmt.Printf("Waiting for account %s to have at least %.5f NIM...\n", address, minNIM)
-			fmt.Printf("Checking every %d seconds...\n\n", interval)
-
-			for {
-				balance, err := rpc.GetBalance(address)
-				if err != nil {
-					fmt.Printf("Error checking balance: %v (will retry)\n", err)
-					time.Sleep(time.Duration(interval) * time.Second)
-					continue
-				}
-
-				nim := float64(balance) / 100000.0
-				fmt.Printf("[%s] Balance: %d Luna (%.5f NIM)", 
-					time.Now().Format("15:04:05"), balance, nim)
-
-				if balance >= minLuna {
-					fmt.Printf(" ✅ Ready!\n")
-					return nil
-				}
-
-				fmt.Printf(" ⏳ Waiting...\n")
-				time.Sleep(time.Duration(interval) * time.Second)
-			}
+			return waitForFunds(cmd.Context(), rpc, address, minLuna, time.Duration(interval)*time.Second)
 		},
 	}
 
@@ -144,3 +123,34 @@ func newAccountWaitFundsCmd() *cobra.Command {
 
 	return cmd
 }
+
+// waitForFunds polls the balance of address until it reaches minLuna
+func waitForFunds(ctx context.Context, rpc *NimiqRPC, address string, minLuna int64, interval time.Duration) error {
+	fmt.Printf("Waiting for account %s to have at least %.5f NIM...\n", address, float64(minLuna)/100000.0)
+	fmt.Printf("Checking every %s...\n\n", interval)
+
+	for {
+		balance, err := rpc.GetBalance(address)
+		if err != nil {
+			fmt.Printf("Error checking balance: %v (will retry)\n", err)
+			if err := sleepContext(ctx, interval); err != nil {
+				return err
+			}
+			continue
+		}
+
+		nim := float64(balance) / 100000.0
+		fmt.Printf("[%s] Balance: %d Luna (%.5f NIM)", 
+			time.Now().Format("15:04:05"), balance, nim)
+
+		if balance >= minLuna {
+			fmt.Printf(" ✅ Ready!\n")
+			return nil
+		}
+
+		fmt.Printf(" ⏳ Waiting...\n")
+		if err := sleepContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
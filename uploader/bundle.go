@@ -0,0 +1,600 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+const bundleVersion = 1
+
+// BundleHeader is the first line of a signed transaction bundle (sign-bundle).
+// BundleHash covers the header (with an empty BundleHash) and every transaction line.
+type BundleHeader struct {
+	Type                string `json:"type"` // "bundle"
+	Version             int    `json:"version"`
+	CreatedAt           string `json:"created_at"`
+	PlanHash            string `json:"plan_hash"`
+	AppID               uint32 `json:"app_id"`
+	CartridgeID         uint32 `json:"cartridge_id"`
+	CartridgeAddr       string `json:"cartridge_addr"`
+	CatalogAddr         string `json:"catalog_addr"`
+	Publisher           string `json:"publisher"`
+	Title               string `json:"title"`
+	Semver              string `json:"semver"`
+	NetworkID           uint8  `json:"network_id"`
+	ValidityStartHeight uint32 `json:"validity_start_height"`
+	ValidUntilHeight    uint32 `json:"valid_until_height"` // last block the transactions can be included in
+	Txs                 int    `json:"txs"`
+	BundleHash          string `json:"bundle_hash"`
+}
+
+// BundleTx is a signed transaction, serialized for sendRawTransaction
+type BundleTx struct {
+	Type  string `json:"type"` // "tx"
+	Step  int    `json:"step"`
+	Kind  string `json:"kind"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Value int64  `json:"value"`
+	Fee   int64  `json:"fee"`
+	Data  string `json:"data_hex"` // recipient data, to check the node's decoding
+	Raw   string `json:"raw_hex"`
+}
+
+// Bundle is a plan signed for one validity window
+type Bundle struct {
+	Header BundleHeader
+	Txs    []BundleTx
+}
+
+func (b *Bundle) lines() ([][]byte, error) {
+	header, err := json.Marshal(b.Header)
+	if err != nil {
+		return nil, err
+	}
+	lines := [][]byte{header}
+	for _, tx := range b.Txs {
+		line, err := json.Marshal(tx)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// Hash computes the bundle hash over the header (without its hash) and all transactions
+func (b *Bundle) Hash() (string, error) {
+	unhashed := *b
+	unhashed.Header.BundleHash = ""
+	lines, err := unhashed.lines()
+	if err != nil {
+		return "", err
+	}
+	return jsonLinesHash(lines), nil
+}
+
+// ReadBundle reads a bundle file and refuses it if it was edited after signing
+func ReadBundle(path string) (*Bundle, error) {
+	lines, numbers, err := readJSONLines(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	bundle := &Bundle{}
+	if err := decodeStrict(lines[0], &bundle.Header); err != nil || bundle.Header.Type != "bundle" {
+		return nil, fmt.Errorf("%s is not a transaction bundle (the first line must be the header)", path)
+	}
+	if bundle.Header.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d (expected %d)", bundle.Header.Version, bundleVersion)
+	}
+	for i, line := range lines[1:] {
+		var tx BundleTx
+		if err := decodeStrict(line, &tx); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, numbers[i+1], err)
+		}
+		if tx.Type != "tx" || tx.Step != len(bundle.Txs) {
+			return nil, fmt.Errorf("%s line %d: expected transaction %d", path, numbers[i+1], len(bundle.Txs))
+		}
+		if _, err := hex.DecodeString(tx.Raw); err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid raw transaction: %w", path, numbers[i+1], err)
+		}
+		bundle.Txs = append(bundle.Txs, tx)
+	}
+	if bundle.Header.Txs != len(bundle.Txs) {
+		return nil, fmt.Errorf("bundle is truncated: header lists %d transactions, file has %d", bundle.Header.Txs, len(bundle.Txs))
+	}
+	hash, err := bundle.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if hash != bundle.Header.BundleHash {
+		return nil, fmt.Errorf("bundle hash mismatch (header %s, content %s) - the bundle was modified after signing", shortHash(bundle.Header.BundleHash), shortHash(hash))
+	}
+	return bundle, nil
+}
+
+// SignTxPlan signs every step of a plan for the window starting at
+// validityStartHeight. keys are the signing keys by normalized address.
+func SignTxPlan(plan *TxPlan, keys map[string]*SigningKey, networkID uint8, validityStartHeight uint32) (*Bundle, error) {
+	h := plan.Header
+	bundle := &Bundle{Header: BundleHeader{
+		Type:                "bundle",
+		Version:             bundleVersion,
+		CreatedAt:           time.Now().UTC().Format(time.RFC3339),
+		PlanHash:            h.PlanHash,
+		AppID:               h.AppID,
+		CartridgeID:         h.CartridgeID,
+		CartridgeAddr:       h.CartridgeAddr,
+		CatalogAddr:         h.CatalogAddr,
+		Publisher:           h.Publisher,
+		Title:               h.Title,
+		Semver:              h.Semver,
+		NetworkID:           networkID,
+		ValidityStartHeight: validityStartHeight,
+		ValidUntilHeight:    validityStartHeight + txValidityWindow,
+	}}
+
+	for _, step := range plan.Steps {
+		key := keys[normalizeAddress(step.From)]
+		if key == nil {
+			return nil, fmt.Errorf("no key for %s (step %d, %s) - add its key file with --key-file", step.From, step.Step, step.Kind)
+		}
+		payload, err := hex.DecodeString(step.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", step.Step, err)
+		}
+		tx, err := NewRawTransaction(step.From, step.To, payload, step.Value, step.Fee, validityStartHeight, networkID)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", step.Step, err)
+		}
+		raw, err := tx.Sign(key)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", step.Step, err)
+		}
+		bundle.Txs = append(bundle.Txs, BundleTx{
+			Type:  "tx",
+			Step:  step.Step,
+			Kind:  step.Kind,
+			From:  step.From,
+			To:    step.To,
+			Value: step.Value,
+			Fee:   step.Fee,
+			Data:  step.Payload,
+			Raw:   hex.EncodeToString(raw),
+		})
+	}
+
+	bundle.Header.Txs = len(bundle.Txs)
+	hash, err := bundle.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash bundle: %w", err)
+	}
+	bundle.Header.BundleHash = hash
+	return bundle, nil
+}
+
+// estimateValidityStartHeight extrapolates the block height at a future time
+// from a known height@time anchor (Albatross produces about one block per second)
+func estimateValidityStartHeight(anchor, at string) (uint32, error) {
+	parts := strings.SplitN(anchor, "@", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid --anchor %q (use HEIGHT@TIME, e.g. 3456789@2026-01-02T15:04:05Z)", anchor)
+	}
+	height, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --anchor height %q", parts[0])
+	}
+	anchorTime, err := time.Parse(time.RFC3339, parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid --anchor time %q (use RFC 3339)", parts[1])
+	}
+	broadcastAt, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --broadcast-at %q (use RFC 3339)", at)
+	}
+	if broadcastAt.Before(anchorTime) {
+		return 0, fmt.Errorf("--broadcast-at is before the anchor")
+	}
+	return uint32(height) + uint32(broadcastAt.Sub(anchorTime)/time.Second), nil
+}
+
+func newSignBundleCmd() *cobra.Command {
+	var (
+		planPath            string
+		outPath             string
+		keyFiles            []string
+		keyPassphrase       string
+		network             string
+		validityStartHeight uint32
+		anchor              string
+		broadcastAt         string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sign-bundle",
+		Short: "Sign a transaction plan offline into a bundle for 'broadcast'",
+		Long: `Sign every transaction of a plan (upload-cartridge --plan-out, which also
+works without a node with --offline) with keys held in encrypted key files.
+No node is contacted and no key is imported anywhere.
+
+Signed transactions are valid for 7200 blocks (about 2 hours) from their
+validity start height. Give the height directly, or pick the window ahead of
+time from a known height@time anchor and the time you plan to broadcast.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := ReadTxPlan(planPath)
+			if err != nil {
+				return err
+			}
+			h := plan.Header
+			if _, err := os.Stat(h.File); err == nil {
+				if err := verifyPlanFile(h); err != nil {
+					return err
+				}
+			} else {
+				fmt.Printf("⚠️  %s is not here; signing the plan without re-checking the file\n", h.File)
+			}
+
+			networkID, _, err := expectedNetwork(h.CatalogAddr, network)
+			if err != nil {
+				return err
+			}
+			if networkID == 0 {
+				networkID = h.NetworkID
+			}
+			if networkID == 0 {
+				return fmt.Errorf("--network is required for catalog %s", h.CatalogAddr)
+			}
+			if err := checkPlanNetwork("plan", h.NetworkID, networkID); err != nil {
+				return err
+			}
+
+			switch {
+			case validityStartHeight > 0:
+			case anchor != "" && broadcastAt != "":
+				if validityStartHeight, err = estimateValidityStartHeight(anchor, broadcastAt); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("supply --validity-start-height, or --anchor with --broadcast-at")
+			}
+
+			passphrase := resolveKeyPassphrase(keyPassphrase)
+			keys := make(map[string]*SigningKey)
+			for _, path := range keyFiles {
+				key, err := LoadSigningKey(path, passphrase)
+				if err != nil {
+					return err
+				}
+				keys[normalizeAddress(key.Address)] = key
+				fmt.Printf("Loaded key for %s\n", key.Address)
+			}
+
+			bundle, err := SignTxPlan(plan, keys, networkID, validityStartHeight)
+			if err != nil {
+				return err
+			}
+			lines, err := bundle.lines()
+			if err != nil {
+				return fmt.Errorf("failed to encode bundle: %w", err)
+			}
+			if outPath == "" {
+				outPath = strings.TrimSuffix(planPath, ".jsonl") + ".bundle.jsonl"
+			}
+			if err := writeJSONLines(outPath, lines); err != nil {
+				return fmt.Errorf("failed to write bundle %s: %w", outPath, err)
+			}
+
+			fmt.Printf("\n✓ Signed %d transactions for network %d into %s\n", len(bundle.Txs), networkID, outPath)
+			fmt.Printf("  Plan hash: %s\n  Bundle hash: %s\n", h.PlanHash, bundle.Header.BundleHash)
+			fmt.Printf("  Valid from block %d to %d (about 2 hours)\n", bundle.Header.ValidityStartHeight, bundle.Header.ValidUntilHeight)
+			fmt.Printf("  On the online machine run:\n    nimiq-uploader broadcast --bundle %s\n", outPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&planPath, "plan", "", "Plan file written by upload-cartridge --plan-out (required)")
+	cmd.Flags().StringVar(&outPath, "out", "", "Bundle file (default: <plan>.bundle.jsonl)")
+	cmd.Flags().StringArrayVar(&keyFiles, "key-file", nil, "Encrypted key file of a sending account (repeat for sender pools; see offline-key)")
+	cmd.Flags().StringVar(&keyPassphrase, "key-passphrase", "", "Passphrase of the key files (defaults to NIMIQ_KEY_PASSPHRASE or the account passphrase)")
+	cmd.Flags().StringVar(&network, "network", "", "Network: main, test, dev or a numeric network ID (default: from the catalog address or the plan)")
+	cmd.Flags().Uint32Var(&validityStartHeight, "validity-start-height", 0, "Block height the transactions become valid at")
+	cmd.Flags().StringVar(&anchor, "anchor", "", "Known HEIGHT@TIME (RFC 3339) to estimate the validity start height from")
+	cmd.Flags().StringVar(&broadcastAt, "broadcast-at", "", "Time (RFC 3339) you plan to broadcast, used with --anchor")
+
+	cmd.MarkFlagRequired("plan")
+	cmd.MarkFlagRequired("key-file")
+
+	return cmd
+}
+
+func newBroadcastCmd() *cobra.Command {
+	var (
+		bundlePath   string
+		progressPath string
+		rpcURL       string
+		rateLimit    float64
+		noWait       bool
+		skipVerify   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "broadcast",
+		Short: "Broadcast a signed bundle (sign-bundle) and track confirmations",
+		Long: `Push the signed transactions of a bundle through the node with
+sendRawTransaction, in step order, then wait until all of them are included
+in blocks. The node needs no keys.
+
+Before sending, every transaction is decoded by the node (getRawTransactionInfo)
+and compared with the bundle. Sent and confirmed steps are recorded in
+<bundle>.progress.json; rerunning re-sends unconfirmed transactions unchanged
+(same bytes, same hash), which is safe.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			bundle, err := ReadBundle(bundlePath)
+			if err != nil {
+				return err
+			}
+			h := bundle.Header
+			fmt.Printf("Bundle: %s (hash %s, plan %s)\n", bundlePath, shortHash(h.BundleHash), shortHash(h.PlanHash))
+			fmt.Printf("  \"%s\" %s: app-id %d, cartridge-id %d, %d transactions on network %d\n", h.Title, h.Semver, h.AppID, h.CartridgeID, len(bundle.Txs), h.NetworkID)
+			fmt.Printf("  Valid from block %d to %d\n", h.ValidityStartHeight, h.ValidUntilHeight)
+
+			if progressPath == "" {
+				progressPath = strings.TrimSuffix(bundlePath, ".jsonl") + ".progress.json"
+			}
+			lock, err := acquireFileLock(progressPath + ".lock")
+			if err != nil {
+				return err
+			}
+			defer releaseFileLock(lock)
+			progress, err := loadTxPlanProgress(progressPath, h.BundleHash)
+			if err != nil {
+				return err
+			}
+			if len(progress.Confirmed) == len(bundle.Txs) {
+				fmt.Printf("\n✅ All %d transactions are confirmed (see %s)\n", len(bundle.Txs), progressPath)
+				return nil
+			}
+
+			// Get RPC URL from env, credentials file, or default
+			if rpcURL == "" {
+				rpcURL = GetDefaultRPCURL()
+			}
+			rpc := NewNimiqRPC(rpcURL).WithContext(ctx)
+
+			consensus, err := rpc.IsConsensusEstablished()
+			if err != nil {
+				return fmt.Errorf("failed to check consensus: %w", err)
+			}
+			if !consensus {
+				return fmt.Errorf("node does not have consensus with the network - wait for sync")
+			}
+
+			// The signatures only hold on the bundle's network
+			networkID, err := checkNodeNetwork(rpc, h.CatalogAddr, "")
+			if err != nil {
+				return err
+			}
+			if err := checkPlanNetwork("bundle", h.NetworkID, networkID); err != nil {
+				return err
+			}
+			if err := progress.bindNetwork(networkID); err != nil {
+				return err
+			}
+
+			if !skipVerify {
+				for _, tx := range bundle.Txs {
+					if _, done := progress.Confirmed[tx.Step]; done {
+						continue
+					}
+					info, err := rpc.GetRawTransactionInfo(tx.Raw)
+					if err != nil {
+						return fmt.Errorf("node could not decode step %d: %w (use --skip-verify to send anyway)", tx.Step, err)
+					}
+					if normalizeAddress(info.From) != normalizeAddress(tx.From) || normalizeAddress(info.To) != normalizeAddress(tx.To) ||
+						info.Value != tx.Value || info.Fee != tx.Fee || info.ValidityStartHeight != int64(h.ValidityStartHeight) ||
+						!strings.EqualFold(info.RecipientData, tx.Data) {
+						return fmt.Errorf("node decodes step %d differently than signed (from %s to %s, value %d, fee %d, height %d) - not sending", tx.Step, info.From, info.To, info.Value, info.Fee, info.ValidityStartHeight)
+					}
+				}
+				fmt.Println("✓ Node decodes all transactions as signed")
+			}
+
+			// The transactions only get in within their validity window
+			height, err := rpc.GetBlockNumber()
+			if err != nil {
+				return fmt.Errorf("failed to get block number: %w", err)
+			}
+			if height > int64(h.ValidUntilHeight) {
+				return fmt.Errorf("bundle expired at block %d (now %d) - sign the plan again with a newer --validity-start-height", h.ValidUntilHeight, height)
+			}
+			if height < int64(h.ValidityStartHeight) {
+				fmt.Printf("Waiting for block %d (now %d)...\n", h.ValidityStartHeight, height)
+				for height < int64(h.ValidityStartHeight) {
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-time.After(10 * time.Second):
+					}
+					if height, err = rpc.GetBlockNumber(); err != nil {
+						return fmt.Errorf("failed to get block number: %w", err)
+					}
+				}
+			}
+
+			limiter := rate.NewLimiter(rate.Limit(rateLimit), 1)
+			sent := 0
+			fmt.Println()
+			for _, tx := range bundle.Txs {
+				if _, done := progress.Confirmed[tx.Step]; done {
+					continue
+				}
+				previous, resend := progress.Sent[tx.Step]
+				if resend {
+					if info, err := rpc.GetTransactionByHash(previous); err == nil && info.BlockNumber > 0 {
+						if err := progress.confirm(tx.Step, info.BlockNumber); err != nil {
+							fmt.Printf("Warning: %v\n", err)
+						}
+						continue
+					}
+				}
+				if err := limiter.Wait(ctx); err != nil {
+					return fmt.Errorf("interrupted (progress saved to %s, run broadcast again to resume)", progressPath)
+				}
+				txHash, err := rpc.SendRawTransaction(tx.Raw)
+				if err != nil {
+					if resend {
+						fmt.Printf("[%d/%d] %s not re-sent (may still be pending): %v\n", tx.Step+1, len(bundle.Txs), tx.Kind, err)
+						continue
+					}
+					return fmt.Errorf("step %d (%s) failed: %w (progress saved to %s, run broadcast again to resume)", tx.Step, tx.Kind, err, progressPath)
+				}
+				if err := progress.record(tx.Step, txHash); err != nil {
+					fmt.Printf("Warning: %v\n", err)
+				}
+				sent++
+				fmt.Printf("[%d/%d] %s: %s\n", tx.Step+1, len(bundle.Txs), tx.Kind, txHash)
+			}
+			fmt.Printf("\n✓ Broadcast %d transactions\n", sent)
+			logCartridgeUpload(fmt.Sprintf("Broadcast bundle %s (hash %s): app-id %d, cartridge-id %d, %d transactions", bundlePath, h.BundleHash, h.AppID, h.CartridgeID, sent))
+
+			if noWait {
+				fmt.Printf("Not waiting for confirmations; run broadcast again to check them\n")
+				return nil
+			}
+
+			// Track confirmations until everything is in a block or the window closes
+			last := -1
+			for {
+				for _, tx := range bundle.Txs {
+					txHash, ok := progress.Sent[tx.Step]
+					if _, done := progress.Confirmed[tx.Step]; done || !ok {
+						continue
+					}
+					if info, err := rpc.GetTransactionByHash(txHash); err == nil && info.BlockNumber > 0 {
+						if err := progress.confirm(tx.Step, info.BlockNumber); err != nil {
+							fmt.Printf("Warning: %v\n", err)
+						}
+					}
+				}
+				if n := len(progress.Confirmed); n != last {
+					fmt.Printf("Confirmed %d/%d\n", n, len(bundle.Txs))
+					last = n
+				}
+				if len(progress.Confirmed) == len(bundle.Txs) {
+					fmt.Printf("\n✅ All transactions confirmed (progress in %s)\n", progressPath)
+					return nil
+				}
+				if height, err := rpc.GetBlockNumber(); err == nil && height > int64(h.ValidUntilHeight) {
+					return fmt.Errorf("%d transactions expired unconfirmed - sign the plan again with a newer --validity-start-height", len(bundle.Txs)-len(progress.Confirmed))
+				}
+				select {
+				case <-ctx.Done():
+					fmt.Printf("⏸  Stopped waiting; run broadcast again to keep tracking (%s)\n", progressPath)
+					return ctx.Err()
+				case <-time.After(5 * time.Second):
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&bundlePath, "bundle", "", "Bundle file written by sign-bundle (required)")
+	cmd.Flags().StringVar(&progressPath, "progress", "", "Progress file (default: <bundle>.progress.json)")
+	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
+	cmd.Flags().Float64Var(&rateLimit, "rate", 25.0, "Transaction rate limit (tx/s, default: 25)")
+	cmd.Flags().BoolVar(&noWait, "no-wait", false, "Don't wait for the transactions to be confirmed")
+	cmd.Flags().BoolVar(&skipVerify, "skip-verify", false, "Don't have the node decode the transactions before sending")
+
+	cmd.MarkFlagRequired("bundle")
+
+	return cmd
+}
+
+func newOfflineKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "offline-key",
+		Short: "Create or import encrypted key files for sign-bundle (no node needed)",
+	}
+
+	var createOut, createPassphrase string
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Generate a new account into an encrypted key file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			account, err := GenerateAccount()
+			if err != nil {
+				return err
+			}
+			if err := SaveSigningKey(createOut, account, resolveKeyPassphrase(createPassphrase)); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Created %s\n  Address: %s\n", createOut, account.Address)
+			return nil
+		},
+	}
+	createCmd.Flags().StringVar(&createOut, "out", "", "Key file to write (required)")
+	createCmd.Flags().StringVar(&createPassphrase, "key-passphrase", "", "Passphrase to encrypt the key (defaults to NIMIQ_KEY_PASSPHRASE or the account passphrase)")
+	createCmd.MarkFlagRequired("out")
+
+	var importOut, importPassphrase string
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Encrypt an existing private key (hex, read from stdin) into a key file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Fprint(os.Stderr, "Private key (hex): ")
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("failed to read private key: %w", err)
+			}
+			key, err := NewSigningKey(&AccountInfo{PrivateKey: strings.TrimSpace(line)})
+			if err != nil {
+				return err
+			}
+			account := &AccountInfo{
+				Address:    key.Address,
+				PublicKey:  hex.EncodeToString(key.PublicKey),
+				PrivateKey: strings.TrimSpace(line),
+			}
+			if err := SaveSigningKey(importOut, account, resolveKeyPassphrase(importPassphrase)); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Imported %s into %s\n", account.Address, importOut)
+			return nil
+		},
+	}
+	importCmd.Flags().StringVar(&importOut, "out", "", "Key file to write (required)")
+	importCmd.Flags().StringVar(&importPassphrase, "key-passphrase", "", "Passphrase to encrypt the key (defaults to NIMIQ_KEY_PASSPHRASE or the account passphrase)")
+	importCmd.MarkFlagRequired("out")
+
+	showCmd := &cobra.Command{
+		Use:   "show <key-file>",
+		Short: "Show the address of a key file (no passphrase needed)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read key file: %w", err)
+			}
+			var keyFile CartridgeKeyFile
+			if err := json.Unmarshal(data, &keyFile); err != nil || keyFile.Key == nil {
+				return fmt.Errorf("%s is not a key file", args[0])
+			}
+			fmt.Printf("%s\n", keyFile.Address)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(createCmd, importCmd, showCmd)
+	return cmd
+}
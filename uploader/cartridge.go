@@ -1,9 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -12,9 +14,11 @@ const (
 	MagicCART = "CART"
 	MagicDATA = "DATA"
 	MagicCENT = "CENT"
+	MagicCSGN = "CSGN"
 
 	// CENT flags
 	FlagRetired = 0x01 // Bit 0: App is retired and should not be shown in listings
+	FlagYanked  = 0x02 // Bit 1: This version is withdrawn (the latest CENT per app-id/semver wins)
 )
 
 // CARTHeader represents a cartridge header payload (64 bytes)
@@ -136,10 +140,7 @@ func EncodeCENT(entry CENTEntry) ([]byte, error) {
 	copy(payload[14:34], entry.CartridgeAddr[:])
 
 	// title_short (16 bytes, null-terminated)
-	titleBytes := []byte(entry.TitleShort)
-	if len(titleBytes) > 15 {
-		titleBytes = titleBytes[:15]
-	}
+	titleBytes := []byte(truncateUTF8(entry.TitleShort, 15))
 	copy(payload[34:34+len(titleBytes)], titleBytes)
 	// null terminator is already zero (rest of buffer is zero)
 
@@ -148,6 +149,157 @@ func EncodeCENT(entry CENTEntry) ([]byte, error) {
 	return payload, nil
 }
 
+// CSGNMaxAddresses is the number of co-signer addresses that fit in one CSGN record
+const CSGNMaxAddresses = 2
+
+// CSGNRecord declares accounts (besides the publisher) that sent DATA chunks
+// for a cartridge. It is sent by the publisher to the cartridge address, so
+// readers only trust co-signers the publisher vouched for.
+type CSGNRecord struct {
+	CartridgeID uint32
+	CoSigners   [][20]byte // at most CSGNMaxAddresses
+}
+
+// EncodeCSGN encodes a co-signer declaration into a 64-byte payload
+func EncodeCSGN(record CSGNRecord) ([]byte, error) {
+	if len(record.CoSigners) == 0 || len(record.CoSigners) > CSGNMaxAddresses {
+		return nil, fmt.Errorf("CSGN record must declare 1-%d co-signers (got %d)", CSGNMaxAddresses, len(record.CoSigners))
+	}
+
+	payload := make([]byte, 64)
+
+	// MAGIC "CSGN" (4 bytes)
+	copy(payload[0:4], MagicCSGN)
+
+	// cartridge_id (u32, little-endian)
+	binary.LittleEndian.PutUint32(payload[4:8], record.CartridgeID)
+
+	// count (1 byte)
+	payload[8] = uint8(len(record.CoSigners))
+
+	// co-signer addresses (20 bytes each)
+	for i, addr := range record.CoSigners {
+		copy(payload[9+i*20:29+i*20], addr[:])
+	}
+
+	// reserved (15 bytes) - already zero
+
+	return payload, nil
+}
+
+// EncodeCSGNRecords splits co-signer addresses (NQ... format) into as many
+// CSGN payloads as needed
+func EncodeCSGNRecords(cartridgeID uint32, coSigners []string) ([][]byte, error) {
+	var payloads [][]byte
+	for start := 0; start < len(coSigners); start += CSGNMaxAddresses {
+		end := start + CSGNMaxAddresses
+		if end > len(coSigners) {
+			end = len(coSigners)
+		}
+
+		record := CSGNRecord{CartridgeID: cartridgeID}
+		for _, addr := range coSigners[start:end] {
+			addrBytes, err := AddressNQToBytes(addr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid co-signer address %s: %w", addr, err)
+			}
+			record.CoSigners = append(record.CoSigners, addrBytes)
+		}
+
+		payload, err := EncodeCSGN(record)
+		if err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, payload)
+	}
+	return payloads, nil
+}
+
+// checkRecord checks the length and magic of a 64-byte record
+func checkRecord(payload []byte, magic string) error {
+	if len(payload) < 64 {
+		return fmt.Errorf("payload too short: %d bytes", len(payload))
+	}
+	if string(payload[0:4]) != magic {
+		return fmt.Errorf("invalid magic: %q (expected %s)", payload[0:4], magic)
+	}
+	return nil
+}
+
+// DecodeCART decodes a CART header payload (the inverse of EncodeCART)
+func DecodeCART(payload []byte) (CARTHeader, error) {
+	if err := checkRecord(payload, MagicCART); err != nil {
+		return CARTHeader{}, err
+	}
+	header := CARTHeader{
+		Schema:      payload[4],
+		Platform:    payload[5],
+		ChunkSize:   payload[6],
+		Flags:       payload[7],
+		CartridgeID: binary.LittleEndian.Uint32(payload[8:12]),
+		TotalSize:   binary.LittleEndian.Uint64(payload[12:20]),
+	}
+	copy(header.SHA256[:], payload[20:52])
+	return header, nil
+}
+
+// DecodeDATA decodes a DATA chunk payload (the inverse of EncodeDATA)
+func DecodeDATA(payload []byte) (DATAPayload, error) {
+	if err := checkRecord(payload, MagicDATA); err != nil {
+		return DATAPayload{}, err
+	}
+	length := payload[12]
+	if length > 51 {
+		return DATAPayload{}, fmt.Errorf("invalid chunk length: %d (max 51)", length)
+	}
+	return DATAPayload{
+		CartridgeID: binary.LittleEndian.Uint32(payload[4:8]),
+		ChunkIndex:  binary.LittleEndian.Uint32(payload[8:12]),
+		Length:      length,
+		Data:        append([]byte(nil), payload[13:13+int(length)]...),
+	}, nil
+}
+
+// DecodeCENT decodes a CENT entry payload (the inverse of EncodeCENT). The
+// title ends at the first NUL byte.
+func DecodeCENT(payload []byte) (CENTEntry, error) {
+	if err := checkRecord(payload, MagicCENT); err != nil {
+		return CENTEntry{}, err
+	}
+	entry := CENTEntry{
+		Schema:   payload[4],
+		Platform: payload[5],
+		Flags:    payload[6],
+		AppID:    binary.LittleEndian.Uint32(payload[7:11]),
+		Semver:   [3]uint8{payload[11], payload[12], payload[13]},
+	}
+	copy(entry.CartridgeAddr[:], payload[14:34])
+	title := payload[34:50]
+	if end := bytes.IndexByte(title, 0); end >= 0 {
+		title = title[:end]
+	}
+	entry.TitleShort = string(title)
+	return entry, nil
+}
+
+// DecodeCSGN decodes a co-signer declaration payload (the inverse of EncodeCSGN)
+func DecodeCSGN(payload []byte) (CSGNRecord, error) {
+	if err := checkRecord(payload, MagicCSGN); err != nil {
+		return CSGNRecord{}, err
+	}
+	count := int(payload[8])
+	if count == 0 || count > CSGNMaxAddresses {
+		return CSGNRecord{}, fmt.Errorf("invalid co-signer count: %d (1-%d)", count, CSGNMaxAddresses)
+	}
+	record := CSGNRecord{CartridgeID: binary.LittleEndian.Uint32(payload[4:8])}
+	for i := 0; i < count; i++ {
+		var addr [20]byte
+		copy(addr[:], payload[9+i*20:29+i*20])
+		record.CoSigners = append(record.CoSigners, addr)
+	}
+	return record, nil
+}
+
 // Nimiq base32 alphabet (excludes I, O, U, V, W, Z to avoid confusion)
 const nimiqBase32Alphabet = "0123456789ABCDEFGHJKLMNPQRSTUVXY"
 
@@ -208,14 +360,55 @@ func AddressNQToBytes(address string) ([20]byte, error) {
 	return result, nil
 }
 
-// CalculateFileSHA256 calculates SHA256 hash of a file
+// AddressBytesToNQ converts a 20-byte address to the user-friendly
+// "NQxx XXXX ..." format, computing the IBAN check digits
+func AddressBytesToNQ(addr [20]byte) string {
+	// Encode 160 bits as 32 base32 characters
+	body := make([]byte, 0, 32)
+	bitBuffer := uint64(0)
+	bitsInBuffer := 0
+	for _, b := range addr {
+		bitBuffer = (bitBuffer << 8) | uint64(b)
+		bitsInBuffer += 8
+		for bitsInBuffer >= 5 {
+			body = append(body, nimiqBase32Alphabet[(bitBuffer>>(bitsInBuffer-5))&0x1f])
+			bitsInBuffer -= 5
+		}
+	}
+
+	// MOD-97-10 over body + "NQ00", with letters as 10..35
+	remainder := 0
+	for _, c := range string(body) + "NQ00" {
+		digits := string(c)
+		if c >= 'A' && c <= 'Z' {
+			digits = fmt.Sprintf("%d", c-'A'+10)
+		}
+		for _, d := range digits {
+			remainder = (remainder*10 + int(d-'0')) % 97
+		}
+	}
+	full := fmt.Sprintf("NQ%02d%s", 98-remainder, body)
+
+	groups := make([]string, 0, 9)
+	for i := 0; i < len(full); i += 4 {
+		groups = append(groups, full[i:i+4])
+	}
+	return strings.Join(groups, " ")
+}
+
+// CalculateFileSHA256 calculates SHA256 hash of a file (streamed, not read into memory)
 func CalculateFileSHA256(filePath string) ([32]byte, error) {
 	var hash [32]byte
-	data, err := os.ReadFile(filePath)
+	file, err := os.Open(filePath)
 	if err != nil {
 		return hash, err
 	}
-	h := sha256.Sum256(data)
-	copy(hash[:], h[:])
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return hash, err
+	}
+	copy(hash[:], h.Sum(nil))
 	return hash, nil
 }
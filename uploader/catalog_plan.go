@@ -0,0 +1,1009 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v3"
+)
+
+// CatalogSpec is the desired state of a publisher's apps in a catalog, read
+// from a YAML (or JSON) file
+type CatalogSpec struct {
+	Catalog   string    `yaml:"catalog"`
+	Publisher string    `yaml:"publisher"`
+	Apps      []AppSpec `yaml:"apps"`
+
+	dir string // source files are relative to the spec file
+}
+
+// AppSpec is one app in a CatalogSpec. Without app_id the app is matched by
+// title, or gets a new app-id.
+type AppSpec struct {
+	AppID    uint32        `yaml:"app_id"`
+	Title    string        `yaml:"title"`
+	Platform string        `yaml:"platform"`
+	Retired  bool          `yaml:"retired"`
+	Versions []VersionSpec `yaml:"versions"`
+}
+
+// VersionSpec is one version of an app
+type VersionSpec struct {
+	Semver string `yaml:"semver"`
+	File   string `yaml:"file"`
+	Yanked bool   `yaml:"yanked"`
+}
+
+// LoadCatalogSpec reads and validates a desired-state file
+func LoadCatalogSpec(path string) (*CatalogSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	// JSON is valid YAML, so one decoder handles both
+	var spec CatalogSpec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	spec.dir = filepath.Dir(path)
+
+	titles := make(map[string]bool)
+	appIDs := make(map[uint32]bool)
+	for i, app := range spec.Apps {
+		where := fmt.Sprintf("apps[%d] (%q)", i, app.Title)
+		if app.Title == "" || len(app.Title) > 16 {
+			return nil, fmt.Errorf("%s: title must be 1-16 characters", where)
+		}
+		if titles[strings.ToLower(app.Title)] {
+			return nil, fmt.Errorf("%s: duplicate title", where)
+		}
+		titles[strings.ToLower(app.Title)] = true
+		if app.AppID != 0 {
+			if appIDs[app.AppID] {
+				return nil, fmt.Errorf("%s: duplicate app_id %d", where, app.AppID)
+			}
+			appIDs[app.AppID] = true
+		}
+		if _, err := PlatformCode(app.Platform); err != nil {
+			return nil, fmt.Errorf("%s: %w", where, err)
+		}
+
+		semvers := make(map[string]bool)
+		for j, version := range app.Versions {
+			semver, err := parseSemver(version.Semver)
+			if err != nil {
+				return nil, fmt.Errorf("%s versions[%d]: %w", where, j, err)
+			}
+			key := formatSemver(semver)
+			if semvers[key] {
+				return nil, fmt.Errorf("%s: duplicate version %s", where, key)
+			}
+			semvers[key] = true
+			spec.Apps[i].Versions[j].Semver = key
+		}
+	}
+	return &spec, nil
+}
+
+// path resolves a source file relative to the spec file
+func (s *CatalogSpec) path(file string) string {
+	if file == "" || filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(s.dir, file)
+}
+
+func formatSemver(semver [3]uint8) string {
+	return fmt.Sprintf("%d.%d.%d", semver[0], semver[1], semver[2])
+}
+
+// Catalog plan actions
+const (
+	ActionUpload = "upload" // upload a new version (DATA, CART, CENT)
+	ActionYank   = "yank"   // re-send the version's CENT with FlagYanked
+	ActionUnyank = "unyank" // re-send the version's CENT without FlagYanked
+	ActionRetire = "retire" // re-send the latest CENT with FlagRetired
+)
+
+// CatalogAction is one step of a catalog plan
+type CatalogAction struct {
+	Kind     string `json:"kind"`
+	AppID    uint32 `json:"app_id"`
+	Title    string `json:"title"`
+	Platform uint8  `json:"platform"`
+	Semver   string `json:"semver"`
+
+	// ActionUpload
+	File        string `json:"file,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	Chunks      int64  `json:"chunks,omitempty"`
+	SHA256      string `json:"sha256,omitempty"`
+	CartridgeID uint32 `json:"cartridge_id,omitempty"`
+
+	// CENT-only actions: the entry to send
+	Schema        uint8  `json:"schema,omitempty"`
+	Flags         uint8  `json:"flags,omitempty"`
+	CartridgeAddr string `json:"cartridge_addr,omitempty"`
+}
+
+// Key identifies the action in apply progress
+func (a *CatalogAction) Key() string {
+	return fmt.Sprintf("%s:%d:%s", a.Kind, a.AppID, a.Semver)
+}
+
+// String describes the action for plan output
+func (a *CatalogAction) String() string {
+	switch a.Kind {
+	case ActionUpload:
+		return fmt.Sprintf("+ upload  app %d %q %s from %s (%d bytes, %d chunks) as cartridge %d", a.AppID, a.Title, a.Semver, a.File, a.Size, a.Chunks, a.CartridgeID)
+	case ActionRetire:
+		return fmt.Sprintf("- retire  app %d %q (CENT for %s)", a.AppID, a.Title, a.Semver)
+	default:
+		return fmt.Sprintf("~ %-7s app %d %q %s", a.Kind, a.AppID, a.Title, a.Semver)
+	}
+}
+
+// CENT returns the catalog entry a CENT-only action sends
+func (a *CatalogAction) CENT() (CENTEntry, error) {
+	semver, err := parseSemver(a.Semver)
+	if err != nil {
+		return CENTEntry{}, err
+	}
+	cartAddrBytes, err := AddressNQToBytes(a.CartridgeAddr)
+	if err != nil {
+		return CENTEntry{}, fmt.Errorf("invalid cartridge address %s: %w", a.CartridgeAddr, err)
+	}
+	return CENTEntry{
+		Schema:        a.Schema,
+		Platform:      a.Platform,
+		Flags:         a.Flags,
+		AppID:         a.AppID,
+		Semver:        semver,
+		CartridgeAddr: cartAddrBytes,
+		TitleShort:    a.Title,
+	}, nil
+}
+
+// CatalogPlan is the difference between a CatalogSpec and the chain
+type CatalogPlan struct {
+	Catalog   string          `json:"catalog"`
+	Publisher string          `json:"publisher"`
+	Actions   []CatalogAction `json:"actions"`
+	Warnings  []string        `json:"warnings,omitempty"`
+	Conflicts []string        `json:"conflicts,omitempty"` // differences that cannot be applied
+
+	Estimate *UploadEstimate `json:"-"`
+}
+
+// BuildCatalogPlan diffs spec against the on-chain state of the publisher's apps
+func BuildCatalogPlan(spec *CatalogSpec, state map[uint32]*CatalogApp, catalogAddr, publisher string, pool []string, chunkSize uint8, fee int64) (*CatalogPlan, error) {
+	plan := &CatalogPlan{Catalog: catalogAddr, Publisher: publisher}
+	warn := func(format string, args ...interface{}) {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	// New apps get app-ids after everything on chain or in the file
+	nextAppID := uint32(1)
+	byTitle := make(map[string]uint32)
+	for id, app := range state {
+		if id >= nextAppID {
+			nextAppID = id + 1
+		}
+		byTitle[strings.ToLower(strings.TrimSpace(app.Title))] = id
+	}
+	for _, app := range spec.Apps {
+		if app.AppID >= nextAppID {
+			nextAppID = app.AppID + 1
+		}
+	}
+
+	managed := make(map[uint32]bool)
+	var uploads, flags, retires []CatalogAction
+	for _, appSpec := range spec.Apps {
+		platform, _ := PlatformCode(appSpec.Platform) // validated when loading
+
+		appID := appSpec.AppID
+		if appID == 0 {
+			appID = byTitle[strings.ToLower(appSpec.Title)]
+		}
+		if appID == 0 {
+			appID = nextAppID
+			nextAppID++
+		}
+		managed[appID] = true
+
+		chain := state[appID]
+		if chain == nil {
+			chain = &CatalogApp{AppID: appID, Versions: map[string]*CatalogVersion{}}
+		}
+		if len(chain.Versions) > 0 {
+			if !strings.EqualFold(chain.Title, appSpec.Title) {
+				warn("app %d: title on chain is %q, file says %q (only new versions use the new title)", appID, chain.Title, appSpec.Title)
+			}
+			if chain.Platform != platform {
+				warn("app %d %q: platform on chain is %d, file says %s", appID, appSpec.Title, chain.Platform, appSpec.Platform)
+			}
+		}
+		if chain.Retired {
+			if !appSpec.Retired {
+				plan.Conflicts = append(plan.Conflicts, fmt.Sprintf("app %d %q is retired on chain and cannot be un-retired", appID, appSpec.Title))
+			}
+			continue
+		}
+
+		listed := make(map[string]bool)
+		nextCartridgeID := chain.MaxCartridgeID + 1
+		for _, version := range appSpec.Versions {
+			listed[version.Semver] = true
+			onChain := chain.Versions[version.Semver]
+
+			if onChain == nil {
+				switch {
+				case version.Yanked:
+					warn("app %d %q %s: yanked but never published, skipped", appID, appSpec.Title, version.Semver)
+				case appSpec.Retired:
+					warn("app %d %q %s: not uploaded because the app is retired", appID, appSpec.Title, version.Semver)
+				default:
+					action, err := planUpload(spec, appID, appSpec, platform, version, chunkSize)
+					if err != nil {
+						return nil, err
+					}
+					action.CartridgeID = nextCartridgeID
+					nextCartridgeID++
+					uploads = append(uploads, action)
+				}
+				continue
+			}
+
+			// Published content cannot change: a different file needs a new semver
+			if version.File != "" && onChain.HasCART {
+				sum, err := CalculateFileSHA256(spec.path(version.File))
+				if err != nil {
+					return nil, fmt.Errorf("app %q %s: failed to hash %s: %w", appSpec.Title, version.Semver, version.File, err)
+				}
+				if hex.EncodeToString(sum[:]) != onChain.SHA256 {
+					plan.Conflicts = append(plan.Conflicts, fmt.Sprintf("app %d %q %s: %s differs from the published cartridge (SHA256 %s) - bump the semver", appID, appSpec.Title, version.Semver, version.File, shortHash(onChain.SHA256)))
+				}
+			}
+			if !onChain.HasCART {
+				warn("app %d %q %s: CART header not found at %s", appID, appSpec.Title, version.Semver, onChain.CartridgeAddr)
+			}
+
+			yanked := onChain.Entry.Flags&FlagYanked != 0
+			if version.Yanked != yanked {
+				action := centAction(ActionYank, onChain, onChain.Entry.Flags|FlagYanked)
+				if !version.Yanked {
+					action = centAction(ActionUnyank, onChain, onChain.Entry.Flags&^FlagYanked)
+				}
+				flags = append(flags, action)
+			}
+		}
+
+		var published []string
+		for semver := range chain.Versions {
+			published = append(published, semver)
+			if !listed[semver] {
+				warn("app %d %q %s: on chain but not in the file (left alone)", appID, appSpec.Title, semver)
+			}
+		}
+		sort.Strings(published)
+
+		if appSpec.Retired {
+			if len(published) == 0 {
+				warn("app %d %q: retired but never published, skipped", appID, appSpec.Title)
+				continue
+			}
+			// Any entry with the retired flag retires the whole app; use the newest
+			var latest *CatalogVersion
+			for _, semver := range published {
+				if v := chain.Versions[semver]; latest == nil || v.Height > latest.Height {
+					latest = v
+				}
+			}
+			retires = append(retires, centAction(ActionRetire, latest, latest.Entry.Flags|FlagRetired))
+		}
+	}
+
+	var unmanaged []uint32
+	for id := range state {
+		if !managed[id] {
+			unmanaged = append(unmanaged, id)
+		}
+	}
+	sort.Slice(unmanaged, func(i, j int) bool { return unmanaged[i] < unmanaged[j] })
+	for _, id := range unmanaged {
+		warn("app %d %q: on chain but not in the file (left alone)", id, state[id].Title)
+	}
+
+	// Uploads first, so yanking an old version never leaves an app without one
+	plan.Actions = append(append(uploads, flags...), retires...)
+
+	plan.Estimate = EstimateUpload(publisher, nil, 0, false, false, nil, fee)
+	for _, action := range plan.Actions {
+		if action.Kind == ActionUpload {
+			plan.Estimate.Add(EstimateUpload(publisher, pool, action.Chunks, true, true, nil, fee))
+		} else {
+			plan.Estimate.Add(EstimateUpload(publisher, nil, 0, false, true, nil, fee))
+		}
+	}
+	return plan, nil
+}
+
+// planUpload prepares the upload of a version that is not on chain yet
+func planUpload(spec *CatalogSpec, appID uint32, app AppSpec, platform uint8, version VersionSpec, chunkSize uint8) (CatalogAction, error) {
+	if version.File == "" {
+		return CatalogAction{}, fmt.Errorf("app %q %s: file is required to publish a version", app.Title, version.Semver)
+	}
+	path := spec.path(version.File)
+	info, err := os.Stat(path)
+	if err != nil {
+		return CatalogAction{}, fmt.Errorf("app %q %s: %w", app.Title, version.Semver, err)
+	}
+	chunks, _, err := ValidateChunkLayout(info.Size(), chunkSize)
+	if err != nil {
+		return CatalogAction{}, fmt.Errorf("app %q %s: %w", app.Title, version.Semver, err)
+	}
+	sum, err := CalculateFileSHA256(path)
+	if err != nil {
+		return CatalogAction{}, fmt.Errorf("app %q %s: failed to hash %s: %w", app.Title, version.Semver, path, err)
+	}
+	return CatalogAction{
+		Kind:     ActionUpload,
+		AppID:    appID,
+		Title:    app.Title,
+		Platform: platform,
+		Semver:   version.Semver,
+		File:     path,
+		Size:     info.Size(),
+		Chunks:   chunks,
+		SHA256:   hex.EncodeToString(sum[:]),
+		Schema:   1,
+	}, nil
+}
+
+// centAction re-sends the CENT entry of a published version with new flags
+func centAction(kind string, version *CatalogVersion, flags uint8) CatalogAction {
+	return CatalogAction{
+		Kind:          kind,
+		AppID:         version.Entry.AppID,
+		Title:         version.Entry.TitleShort,
+		Platform:      version.Entry.Platform,
+		Semver:        version.Semver(),
+		Schema:        version.Entry.Schema,
+		Flags:         flags,
+		CartridgeAddr: version.CartridgeAddr,
+	}
+}
+
+// Print writes the plan in the usual summary format
+func (p *CatalogPlan) Print() {
+	fmt.Printf("Catalog: %s\n", p.Catalog)
+	fmt.Printf("Publisher: %s\n\n", p.Publisher)
+
+	if len(p.Warnings) > 0 {
+		for _, w := range p.Warnings {
+			fmt.Printf("⚠️  %s\n", w)
+		}
+		fmt.Println()
+	}
+	if len(p.Conflicts) > 0 {
+		for _, c := range p.Conflicts {
+			fmt.Printf("✗ %s\n", c)
+		}
+		fmt.Println()
+	}
+
+	if len(p.Actions) == 0 {
+		fmt.Println("✓ Catalog is up to date, nothing to do")
+		return
+	}
+	fmt.Printf("Planned actions (%d):\n", len(p.Actions))
+	for i := range p.Actions {
+		fmt.Printf("  %s\n", p.Actions[i].String())
+	}
+	fmt.Println("\n=== Cost estimate ===")
+	p.Estimate.Print()
+}
+
+// CatalogPlanFile is what plan writes for apply --plan: the actions and a
+// hash over them, so apply can tell that it is about to send what was reviewed
+type CatalogPlanFile struct {
+	CreatedAt string          `json:"created_at"`
+	Spec      string          `json:"spec"`
+	Catalog   string          `json:"catalog"`
+	Publisher string          `json:"publisher"`
+	Actions   []CatalogAction `json:"actions"`
+	PlanHash  string          `json:"plan_hash"`
+}
+
+// Hash returns the SHA256 over the catalog, publisher and actions of the plan
+func (p *CatalogPlan) Hash() (string, error) {
+	data, err := json.Marshal(struct {
+		Catalog   string          `json:"catalog"`
+		Publisher string          `json:"publisher"`
+		Actions   []CatalogAction `json:"actions"`
+	}{normalizeAddress(p.Catalog), normalizeAddress(p.Publisher), p.Actions})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash plan: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// WriteFile stores the plan and its hash for apply --plan
+func (p *CatalogPlan) WriteFile(path, specPath string) (string, error) {
+	hash, err := p.Hash()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(CatalogPlanFile{
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Spec:      specPath,
+		Catalog:   p.Catalog,
+		Publisher: p.Publisher,
+		Actions:   p.Actions,
+		PlanHash:  hash,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write plan %s: %w", path, err)
+	}
+	return hash, nil
+}
+
+// CheckPlanFile refuses a plan file that was edited or whose actions differ
+// from p (the chain or the desired-state file changed since it was written).
+// Actions of the file that p no longer has are accepted if progress records
+// them as sent since the plan was written: an interrupted apply of the plan
+// carried them out and they landed on chain. The other actions must match p
+// exactly and in order.
+func (p *CatalogPlan) CheckPlanFile(path string, progress *CatalogApplyProgress) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plan: %w", err)
+	}
+	var saved CatalogPlanFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	savedPlan := CatalogPlan{Catalog: saved.Catalog, Publisher: saved.Publisher, Actions: saved.Actions}
+	savedHash, err := savedPlan.Hash()
+	if err != nil {
+		return err
+	}
+	if savedHash != saved.PlanHash {
+		return fmt.Errorf("plan %s was modified after it was written (hash %s, recorded %s)", path, savedHash, saved.PlanHash)
+	}
+
+	planned := make(map[string]bool, len(p.Actions))
+	for i := range p.Actions {
+		planned[p.Actions[i].Key()] = true
+	}
+	createdAt, _ := time.Parse(time.RFC3339, saved.CreatedAt)
+	remaining := CatalogPlan{Catalog: saved.Catalog, Publisher: saved.Publisher}
+	var carriedOut []string
+	for _, action := range saved.Actions {
+		key := action.Key()
+		if !planned[key] {
+			if done := progress.Actions[key]; done != nil && done.Done {
+				sentAt, err := time.Parse(time.RFC3339, done.UpdatedAt)
+				if err == nil && !sentAt.Before(createdAt) {
+					carriedOut = append(carriedOut, key)
+					continue
+				}
+			}
+			return fmt.Errorf("%s of plan %s is no longer needed, but was not sent by apply since the plan was made: the chain or the file changed - run plan again and review it",
+				key, path)
+		}
+		remaining.Actions = append(remaining.Actions, action)
+	}
+
+	remainingHash, err := remaining.Hash()
+	if err != nil {
+		return err
+	}
+	hash, err := p.Hash()
+	if err != nil {
+		return err
+	}
+	if hash != remainingHash {
+		return fmt.Errorf("the actions differ from plan %s made %s (hash %s, now %s): the chain or the file changed - run plan again and review it",
+			path, saved.CreatedAt, shortHash(remainingHash), shortHash(hash))
+	}
+	if len(carriedOut) > 0 {
+		fmt.Printf("Already carried out from plan %s: %s\n", path, strings.Join(carriedOut, ", "))
+	}
+	return nil
+}
+
+// defaultCatalogPlanPath is where plan writes and apply --plan looks by default
+func defaultCatalogPlanPath(catalogAddr string) string {
+	return fmt.Sprintf("catalog_plan_%s.json", strings.ToLower(normalizeAddress(catalogAddr)))
+}
+
+// confirmApply asks on the terminal before apply sends anything
+func confirmApply(actions int) error {
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("refusing to send without confirmation: pass --yes, or --plan with a reviewed plan file")
+	}
+	fmt.Fprintf(os.Stderr, "\nSend the transactions for %d action(s)? Type 'yes' to continue: ", actions)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if strings.TrimSpace(line) != "yes" {
+		return fmt.Errorf("apply cancelled, nothing was sent")
+	}
+	return nil
+}
+
+// sentTxValidity is how long a sent transaction can still be included in a
+// block (7200 blocks of ~1s); apply waits that long before re-sending
+const sentTxValidity = 2 * time.Hour
+
+// CatalogActionProgress is what apply recorded for one action
+type CatalogActionProgress struct {
+	Done          bool   `json:"done"`
+	TxHash        string `json:"tx_hash,omitempty"` // the CENT entry
+	AppID         uint32 `json:"app_id,omitempty"`
+	CartridgeID   uint32 `json:"cartridge_id,omitempty"`
+	CartridgeAddr string `json:"cartridge_addr,omitempty"` // generated for uploads, reused on resume
+	Error         string `json:"error,omitempty"`
+	UpdatedAt     string `json:"updated_at"`
+	// Landed is set once the chain shows the action; the entry is kept so
+	// apply --plan can tell which actions of the plan were carried out
+	Landed bool `json:"landed,omitempty"`
+}
+
+// CatalogApplyProgress is the resumable state of apply runs for one catalog.
+// Sent actions stay skipped until the chain shows them, so a rerun right after
+// an apply does not send anything twice.
+type CatalogApplyProgress struct {
+	Catalog   string                            `json:"catalog"`
+	NetworkID uint8                             `json:"network_id,omitempty"` // network of the node the actions were sent to
+	Actions   map[string]*CatalogActionProgress `json:"actions"`
+
+	path string
+}
+
+func loadCatalogApplyProgress(path, catalogAddr string) (*CatalogApplyProgress, error) {
+	progress := &CatalogApplyProgress{Catalog: catalogAddr, Actions: make(map[string]*CatalogActionProgress), path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return progress, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, progress); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if normalizeAddress(progress.Catalog) != normalizeAddress(catalogAddr) {
+		return nil, fmt.Errorf("%s belongs to catalog %s, not %s (use --progress)", path, progress.Catalog, catalogAddr)
+	}
+	if progress.Actions == nil {
+		progress.Actions = make(map[string]*CatalogActionProgress)
+	}
+	return progress, nil
+}
+
+func (p *CatalogApplyProgress) record(key string, action *CatalogActionProgress) error {
+	action.UpdatedAt = time.Now().Format(time.RFC3339)
+	p.Actions[key] = action
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p.path, data, 0644)
+}
+
+// catalogPlanOptions are the flags shared by plan and apply
+type catalogPlanOptions struct {
+	catalogAddr string
+	sender      string
+	senders     string
+	rpcURL      string
+	feeFlag     string
+	chunkSize   uint8
+}
+
+func (o *catalogPlanOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.catalogAddr, "catalog-addr", "", "Catalog address (NQ..., 'main', 'test'; overrides catalog in the file)")
+	cmd.Flags().StringVar(&o.sender, "sender", "", "Publisher address (overrides publisher in the file; defaults to address from credentials.json)")
+	cmd.Flags().StringVar(&o.senders, "senders", "", "Comma-separated sender pool for DATA chunks (defaults to senders from credentials)")
+	cmd.Flags().StringVar(&o.rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
+	cmd.Flags().StringVar(&o.feeFlag, "fee", "0", "Transaction fee: a fixed amount in Luna per transaction, or auto, min or fast")
+	cmd.Flags().Uint8Var(&o.chunkSize, "chunk-size", 51, "Chunk size in bytes for new uploads (default: 51)")
+}
+
+// build loads the spec and the chain state and computes the plan
+func (o *catalogPlanOptions) build(ctx context.Context, specPath string) (*CatalogPlan, *NimiqRPC, *FeeOracle, error) {
+	spec, err := LoadCatalogSpec(specPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Get RPC URL from env, credentials file, or default
+	if o.rpcURL == "" {
+		o.rpcURL = GetDefaultRPCURL()
+	}
+	if o.catalogAddr == "" {
+		o.catalogAddr = spec.Catalog
+	}
+	if o.catalogAddr == "" {
+		return nil, nil, nil, fmt.Errorf("catalog address is required (catalog in the file or --catalog-addr)")
+	}
+	o.catalogAddr = resolveCatalogAddress(o.catalogAddr)
+	if o.sender == "" {
+		o.sender = spec.Publisher
+	}
+	if o.sender == "" {
+		o.sender = GetDefaultAddress()
+	}
+	if o.sender == "" {
+		return nil, nil, nil, fmt.Errorf("publisher address is required (publisher in the file, --sender or address in credentials.json)")
+	}
+	if o.chunkSize == 0 {
+		o.chunkSize = 51
+	}
+
+	feePolicy, err := ParseFeePolicy(o.feeFlag)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pool := parseAddressList(o.senders)
+	if o.senders == "" {
+		pool = GetDefaultSenders()
+	}
+
+	rpc := NewNimiqRPC(o.rpcURL).WithContext(ctx)
+	feeOracle := NewFeeOracle(rpc, feePolicy)
+	fee, err := feeOracle.Fee()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to determine fee (use --fee <luna> to set it explicitly): %w", err)
+	}
+
+	fmt.Printf("Reading catalog state from %s...\n", o.rpcURL)
+	state, err := LoadCatalogState(rpc, o.catalogAddr, o.sender)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	plan, err := BuildCatalogPlan(spec, state, o.catalogAddr, o.sender, pool, o.chunkSize, fee)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return plan, rpc, feeOracle, nil
+}
+
+func newPlanCmd() *cobra.Command {
+	var (
+		opts    = &catalogPlanOptions{}
+		outPath string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "plan <catalog.yaml>",
+		Short: "Show the transactions needed to make the catalog match a desired-state file",
+		Long: `Compare a desired-state file (YAML or JSON) with the publisher's CENT entries
+and CART headers on chain, and print what 'apply' would send and what it costs.
+
+Example file:
+
+  catalog: test
+  apps:
+    - title: Doom
+      platform: DOS
+      versions:
+        - semver: 1.0.0
+          file: games/doom.zip
+          yanked: true
+        - semver: 1.1.0
+          file: games/doom-1.1.zip
+    - app_id: 7
+      title: Keen
+      platform: DOS
+      retired: true
+
+Versions missing on chain are uploaded. Changing 'yanked' or 'retired' re-sends
+the CENT entry with the flag set or cleared. Apps and versions on chain that
+are not in the file are left alone.
+
+The plan is written with its hash to --out (default
+catalog_plan_<catalog>.json). 'apply --plan' sends it only if the actions it
+computes are still exactly the same.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, _, _, err := opts.build(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Println()
+			plan.Print()
+			if len(plan.Conflicts) > 0 {
+				return fmt.Errorf("%d conflict(s) must be fixed in the file before applying", len(plan.Conflicts))
+			}
+			if len(plan.Actions) == 0 {
+				return nil
+			}
+
+			if outPath == "" {
+				outPath = defaultCatalogPlanPath(plan.Catalog)
+			}
+			hash, err := plan.WriteFile(outPath, args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("\n📝 Plan written to %s (hash %s)\n", outPath, hash)
+			fmt.Printf("  Review it, then send it with:\n    nimiq-uploader apply %s --plan %s\n", args[0], outPath)
+			return nil
+		},
+	}
+	opts.addFlags(cmd)
+	cmd.Flags().StringVar(&outPath, "out", "", "Plan file to write (default: catalog_plan_<catalog>.json)")
+
+	return cmd
+}
+
+func newApplyCmd() *cobra.Command {
+	var (
+		opts             = &catalogPlanOptions{}
+		progressPath     string
+		keyPassphrase    string
+		rateLimit        float64
+		concurrency      int
+		maxSize          string
+		waitForFundsFlag bool
+		skipPreflight    bool
+		planPath         string
+		yes              bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply <catalog.yaml>",
+		Short: "Send the transactions that make the catalog match a desired-state file",
+		Long: `Compute the same plan as 'plan' and carry it out: upload new versions (with
+generated cartridge addresses), then re-send CENT entries for yanked, un-yanked
+and retired versions.
+
+Nothing is sent without a confirmation: with --plan <file> (written by 'plan')
+apply refuses unless it computes exactly the actions in that file, less those
+an earlier apply of it already carried out; otherwise it asks on the terminal,
+or --yes skips the question.
+
+Progress is kept in a file (--progress). An interrupted apply resumes uploads
+with the same IDs and cartridge addresses, and actions that were already sent
+are not sent again.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			plan, rpc, feeOracle, err := opts.build(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Println()
+			plan.Print()
+			if len(plan.Conflicts) > 0 {
+				return fmt.Errorf("%d conflict(s) must be fixed in the file before applying", len(plan.Conflicts))
+			}
+			if progressPath == "" {
+				progressPath = fmt.Sprintf("catalog_apply_%s.json", strings.ToLower(normalizeAddress(plan.Catalog)))
+			}
+			progress, err := loadCatalogApplyProgress(progressPath, plan.Catalog)
+			if err != nil {
+				return err
+			}
+			if planPath != "" {
+				if err := plan.CheckPlanFile(planPath, progress); err != nil {
+					cmd.SilenceUsage = true
+					return err
+				}
+				fmt.Printf("\n✓ Actions match the plan in %s\n", planPath)
+			}
+			if len(plan.Actions) == 0 {
+				return nil
+			}
+
+			// Check consensus before proceeding
+			consensus, err := rpc.IsConsensusEstablished()
+			if err != nil {
+				return fmt.Errorf("failed to check consensus: %w", err)
+			}
+			if !consensus {
+				return fmt.Errorf("node does not have consensus with the network - wait for sync")
+			}
+			networkID, err := checkNodeNetwork(rpc, plan.Catalog, "")
+			if err != nil {
+				return err
+			}
+
+			if !skipPreflight {
+				fmt.Println()
+				if err := preflightBalances(ctx, rpc, plan.Estimate, waitForFundsFlag); err != nil {
+					return err
+				}
+			}
+
+			if planPath == "" && !yes {
+				if err := confirmApply(len(plan.Actions)); err != nil {
+					cmd.SilenceUsage = true
+					return err
+				}
+			}
+
+			if err := checkProgressNetwork(progressPath, progress.NetworkID, networkID); err != nil {
+				return err
+			}
+			if progress.NetworkID == 0 {
+				progress.NetworkID = networkID
+			}
+
+			// Actions that are no longer planned have landed on chain
+			planned := make(map[string]bool)
+			for i := range plan.Actions {
+				planned[plan.Actions[i].Key()] = true
+			}
+			for key, action := range progress.Actions {
+				if !planned[key] {
+					action.Landed = true
+				}
+			}
+
+			limiter := rate.NewLimiter(rate.Limit(rateLimit), 1)
+			applied, skipped := 0, 0
+			for i := range plan.Actions {
+				action := &plan.Actions[i]
+				fmt.Printf("\n[%d/%d] %s\n", i+1, len(plan.Actions), action)
+
+				previous := progress.Actions[action.Key()]
+				if previous != nil && previous.Landed {
+					previous = nil // planned again: an earlier action with the same key landed
+				}
+				if previous != nil && previous.Done {
+					sentAt, _ := time.Parse(time.RFC3339, previous.UpdatedAt)
+					if time.Since(sentAt) < sentTxValidity {
+						fmt.Printf("  already sent (%s), waiting for it to show up on chain\n", previous.TxHash)
+						skipped++
+						continue
+					}
+					fmt.Printf("  sent %s but still not on chain, sending again\n", previous.UpdatedAt)
+					previous.Done = false
+				}
+
+				var result *CatalogActionProgress
+				if action.Kind == ActionUpload {
+					result, err = applyUpload(ctx, opts, action, previous, keyPassphrase, limiter, rateLimit, concurrency, maxSize)
+				} else {
+					result, err = applyCENT(ctx, opts, action, feeOracle, limiter)
+				}
+				if err != nil {
+					result.Error = err.Error()
+				}
+				if rerr := progress.record(action.Key(), result); rerr != nil {
+					fmt.Printf("Warning: failed to write %s: %v\n", progressPath, rerr)
+				}
+				if err != nil {
+					return fmt.Errorf("%s failed: %w (progress saved to %s, run apply again to resume)", action.Key(), err, progressPath)
+				}
+				applied++
+			}
+
+			fmt.Printf("\n✅ Applied %d action(s)", applied)
+			if skipped > 0 {
+				fmt.Printf(", %d already sent earlier", skipped)
+			}
+			fmt.Printf(" - progress in %s\n", progressPath)
+			return nil
+		},
+	}
+	opts.addFlags(cmd)
+	cmd.Flags().StringVar(&progressPath, "progress", "", "Apply progress file (default: catalog_apply_<catalog>.json)")
+	cmd.Flags().StringVar(&keyPassphrase, "key-passphrase", "", "Passphrase to encrypt generated cartridge keys (defaults to NIMIQ_KEY_PASSPHRASE or the account passphrase)")
+	cmd.Flags().Float64Var(&rateLimit, "rate", 25.0, "Transaction rate limit (tx/s, default: 25)")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 5, "Parallel upload workers per cartridge (max: 10)")
+	cmd.Flags().StringVar(&maxSize, "max-size", "6MB", "Maximum file size to upload (e.g. 6MB, 100MB, 0 = no limit)")
+	cmd.Flags().BoolVar(&waitForFundsFlag, "wait-for-funds", false, "Wait for the sender account(s) to be funded instead of refusing to start")
+	cmd.Flags().BoolVar(&skipPreflight, "skip-preflight", false, "Skip the balance check")
+	cmd.Flags().StringVar(&planPath, "plan", "", "Plan file written by 'plan'; refuse to send if the actions differ from it")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Send without asking for confirmation")
+
+	return cmd
+}
+
+// applyUpload uploads a new version, resuming with the IDs and cartridge
+// address of an earlier attempt
+func applyUpload(ctx context.Context, opts *catalogPlanOptions, action *CatalogAction, previous *CatalogActionProgress, keyPassphrase string, limiter *rate.Limiter, rateLimit float64, concurrency int, maxSize string) (*CatalogActionProgress, error) {
+	u := &cartridgeUpload{
+		filePath:         action.File,
+		appID:            action.AppID,
+		cartridgeID:      action.CartridgeID,
+		title:            action.Title,
+		semver:           action.Semver,
+		platform:         action.Platform,
+		catalogAddr:      opts.catalogAddr,
+		sender:           opts.sender,
+		senders:          opts.senders,
+		rateLimit:        rateLimit,
+		rpcURL:           opts.rpcURL,
+		feeFlag:          opts.feeFlag,
+		generateCartAddr: true,
+		keyPassphrase:    keyPassphrase,
+		skipPreflight:    true, // covered by the plan's preflight
+		schema:           action.Schema,
+		chunkSize:        opts.chunkSize,
+		concurrency:      concurrency,
+		maxSize:          maxSize,
+		limiter:          limiter,
+		noResumeHint:     true,
+	}
+	if previous != nil && previous.CartridgeAddr != "" {
+		u.appID, u.cartridgeID, u.cartridgeAddr = previous.AppID, previous.CartridgeID, previous.CartridgeAddr
+		u.generateCartAddr = false
+		fmt.Printf("  resuming with cartridge %d at %s\n", u.cartridgeID, u.cartridgeAddr)
+	}
+
+	uploadProgress, err := u.run(ctx)
+	result := &CatalogActionProgress{AppID: u.appID, CartridgeID: u.cartridgeID, CartridgeAddr: u.cartridgeAddr}
+	if err != nil {
+		return result, err
+	}
+	if uploadProgress.CENTTxHash == "" {
+		return result, fmt.Errorf("not all chunks were sent (%d/%d)", uploadProgress.SentChunks, uploadProgress.TotalChunks)
+	}
+	result.Done = true
+	result.TxHash = uploadProgress.CENTTxHash
+	return result, nil
+}
+
+// applyCENT sends the CENT entry of a yank, unyank or retire action
+func applyCENT(ctx context.Context, opts *catalogPlanOptions, action *CatalogAction, feeOracle *FeeOracle, limiter *rate.Limiter) (*CatalogActionProgress, error) {
+	result := &CatalogActionProgress{AppID: action.AppID, CartridgeAddr: action.CartridgeAddr}
+
+	entry, err := action.CENT()
+	if err != nil {
+		return result, err
+	}
+	payload, err := EncodeCENT(entry)
+	if err != nil {
+		return result, fmt.Errorf("failed to encode CENT entry: %w", err)
+	}
+
+	fee, err := feeOracle.Fee()
+	if err != nil {
+		return result, fmt.Errorf("failed to determine fee: %w", err)
+	}
+	sender, err := NewRPCSender(opts.rpcURL, opts.sender, opts.catalogAddr, fee)
+	if err != nil {
+		return result, fmt.Errorf("failed to initialize catalog RPC sender: %w", err)
+	}
+	if err := limiter.Wait(ctx); err != nil {
+		return result, err
+	}
+	txHash, err := sender.WithContext(ctx).WithFeeOracle(feeOracle).SendTransaction(payload)
+	if err != nil {
+		return result, fmt.Errorf("failed to send CENT entry: %w", err)
+	}
+
+	fmt.Printf("✓ CENT entry sent to catalog: %s\n", txHash)
+	logCartridgeUpload(fmt.Sprintf("Catalog %s app %d %s: CENT entry sent: %s", action.Kind, action.AppID, action.Semver, txHash))
+	result.Done = true
+	result.TxHash = txHash
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apply --plan accepts a plan that an interrupted apply partly carried out,
+// as long as what is left matches it exactly
+func TestCheckPlanFile(t *testing.T) {
+	yank := func(appID uint32, semver string) CatalogAction {
+		return CatalogAction{Kind: ActionYank, AppID: appID, Title: "Game", Semver: semver, Schema: 1, Flags: FlagYanked,
+			CartridgeAddr: testCartridgeAddr(byte(appID))}
+	}
+	saved := &CatalogPlan{Catalog: resolveCatalogAddress("test"), Publisher: testCartridgeAddr(9),
+		Actions: []CatalogAction{yank(1, "1.0.0"), yank(2, "1.0.0"), yank(3, "1.0.0")}}
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if _, err := saved.WriteFile(path, "catalog.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	before := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	changed := yank(3, "1.0.0")
+	changed.Title = "Renamed"
+
+	tests := []struct {
+		name     string
+		actions  []CatalogAction
+		progress map[string]*CatalogActionProgress
+		err      string
+	}{
+		{name: "unchanged", actions: saved.Actions},
+		{name: "first action carried out", actions: saved.Actions[1:],
+			progress: map[string]*CatalogActionProgress{"yank:1:1.0.0": {Done: true, UpdatedAt: now}}},
+		{name: "all carried out",
+			progress: map[string]*CatalogActionProgress{
+				"yank:1:1.0.0": {Done: true, UpdatedAt: now, Landed: true},
+				"yank:2:1.0.0": {Done: true, UpdatedAt: now},
+				"yank:3:1.0.0": {Done: true, UpdatedAt: now},
+			}},
+		{name: "sent but pending", actions: saved.Actions,
+			progress: map[string]*CatalogActionProgress{"yank:1:1.0.0": {Done: true, UpdatedAt: now}}},
+		{name: "missing without progress", actions: saved.Actions[1:], err: "yank:1:1.0.0 of plan"},
+		{name: "missing, failed", actions: saved.Actions[1:],
+			progress: map[string]*CatalogActionProgress{"yank:1:1.0.0": {Error: "failed", UpdatedAt: now}}, err: "yank:1:1.0.0 of plan"},
+		{name: "missing, sent before the plan", actions: saved.Actions[1:],
+			progress: map[string]*CatalogActionProgress{"yank:1:1.0.0": {Done: true, UpdatedAt: before}}, err: "yank:1:1.0.0 of plan"},
+		{name: "remaining action changed", actions: []CatalogAction{saved.Actions[1], changed},
+			progress: map[string]*CatalogActionProgress{"yank:1:1.0.0": {Done: true, UpdatedAt: now}}, err: "the actions differ"},
+		{name: "new action", actions: append(append([]CatalogAction(nil), saved.Actions...), yank(4, "1.0.0")), err: "the actions differ"},
+		{name: "reordered", actions: []CatalogAction{saved.Actions[2], saved.Actions[1]},
+			progress: map[string]*CatalogActionProgress{"yank:1:1.0.0": {Done: true, UpdatedAt: now}}, err: "the actions differ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress := &CatalogApplyProgress{Catalog: saved.Catalog, Actions: tt.progress}
+			if progress.Actions == nil {
+				progress.Actions = make(map[string]*CatalogActionProgress)
+			}
+			current := &CatalogPlan{Catalog: saved.Catalog, Publisher: saved.Publisher, Actions: tt.actions}
+			err := current.CheckPlanFile(path, progress)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("refused: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error %v, expected one containing %q", err, tt.err)
+			}
+		})
+	}
+
+	// An edited plan file is refused even if the actions match
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(string(data), `"semver": "1.0.0"`, `"semver": "1.0.1"`, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	progress := &CatalogApplyProgress{Catalog: saved.Catalog, Actions: make(map[string]*CatalogActionProgress)}
+	if err := saved.CheckPlanFile(path, progress); err == nil || !strings.Contains(err.Error(), "was modified") {
+		t.Errorf("edited plan: error %v, expected it to be refused as modified", err)
+	}
+}
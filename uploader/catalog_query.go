@@ -302,62 +302,9 @@ func GetAllTransactionsByAddress(rpc *NimiqRPC, address string, maxPerPage int)
 			return nil, fmt.Errorf("failed to call getTransactionsByAddress: %w", err)
 		}
 
-		// Parse response - RPC returns {"data": [...]} format
-		var responseWrapper struct {
-			Data []Transaction `json:"data"`
-		}
-
-		var txs []Transaction
-		if err := json.Unmarshal(result, &responseWrapper); err == nil && len(responseWrapper.Data) > 0 {
-			// Successfully parsed from "data" field
-			txs = responseWrapper.Data
-		} else {
-			// Try direct array format
-			if err := json.Unmarshal(result, &txs); err != nil {
-				// Try wrapped format with "transactions" field
-				var wrapped struct {
-					Transactions []Transaction `json:"transactions"`
-				}
-				if err2 := json.Unmarshal(result, &wrapped); err2 == nil {
-					txs = wrapped.Transactions
-				} else {
-					// Try as map to extract from various fields
-					var resultMap map[string]interface{}
-					if err3 := json.Unmarshal(result, &resultMap); err3 == nil {
-						// Try to extract transactions from various possible fields
-						if txsRaw, ok := resultMap["data"]; ok {
-							if txsBytes, err := json.Marshal(txsRaw); err == nil {
-								json.Unmarshal(txsBytes, &txs)
-							}
-						} else if txsRaw, ok := resultMap["transactions"]; ok {
-							if txsBytes, err := json.Marshal(txsRaw); err == nil {
-								json.Unmarshal(txsBytes, &txs)
-							}
-						} else if txsRaw, ok := resultMap["result"]; ok {
-							if txsBytes, err := json.Marshal(txsRaw); err == nil {
-								json.Unmarshal(txsBytes, &txs)
-							}
-						}
-					}
-
-					// If still no transactions, log the error
-					if len(txs) == 0 {
-						responsePreview := string(result)
-						if len(responsePreview) > 1000 {
-							responsePreview = responsePreview[:1000] + "..."
-						}
-						fmt.Printf("Failed to parse transactions. Response: %s\n", responsePreview)
-						return nil, fmt.Errorf("failed to parse transactions: %w (tried multiple formats)", err)
-					}
-				}
-			}
-		}
-
-		// Normalize transactions: use blockNumber as height if height is 0
-		for i := range txs {
-			if txs[i].Height == 0 && txs[i].BlockNumber > 0 {
-				txs[i].Height = txs[i].BlockNumber
-			}
+		txs, err := parseTransactionsPage(result)
+		if err != nil {
+			return nil, err
 		}
 
 		if len(txs) == 0 {
@@ -377,3 +324,194 @@ func GetAllTransactionsByAddress(rpc *NimiqRPC, address string, maxPerPage int)
 
 	return allTxs, nil
 }
+
+// parseTransactionsPage parses a page of getTransactionsByAddress in the
+// shapes nodes and proxies answer with
+func parseTransactionsPage(result json.RawMessage) ([]Transaction, error) {
+	// Parse response - RPC returns {"data": [...]} format
+	var responseWrapper struct {
+		Data []Transaction `json:"data"`
+	}
+
+	var txs []Transaction
+	if err := json.Unmarshal(result, &responseWrapper); err == nil && len(responseWrapper.Data) > 0 {
+		// Successfully parsed from "data" field
+		txs = responseWrapper.Data
+	} else {
+		// Try direct array format
+		if err := json.Unmarshal(result, &txs); err != nil {
+			// Try wrapped format with "transactions" field
+			var wrapped struct {
+				Transactions []Transaction `json:"transactions"`
+			}
+			if err2 := json.Unmarshal(result, &wrapped); err2 == nil {
+				txs = wrapped.Transactions
+			} else {
+				// Try as map to extract from various fields
+				var resultMap map[string]interface{}
+				if err3 := json.Unmarshal(result, &resultMap); err3 == nil {
+					// Try to extract transactions from various possible fields
+					if txsRaw, ok := resultMap["data"]; ok {
+						if txsBytes, err := json.Marshal(txsRaw); err == nil {
+							json.Unmarshal(txsBytes, &txs)
+						}
+					} else if txsRaw, ok := resultMap["transactions"]; ok {
+						if txsBytes, err := json.Marshal(txsRaw); err == nil {
+							json.Unmarshal(txsBytes, &txs)
+						}
+					} else if txsRaw, ok := resultMap["result"]; ok {
+						if txsBytes, err := json.Marshal(txsRaw); err == nil {
+							json.Unmarshal(txsBytes, &txs)
+						}
+					}
+				}
+
+				// If still no transactions, log the error
+				if len(txs) == 0 {
+					responsePreview := string(result)
+					if len(responsePreview) > 1000 {
+						responsePreview = responsePreview[:1000] + "..."
+					}
+					fmt.Printf("Failed to parse transactions. Response: %s\n", responsePreview)
+					return nil, fmt.Errorf("failed to parse transactions: %w (tried multiple formats)", err)
+				}
+			}
+		}
+	}
+
+	// Normalize transactions: use blockNumber as height if height is 0
+	for i := range txs {
+		if txs[i].Height == 0 && txs[i].BlockNumber > 0 {
+			txs[i].Height = txs[i].BlockNumber
+		}
+	}
+	return txs, nil
+}
+
+// transactionPayload returns the decoded data of a transaction, or nil if it
+// carries no 64-byte payload
+func transactionPayload(tx Transaction) []byte {
+	dataHex := tx.Data
+	if dataHex == "" {
+		dataHex = tx.RecipientData
+	}
+	if dataHex == "" {
+		dataHex = tx.SenderData
+	}
+	data, err := hex.DecodeString(dataHex)
+	if err != nil || len(data) < 64 {
+		return nil
+	}
+	return data
+}
+
+// CatalogVersion is the on-chain state of one app version: the latest CENT
+// entry for its app-id/semver plus the CART header of its cartridge
+type CatalogVersion struct {
+	Entry         CENTEntry
+	CartridgeAddr string // NQ format
+	Height        int64  // height of the latest CENT entry
+	TxHash        string
+
+	// From the CART header (HasCART is false if it was not found)
+	HasCART     bool
+	CartridgeID uint32
+	TotalSize   uint64
+	SHA256      string
+}
+
+// Semver formats the version as major.minor.patch
+func (v *CatalogVersion) Semver() string {
+	return fmt.Sprintf("%d.%d.%d", v.Entry.Semver[0], v.Entry.Semver[1], v.Entry.Semver[2])
+}
+
+// CatalogApp is the on-chain state of an app published by one publisher
+type CatalogApp struct {
+	AppID    uint32
+	Title    string // title of the latest CENT entry
+	Platform uint8
+	Retired  bool // any CENT entry has the retired flag (as the web frontend reads it)
+	Versions map[string]*CatalogVersion
+
+	// MaxCartridgeID is the highest cartridge-id found in its CART headers
+	MaxCartridgeID uint32
+}
+
+// LoadCatalogState reads all CENT entries of publisherAddr in the catalog and
+// the CART headers of their cartridges
+func LoadCatalogState(rpc *NimiqRPC, catalogAddr, publisherAddr string) (map[uint32]*CatalogApp, error) {
+	transactions, err := GetAllTransactionsByAddress(rpc, normalizeAddress(catalogAddr), 500)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query catalog: %w", err)
+	}
+
+	apps := make(map[uint32]*CatalogApp)
+	latestHeight := make(map[uint32]int64)
+	normalizedPublisher := normalizeAddress(publisherAddr)
+	for _, tx := range transactions {
+		if normalizeAddress(tx.From) != normalizedPublisher {
+			continue
+		}
+		data := transactionPayload(tx)
+		if data == nil || string(data[0:4]) != MagicCENT {
+			continue
+		}
+
+		var entry CENTEntry
+		entry.Schema = data[4]
+		entry.Platform = data[5]
+		entry.Flags = data[6]
+		entry.AppID = binary.LittleEndian.Uint32(data[7:11])
+		copy(entry.Semver[:], data[11:14])
+		copy(entry.CartridgeAddr[:], data[14:34])
+		entry.TitleShort = strings.TrimRight(string(data[34:50]), "\x00")
+
+		app := apps[entry.AppID]
+		if app == nil {
+			app = &CatalogApp{AppID: entry.AppID, Versions: make(map[string]*CatalogVersion)}
+			apps[entry.AppID] = app
+		}
+		if entry.Flags&FlagRetired != 0 {
+			app.Retired = true
+		}
+		if h, ok := latestHeight[entry.AppID]; !ok || tx.Height >= h {
+			latestHeight[entry.AppID] = tx.Height
+			app.Title = entry.TitleShort
+			app.Platform = entry.Platform
+		}
+
+		version := &CatalogVersion{Entry: entry, CartridgeAddr: AddressBytesToNQ(entry.CartridgeAddr), Height: tx.Height, TxHash: tx.Hash}
+		if existing := app.Versions[version.Semver()]; existing == nil || version.Height >= existing.Height {
+			app.Versions[version.Semver()] = version
+		}
+	}
+
+	// Read the CART header of every cartridge
+	for _, app := range apps {
+		for _, version := range app.Versions {
+			cartTxs, err := GetAllTransactionsByAddress(rpc, version.CartridgeAddr, 500)
+			if err != nil {
+				return nil, fmt.Errorf("failed to query cartridge %s: %w", version.CartridgeAddr, err)
+			}
+			for _, tx := range cartTxs {
+				if normalizeAddress(tx.From) != normalizedPublisher {
+					continue
+				}
+				data := transactionPayload(tx)
+				if data == nil || string(data[0:4]) != MagicCART {
+					continue
+				}
+				version.HasCART = true
+				version.CartridgeID = binary.LittleEndian.Uint32(data[8:12])
+				version.TotalSize = binary.LittleEndian.Uint64(data[12:20])
+				version.SHA256 = hex.EncodeToString(data[20:52])
+				if version.CartridgeID > app.MaxCartridgeID {
+					app.MaxCartridgeID = version.CartridgeID
+				}
+				break
+			}
+		}
+	}
+
+	return apps, nil
+}
@@ -28,13 +28,17 @@ const (
 
 // Credentials represents the JSON structure for account credentials
 type Credentials struct {
-	Address    string `json:"address"`
-	PublicKey  string `json:"public_key,omitempty"`
-	PrivateKey string `json:"private_key,omitempty"`
-	Passphrase string `json:"passphrase,omitempty"`
-	RPCURL     string `json:"rpc_url,omitempty"`
-	CreatedAt  string `json:"created_at,omitempty"`
-	Comment    string `json:"comment,omitempty"`
+	Address    string           `json:"address"`
+	PublicKey  string           `json:"public_key,omitempty"`
+	PrivateKey string           `json:"private_key,omitempty"`
+	Passphrase string           `json:"passphrase,omitempty"`
+	Keystore   *EncryptedSecret `json:"keystore,omitempty"` // private_key + passphrase, encrypted (see EncryptCredentials)
+	RPCURL     string           `json:"rpc_url,omitempty"`
+	Senders    []string         `json:"senders,omitempty"` // Sender pool for upload-cartridge (see --senders)
+	Signer     string           `json:"signer,omitempty"`  // Transaction signer (see --signer)
+	Network    string           `json:"network,omitempty"` // main, test or a network ID (needed by key/exec signers)
+	CreatedAt  string           `json:"created_at,omitempty"`
+	Comment    string           `json:"comment,omitempty"`
 }
 
 // GetConfigDir returns the config directory path
@@ -63,6 +67,19 @@ func GetConfigDir() string {
 // 3. Legacy current directory txt (./account_credentials.txt)
 // 4. Legacy config directory txt (~/.config/nimiq-uploader/account_credentials.txt)
 func GetCredentialsPath() string {
+	// NIMIQ_CREDENTIALS or the active profile name the file explicitly
+	if path, source, err := ResolveSetting("credentials"); err == nil && source != "default" {
+		if strings.HasPrefix(path, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, path[2:])
+			}
+		}
+		if strings.HasPrefix(source, "profile") && !filepath.IsAbs(path) {
+			path = filepath.Join(GetConfigDir(), path)
+		}
+		return path
+	}
+
 	// First check current directory for JSON
 	localPath := CredentialsFileName
 	if _, err := os.Stat(localPath); err == nil {
@@ -104,6 +121,7 @@ func LoadCredentials(filename string) (map[string]string, error) {
 	if filename == "" || filename == CredentialsFileName || filename == LegacyCredentialsFileName {
 		filename = GetCredentialsPath()
 	}
+	warnIfExposed(filename)
 
 	// Try to load as JSON first
 	if strings.HasSuffix(filename, ".json") {
@@ -141,6 +159,22 @@ func loadCredentialsJSON(filename string) (map[string]string, error) {
 	if creds.RPCURL != "" {
 		result["RPC_URL"] = creds.RPCURL
 	}
+	if len(creds.Senders) > 0 {
+		result["SENDERS"] = strings.Join(creds.Senders, ",")
+	}
+	if creds.Keystore != nil {
+		keystore, err := json.Marshal(creds.Keystore)
+		if err != nil {
+			return nil, err
+		}
+		result["KEYSTORE"] = string(keystore)
+	}
+	if creds.Signer != "" {
+		result["SIGNER"] = creds.Signer
+	}
+	if creds.Network != "" {
+		result["NETWORK"] = creds.Network
+	}
 
 	return result, nil
 }
@@ -186,61 +220,75 @@ func LoadCredentialsStruct(filename string) (*Credentials, error) {
 		return nil, err
 	}
 
-	return &Credentials{
+	result := &Credentials{
 		Address:    creds["ADDRESS"],
 		PublicKey:  creds["PUBLIC_KEY"],
 		PrivateKey: creds["PRIVATE_KEY"],
 		Passphrase: creds["PASSPHRASE"],
 		RPCURL:     creds["RPC_URL"],
-	}, nil
+		Senders:    parseAddressList(creds["SENDERS"]),
+		Signer:     creds["SIGNER"],
+		Network:    creds["NETWORK"],
+	}
+	if creds["KEYSTORE"] != "" {
+		result.Keystore = &EncryptedSecret{}
+		if err := json.Unmarshal([]byte(creds["KEYSTORE"]), result.Keystore); err != nil {
+			return nil, fmt.Errorf("invalid keystore: %w", err)
+		}
+	}
+	return result, nil
 }
 
-// GetDefaultAddress tries to load address from credentials file
-// Searches in current directory first, then config directory
+// GetDefaultAddress returns the publisher address from NIMIQ_ADDRESS, the
+// active profile or the credentials file (see ResolveSetting)
 func GetDefaultAddress() string {
-	creds, err := LoadCredentials("")
-	if err != nil {
-		return ""
-	}
-	return creds["ADDRESS"]
+	address, _, _ := ResolveSetting("address")
+	return address
 }
 
 // GetDefaultPassphrase tries to load passphrase from credentials file
+// (unlocking the keystore if the secrets are encrypted)
 func GetDefaultPassphrase() string {
-	creds, err := LoadCredentials("")
+	creds, err := LoadCredentialSecrets("")
 	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: %v\n", err)
+		}
 		return ""
 	}
 	return creds["PASSPHRASE"]
 }
 
+// GetDefaultSenders returns the sender pool from NIMIQ_SENDERS, the active
+// profile or the credentials file ("senders" in JSON, or
+// SENDERS=NQ...,NQ... in the legacy txt format)
+func GetDefaultSenders() []string {
+	senders, _, _ := ResolveSetting("senders")
+	return parseAddressList(senders)
+}
+
 // GetDefaultRPCURL returns the RPC URL from (in order):
 // 1. NIMIQ_RPC_URL environment variable
-// 2. rpc_url in credentials file
-// 3. DefaultRPCURL constant (localhost:8648)
+// 2. rpc_url in the active profile
+// 3. rpc_url in credentials file
+// 4. DefaultRPCURL constant (localhost:8648)
 func GetDefaultRPCURL() string {
-	// First check environment variable
-	if url := os.Getenv("NIMIQ_RPC_URL"); url != "" {
-		return url
-	}
-
-	// Then check credentials file
-	creds, err := LoadCredentials("")
-	if err == nil && creds["RPC_URL"] != "" {
-		return creds["RPC_URL"]
-	}
-
-	// Fall back to default
-	return DefaultRPCURL
+	url, _, _ := ResolveSetting("rpc_url")
+	return url
 }
 
-// SaveCredentials saves credentials to a JSON file
+// SaveCredentials saves credentials to a JSON file, readable by the owner only
+// (also when the file already existed with wider permissions)
 func SaveCredentials(creds *Credentials, filename string) error {
 	data, err := json.MarshalIndent(creds, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filename, data, 0600)
+	if err := writeFileAtomic(filename, data, 0600); err != nil {
+		return err
+	}
+	resetSettings()
+	return nil
 }
 
 // SaveCredentialsToConfig saves credentials to the config directory as JSON
@@ -258,8 +306,9 @@ func SaveCredentialsToLocal(creds *Credentials) error {
 	return SaveCredentials(creds, CredentialsFileName)
 }
 
-// MigrateCredentials converts legacy txt credentials to JSON format
-func MigrateCredentials(txtPath string, jsonPath string) error {
+// MigrateCredentials converts legacy txt credentials to JSON format. With a
+// keystore passphrase, the secrets are encrypted before anything is written.
+func MigrateCredentials(txtPath string, jsonPath string, keystorePassphrase string) error {
 	// Load from txt
 	creds, err := loadCredentialsTxt(txtPath)
 	if err != nil {
@@ -273,9 +322,17 @@ func MigrateCredentials(txtPath string, jsonPath string) error {
 		PrivateKey: creds["PRIVATE_KEY"],
 		Passphrase: creds["PASSPHRASE"],
 		RPCURL:     creds["RPC_URL"],
+		Senders:    parseAddressList(creds["SENDERS"]),
+		Signer:     creds["SIGNER"],
+		Network:    creds["NETWORK"],
 		CreatedAt:  time.Now().Format(time.RFC3339),
 		Comment:    "Migrated from account_credentials.txt",
 	}
+	if keystorePassphrase != "" {
+		if err := EncryptCredentials(newCreds, keystorePassphrase); err != nil {
+			return fmt.Errorf("failed to encrypt credentials: %w", err)
+		}
+	}
 
 	// Save as JSON
 	return SaveCredentials(newCreds, jsonPath)
@@ -286,6 +343,7 @@ func newMigrateCmd() *cobra.Command {
 	var inputFile string
 	var outputFile string
 	var global bool
+	var encrypt bool
 
 	cmd := &cobra.Command{
 		Use:   "migrate",
@@ -293,8 +351,35 @@ func newMigrateCmd() *cobra.Command {
 		Long: `Migrate account_credentials.txt to the new credentials.json format.
 		
 This command reads your existing account_credentials.txt file and creates 
-a new credentials.json file with the same data in a structured JSON format.`,
+a new credentials.json file with the same data in a structured JSON format.
+
+With --encrypt, the private key and passphrase are moved into an encrypted
+keystore. If the credentials are already JSON, that file is encrypted in place.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Encrypt an existing JSON credentials file in place
+			if encrypt && inputFile == "" && strings.HasSuffix(GetCredentialsPath(), ".json") {
+				if _, err := os.Stat(GetCredentialsPath()); err == nil {
+					inputFile = GetCredentialsPath()
+				}
+			}
+			if strings.HasSuffix(inputFile, ".json") {
+				if !encrypt {
+					return fmt.Errorf("%s is already in JSON format (use --encrypt to encrypt its secrets)", inputFile)
+				}
+				if creds, err := LoadCredentials(inputFile); err == nil && creds["KEYSTORE"] != "" {
+					return fmt.Errorf("%s is already encrypted", inputFile)
+				}
+				passphrase, err := resolveKeystorePassphrase(true)
+				if err != nil {
+					return err
+				}
+				if err := EncryptCredentialsFile(inputFile, passphrase); err != nil {
+					return fmt.Errorf("failed to encrypt %s: %w", inputFile, err)
+				}
+				fmt.Printf("✓ Encrypted the secrets in %s\n", inputFile)
+				return nil
+			}
+
 			// Determine input file
 			if inputFile == "" {
 				// Look for txt file
@@ -328,11 +413,19 @@ a new credentials.json file with the same data in a structured JSON format.`,
 				return fmt.Errorf("output file already exists: %s (use --output to specify different path)", outputFile)
 			}
 
+			var passphrase string
+			if encrypt {
+				var err error
+				if passphrase, err = resolveKeystorePassphrase(true); err != nil {
+					return err
+				}
+			}
+
 			fmt.Printf("Migrating credentials...\n")
 			fmt.Printf("  From: %s\n", inputFile)
 			fmt.Printf("  To:   %s\n", outputFile)
 
-			if err := MigrateCredentials(inputFile, outputFile); err != nil {
+			if err := MigrateCredentials(inputFile, outputFile, passphrase); err != nil {
 				return err
 			}
 
@@ -346,6 +439,7 @@ a new credentials.json file with the same data in a structured JSON format.`,
 	cmd.Flags().StringVar(&inputFile, "input", "", "Path to legacy txt credentials file")
 	cmd.Flags().StringVar(&outputFile, "output", "", "Path for new JSON credentials file")
 	cmd.Flags().BoolVar(&global, "global", false, "Save to global config directory (~/.config/nimiq-uploader/)")
+	cmd.Flags().BoolVar(&encrypt, "encrypt", false, "Encrypt the private key and passphrase into a keystore (also works on an existing credentials.json)")
 
 	return cmd
 }
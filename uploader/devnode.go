@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maestroi/nimiq-doom/uploader/mocknode"
+	"github.com/spf13/cobra"
+)
+
+func newDevnodeCmd() *cobra.Command {
+	var (
+		host           string
+		port           int
+		network        string
+		blockInterval  time.Duration
+		dropRate       float64
+		minFeePerByte  float64
+		initialBalance float64
+		fund           []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "devnode",
+		Short: "Run an in-memory mock Nimiq node for offline development",
+		Long: `Serve the Nimiq JSON-RPC methods the uploader and the web frontend use from
+an in-memory mock node: wallet accounts, balances, sending transactions and
+looking them up by hash or address (with paging). A block is produced every
+--block-interval; --drop-rate drops that share of mempool transactions instead
+of including them, to exercise resends and confirmations.
+
+Nothing is persisted: every start is a fresh chain. Accounts created or imported
+through the node wallet get --initial-balance NIM; fund other addresses with
+--fund ADDRESS=NIM.
+
+  nimiq-uploader devnode --port 8648
+  NIMIQ_RPC_URL=http://127.0.0.1:8648 NIMIQ_NETWORK=test nimiq-uploader account create`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			networkID, err := ParseNetworkID(network)
+			if err != nil {
+				return err
+			}
+			if dropRate < 0 || dropRate >= 1 {
+				return fmt.Errorf("--drop-rate must be between 0 and 1 (exclusive)")
+			}
+
+			node := mocknode.New(mocknode.Options{
+				NetworkID:      networkID,
+				BlockInterval:  blockInterval,
+				DropRate:       dropRate,
+				MinFeePerByte:  minFeePerByte,
+				InitialBalance: nimToLuna(initialBalance),
+				Logf:           log.Printf,
+			})
+			for _, entry := range fund {
+				address, amount, ok := strings.Cut(entry, "=")
+				if !ok {
+					return fmt.Errorf("invalid --fund %q (use ADDRESS=NIM)", entry)
+				}
+				nim, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+				if err != nil || nim < 0 {
+					return fmt.Errorf("invalid amount in --fund %q", entry)
+				}
+				if _, err := AddressNQToBytes(address); err != nil {
+					return fmt.Errorf("invalid address in --fund %q: %w", entry, err)
+				}
+				node.Fund(address, nimToLuna(nim))
+			}
+
+			listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+			if err != nil {
+				return fmt.Errorf("failed to listen: %w", err)
+			}
+			server := &http.Server{Handler: node}
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			go node.Run(ctx)
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+				defer done()
+				server.Shutdown(shutdownCtx)
+			}()
+
+			url := "http://" + listener.Addr().String()
+			fmt.Printf("🧪 Mock Nimiq node on %s\n", url)
+			fmt.Printf("   Network: %s, block every %s, drop rate %.0f%%\n", networkName(networkID), blockInterval, dropRate*100)
+			fmt.Printf("   Wallet accounts start with %s\n", formatLuna(nimToLuna(initialBalance)))
+			fmt.Printf("   Use it with: export NIMIQ_RPC_URL=%s NIMIQ_NETWORK=%s\n", url, network)
+			fmt.Println("   Press Ctrl-C to stop (the chain is not saved)")
+
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&host, "host", "127.0.0.1", "Address to listen on")
+	cmd.Flags().IntVar(&port, "port", 8648, "Port to listen on")
+	cmd.Flags().StringVar(&network, "network", "test", "Network ID the node reports and accepts: main, test, dev or a number")
+	cmd.Flags().DurationVar(&blockInterval, "block-interval", time.Second, "Time between blocks")
+	cmd.Flags().Float64Var(&dropRate, "drop-rate", 0, "Share of mempool transactions to drop instead of including (0-1)")
+	cmd.Flags().Float64Var(&minFeePerByte, "min-fee-per-byte", 0, "Minimum fee per byte for mempool admission (Luna)")
+	cmd.Flags().Float64Var(&initialBalance, "initial-balance", 1000, "NIM credited to accounts imported into the node wallet")
+	cmd.Flags().StringArrayVar(&fund, "fund", nil, "Fund an address at start: ADDRESS=NIM (repeatable)")
+
+	return cmd
+}
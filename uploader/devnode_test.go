@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/maestroi/nimiq-doom/uploader/mocknode"
+)
+
+// isolateSettings runs the test in an empty directory, without credentials,
+// profiles or NIMIQ_* settings of the machine running it
+func isolateSettings(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		resetSettings()
+	})
+
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("NIMIQ_PROFILE", "")
+	t.Setenv("NIMIQ_KEY_PASSPHRASE", "")
+	for _, def := range settingDefs {
+		t.Setenv(def.env, "")
+	}
+	resetSettings()
+}
+
+// startTestNode serves node over HTTP for the duration of the test and
+// returns the URL to reach it
+func startTestNode(t *testing.T, node *mocknode.Node) string {
+	t.Helper()
+	server := httptest.NewServer(node)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// importTestAccount imports the key derived from seed into the node wallet,
+// unlocks it and funds it
+func importTestAccount(t *testing.T, node *mocknode.Node, rpc *NimiqRPC, seed byte) string {
+	t.Helper()
+	address, err := node.ImportKey(bytes.Repeat([]byte{seed}, 32), "test")
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	if _, err := rpc.UnlockAccount(address, "test", 0); err != nil {
+		t.Fatalf("failed to unlock %s: %v", address, err)
+	}
+	node.Fund(address, 1000*mocknode.LunaPerNIM)
+	return address
+}
+
+// testCartridgeAddr returns a fixed cartridge address for seed
+func testCartridgeAddr(seed byte) string {
+	sum := sha256.Sum256([]byte{seed})
+	var addr [20]byte
+	copy(addr[:], sum[:])
+	return AddressBytesToNQ(addr)
+}
+
+// testFile returns size bytes of test content
+func testFile(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i*7 + i/51)
+	}
+	return data
+}
+
+// DATA chunks sent through NimiqRPC are included in the block after their
+// validity start, dropped ones vanish from the node and are sent again, and
+// paging through the cartridge address returns every included chunk once
+func TestMockNodeUpload(t *testing.T) {
+	isolateSettings(t)
+	node := mocknode.New(mocknode.Options{DropRate: 0.3, Seed: 3})
+	rpc := NewNimiqRPC(startTestNode(t, node))
+	publisher := importTestAccount(t, node, rpc, 1)
+	cartridgeAddr := testCartridgeAddr(1)
+
+	signer, err := NewNodeWalletSigner(rpc, publisher)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	sender := NewRPCSenderWithSigner(rpc, signer, cartridgeAddr, 0)
+
+	content := testFile(9*ChunkSize - 10)
+	type sentChunk struct {
+		payload   []byte
+		txHash    string
+		validFrom int64
+		attempt   int
+	}
+	send := func(c *sentChunk) {
+		t.Helper()
+		txHash, validFrom, err := sender.ResendTransaction(c.payload, c.attempt)
+		if err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+		if height := node.Height(); validFrom != height {
+			t.Fatalf("validity start height %d, expected the current height %d", validFrom, height)
+		}
+		c.txHash, c.validFrom = txHash, validFrom
+	}
+
+	pending := make(map[uint32]*sentChunk)
+	for idx := 0; idx*ChunkSize < len(content); idx++ {
+		data := content[idx*ChunkSize:]
+		if len(data) > ChunkSize {
+			data = data[:ChunkSize]
+		}
+		payload, err := EncodeDATA(DATAPayload{CartridgeID: 1, ChunkIndex: uint32(idx), Length: uint8(len(data)), Data: data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := &sentChunk{payload: payload}
+		send(c)
+		pending[uint32(idx)] = c
+	}
+	chunks := len(pending)
+
+	// Nothing is mined before the next block
+	mempool, err := rpc.GetMempoolInfo()
+	if err != nil {
+		t.Fatalf("failed to get mempool: %v", err)
+	}
+	if mempool.Total != chunks {
+		t.Errorf("mempool holds %d transactions, expected %d", mempool.Total, chunks)
+	}
+	for idx, c := range pending {
+		tx, err := rpc.GetTransactionByHash(c.txHash)
+		if err != nil {
+			t.Fatalf("chunk %d: %v", idx, err)
+		}
+		if tx.BlockNumber != 0 || tx.ValidityStartHeight != c.validFrom {
+			t.Errorf("chunk %d: block %d, validity start %d before mining, expected 0, %d", idx, tx.BlockNumber, tx.ValidityStartHeight, c.validFrom)
+		}
+	}
+
+	dropped := 0
+	for round := 0; len(pending) > 0; round++ {
+		if round == 20 {
+			t.Fatalf("%d chunks still not mined after %d blocks", len(pending), round)
+		}
+		height := node.MineBlock()
+		for idx, c := range pending {
+			tx, err := rpc.GetTransactionByHash(c.txHash)
+			if err != nil {
+				// Dropped from the mempool: the node forgot it, send it again
+				dropped++
+				c.attempt++
+				send(c)
+				continue
+			}
+			if tx.BlockNumber != height {
+				t.Errorf("chunk %d: mined in block %d, expected %d", idx, tx.BlockNumber, height)
+			}
+			delete(pending, idx)
+		}
+	}
+	if dropped == 0 {
+		t.Fatal("no chunk was dropped - the seed no longer exercises resends")
+	}
+	if node.Dropped() != dropped {
+		t.Errorf("node dropped %d transactions, the sender saw %d", node.Dropped(), dropped)
+	}
+
+	// Page sizes below, at and above the number of chunks
+	for _, perPage := range []int{1, 3, 4, chunks, 100} {
+		txs, err := GetAllTransactionsByAddress(rpc, cartridgeAddr, perPage)
+		if err != nil {
+			t.Fatalf("%d per page: %v", perPage, err)
+		}
+		if len(txs) != chunks {
+			t.Fatalf("%d per page: got %d transactions, expected %d", perPage, len(txs), chunks)
+		}
+		got := make([]byte, len(content))
+		seen := make(map[uint32]bool)
+		for i, tx := range txs {
+			if tx.Height == 0 {
+				t.Errorf("%d per page: transaction %s has no block height", perPage, tx.Hash)
+			}
+			if i > 0 && tx.Height > txs[i-1].Height {
+				t.Errorf("%d per page: transaction %d (block %d) is newer than the one before (block %d)", perPage, i, tx.Height, txs[i-1].Height)
+			}
+			chunk, err := DecodeDATA(transactionPayload(tx))
+			if err != nil {
+				t.Fatalf("%d per page: %v", perPage, err)
+			}
+			if seen[chunk.ChunkIndex] {
+				t.Errorf("%d per page: chunk %d returned twice", perPage, chunk.ChunkIndex)
+			}
+			seen[chunk.ChunkIndex] = true
+			copy(got[int(chunk.ChunkIndex)*ChunkSize:], chunk.Data)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("%d per page: reassembled chunks differ from the file", perPage)
+		}
+	}
+}
+
+// With a block interval the node mines on its own timer
+func TestMockNodeBlockInterval(t *testing.T) {
+	isolateSettings(t)
+	node := mocknode.New(mocknode.Options{BlockInterval: 5 * time.Millisecond})
+	rpc := NewNimiqRPC(startTestNode(t, node))
+	publisher := importTestAccount(t, node, rpc, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		node.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	signer, err := NewNodeWalletSigner(rpc, publisher)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	sender := NewRPCSenderWithSigner(rpc, signer, testCartridgeAddr(1), 0)
+	txHash, validFrom, err := sender.ResendTransaction(make([]byte, PayloadSize), 0)
+	if err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tx, err := rpc.GetTransactionByHash(txHash)
+		if err != nil {
+			t.Fatalf("failed to look up %s: %v", txHash, err)
+		}
+		if tx.BlockNumber > 0 {
+			if tx.BlockNumber <= validFrom {
+				t.Errorf("mined in block %d, before its validity start %d", tx.BlockNumber, validFrom)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("not mined after 5s (height %d)", node.Height())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	block, err := rpc.GetLatestBlock()
+	if err != nil {
+		t.Fatalf("failed to get latest block: %v", err)
+	}
+	if block.Number < validFrom+1 {
+		t.Errorf("latest block %d, expected at least %d", block.Number, validFrom+1)
+	}
+}
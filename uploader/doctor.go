@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Doctor check statuses
+const (
+	DoctorOK   = "ok"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+	DoctorSkip = "skip"
+
+	// doctorMaxBlockAge is how old the node's latest block may be before the
+	// node is considered behind (Albatross produces a block every second)
+	doctorMaxBlockAge = 5 * time.Minute
+)
+
+// DoctorCheck is the outcome of one doctor check
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Hint   string `json:"hint,omitempty"` // what to do about a warning or failure
+}
+
+// DoctorReport is everything doctor found, printed as text or with --json
+type DoctorReport struct {
+	RPCURL      string         `json:"rpc_url"`
+	Address     string         `json:"address,omitempty"`
+	CatalogAddr string         `json:"catalog_addr,omitempty"`
+	Signer      string         `json:"signer"`
+	Checks      []*DoctorCheck `json:"checks"`
+	Failed      int            `json:"failed"`
+	Warnings    int            `json:"warnings"`
+}
+
+func (r *DoctorReport) add(name, status, detail, hint string) {
+	r.Checks = append(r.Checks, &DoctorCheck{Name: name, Status: status, Detail: detail, Hint: hint})
+	switch status {
+	case DoctorFail:
+		r.Failed++
+	case DoctorWarn:
+		r.Warnings++
+	}
+}
+
+// Print writes the report as a checklist with a hint under every problem
+func (r *DoctorReport) Print() {
+	fmt.Printf("RPC URL: %s\n", r.RPCURL)
+	if r.Address != "" {
+		fmt.Printf("Account: %s (signer %s)\n", r.Address, r.Signer)
+	}
+	if r.CatalogAddr != "" {
+		fmt.Printf("Catalog: %s\n", r.CatalogAddr)
+	}
+	fmt.Println()
+
+	icons := map[string]string{DoctorOK: "✅", DoctorWarn: "⚠️ ", DoctorFail: "❌", DoctorSkip: "➖"}
+	for _, c := range r.Checks {
+		fmt.Printf("%s %-12s %s\n", icons[c.Status], c.Name, c.Detail)
+		if c.Hint != "" {
+			fmt.Printf("   → %s\n", c.Hint)
+		}
+	}
+
+	fmt.Println()
+	switch {
+	case r.Failed > 0:
+		fmt.Printf("❌ %d check(s) failed, %d warning(s)\n", r.Failed, r.Warnings)
+	case r.Warnings > 0:
+		fmt.Printf("⚠️  Ready to upload, with %d warning(s)\n", r.Warnings)
+	default:
+		fmt.Println("✅ Ready to upload")
+	}
+}
+
+func newDoctorCmd() *cobra.Command {
+	var (
+		rpcURL      string
+		address     string
+		catalogAddr string
+		feeFlag     string
+		typicalSize string
+		jsonOut     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that the node, account and catalog are ready for uploads",
+		Long: `Run every readiness check in one go and print a hint for each problem:
+
+- RPC reachability and latency
+- consensus, sync height and network of the node
+- whether the node keeps the transaction history catalog lookups need
+- wallet import and unlock state (node signer)
+- balance compared to a typical upload
+- credentials file permissions and encryption
+
+Exits with an error if any check fails.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get RPC URL from env, credentials file, or default
+			if rpcURL == "" {
+				rpcURL = GetDefaultRPCURL()
+			}
+			if address == "" {
+				address = GetDefaultAddress()
+			}
+			if catalogAddr != "" {
+				catalogAddr = resolveCatalogAddress(catalogAddr)
+			}
+
+			report := &DoctorReport{RPCURL: rpcURL, Address: address, CatalogAddr: catalogAddr, Signer: GetDefaultSigner()}
+			rpc := NewNimiqRPC(rpcURL).WithContext(cmd.Context())
+
+			// Keep stdout for the JSON report; warnings printed by the checks go to stderr
+			var log io.Writer = os.Stdout
+			if jsonOut {
+				log = os.Stderr
+			} else {
+				fmt.Println("Running checks...")
+			}
+			if checkDoctorNode(report, rpc) {
+				checkDoctorHistory(report, rpc, catalogAddr)
+				checkDoctorWallet(report, rpc, address)
+				checkDoctorBalance(report, rpc, address, feeFlag, typicalSize, log)
+			}
+			checkDoctorCredentials(report)
+
+			if jsonOut {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			} else {
+				fmt.Println()
+				report.Print()
+			}
+
+			if report.Failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d check(s) failed", report.Failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
+	cmd.Flags().StringVar(&address, "address", "", "Account address (defaults to address from credentials.json)")
+	cmd.Flags().StringVar(&catalogAddr, "catalog-addr", "", "Catalog address to check the history for (NQ..., 'main', 'test')")
+	cmd.Flags().StringVar(&feeFlag, "fee", "0", "Transaction fee for the balance check: a fixed amount in Luna, or auto, min or fast")
+	cmd.Flags().StringVar(&typicalSize, "typical-size", "1MB", "File size the balance should cover")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Print the report as JSON")
+
+	return cmd
+}
+
+// checkDoctorNode checks reachability, consensus, sync and network, and
+// reports whether the node answered at all
+func checkDoctorNode(r *DoctorReport, rpc *NimiqRPC) bool {
+	start := time.Now()
+	height, err := rpc.GetBlockNumber()
+	latency := time.Since(start)
+	if err != nil {
+		r.add("rpc", DoctorFail, fmt.Sprintf("unreachable: %v", err),
+			"start the node with the RPC server enabled ([rpc-server] in client.toml), or point --rpc-url, NIMIQ_RPC_URL or the profile's rpc_url at it")
+		for _, name := range []string{"consensus", "sync", "network", "history", "wallet", "balance"} {
+			r.add(name, DoctorSkip, "node not reachable", "")
+		}
+		return false
+	}
+	latencyMs := latency.Milliseconds()
+	if latency > 2*time.Second {
+		r.add("rpc", DoctorWarn, fmt.Sprintf("reachable in %dms", latencyMs),
+			"the node answers slowly; uploads send one request per chunk, so use a node closer to this machine")
+	} else {
+		r.add("rpc", DoctorOK, fmt.Sprintf("reachable in %dms", latencyMs), "")
+	}
+
+	consensus, err := rpc.IsConsensusEstablished()
+	switch {
+	case err != nil:
+		r.add("consensus", DoctorFail, fmt.Sprintf("failed to check: %v", err), "check that the RPC endpoint is a Nimiq Albatross node")
+	case !consensus:
+		r.add("consensus", DoctorFail, "not established", "wait for the node to sync (nimiq-uploader account consensus)")
+	default:
+		r.add("consensus", DoctorOK, "established", "")
+	}
+
+	block, err := rpc.GetLatestBlock()
+	if err != nil {
+		r.add("sync", DoctorWarn, fmt.Sprintf("block %d, latest block not available: %v", height, err), "the node does not support getLatestBlock; sync and network can't be checked")
+		r.add("network", DoctorSkip, "latest block not available", "")
+		return true
+	}
+	age := time.Since(time.UnixMilli(block.Timestamp)).Truncate(time.Second)
+	switch {
+	case block.Timestamp == 0:
+		r.add("sync", DoctorOK, fmt.Sprintf("block %d", block.Number), "")
+	case age > doctorMaxBlockAge:
+		r.add("sync", DoctorWarn, fmt.Sprintf("block %d is %s old", block.Number, age),
+			"the node is behind or stalled; check its logs and peers before sending")
+	default:
+		r.add("sync", DoctorOK, fmt.Sprintf("block %d (%s old)", block.Number, age), "")
+	}
+
+	expected, source, err := expectedNetwork(r.CatalogAddr, "")
+	switch {
+	case err != nil:
+		r.add("network", DoctorFail, err.Error(), "make the catalog and network settings agree (nimiq-uploader config)")
+	case block.Network == 0:
+		r.add("network", DoctorWarn, "node did not report its network", "network mismatches can't be detected with this node")
+	case expected != 0 && block.Network != expected:
+		r.add("network", DoctorFail, fmt.Sprintf("node is on %s, but %s expects %s", networkName(block.Network), source, networkName(expected)),
+			"use a node on the catalog's network, or switch profile (--profile)")
+	default:
+		r.add("network", DoctorOK, networkName(block.Network), "")
+	}
+	return true
+}
+
+// checkDoctorHistory checks that the node can list the catalog's transactions.
+// Catalog lookups (app-id, cartridge-id, versions) need getTransactionsByAddress
+// over the whole history, which only history nodes serve.
+func checkDoctorHistory(r *DoctorReport, rpc *NimiqRPC, catalogAddr string) {
+	address := catalogAddr
+	if address == "" {
+		address = resolveCatalogAddress("main")
+		if id, _, err := expectedNetwork("", ""); err == nil && id == NetworkIDTest {
+			address = resolveCatalogAddress("test")
+		}
+	}
+
+	result, err := rpc.Call("getTransactionsByAddress", map[string]interface{}{
+		"address": normalizeAddress(address),
+		"max":     1,
+	})
+	if err != nil {
+		r.add("history", DoctorFail, fmt.Sprintf("getTransactionsByAddress failed: %v", err),
+			"run a history node (sync_mode = \"history\" and index_history = true in client.toml)")
+		return
+	}
+	var txs []Transaction
+	if err := json.Unmarshal(unwrapData(result), &txs); err != nil || len(txs) == 0 {
+		r.add("history", DoctorWarn, fmt.Sprintf("no transactions found for catalog %s", address),
+			"unless the catalog is new, the node does not keep full history - run a history node (sync_mode = \"history\")")
+		return
+	}
+	r.add("history", DoctorOK, "catalog transactions are available", "")
+}
+
+// checkDoctorWallet checks that the node wallet can sign for address (node signer only)
+func checkDoctorWallet(r *DoctorReport, rpc *NimiqRPC, address string) {
+	if address == "" {
+		r.add("wallet", DoctorFail, "no account address", "create one with 'nimiq-uploader account create' or pass --address")
+		return
+	}
+	if spec := GetDefaultSigner(); spec != "node" {
+		r.add("wallet", DoctorSkip, fmt.Sprintf("signer %s does not use the node wallet", spec), "")
+		return
+	}
+
+	imported, err := rpc.IsAccountImported(address)
+	if err != nil {
+		r.add("wallet", DoctorFail, fmt.Sprintf("failed to check import status: %v", err), "")
+		return
+	}
+	if !imported {
+		r.add("wallet", DoctorFail, "account is not imported into the node wallet", "run 'nimiq-uploader account import', or sign in-process with --signer key")
+		return
+	}
+	unlocked, err := rpc.IsAccountUnlocked(address)
+	switch {
+	case err != nil:
+		r.add("wallet", DoctorFail, fmt.Sprintf("failed to check unlock status: %v", err), "")
+	case !unlocked:
+		r.add("wallet", DoctorFail, "account is locked", "run 'nimiq-uploader account unlock'")
+	default:
+		r.add("wallet", DoctorOK, "imported and unlocked", "")
+	}
+}
+
+// checkDoctorBalance compares the balance with what an upload of typicalSize
+// costs (fee warnings go to log)
+func checkDoctorBalance(r *DoctorReport, rpc *NimiqRPC, address, feeFlag, typicalSize string, log io.Writer) {
+	if address == "" {
+		r.add("balance", DoctorSkip, "no account address", "")
+		return
+	}
+	size, err := parseByteSize(typicalSize)
+	if err != nil {
+		r.add("balance", DoctorFail, fmt.Sprintf("invalid --typical-size: %v", err), "")
+		return
+	}
+	policy, err := ParseFeePolicy(feeFlag)
+	if err != nil {
+		r.add("balance", DoctorFail, err.Error(), "")
+		return
+	}
+	fee, err := NewFeeOracle(rpc, policy).WithLog(log).Fee()
+	if err != nil {
+		r.add("balance", DoctorWarn, fmt.Sprintf("failed to determine fee: %v", err), "pass a fixed --fee in Luna")
+		return
+	}
+	balance, err := rpc.GetBalance(address)
+	if err != nil {
+		r.add("balance", DoctorFail, fmt.Sprintf("failed to get balance: %v", err), "")
+		return
+	}
+
+	estimate := EstimateUpload(address, nil, ChunkCount(size, ChunkSize), true, true, nil, fee)
+	needed := estimate.TotalLuna()
+	if balance < needed {
+		r.add("balance", DoctorWarn, fmt.Sprintf("%s, a %s upload needs %s", formatLuna(balance), typicalSize, formatLuna(needed)),
+			fmt.Sprintf("send at least %s to %s (nimiq-uploader account wait-funds)", formatLuna(needed-balance), address))
+		return
+	}
+	r.add("balance", DoctorOK, fmt.Sprintf("%s, enough for %d upload(s) of %s", formatLuna(balance), balance/needed, typicalSize), "")
+}
+
+// checkDoctorCredentials checks that the credentials file exists, is private
+// and keeps its secrets encrypted
+func checkDoctorCredentials(r *DoctorReport) {
+	path := GetCredentialsPath()
+	if _, err := os.Stat(path); err != nil {
+		r.add("credentials", DoctorWarn, fmt.Sprintf("%s not found", path), "run 'nimiq-uploader account create' or 'nimiq-uploader migrate'")
+		return
+	}
+	if mode, exposed := filePermissionsExposed(path); exposed {
+		r.add("credentials", DoctorFail, fmt.Sprintf("%s is accessible by other users (mode %04o)", path, mode), "chmod 600 "+path)
+		return
+	}
+	creds, err := LoadCredentials(path)
+	if err != nil {
+		r.add("credentials", DoctorFail, fmt.Sprintf("failed to read %s: %v", path, err), "")
+		return
+	}
+	if creds["KEYSTORE"] == "" && (creds["PRIVATE_KEY"] != "" || creds["PASSPHRASE"] != "") {
+		r.add("credentials", DoctorWarn, fmt.Sprintf("%s holds plaintext secrets", path), "run 'nimiq-uploader migrate --encrypt'")
+		return
+	}
+	r.add("credentials", DoctorOK, path, "")
+}
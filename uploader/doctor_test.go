@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/maestroi/nimiq-doom/uploader/mocknode"
+)
+
+// doctor --json writes only the report to stdout, also when a check prints a
+// warning, and leaves os.Stdout alone
+func TestDoctorJSONKeepsStdout(t *testing.T) {
+	isolateSettings(t)
+	node := mocknode.New(mocknode.Options{})
+	// A node without mempool information makes the fee check warn
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"method":"mempool"`) {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		node.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	address := importTestAccount(t, node, NewNimiqRPC(server.URL), 1)
+
+	stdout, stderr := os.Stdout, os.Stderr
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout, os.Stderr = outW, errW
+	cmd := newDoctorCmd()
+	cmd.SetArgs([]string{"--rpc-url", server.URL, "--address", address, "--fee", "auto", "--json"})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	runErr := cmd.Execute()
+	restored := os.Stdout == outW
+	os.Stdout, os.Stderr = stdout, stderr
+	outW.Close()
+	errW.Close()
+	out, _ := io.ReadAll(outR)
+	warnings, _ := io.ReadAll(errR)
+
+	if !restored {
+		t.Error("doctor left os.Stdout reassigned")
+	}
+	var report DoctorReport
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("stdout is not the JSON report (%v): %s", err, out)
+	}
+	if len(report.Checks) == 0 {
+		t.Errorf("report has no checks (doctor: %v)", runErr)
+	}
+	if !strings.Contains(string(warnings), "failed to get mempool info") {
+		t.Errorf("fee warning not on stderr: %q", warnings)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// txValue is the luna every upload transaction sends along (data transactions
+// cannot have a zero value, see RPCSender.SendTransaction)
+const txValue = 1
+
+// UploadEstimate is the number of transactions an upload (still) needs and
+// what they cost, split by the account that pays for them
+type UploadEstimate struct {
+	DataTxs int64 // DATA chunks
+	MetaTxs int64 // CSGN co-signer declarations
+	CartTxs int64
+	CentTxs int64
+	Fee     int64 // per transaction, in Luna
+
+	accounts []string         // paying accounts, publisher first
+	txs      map[string]int64 // transactions per account
+}
+
+// EstimateUpload computes the cost of sending dataTxs chunks (spread round-robin
+// over pool, or all from publisher), plus the CART and CENT if they are still
+// to be sent. coSigners are co-signers that already sent chunks earlier.
+func EstimateUpload(publisher string, pool []string, dataTxs int64, cart, cent bool, coSigners []string, fee int64) *UploadEstimate {
+	e := &UploadEstimate{DataTxs: dataTxs, Fee: fee, txs: make(map[string]int64)}
+	e.addTxs(publisher, 0)
+
+	pool = dedupeAddresses(pool)
+	if len(pool) == 0 {
+		e.addTxs(publisher, dataTxs)
+	} else {
+		// Same distribution as SenderPool (exact unless sends fail and are retried)
+		k := int64(len(pool))
+		for i, addr := range pool {
+			share := dataTxs / k
+			if int64(i) < dataTxs%k {
+				share++
+			}
+			e.addTxs(addr, share)
+		}
+	}
+
+	if cart {
+		e.CartTxs = 1
+		// Co-signers are declared right before the CART
+		declared := make(map[string]bool)
+		for _, addr := range coSigners {
+			declared[normalizeAddress(addr)] = true
+		}
+		for _, addr := range e.accounts {
+			if e.txs[addr] > 0 && normalizeAddress(addr) != normalizeAddress(publisher) {
+				declared[normalizeAddress(addr)] = true
+			}
+		}
+		e.MetaTxs = (int64(len(declared)) + CSGNMaxAddresses - 1) / CSGNMaxAddresses
+	}
+	if cent {
+		e.CentTxs = 1
+	}
+	e.addTxs(publisher, e.MetaTxs+e.CartTxs+e.CentTxs)
+
+	return e
+}
+
+func (e *UploadEstimate) addTxs(addr string, n int64) {
+	key := normalizeAddress(addr)
+	for _, existing := range e.accounts {
+		if normalizeAddress(existing) == key {
+			e.txs[existing] += n
+			return
+		}
+	}
+	e.accounts = append(e.accounts, addr)
+	e.txs[addr] = n
+}
+
+// Add folds another estimate (at the same fee) into e, e.g. for a batch of uploads
+func (e *UploadEstimate) Add(o *UploadEstimate) {
+	e.DataTxs += o.DataTxs
+	e.MetaTxs += o.MetaTxs
+	e.CartTxs += o.CartTxs
+	e.CentTxs += o.CentTxs
+	for _, addr := range o.accounts {
+		e.addTxs(addr, o.txs[addr])
+	}
+}
+
+// Transactions returns the total number of transactions
+func (e *UploadEstimate) Transactions() int64 {
+	return e.DataTxs + e.MetaTxs + e.CartTxs + e.CentTxs
+}
+
+// TotalLuna returns the total cost (value + fee) in Luna
+func (e *UploadEstimate) TotalLuna() int64 {
+	return e.Transactions() * (txValue + e.Fee)
+}
+
+// NeededLuna returns what addr has to pay
+func (e *UploadEstimate) NeededLuna(addr string) int64 {
+	return e.txs[addr] * (txValue + e.Fee)
+}
+
+// Print writes the estimate in the usual summary format
+func (e *UploadEstimate) Print() {
+	fmt.Printf("Transactions: %d\n", e.Transactions())
+	fmt.Printf("  DATA: %d\n", e.DataTxs)
+	if e.MetaTxs > 0 {
+		fmt.Printf("  CSGN: %d\n", e.MetaTxs)
+	}
+	fmt.Printf("  CART: %d\n", e.CartTxs)
+	fmt.Printf("  CENT: %d\n", e.CentTxs)
+	fmt.Printf("Per transaction: %d Luna value + %d Luna fee\n", txValue, e.Fee)
+	fmt.Printf("Total cost: %s\n", formatLuna(e.TotalLuna()))
+	if len(e.accounts) > 1 {
+		for _, addr := range e.accounts {
+			fmt.Printf("  %s: %d tx, %s\n", addr, e.txs[addr], formatLuna(e.NeededLuna(addr)))
+		}
+	}
+}
+
+// Shortfall is an account that cannot pay its part of an upload
+type Shortfall struct {
+	Address string
+	Balance int64
+	Needed  int64
+}
+
+// CheckBalances compares the cost per account with its balance
+func (e *UploadEstimate) CheckBalances(rpc *NimiqRPC) ([]Shortfall, error) {
+	var shortfalls []Shortfall
+	for _, addr := range e.accounts {
+		needed := e.NeededLuna(addr)
+		if needed == 0 {
+			continue
+		}
+		balance, err := rpc.GetBalance(addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get balance of %s: %w", addr, err)
+		}
+		fmt.Printf("Balance %s: %s (needs %s)", addr, formatLuna(balance), formatLuna(needed))
+		if balance < needed {
+			fmt.Printf(" ⚠️  short by %s\n", formatLuna(needed-balance))
+			shortfalls = append(shortfalls, Shortfall{Address: addr, Balance: balance, Needed: needed})
+		} else {
+			fmt.Printf(" ✓\n")
+		}
+	}
+	return shortfalls, nil
+}
+
+// preflightBalances checks that every paying account can cover its part of the
+// upload. With wait set it polls until they can, otherwise it refuses.
+func preflightBalances(ctx context.Context, rpc *NimiqRPC, estimate *UploadEstimate, wait bool) error {
+	shortfalls, err := estimate.CheckBalances(rpc)
+	if err != nil {
+		return err
+	}
+	if len(shortfalls) == 0 {
+		return nil
+	}
+
+	if !wait {
+		var lines []string
+		for _, s := range shortfalls {
+			lines = append(lines, fmt.Sprintf("  %s has %s, needs %s", s.Address, formatLuna(s.Balance), formatLuna(s.Needed)))
+		}
+		return fmt.Errorf("insufficient funds for this upload:\n%s\nFund the account(s) and retry, or use --wait-for-funds", strings.Join(lines, "\n"))
+	}
+
+	for _, s := range shortfalls {
+		if err := waitForFunds(ctx, rpc, s.Address, s.Needed, 10*time.Second); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newEstimateCmd() *cobra.Command {
+	var (
+		filePath     string
+		sender       string
+		senders      string
+		chunkSize    uint8
+		feeFlag      string
+		maxSize      string
+		rpcURL       string
+		checkBalance bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "estimate",
+		Short: "Estimate the transactions and NIM an upload-cartridge run needs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			maxFileSize, err := parseByteSize(maxSize)
+			if err != nil {
+				return fmt.Errorf("invalid --max-size: %w", err)
+			}
+			source, err := OpenChunkSource(filePath, maxFileSize)
+			if err != nil {
+				return err
+			}
+			defer source.Close()
+
+			chunks, warning, err := ValidateChunkLayout(source.Size, chunkSize)
+			if err != nil {
+				return err
+			}
+			if warning != "" {
+				fmt.Printf("⚠️  Warning: %s\n", warning)
+			}
+
+			if sender == "" {
+				sender = GetDefaultAddress()
+			}
+			if sender == "" {
+				sender = "publisher"
+			}
+			pool := parseAddressList(senders)
+			if senders == "" {
+				pool = GetDefaultSenders()
+			}
+
+			feePolicy, err := ParseFeePolicy(feeFlag)
+			if err != nil {
+				return err
+			}
+
+			// Get RPC URL from env, credentials file, or default
+			if rpcURL == "" {
+				rpcURL = GetDefaultRPCURL()
+			}
+			rpc := NewNimiqRPC(rpcURL).WithContext(cmd.Context())
+
+			// Dynamic fees need the node; fixed fees work offline
+			fee, err := NewFeeOracle(rpc, feePolicy).Fee()
+			if err != nil {
+				return fmt.Errorf("failed to determine fee (use --fee <luna> to estimate offline): %w", err)
+			}
+
+			fmt.Printf("File: %s (%d bytes, %d chunks of %d bytes)\n", source.Name, source.Size, chunks, chunkSize)
+			fmt.Printf("Fee: %s -> %d Luna per transaction\n\n", feePolicy, fee)
+			estimate := EstimateUpload(sender, pool, chunks, true, true, nil, fee)
+			estimate.Print()
+
+			if !checkBalance || sender == "publisher" {
+				return nil
+			}
+
+			fmt.Println()
+			shortfalls, err := estimate.CheckBalances(rpc)
+			if err != nil {
+				fmt.Printf("⚠️  Could not check balances: %v\n", err)
+				return nil
+			}
+			if len(shortfalls) > 0 {
+				fmt.Printf("\n⚠️  %d account(s) need more funds before uploading\n", len(shortfalls))
+			} else {
+				fmt.Println("\n✅ Enough funds for this upload")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&filePath, "file", "", "Path to file to upload, or - for stdin (required)")
+	cmd.Flags().StringVar(&sender, "sender", "", "Publisher address (defaults to ADDRESS from credentials)")
+	cmd.Flags().StringVar(&senders, "senders", "", "Comma-separated sender pool for DATA chunks (defaults to senders from credentials)")
+	cmd.Flags().Uint8Var(&chunkSize, "chunk-size", 51, "Chunk size in bytes (default: 51)")
+	cmd.Flags().StringVar(&feeFlag, "fee", "0", "Transaction fee: a fixed amount in Luna per transaction, or auto, min or fast")
+	cmd.Flags().StringVar(&maxSize, "max-size", "6MB", "Maximum file size to upload (e.g. 6MB, 100MB, 0 = no limit)")
+	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
+	cmd.Flags().BoolVar(&checkBalance, "check-balance", true, "Compare the cost with the account balances")
+
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+func newExecuteCmd() *cobra.Command {
+	var (
+		planPath         string
+		progressPath     string
+		rpcURL           string
+		rateLimit        float64
+		waitForFundsFlag bool
+		skipPreflight    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "execute",
+		Short: "Send exactly the transactions of a plan written by upload-cartridge --plan-out",
+		Long: `Send the transactions of a plan file in step order, with the payloads,
+senders, recipients and fees it lists. Nothing is re-encoded.
+
+The plan is refused if it was edited (plan hash), if the file it was made from
+changed, or if the publisher sent anything to the catalog since the plan was
+made (the planned app-id and cartridge-id may no longer be free). Sent steps
+are recorded in <plan>.progress.json, so an interrupted run resumes where it
+stopped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			plan, err := ReadTxPlan(planPath)
+			if err != nil {
+				return err
+			}
+			h := plan.Header
+			fmt.Printf("Plan: %s (hash %s, made %s)\n", planPath, h.PlanHash, h.CreatedAt)
+			fmt.Printf("  %s \"%s\" %s: app-id %d, cartridge-id %d\n", h.File, h.Title, h.Semver, h.AppID, h.CartridgeID)
+			fmt.Printf("  Cartridge: %s\n  Catalog: %s\n  Publisher: %s\n", h.CartridgeAddr, h.CatalogAddr, h.Publisher)
+			fmt.Printf("  %d transactions: %d DATA, %d CSGN, %d CART, %d CENT\n",
+				len(plan.Steps), plan.Count(StepDATA), plan.Count(StepCSGN), plan.Count(StepCART), plan.Count(StepCENT))
+
+			if err := verifyPlanFile(h); err != nil {
+				return err
+			}
+
+			if progressPath == "" {
+				progressPath = strings.TrimSuffix(planPath, ".jsonl") + ".progress.json"
+			}
+			lock, err := acquireFileLock(progressPath + ".lock")
+			if err != nil {
+				return err
+			}
+			defer releaseFileLock(lock)
+			progress, err := loadTxPlanProgress(progressPath, h.PlanHash)
+			if err != nil {
+				return err
+			}
+
+			var remaining []TxPlanStep
+			for _, step := range plan.Steps {
+				if _, sent := progress.Sent[step.Step]; !sent {
+					remaining = append(remaining, step)
+				}
+			}
+			if len(remaining) == 0 {
+				fmt.Printf("\n✅ All %d transactions of this plan were already sent (see %s)\n", len(plan.Steps), progressPath)
+				return nil
+			}
+			if len(remaining) < len(plan.Steps) {
+				fmt.Printf("Resuming: %d/%d transactions already sent\n", len(plan.Steps)-len(remaining), len(plan.Steps))
+			}
+
+			// Get RPC URL from env, credentials file, or default
+			if rpcURL == "" {
+				rpcURL = GetDefaultRPCURL()
+			}
+			rpc := NewNimiqRPC(rpcURL).WithContext(ctx)
+
+			consensus, err := rpc.IsConsensusEstablished()
+			if err != nil {
+				return fmt.Errorf("failed to check consensus: %w", err)
+			}
+			if !consensus {
+				return fmt.Errorf("node does not have consensus with the network - wait for sync")
+			}
+
+			networkID, err := checkNodeNetwork(rpc, h.CatalogAddr, "")
+			if err != nil {
+				return err
+			}
+			if err := checkPlanNetwork("plan", h.NetworkID, networkID); err != nil {
+				return err
+			}
+			if err := progress.bindNetwork(networkID); err != nil {
+				return err
+			}
+
+			if h.CatalogState == "" {
+				return fmt.Errorf("plan was made with --offline and has no catalog state to check - sign it with sign-bundle and send it with broadcast")
+			}
+
+			// Only the plan's own CENT (the last step) may change the catalog
+			state, err := catalogStateHash(rpc, h.CatalogAddr, h.Publisher)
+			if err != nil {
+				return err
+			}
+			if state != h.CatalogState {
+				return fmt.Errorf("catalog %s changed since the plan was made - make a new plan", h.CatalogAddr)
+			}
+
+			if !skipPreflight {
+				estimate := &UploadEstimate{Fee: remaining[0].Fee, txs: make(map[string]int64)}
+				estimate.addTxs(h.Publisher, 0)
+				for _, step := range remaining {
+					switch step.Kind {
+					case StepDATA:
+						estimate.DataTxs++
+					case StepCSGN:
+						estimate.MetaTxs++
+					case StepCART:
+						estimate.CartTxs++
+					case StepCENT:
+						estimate.CentTxs++
+					}
+					estimate.addTxs(step.From, 1)
+				}
+				fmt.Println("\n=== Cost estimate ===")
+				estimate.Print()
+				if err := preflightBalances(ctx, rpc, estimate, waitForFundsFlag); err != nil {
+					return err
+				}
+			}
+
+			// One sender per sender/recipient/fee, checked (imported/unlocked) before the first send
+			senders := make(map[string]*RPCSender)
+			for _, step := range remaining {
+				key := fmt.Sprintf("%s>%s@%d", normalizeAddress(step.From), normalizeAddress(step.To), step.Fee)
+				if senders[key] != nil {
+					continue
+				}
+				if step.Value != txValue {
+					return fmt.Errorf("step %d sends %d Luna, but transactions always carry %d Luna", step.Step, step.Value, txValue)
+				}
+				sender, err := NewRPCSender(rpcURL, step.From, step.To, step.Fee)
+				if err != nil {
+					return fmt.Errorf("failed to initialize sender %s: %w", step.From, err)
+				}
+				senders[key] = sender.WithContext(ctx)
+			}
+
+			limiter := rate.NewLimiter(rate.Limit(rateLimit), 1)
+			fmt.Println()
+			for i, step := range remaining {
+				if err := limiter.Wait(ctx); err != nil {
+					return fmt.Errorf("interrupted after %d/%d transactions (progress saved to %s, run execute again to resume)", len(plan.Steps)-len(remaining)+i, len(plan.Steps), progressPath)
+				}
+				payload, err := hex.DecodeString(step.Payload)
+				if err != nil {
+					return fmt.Errorf("step %d: %w", step.Step, err)
+				}
+				key := fmt.Sprintf("%s>%s@%d", normalizeAddress(step.From), normalizeAddress(step.To), step.Fee)
+				txHash, err := senders[key].SendTransaction(payload)
+				if err != nil {
+					return fmt.Errorf("step %d (%s) failed: %w (progress saved to %s, run execute again to resume)", step.Step, step.Kind, err, progressPath)
+				}
+				if err := progress.record(step.Step, txHash); err != nil {
+					fmt.Printf("Warning: %v\n", err)
+				}
+				if step.Kind == StepDATA {
+					fmt.Printf("[%d/%d] DATA chunk %d: %s\n", step.Step+1, len(plan.Steps), *step.Chunk, shortHash(txHash))
+				} else {
+					fmt.Printf("[%d/%d] ✓ %s: %s\n", step.Step+1, len(plan.Steps), step.Kind, txHash)
+				}
+			}
+
+			logCartridgeUpload(fmt.Sprintf("Executed plan %s (hash %s): app-id %d, cartridge-id %d, %d transactions", planPath, h.PlanHash, h.AppID, h.CartridgeID, len(remaining)))
+			fmt.Printf("\n✓ Plan executed: %d transactions sent (progress in %s)\n", len(remaining), progressPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&planPath, "plan", "", "Plan file written by upload-cartridge --plan-out (required)")
+	cmd.Flags().StringVar(&progressPath, "progress", "", "Progress file (default: <plan>.progress.json)")
+	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
+	cmd.Flags().Float64Var(&rateLimit, "rate", 25.0, "Transaction rate limit (tx/s, default: 25)")
+	cmd.Flags().BoolVar(&waitForFundsFlag, "wait-for-funds", false, "Wait for the sender account(s) to be funded instead of refusing to start")
+	cmd.Flags().BoolVar(&skipPreflight, "skip-preflight", false, "Skip the balance check")
+
+	cmd.MarkFlagRequired("plan")
+
+	return cmd
+}
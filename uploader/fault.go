@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault injection exercises the resume and retry paths of uploads: sends that
+// fail, hang, return someone else's hash, time out after reaching the node,
+// or a process that dies between a send and its journal entry. It is enabled
+// with the hidden --inject-faults flag, e.g.
+//
+//	--inject-faults "fail=0.1,timeout=0.05,duplicate=0.02,latency=200ms,crash-after=40,seed=7"
+//
+// and applies to the RPC transport (every client, default) or, with
+// layer=sender, to the TxSenders of upload-cartridge only.
+
+// Fault injection layers
+const (
+	FaultLayerRPC    = "rpc"
+	FaultLayerSender = "sender"
+)
+
+// FaultConfig describes the faults to inject. Rates are probabilities per send.
+type FaultConfig struct {
+	Layer         string        // FaultLayerRPC or FaultLayerSender
+	FailRate      float64       // the send fails before reaching the node
+	TimeoutRate   float64       // the send reaches the node, but the answer is lost
+	DuplicateRate float64       // the send succeeds, but returns the hash of an earlier send
+	Latency       time.Duration // up to this much delay before every call
+	CrashAfter    int           // exit the process right after the Nth successful send (0 = never)
+	Seed          int64         // for reproducible runs (0 = random)
+}
+
+// injectFaultsFlag is the hidden root --inject-faults flag
+var injectFaultsFlag string
+
+// activeFaults is the injector configured by --inject-faults (nil if none)
+var activeFaults *FaultInjector
+
+// ParseFaultConfig parses a comma-separated key=value fault spec (see above)
+func ParseFaultConfig(spec string) (FaultConfig, error) {
+	cfg := FaultConfig{Layer: FaultLayerRPC}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return cfg, fmt.Errorf("invalid fault %q (use key=value)", part)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "layer":
+			if value != FaultLayerRPC && value != FaultLayerSender {
+				return cfg, fmt.Errorf("invalid fault layer %q (use rpc or sender)", value)
+			}
+			cfg.Layer = value
+		case "fail":
+			cfg.FailRate, err = parseFaultRate(value)
+		case "timeout":
+			cfg.TimeoutRate, err = parseFaultRate(value)
+		case "duplicate":
+			cfg.DuplicateRate, err = parseFaultRate(value)
+		case "latency":
+			cfg.Latency, err = time.ParseDuration(value)
+		case "crash-after":
+			cfg.CrashAfter, err = strconv.Atoi(value)
+			if err == nil && cfg.CrashAfter < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		case "seed":
+			cfg.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return cfg, fmt.Errorf("unknown fault %q (use fail, timeout, duplicate, latency, crash-after, seed or layer)", key)
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("invalid fault %s=%s: %v", key, value, err)
+		}
+	}
+	return cfg, nil
+}
+
+func parseFaultRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("must be between 0 and 1")
+	}
+	return rate, nil
+}
+
+// setupFaultInjection enables the faults from --inject-faults, if set
+func setupFaultInjection() error {
+	if injectFaultsFlag == "" {
+		return nil
+	}
+	cfg, err := ParseFaultConfig(injectFaultsFlag)
+	if err != nil {
+		return err
+	}
+	activeFaults = NewFaultInjector(cfg)
+	fmt.Fprintf(os.Stderr, "⚠️  Fault injection enabled (%s layer): %s\n", cfg.Layer, injectFaultsFlag)
+	return nil
+}
+
+// errInjectedTimeout is the lost answer of a send that reached the node
+var errInjectedTimeout = errors.New("injected fault: timeout after send")
+
+// FaultInjector decides which sends fail. One injector is shared by every
+// sender or transport it is applied to, so crash-after counts all sends.
+type FaultInjector struct {
+	cfg FaultConfig
+
+	// Crash is called instead of exiting when the crash-after send is reached
+	// (for use in tests); it defaults to exiting without any cleanup
+	Crash func(sends int)
+
+	mu     sync.Mutex
+	rng    *rand.Rand
+	sends  int
+	hashes []string
+}
+
+// NewFaultInjector creates an injector for cfg
+func NewFaultInjector(cfg FaultConfig) *FaultInjector {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &FaultInjector{cfg: cfg, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Sends returns the number of successful sends so far
+func (f *FaultInjector) Sends() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sends
+}
+
+func (f *FaultInjector) chance(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rng.Float64() < rate
+}
+
+// delay sleeps for a random share of the configured latency
+func (f *FaultInjector) delay(ctx context.Context) {
+	if f.cfg.Latency <= 0 {
+		return
+	}
+	f.mu.Lock()
+	d := time.Duration(f.rng.Int63n(int64(f.cfg.Latency) + 1))
+	f.mu.Unlock()
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
+
+// beforeSend returns an error if the send should fail before reaching the node
+func (f *FaultInjector) beforeSend() error {
+	if f.chance(f.cfg.FailRate) {
+		return fmt.Errorf("injected fault: send failed")
+	}
+	return nil
+}
+
+// afterSend is called with the hash of a send that reached the node and
+// returns what the caller gets to see instead
+func (f *FaultInjector) afterSend(txHash string) (string, error) {
+	f.mu.Lock()
+	f.sends++
+	sends := f.sends
+	previous := ""
+	if len(f.hashes) > 0 {
+		previous = f.hashes[f.rng.Intn(len(f.hashes))]
+	}
+	f.hashes = append(f.hashes, txHash)
+	f.mu.Unlock()
+
+	if f.cfg.CrashAfter > 0 && sends == f.cfg.CrashAfter {
+		if f.Crash != nil {
+			f.Crash(sends)
+		} else {
+			fmt.Fprintf(os.Stderr, "\n💥 Injected fault: crashing after %d sends\n", sends)
+			os.Exit(99)
+		}
+	}
+	if f.chance(f.cfg.TimeoutRate) {
+		return "", errInjectedTimeout
+	}
+	if previous != "" && f.chance(f.cfg.DuplicateRate) {
+		return previous, nil
+	}
+	return txHash, nil
+}
+
+// FaultSender wraps a TxSender with injected faults
+type FaultSender struct {
+	inner  TxSender
+	faults *FaultInjector
+}
+
+// NewFaultSender wraps inner with the faults of f
+func NewFaultSender(inner TxSender, f *FaultInjector) *FaultSender {
+	return &FaultSender{inner: inner, faults: f}
+}
+
+func (s *FaultSender) SendTransaction(payload []byte) (string, error) {
+	txHash, _, err := s.send(func() (string, int64, error) {
+		txHash, err := s.inner.SendTransaction(payload)
+		return txHash, 0, err
+	})
+	return txHash, err
+}
+
+// ResendTransaction resends with an escalated fee if the wrapped sender can
+func (s *FaultSender) ResendTransaction(payload []byte, attempt int) (string, int64, error) {
+	if resender, ok := s.inner.(Resender); ok {
+		return s.send(func() (string, int64, error) { return resender.ResendTransaction(payload, attempt) })
+	}
+	txHash, err := s.SendTransaction(payload)
+	return txHash, 0, err
+}
+
+func (s *FaultSender) send(send func() (string, int64, error)) (string, int64, error) {
+	s.faults.delay(context.Background())
+	if err := s.faults.beforeSend(); err != nil {
+		return "", 0, err
+	}
+	txHash, validFrom, err := send()
+	if err != nil {
+		return "", 0, err
+	}
+	txHash, err = s.faults.afterSend(txHash)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w (%w)", err, ErrOutcomeUnknown)
+	}
+	return txHash, validFrom, nil
+}
+
+// faultSendMethods are the RPC methods that broadcast a transaction
+var faultSendMethods = map[string]bool{
+	"sendRawTransaction": true,
+}
+
+// FaultTransport wraps an HTTP transport with injected faults. Every call is
+// delayed; only calls that broadcast a transaction fail.
+type FaultTransport struct {
+	inner  http.RoundTripper
+	faults *FaultInjector
+}
+
+// NewFaultTransport wraps inner (http.DefaultTransport if nil) with the faults of f
+func NewFaultTransport(inner http.RoundTripper, f *FaultInjector) *FaultTransport {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	return &FaultTransport{inner: inner, faults: f}
+}
+
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.faults.delay(req.Context())
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	var rpcReq struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if json.Unmarshal(body, &rpcReq) != nil || !faultSendMethods[rpcReq.Method] {
+		return t.inner.RoundTrip(req)
+	}
+
+	if err := t.faults.beforeSend(); err != nil {
+		// Never reached the node, like a refused connection
+		return nil, &net.OpError{Op: "dial", Net: "fault", Err: err}
+	}
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var rpcResp JSONRPCResponse
+	if json.Unmarshal(respBody, &rpcResp) != nil || rpcResp.Error != nil {
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+		return resp, nil
+	}
+	txHash, _ := parseTransactionHash(unwrapData(rpcResp.Result))
+
+	shown, err := t.faults.afterSend(txHash)
+	if err != nil {
+		return nil, err // the client reports it as outcome unknown
+	}
+	if shown != txHash {
+		respBody, _ = json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": rpcReq.ID, "result": shown})
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	resp.ContentLength = int64(len(respBody))
+	return resp, nil
+}
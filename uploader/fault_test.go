@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/maestroi/nimiq-doom/uploader/mocknode"
+)
+
+// uploadOutcome is what an upload leaves behind, without the parts that
+// differ between runs (transaction hashes, heights, fees)
+type uploadOutcome struct {
+	Journal  journalOutcome
+	Catalog  []catalogOutcome
+	Chunks   int    // distinct DATA chunks on chain
+	Contents []byte // file reassembled from the chain
+}
+
+type journalOutcome struct {
+	AppID, CartridgeID       uint32
+	CartridgeAddr            string
+	TotalChunks, SentChunks  int
+	SHA256                   string
+	Payloads                 map[uint32]string // chunk index -> DATA payload
+	Senders                  []string          // co-signers that sent chunks
+	HasCART, HasCENT         bool
+	CSGNRecords              int
+	FailedChunks, UnknownIdx []int
+}
+
+type catalogOutcome struct {
+	AppID                 uint32
+	Title                 string
+	Platform              uint8
+	Semver, CartridgeAddr string
+	HasCART               bool
+	CartridgeID           uint32
+	TotalSize             uint64
+	SHA256                string
+}
+
+// faultTestUpload uploads content to a fresh mock node until the upload is
+// complete on chain, resuming after every failed or unfinished run like a
+// user following the resume hint. faults (may be nil) is active for all runs.
+func faultTestUpload(t *testing.T, content []byte, dropRate float64, faults *FaultInjector) uploadOutcome {
+	t.Helper()
+	isolateSettings(t)
+	previous := activeFaults
+	activeFaults = faults
+	defer func() { activeFaults = previous }()
+
+	node := mocknode.New(mocknode.Options{DropRate: dropRate, Seed: 11})
+	rpcURL := startTestNode(t, node)
+	rpc := NewNimiqRPC(rpcURL)
+	publisher := importTestAccount(t, node, rpc, 1)
+	senders := []string{importTestAccount(t, node, rpc, 2), importTestAccount(t, node, rpc, 3)}
+
+	file := filepath.Join(t.TempDir(), "game.zip")
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const maxRuns = 10
+	for run := 1; ; run++ {
+		if run > maxRuns {
+			t.Fatalf("upload not complete on chain after %d runs (%d transactions dropped, %d sends)", maxRuns, node.Dropped(), faults.Sends())
+		}
+		u := &cartridgeUpload{
+			filePath:      file,
+			appID:         1,
+			cartridgeID:   1,
+			title:         "Fault Test",
+			semver:        "1.0.0",
+			cartridgeAddr: testCartridgeAddr(2),
+			catalogAddr:   "test",
+			sender:        publisher,
+			senders:       strings.Join(senders, ","),
+			rpcURL:        rpcURL,
+			feeFlag:       "0",
+			rateLimit:     1000,
+			skipPreflight: true,
+			chunkSize:     ChunkSize,
+			concurrency:   2,
+			maxSize:       "1MB",
+			noResumeHint:  true,
+		}
+		_, err := u.run(context.Background())
+		if err != nil {
+			t.Logf("run %d: %v", run, err)
+		}
+		node.MineBlock()
+
+		outcome := readUploadOutcome(t, rpc, publisher, u.cartridgeAddr)
+		if err == nil && uploadComplete(outcome, len(content)) {
+			if faults != nil || dropRate > 0 {
+				t.Logf("complete after %d runs (%d transactions dropped, %d sends)", run, node.Dropped(), faults.Sends())
+			}
+			return outcome
+		}
+		// Let everything that was not mined expire, so the next run sends it again
+		for i := 0; i <= mocknode.ValidityWindow; i++ {
+			node.MineBlock()
+		}
+	}
+}
+
+// uploadComplete reports whether the journal and the chain both hold the
+// whole upload
+func uploadComplete(o uploadOutcome, size int) bool {
+	j := o.Journal
+	return j.TotalChunks > 0 && j.SentChunks == j.TotalChunks && j.HasCART && j.HasCENT &&
+		len(j.FailedChunks) == 0 && len(j.UnknownIdx) == 0 &&
+		o.Chunks == j.TotalChunks && len(o.Catalog) == 1 && o.Catalog[0].HasCART && len(o.Contents) == size
+}
+
+func readUploadOutcome(t *testing.T, rpc *NimiqRPC, publisher, cartridgeAddr string) uploadOutcome {
+	t.Helper()
+	var outcome uploadOutcome
+
+	data, err := os.ReadFile("upload_cartridge_1_1.json")
+	if err != nil {
+		t.Fatalf("failed to read progress: %v", err)
+	}
+	var p CartridgeUploadProgress
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatalf("failed to parse progress: %v", err)
+	}
+	j := journalOutcome{
+		AppID: p.AppID, CartridgeID: p.CartridgeID, CartridgeAddr: p.CartridgeAddr,
+		TotalChunks: p.TotalChunks, SentChunks: p.SentChunks, SHA256: p.SHA256,
+		Payloads: make(map[uint32]string), HasCART: p.CARTTxHash != "", HasCENT: p.CENTTxHash != "",
+		CSGNRecords: len(p.CSGNTxHashes), FailedChunks: p.FailedChunks, UnknownIdx: p.UnknownChunks,
+	}
+	for _, plan := range p.Plan {
+		j.Payloads[plan.Index] = plan.Payload
+	}
+	j.Senders = uploadCoSigners(p.Plan)
+	sort.Strings(j.Senders)
+	outcome.Journal = j
+
+	apps, err := LoadCatalogState(rpc, resolveCatalogAddress("test"), publisher)
+	if err != nil {
+		t.Fatalf("failed to load catalog: %v", err)
+	}
+	for _, app := range apps {
+		for _, v := range app.Versions {
+			outcome.Catalog = append(outcome.Catalog, catalogOutcome{
+				AppID: app.AppID, Title: app.Title, Platform: app.Platform,
+				Semver: v.Semver(), CartridgeAddr: v.CartridgeAddr,
+				HasCART: v.HasCART, CartridgeID: v.CartridgeID, TotalSize: v.TotalSize, SHA256: v.SHA256,
+			})
+		}
+	}
+
+	txs, err := GetAllTransactionsByAddress(rpc, cartridgeAddr, 50)
+	if err != nil {
+		t.Fatalf("failed to read cartridge: %v", err)
+	}
+	chunks := make(map[uint32][]byte)
+	for _, tx := range txs {
+		if chunk, err := DecodeDATA(transactionPayload(tx)); err == nil {
+			chunks[chunk.ChunkIndex] = chunk.Data // duplicates carry the same data
+		}
+	}
+	outcome.Chunks = len(chunks)
+	var contents bytes.Buffer
+	for idx := uint32(0); ; idx++ {
+		chunk, ok := chunks[idx]
+		if !ok {
+			break
+		}
+		contents.Write(chunk)
+	}
+	outcome.Contents = contents.Bytes()
+	return outcome
+}
+
+// An upload against a node that drops transactions, with sends that fail
+// before reaching the node and answers lost after it, ends in the same
+// journal and catalog state as a clean run once it is resumed to completion
+func TestFaultInjectedUploadConverges(t *testing.T) {
+	content := testFile(40*ChunkSize + 17)
+
+	var clean uploadOutcome
+	t.Run("clean", func(t *testing.T) {
+		clean = faultTestUpload(t, content, 0, nil)
+	})
+	if t.Failed() {
+		return
+	}
+	if !bytes.Equal(clean.Contents, content) {
+		t.Fatalf("clean run: chain holds other contents than the file")
+	}
+
+	for _, layer := range []string{FaultLayerSender, FaultLayerRPC} {
+		t.Run(layer, func(t *testing.T) {
+			faults := NewFaultInjector(FaultConfig{Layer: layer, FailRate: 0.1, TimeoutRate: 0.1, Seed: 7})
+			faulty := faultTestUpload(t, content, 0.2, faults)
+			if !reflect.DeepEqual(faulty, clean) {
+				t.Errorf("state differs from the clean run:\n got %s\nwant %s", describeOutcome(faulty), describeOutcome(clean))
+			}
+		})
+	}
+}
+
+func describeOutcome(o uploadOutcome) string {
+	o.Journal.Payloads = nil
+	return fmt.Sprintf("%+v (%d payloads)", struct {
+		Journal journalOutcome
+		Catalog []catalogOutcome
+		Chunks  int
+	}{o.Journal, o.Catalog, o.Chunks}, len(o.Journal.Payloads))
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// dataTxSize is the serialized size in bytes of one of our transactions: an
+	// extended transaction carrying a 64-byte payload plus a signature proof.
+	// Fees are charged per byte, so this turns fee/byte into fee/transaction.
+	dataTxSize = 233
+
+	// blockTxCapacity is roughly how many transactions fit in the next blocks;
+	// if fewer transactions than this pay more than us, we get in quickly
+	blockTxCapacity = 1000
+
+	// feeRefreshInterval is how often dynamic fees are recomputed during an upload
+	feeRefreshInterval = 30 * time.Second
+)
+
+// Fee modes for --fee
+const (
+	FeeFixed = "fixed" // an explicit amount in Luna
+	FeeMin   = "min"   // the node's minimum fee per byte
+	FeeAuto  = "auto"  // enough to get into the next blocks given the mempool
+	FeeFast  = "fast"  // one fee level above auto
+)
+
+// FeePolicy decides the fee per transaction
+type FeePolicy struct {
+	Mode   string
+	Fixed  int64 // Luna per transaction (FeeFixed)
+	MaxFee int64 // cap per transaction in Luna (if Capped; 0 is a valid cap)
+	Capped bool
+}
+
+// ParseFeePolicy parses --fee: auto, min, fast or a fixed amount in Luna
+func ParseFeePolicy(value string) (FeePolicy, error) {
+	switch v := strings.ToLower(strings.TrimSpace(value)); v {
+	case FeeMin, FeeAuto, FeeFast:
+		return FeePolicy{Mode: v}, nil
+	case "":
+		return FeePolicy{Mode: FeeFixed}, nil
+	default:
+		fee, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || fee < 0 {
+			return FeePolicy{}, fmt.Errorf("invalid fee %q (use auto, min, fast or an amount in Luna)", value)
+		}
+		return FeePolicy{Mode: FeeFixed, Fixed: fee}, nil
+	}
+}
+
+// String describes the policy for output
+func (p FeePolicy) String() string {
+	s := p.Mode
+	if p.Mode == FeeFixed {
+		s = fmt.Sprintf("%d Luna", p.Fixed)
+	}
+	if p.Capped {
+		s += fmt.Sprintf(" (max %d Luna/tx)", p.MaxFee)
+	}
+	return s
+}
+
+// FeeOracle resolves a FeePolicy against the node's fee information. Dynamic
+// fees are cached and refreshed every feeRefreshInterval. Safe for concurrent use.
+type FeeOracle struct {
+	policy FeePolicy
+	rpc    *NimiqRPC
+	log    io.Writer // warnings (stdout unless set with WithLog)
+
+	mu      sync.Mutex
+	fee     int64
+	updated time.Time
+}
+
+// NewFeeOracle creates an oracle for policy (rpc may be nil for fixed fees)
+func NewFeeOracle(rpc *NimiqRPC, policy FeePolicy) *FeeOracle {
+	return &FeeOracle{policy: policy, rpc: rpc, log: os.Stdout}
+}
+
+// WithLog sends the oracle's warnings to w
+func (o *FeeOracle) WithLog(w io.Writer) *FeeOracle {
+	o.log = w
+	return o
+}
+
+// FixedFeeOracle returns an oracle that always answers fee
+func FixedFeeOracle(fee int64) *FeeOracle {
+	return NewFeeOracle(nil, FeePolicy{Mode: FeeFixed, Fixed: fee})
+}
+
+// Policy returns the policy the oracle applies
+func (o *FeeOracle) Policy() FeePolicy {
+	return o.policy
+}
+
+// SetMaxFee caps the fee per transaction (including escalated fees); a cap
+// of 0 allows only fee-free transactions
+func (o *FeeOracle) SetMaxFee(maxFee int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.policy.MaxFee = maxFee
+	o.policy.Capped = true
+}
+
+// Fee returns the current fee per transaction in Luna
+func (o *FeeOracle) Fee() (int64, error) {
+	return o.FeeForAttempt(0)
+}
+
+// FeeForAttempt returns the fee for the attempt-th resend of a transaction.
+// Dynamic policies double the fee for every earlier attempt (at least 1 Luna
+// per byte per attempt), so transactions that expired in the mempool get in.
+// Fixed fees are never escalated.
+func (o *FeeOracle) FeeForAttempt(attempt int) (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.policy.Mode == FeeFixed {
+		return o.capFee(o.policy.Fixed), nil
+	}
+
+	if o.updated.IsZero() || time.Since(o.updated) > feeRefreshInterval {
+		fee, err := o.resolve()
+		if err != nil {
+			if o.updated.IsZero() {
+				return 0, err
+			}
+			// Keep using the last known fee if the node hiccups
+			fmt.Fprintf(o.log, "Warning: failed to refresh fee, keeping %d Luna: %v\n", o.fee, err)
+		} else {
+			o.fee = fee
+		}
+		o.updated = time.Now()
+	}
+
+	fee := o.fee
+	if attempt > 0 {
+		shift := attempt
+		if shift > 20 {
+			shift = 20
+		}
+		escalated := fee << uint(shift)
+		if floor := int64(attempt) * dataTxSize; escalated < floor {
+			escalated = floor
+		}
+		fee = escalated
+	}
+	return o.capFee(fee), nil
+}
+
+func (o *FeeOracle) capFee(fee int64) int64 {
+	if o.policy.Capped && fee > o.policy.MaxFee {
+		return o.policy.MaxFee
+	}
+	return fee
+}
+
+// resolve computes the fee per transaction from the node (caller holds mu)
+func (o *FeeOracle) resolve() (int64, error) {
+	minPerByte, err := o.rpc.GetMinFeePerByte()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get minimum fee: %w", err)
+	}
+	if o.policy.Mode == FeeMin {
+		return feePerTx(minPerByte), nil
+	}
+
+	mempool, err := o.rpc.GetMempoolInfo()
+	if err != nil {
+		// Without mempool information the minimum fee is the best guess
+		fmt.Fprintf(o.log, "Warning: failed to get mempool info, using minimum fee: %v\n", err)
+		return feePerTx(minPerByte), nil
+	}
+	return feePerTx(chooseFeePerByte(o.policy.Mode, minPerByte, mempool)), nil
+}
+
+// chooseFeePerByte picks the lowest fee level at or above which fewer than
+// blockTxCapacity mempool transactions are waiting (auto), or the level above it (fast)
+func chooseFeePerByte(mode string, minPerByte float64, mempool *MempoolInfo) float64 {
+	levels := make([]float64, 0, len(mempool.Buckets))
+	for level := range mempool.Buckets {
+		levels = append(levels, level)
+	}
+	sort.Float64s(levels)
+
+	// Number of transactions paying at least levels[i]
+	atOrAbove := make([]int, len(levels))
+	total := 0
+	for i := len(levels) - 1; i >= 0; i-- {
+		total += mempool.Buckets[levels[i]]
+		atOrAbove[i] = total
+	}
+
+	chosen := -1
+	for i, level := range levels {
+		if level < minPerByte {
+			continue
+		}
+		// Transactions in this bucket or above may pay as much as we would
+		if atOrAbove[i] < blockTxCapacity {
+			chosen = i
+			break
+		}
+	}
+
+	feePerByte := minPerByte
+	if chosen >= 0 && levels[chosen] > feePerByte {
+		feePerByte = levels[chosen]
+	}
+	if chosen < 0 && len(levels) > 0 {
+		// Everything is congested: pay the top level
+		feePerByte = math.Max(minPerByte, levels[len(levels)-1])
+	}
+
+	if mode == FeeFast {
+		if chosen >= 0 && chosen+1 < len(levels) && levels[chosen+1] > feePerByte {
+			feePerByte = levels[chosen+1]
+		} else {
+			feePerByte = math.Max(feePerByte*2, 1)
+		}
+	}
+	return feePerByte
+}
+
+// feePerTx converts a fee per byte to a fee for one of our transactions
+func feePerTx(feePerByte float64) int64 {
+	return int64(math.Ceil(feePerByte * dataTxSize))
+}
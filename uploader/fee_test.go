@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/maestroi/nimiq-doom/uploader/mocknode"
+)
+
+// Escalated resend fees stay within --max-spend, also when the budget only
+// covers the transaction values (a fee cap of 0)
+func TestMaxSpendCapsEscalatedFees(t *testing.T) {
+	const dataTxs = 40
+	estimateAt := func(fee int64) *UploadEstimate {
+		return EstimateUpload("NQ00 PUBLISHER", nil, dataTxs, true, true, nil, fee)
+	}
+	txs := estimateAt(0).Transactions()
+
+	tests := []struct {
+		name     string
+		budget   int64 // Luna
+		wantCap  int64
+		wantFail bool
+	}{
+		{name: "values only", budget: txs * txValue, wantCap: 0},
+		{name: "values plus 100 Luna per tx", budget: txs * (txValue + 100), wantCap: 100},
+		{name: "values plus a remainder", budget: txs*(txValue+100) + txs - 1, wantCap: 100},
+		{name: "less than the values", budget: txs*txValue - 1, wantFail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := mocknode.New(mocknode.Options{MinFeePerByte: 2})
+			rpc := NewNimiqRPC(startTestNode(t, node))
+			feeOracle := NewFeeOracle(rpc, FeePolicy{Mode: FeeAuto})
+			fee, err := feeOracle.Fee()
+			if err != nil {
+				t.Fatalf("failed to get fee: %v", err)
+			}
+
+			u := &cartridgeUpload{maxSpend: float64(tt.budget) / lunaPerNIM}
+			fee, estimate, err := u.capFee(feeOracle, fee, estimateAt)
+			if tt.wantFail {
+				if err == nil {
+					t.Fatalf("budget of %d Luna for %d transactions accepted", tt.budget, txs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("capFee: %v", err)
+			}
+			if fee != tt.wantCap || estimate.TotalLuna() > tt.budget {
+				t.Errorf("fee %d Luna (total %d Luna), expected %d Luna within %d Luna", fee, estimate.TotalLuna(), tt.wantCap, tt.budget)
+			}
+			if !feeOracle.Policy().Capped {
+				t.Errorf("policy %s is not capped", feeOracle.Policy())
+			}
+			for attempt := 0; attempt <= 25; attempt++ {
+				resendFee, err := feeOracle.FeeForAttempt(attempt)
+				if err != nil {
+					t.Fatalf("attempt %d: %v", attempt, err)
+				}
+				if resendFee > tt.wantCap || txs*(txValue+resendFee) > tt.budget {
+					t.Errorf("attempt %d: fee %d Luna exceeds the cap of %d Luna (budget %d Luna)", attempt, resendFee, tt.wantCap, tt.budget)
+				}
+			}
+		})
+	}
+}
+
+// Without --max-spend resends escalate freely
+func TestFeeEscalationUncapped(t *testing.T) {
+	node := mocknode.New(mocknode.Options{MinFeePerByte: 2})
+	rpc := NewNimiqRPC(startTestNode(t, node))
+	feeOracle := NewFeeOracle(rpc, FeePolicy{Mode: FeeMin})
+	fee, err := feeOracle.Fee()
+	if err != nil {
+		t.Fatalf("failed to get fee: %v", err)
+	}
+	escalated, err := feeOracle.FeeForAttempt(3)
+	if err != nil {
+		t.Fatalf("failed to get fee: %v", err)
+	}
+	if escalated != fee<<3 {
+		t.Errorf("third resend pays %d Luna, expected %d", escalated, fee<<3)
+	}
+}
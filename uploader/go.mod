@@ -4,10 +4,13 @@ go 1.21
 
 require (
 	github.com/spf13/cobra v1.8.0
+	golang.org/x/crypto v0.17.0
 	golang.org/x/time v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/sys v0.15.0 // indirect
 )
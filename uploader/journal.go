@@ -0,0 +1,588 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Journal operations (one per state transition of an upload)
+const (
+	JournalChunkSent   = "chunk_sent"
+	JournalChunkFailed = "chunk_failed"
+	// JournalChunkUnknown marks a send that was interrupted after the request
+	// may have reached the node; the chunk is re-sent on resume
+	JournalChunkUnknown = "chunk_unknown"
+	// JournalChunkExpired marks a sent chunk whose transaction left its
+	// validity window without being mined; the chunk is re-sent
+	JournalChunkExpired = "chunk_expired"
+	JournalCARTSent     = "cart_sent"
+	JournalCENTSent     = "cent_sent"
+	JournalCSGNSent     = "csgn_sent" // idx is the position of the CSGN record
+	// JournalHeaderExpired marks a CART, CENT or CSGN transaction (tx_hash)
+	// that left its validity window without being mined; it is sent again
+	JournalHeaderExpired = "header_expired"
+	JournalFileHash      = "file_sha256"
+	JournalNetwork       = "network" // idx is the network ID of the node
+
+	// journalCompactEvery is the number of journal entries after which the
+	// journal is folded into the snapshot and truncated
+	journalCompactEvery = 500
+)
+
+// JournalEntry is a single line in the append-only progress journal
+type JournalEntry struct {
+	Op      string `json:"op"`
+	Index   uint32 `json:"idx"`
+	Payload string `json:"payload_hex,omitempty"`
+	TxHash  string `json:"tx_hash,omitempty"`
+	Sender  string `json:"sender,omitempty"` // Set when the chunk was sent by a co-signer
+	Error   string `json:"error,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
+	// ValidFrom and Attempt describe a sent chunk (see UploadPlan); ValidFrom
+	// is also set for CART, CENT and CSGN transactions
+	ValidFrom int64 `json:"validity_start_height,omitempty"`
+	Attempt   int   `json:"attempt,omitempty"`
+	Time      int64 `json:"ts"`
+}
+
+// forceUnlockFlag is the root --force-unlock flag: take over lock files left
+// behind by other processes (only needed where locks are plain files, see
+// lock_other.go)
+var forceUnlockFlag bool
+
+// ProgressJournal keeps the progress of a cartridge upload crash-safe.
+//
+// Every state transition is appended to <base>.journal and fsync'd before the
+// caller continues, so a crash loses at most the transaction in flight. The
+// journal is periodically compacted into the <base>.json snapshot (written to a
+// temp file and renamed into place) and then truncated. A lock file prevents
+// two processes from driving the same upload at once.
+type ProgressJournal struct {
+	mu           sync.Mutex
+	snapshotPath string
+	journalPath  string
+	lock         *os.File
+	journal      *os.File
+	progress     *CartridgeUploadProgress
+	sent         map[uint32]int // chunk index -> position in progress.Plan
+	failed       map[uint32]bool
+	unknown      map[uint32]bool
+	pending      int // journal entries since the last compaction
+}
+
+// OpenProgressJournal locks and opens the journal for base (e.g. "upload_cartridge_1_2").
+//
+// An existing <base>.json snapshot - including progress files written before the
+// journal existed - is imported if accept returns true for it; otherwise the upload
+// starts from fresh. Any journal entries left behind by a crash are replayed on top.
+func OpenProgressJournal(base string, fresh *CartridgeUploadProgress, accept func(*CartridgeUploadProgress) bool) (*ProgressJournal, error) {
+	j := &ProgressJournal{
+		snapshotPath: base + ".json",
+		journalPath:  base + ".journal",
+		sent:         make(map[uint32]int),
+		failed:       make(map[uint32]bool),
+		unknown:      make(map[uint32]bool),
+	}
+
+	lock, err := acquireFileLock(base + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	j.lock = lock
+
+	progress := fresh
+	resumed := false
+	if data, err := os.ReadFile(j.snapshotPath); err == nil {
+		var loaded CartridgeUploadProgress
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			fmt.Printf("Warning: progress file %s is corrupt (%v). Starting fresh.\n", j.snapshotPath, err)
+		} else if accept(&loaded) {
+			progress = &loaded
+			resumed = true
+			fmt.Printf("Resuming from progress file: %s\n", j.snapshotPath)
+		} else {
+			fmt.Printf("Progress file exists but doesn't match current upload. Starting fresh.\n")
+		}
+	}
+
+	j.progress = progress
+	j.rebuildIndex()
+
+	// Entries in the journal belong to the snapshot they were written against,
+	// so they are only meaningful if that snapshot was accepted
+	if resumed {
+		replayed, err := j.replay()
+		if err != nil {
+			j.releaseLock()
+			return nil, err
+		}
+		if replayed > 0 {
+			fmt.Printf("Recovered %d journal entries from %s\n", replayed, j.journalPath)
+		}
+	}
+
+	// Fold everything into a fresh snapshot and start with an empty journal
+	if err := j.compactLocked(); err != nil {
+		j.releaseLock()
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// rebuildIndex derives the lookup maps from progress, dropping duplicate plan
+// entries and failures for chunks that were sent later
+func (j *ProgressJournal) rebuildIndex() {
+	plan := make([]UploadPlan, 0, len(j.progress.Plan))
+	for _, p := range j.progress.Plan {
+		if p.TxHash == "" {
+			continue
+		}
+		if _, ok := j.sent[p.Index]; ok {
+			continue
+		}
+		j.sent[p.Index] = len(plan)
+		plan = append(plan, p)
+	}
+	j.progress.Plan = plan
+
+	for _, idx := range j.progress.FailedChunks {
+		if _, ok := j.sent[uint32(idx)]; !ok {
+			j.failed[uint32(idx)] = true
+		}
+	}
+	for _, idx := range j.progress.UnknownChunks {
+		if _, ok := j.sent[uint32(idx)]; !ok {
+			j.unknown[uint32(idx)] = true
+		}
+	}
+	for idx := range j.progress.ResendAttempts {
+		if _, ok := j.sent[idx]; ok {
+			delete(j.progress.ResendAttempts, idx)
+		}
+	}
+	j.syncCounters()
+}
+
+// syncCounters recomputes the derived fields of progress from the maps
+func (j *ProgressJournal) syncCounters() {
+	j.progress.SentChunks = len(j.sent)
+	j.progress.FailedChunks = sortedIndices(j.failed)
+	j.progress.UnknownChunks = sortedIndices(j.unknown)
+}
+
+// sortedIndices returns the keys of set in ascending order (nil if empty)
+func sortedIndices(set map[uint32]bool) []int {
+	if len(set) == 0 {
+		return nil
+	}
+	indices := make([]int, 0, len(set))
+	for idx := range set {
+		indices = append(indices, int(idx))
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+// replay applies the entries in the journal file to the in-memory state
+func (j *ProgressJournal) replay() (int, error) {
+	file, err := os.Open(j.journalPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// Only the last line can be torn by a crash; anything after it is gone anyway
+			fmt.Printf("Warning: ignoring truncated journal entry in %s\n", j.journalPath)
+			break
+		}
+		j.apply(entry)
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	j.syncCounters()
+	return count, nil
+}
+
+// apply updates the in-memory state for one journal entry (caller holds mu)
+func (j *ProgressJournal) apply(entry JournalEntry) {
+	switch entry.Op {
+	case JournalChunkSent:
+		if _, ok := j.sent[entry.Index]; ok {
+			return
+		}
+		j.sent[entry.Index] = len(j.progress.Plan)
+		j.progress.Plan = append(j.progress.Plan, UploadPlan{
+			Index:     entry.Index,
+			Payload:   entry.Payload,
+			TxHash:    entry.TxHash,
+			Sender:    entry.Sender,
+			ValidFrom: entry.ValidFrom,
+			Attempt:   entry.Attempt,
+		})
+		delete(j.failed, entry.Index)
+		delete(j.unknown, entry.Index)
+		delete(j.progress.ResendAttempts, entry.Index)
+	case JournalChunkExpired:
+		pos, ok := j.sent[entry.Index]
+		if !ok {
+			return
+		}
+		attempts := j.progress.Plan[pos].Attempt + 1
+		j.progress.Plan = append(j.progress.Plan[:pos], j.progress.Plan[pos+1:]...)
+		j.sent = make(map[uint32]int, len(j.progress.Plan))
+		for i, p := range j.progress.Plan {
+			j.sent[p.Index] = i
+		}
+		if j.progress.ResendAttempts == nil {
+			j.progress.ResendAttempts = make(map[uint32]int)
+		}
+		j.progress.ResendAttempts[entry.Index] = attempts
+	case JournalChunkFailed:
+		if _, ok := j.sent[entry.Index]; !ok {
+			j.failed[entry.Index] = true
+			j.countAttempt(entry.Index)
+		}
+	case JournalChunkUnknown:
+		if _, ok := j.sent[entry.Index]; !ok {
+			j.unknown[entry.Index] = true
+			j.countAttempt(entry.Index)
+		}
+	case JournalCARTSent:
+		j.progress.CARTTxHash = entry.TxHash
+		j.headerSent(entry)
+	case JournalCENTSent:
+		j.progress.CENTTxHash = entry.TxHash
+		j.headerSent(entry)
+	case JournalCSGNSent:
+		if int(entry.Index) == len(j.progress.CSGNTxHashes) {
+			j.progress.CSGNTxHashes = append(j.progress.CSGNTxHashes, entry.TxHash)
+			j.headerSent(entry)
+		}
+	case JournalHeaderExpired:
+		switch entry.TxHash {
+		case "":
+			return
+		case j.progress.CARTTxHash:
+			j.progress.CARTTxHash = ""
+		case j.progress.CENTTxHash:
+			j.progress.CENTTxHash = ""
+		}
+		// CSGN records are sent in order, so the expired one and all after it go again
+		for i, txHash := range j.progress.CSGNTxHashes {
+			if txHash == entry.TxHash {
+				for _, later := range j.progress.CSGNTxHashes[i:] {
+					delete(j.progress.HeaderValidFrom, later)
+				}
+				j.progress.CSGNTxHashes = j.progress.CSGNTxHashes[:i]
+				break
+			}
+		}
+		delete(j.progress.HeaderValidFrom, entry.TxHash)
+	case JournalFileHash:
+		j.progress.SHA256 = entry.SHA256
+	case JournalNetwork:
+		j.progress.NetworkID = uint8(entry.Index)
+	}
+}
+
+// headerSent remembers the validity start height of a CART, CENT or CSGN
+// transaction (caller holds mu)
+func (j *ProgressJournal) headerSent(entry JournalEntry) {
+	if entry.ValidFrom <= 0 || entry.TxHash == "" {
+		return
+	}
+	if j.progress.HeaderValidFrom == nil {
+		j.progress.HeaderValidFrom = make(map[string]int64)
+	}
+	j.progress.HeaderValidFrom[entry.TxHash] = entry.ValidFrom
+}
+
+// countAttempt records one more unsuccessful send of chunk idx (caller holds mu)
+func (j *ProgressJournal) countAttempt(idx uint32) {
+	if j.progress.ResendAttempts == nil {
+		j.progress.ResendAttempts = make(map[uint32]int)
+	}
+	j.progress.ResendAttempts[idx]++
+}
+
+// append writes entry to the journal and fsyncs it, then applies it in memory
+func (j *ProgressJournal) append(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry.Time = time.Now().Unix()
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	if _, err := j.journal.Write(line); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := j.journal.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+
+	j.apply(entry)
+	j.syncCounters()
+
+	j.pending++
+	if j.pending >= journalCompactEvery {
+		if err := j.compactLocked(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordChunkSent records that chunk idx was accepted by the node as txHash.
+// sender is the co-signer that sent it ("" for the publisher), validFrom the
+// validity start height of the transaction (0 if unknown) and attempt the
+// number of earlier failed or expired sends. Returns the number of chunks sent so far.
+func (j *ProgressJournal) RecordChunkSent(idx uint32, payloadHex, txHash, sender string, validFrom int64, attempt int) (int, error) {
+	err := j.append(JournalEntry{Op: JournalChunkSent, Index: idx, Payload: payloadHex, TxHash: txHash, Sender: sender,
+		ValidFrom: validFrom, Attempt: attempt})
+	return j.SentCount(), err
+}
+
+// RecordChunkExpired records that the transaction of sent chunk idx expired
+// without being mined, so the chunk is sent again with one more attempt
+func (j *ProgressJournal) RecordChunkExpired(idx uint32) error {
+	return j.append(JournalEntry{Op: JournalChunkExpired, Index: idx})
+}
+
+// RecordChunkFailed records that sending chunk idx failed
+func (j *ProgressJournal) RecordChunkFailed(idx uint32, cause error) error {
+	entry := JournalEntry{Op: JournalChunkFailed, Index: idx}
+	if cause != nil {
+		entry.Error = cause.Error()
+	}
+	return j.append(entry)
+}
+
+// RecordChunkUnknown records that chunk idx may or may not have been broadcast
+func (j *ProgressJournal) RecordChunkUnknown(idx uint32, cause error) error {
+	entry := JournalEntry{Op: JournalChunkUnknown, Index: idx}
+	if cause != nil {
+		entry.Error = cause.Error()
+	}
+	return j.append(entry)
+}
+
+// RecordCART records the transaction hash of the CART header and its
+// validity start height (0 if unknown)
+func (j *ProgressJournal) RecordCART(txHash string, validFrom int64) error {
+	return j.append(JournalEntry{Op: JournalCARTSent, TxHash: txHash, ValidFrom: validFrom})
+}
+
+// RecordCENT records the transaction hash of the catalog entry and its
+// validity start height (0 if unknown)
+func (j *ProgressJournal) RecordCENT(txHash string, validFrom int64) error {
+	return j.append(JournalEntry{Op: JournalCENTSent, TxHash: txHash, ValidFrom: validFrom})
+}
+
+// RecordCSGN records the transaction hash of the n-th co-signer declaration
+// and its validity start height (0 if unknown)
+func (j *ProgressJournal) RecordCSGN(n int, txHash string, validFrom int64) error {
+	return j.append(JournalEntry{Op: JournalCSGNSent, Index: uint32(n), TxHash: txHash, ValidFrom: validFrom})
+}
+
+// RecordHeaderExpired records that the CART, CENT or CSGN transaction txHash
+// expired without being mined, so it is sent again
+func (j *ProgressJournal) RecordHeaderExpired(txHash string) error {
+	return j.append(JournalEntry{Op: JournalHeaderExpired, TxHash: txHash})
+}
+
+// RecordSHA256 records the content hash of the uploaded file
+func (j *ProgressJournal) RecordSHA256(sha256Hex string) error {
+	return j.append(JournalEntry{Op: JournalFileHash, SHA256: sha256Hex})
+}
+
+// RecordNetworkID records the network of the node the upload is sent to
+func (j *ProgressJournal) RecordNetworkID(networkID uint8) error {
+	return j.append(JournalEntry{Op: JournalNetwork, Index: uint32(networkID)})
+}
+
+// SentChunk returns the plan entry (transaction hash and payload) of chunk
+// idx if it was already sent
+func (j *ProgressJournal) SentChunk(idx uint32) (UploadPlan, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	pos, ok := j.sent[idx]
+	if !ok {
+		return UploadPlan{}, false
+	}
+	return j.progress.Plan[pos], true
+}
+
+// Attempts returns how many earlier sends of chunk idx failed, expired or had
+// an unknown outcome (used to escalate the fee when re-sending)
+func (j *ProgressJournal) Attempts(idx uint32) int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress.ResendAttempts[idx]
+}
+
+// SentCount returns the number of distinct chunks sent so far
+func (j *ProgressJournal) SentCount() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.sent)
+}
+
+// Progress returns a copy of the current progress
+func (j *ProgressJournal) Progress() CartridgeUploadProgress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	p := *j.progress
+	p.Plan = append([]UploadPlan(nil), j.progress.Plan...)
+	p.FailedChunks = append([]int(nil), j.progress.FailedChunks...)
+	p.UnknownChunks = append([]int(nil), j.progress.UnknownChunks...)
+	p.CSGNTxHashes = append([]string(nil), j.progress.CSGNTxHashes...)
+	if j.progress.HeaderValidFrom != nil {
+		p.HeaderValidFrom = make(map[string]int64, len(j.progress.HeaderValidFrom))
+		for txHash, validFrom := range j.progress.HeaderValidFrom {
+			p.HeaderValidFrom[txHash] = validFrom
+		}
+	}
+	if j.progress.ResendAttempts != nil {
+		p.ResendAttempts = make(map[uint32]int, len(j.progress.ResendAttempts))
+		for idx, n := range j.progress.ResendAttempts {
+			p.ResendAttempts[idx] = n
+		}
+	}
+	return p
+}
+
+// SnapshotPath returns the path of the JSON snapshot
+func (j *ProgressJournal) SnapshotPath() string {
+	return j.snapshotPath
+}
+
+// Compact writes the current state to the snapshot and truncates the journal
+func (j *ProgressJournal) Compact() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.compactLocked()
+}
+
+// compactLocked does the work of Compact (caller holds mu)
+func (j *ProgressJournal) compactLocked() error {
+	sort.Slice(j.progress.Plan, func(a, b int) bool {
+		return j.progress.Plan[a].Index < j.progress.Plan[b].Index
+	})
+	for i, p := range j.progress.Plan {
+		j.sent[p.Index] = i
+	}
+
+	data, err := json.MarshalIndent(j.progress, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress: %w", err)
+	}
+	if err := writeFileAtomic(j.snapshotPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to save progress: %w", err)
+	}
+
+	// The snapshot now holds everything, so the journal can start over
+	if j.journal != nil {
+		j.journal.Close()
+	}
+	journal, err := os.OpenFile(j.journalPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	j.journal = journal
+	j.pending = 0
+	return nil
+}
+
+// Close compacts the journal, removes it and releases the lock
+func (j *ProgressJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	err := j.compactLocked()
+	if j.journal != nil {
+		j.journal.Close()
+		j.journal = nil
+	}
+	if err == nil {
+		os.Remove(j.journalPath)
+	}
+	j.releaseLock()
+	return err
+}
+
+// releaseLock drops the lock (see releaseFileLock)
+func (j *ProgressJournal) releaseLock() {
+	if j.lock == nil {
+		return
+	}
+	releaseFileLock(j.lock)
+	j.lock = nil
+}
+
+// writeFileAtomic writes data to a temp file next to filename, fsyncs it and
+// renames it into place, so readers see either the old or the new content
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, filename); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	// Persist the rename itself
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testJournalBase = "upload_cartridge_1_2"
+
+// journalState is the part of the progress a resumed upload acts on
+type journalState struct {
+	Sent          []uint32
+	Failed        []int
+	Unknown       []int
+	Attempts      map[uint32]int
+	CART, CENT    string
+	CSGN          []string
+	SHA256        string
+	NetworkID     uint8
+	HeaderHeights map[string]int64
+}
+
+func journalStateOf(p CartridgeUploadProgress) journalState {
+	s := journalState{Failed: p.FailedChunks, Unknown: p.UnknownChunks, Attempts: p.ResendAttempts,
+		CART: p.CARTTxHash, CENT: p.CENTTxHash, CSGN: p.CSGNTxHashes, SHA256: p.SHA256, NetworkID: p.NetworkID,
+		HeaderHeights: p.HeaderValidFrom}
+	for _, entry := range p.Plan {
+		s.Sent = append(s.Sent, entry.Index)
+	}
+	if len(s.Attempts) == 0 {
+		s.Attempts = nil
+	}
+	if len(s.HeaderHeights) == 0 {
+		s.HeaderHeights = nil
+	}
+	return s
+}
+
+// openTestJournal opens the journal of a 10-chunk upload of app 1, cartridge 2
+func openTestJournal(t *testing.T) (*ProgressJournal, error) {
+	t.Helper()
+	return OpenProgressJournal(testJournalBase, &CartridgeUploadProgress{
+		AppID: 1, CartridgeID: 2, CartridgeAddr: testCartridgeAddr(2), TotalChunks: 10,
+	}, func(loaded *CartridgeUploadProgress) bool {
+		return loaded.AppID == 1 && loaded.CartridgeID == 2 && loaded.TotalChunks == 10
+	})
+}
+
+// crash drops the journal like a killed process would: no compaction, no
+// removal of the journal, only the lock goes away
+func crash(j *ProgressJournal) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.journal.Close()
+	j.journal = nil
+	j.releaseLock()
+}
+
+// Progress left behind on disk is recovered from the snapshot (or a progress
+// file of an older version) and whatever the journal holds on top of it
+func TestProgressJournalRecovery(t *testing.T) {
+	snapshot := `{"app_id": 1, "cartridge_id": 2, "cartridge_addr": "` + testCartridgeAddr(2) + `", "total_chunks": 10, "sent_chunks": 2,
+		"plan": [{"idx": 0, "payload_hex": "aa", "tx_hash": "t0"}, {"idx": 1, "payload_hex": "bb", "tx_hash": "t1"}]}`
+	sent := func(idx, tx string) string {
+		return `{"op":"chunk_sent","idx":` + idx + `,"payload_hex":"cc","tx_hash":"` + tx + `","ts":1}` + "\n"
+	}
+
+	tests := []struct {
+		name     string
+		snapshot string
+		journal  string
+		want     journalState
+	}{
+		{name: "fresh", want: journalState{}},
+		{name: "snapshot only", snapshot: snapshot, want: journalState{Sent: []uint32{0, 1}}},
+		{name: "journal on top of the snapshot", snapshot: snapshot,
+			journal: sent("2", "t2") + `{"op":"chunk_failed","idx":3,"error":"boom","ts":1}` + "\n" +
+				`{"op":"chunk_unknown","idx":4,"error":"timeout","ts":1}` + "\n" +
+				`{"op":"file_sha256","idx":0,"sha256":"abcd","ts":1}` + "\n" + `{"op":"network","idx":5,"ts":1}` + "\n",
+			want: journalState{Sent: []uint32{0, 1, 2}, Failed: []int{3}, Unknown: []int{4}, Attempts: map[uint32]int{3: 1, 4: 1},
+				SHA256: "abcd", NetworkID: 5}},
+		{name: "truncated last record", snapshot: snapshot,
+			journal: sent("2", "t2") + `{"op":"chunk_sent","idx":3,"payload_hex":"dd","tx_ha`,
+			want:    journalState{Sent: []uint32{0, 1, 2}}},
+		{name: "truncated only record", snapshot: snapshot, journal: `{"op":"cart_sent","tx_h`,
+			want: journalState{Sent: []uint32{0, 1}}},
+		// A crash between writing the snapshot and truncating the journal
+		// replays entries the snapshot already holds
+		{name: "entries already in the snapshot", snapshot: snapshot, journal: sent("0", "t0") + sent("1", "t1") + sent("2", "t2"),
+			want: journalState{Sent: []uint32{0, 1, 2}}},
+		{name: "failure of a chunk sent later", snapshot: snapshot,
+			journal: `{"op":"chunk_failed","idx":2,"error":"boom","ts":1}` + "\n" + sent("2", "t2"),
+			want:    journalState{Sent: []uint32{0, 1, 2}}},
+		{name: "expired chunk", snapshot: snapshot, journal: `{"op":"chunk_expired","idx":1,"ts":1}` + "\n",
+			want: journalState{Sent: []uint32{0}, Attempts: map[uint32]int{1: 1}}},
+		{name: "headers sent and expired", snapshot: snapshot,
+			journal: `{"op":"csgn_sent","idx":0,"tx_hash":"s0","validity_start_height":7,"ts":1}` + "\n" +
+				`{"op":"csgn_sent","idx":1,"tx_hash":"s1","validity_start_height":7,"ts":1}` + "\n" +
+				`{"op":"cart_sent","idx":0,"tx_hash":"c","validity_start_height":8,"ts":1}` + "\n" +
+				`{"op":"cent_sent","idx":0,"tx_hash":"e","validity_start_height":9,"ts":1}` + "\n" +
+				`{"op":"header_expired","idx":0,"tx_hash":"s1","ts":1}` + "\n" +
+				`{"op":"header_expired","idx":0,"tx_hash":"e","ts":1}` + "\n",
+			want: journalState{Sent: []uint32{0, 1}, CART: "c", CSGN: []string{"s0"}, HeaderHeights: map[string]int64{"s0": 7, "c": 8}}},
+		// Progress files written before the journal existed: duplicate plan
+		// entries, entries never sent and failures of chunks sent later
+		{name: "old progress file",
+			snapshot: `{"app_id": 1, "cartridge_id": 2, "cartridge_addr": "x", "total_chunks": 10, "sent_chunks": 3,
+				"failed_chunks": [2, 5], "cart_tx_hash": "c",
+				"plan": [{"idx": 0, "payload_hex": "aa", "tx_hash": "t0"}, {"idx": 2, "payload_hex": "cc", "tx_hash": "t2"},
+					{"idx": 0, "payload_hex": "aa", "tx_hash": "t0"}, {"idx": 3, "payload_hex": "dd"}]}`,
+			want: journalState{Sent: []uint32{0, 2}, Failed: []int{5}, CART: "c"}},
+		{name: "progress of another upload", journal: sent("2", "t2"),
+			snapshot: `{"app_id": 1, "cartridge_id": 2, "total_chunks": 11, "plan": [{"idx": 0, "payload_hex": "aa", "tx_hash": "t0"}]}`,
+			want:     journalState{}},
+		{name: "corrupt snapshot", snapshot: `{"app_id": 1, "plan": [`, journal: sent("2", "t2"), want: journalState{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateSettings(t)
+			if tt.snapshot != "" {
+				if err := os.WriteFile(testJournalBase+".json", []byte(tt.snapshot), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.journal != "" {
+				if err := os.WriteFile(testJournalBase+".journal", []byte(tt.journal), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			j, err := openTestJournal(t)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			got := journalStateOf(j.Progress())
+			if err := j.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recovered %+v, expected %+v", got, tt.want)
+			}
+
+			// What was recovered is in the snapshot now
+			j, err = openTestJournal(t)
+			if err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			defer j.Close()
+			if again := journalStateOf(j.Progress()); !reflect.DeepEqual(again, got) {
+				t.Errorf("after reopening %+v, expected %+v", again, got)
+			}
+			if p := j.Progress(); p.SentChunks != len(p.Plan) {
+				t.Errorf("sent_chunks %d for %d plan entries", p.SentChunks, len(p.Plan))
+			}
+		})
+	}
+}
+
+// Entries recorded after a compaction survive a crash, and so do those
+// compacted into the snapshot before it
+func TestProgressJournalReplayAfterCompaction(t *testing.T) {
+	isolateSettings(t)
+	j, err := openTestJournal(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx := uint32(0); idx < 3; idx++ {
+		if _, err := j.RecordChunkSent(idx, "aa", "t", "", 5, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(testJournalBase + ".journal"); err != nil || len(data) != 0 {
+		t.Fatalf("journal after compaction: %q, %v; expected it empty", data, err)
+	}
+	if _, err := j.RecordChunkSent(3, "aa", "t", "NQ00 COSIGNER", 6, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.RecordChunkFailed(4, os.ErrDeadlineExceeded); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.RecordCART("c", 7); err != nil {
+		t.Fatal(err)
+	}
+	want := journalStateOf(j.Progress())
+	crash(j)
+
+	j, err = openTestJournal(t)
+	if err != nil {
+		t.Fatalf("reopen after crash: %v", err)
+	}
+	defer j.Close()
+	if got := journalStateOf(j.Progress()); !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered %+v, expected %+v", got, want)
+	}
+	if sent, ok := j.SentChunk(3); !ok || sent.Sender != "NQ00 COSIGNER" || sent.ValidFrom != 6 || sent.Attempt != 2 {
+		t.Errorf("chunk 3 recovered as %+v, %v", sent, ok)
+	}
+	if _, err := os.Stat(testJournalBase + ".journal"); err != nil {
+		t.Errorf("journal not reopened: %v", err)
+	}
+}
+
+// Only one process drives an upload; the lock is free again once it closes
+// the journal (or dies)
+func TestProgressJournalLock(t *testing.T) {
+	isolateSettings(t)
+	first, err := openTestJournal(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second, err := openTestJournal(t); err == nil {
+		second.Close()
+		t.Fatal("second journal opened while the first holds the lock")
+	} else if !strings.Contains(err.Error(), "another upload is already running") {
+		t.Errorf("error %v, expected one about the running upload", err)
+	}
+	if _, err := first.RecordChunkSent(0, "aa", "t0", "", 0, 0); err != nil {
+		t.Fatalf("the lock holder can no longer record: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := openTestJournal(t)
+	if err != nil {
+		t.Fatalf("open after close: %v", err)
+	}
+	if p := again.Progress(); len(p.Plan) != 1 {
+		t.Errorf("%d chunks after reopening, expected 1", len(p.Plan))
+	}
+	crash(again)
+	if j, err := openTestJournal(t); err != nil {
+		t.Errorf("open after a crash: %v", err)
+	} else {
+		j.Close()
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// CartridgeKeysDirName is the directory (inside the config dir) holding
+	// the encrypted keys of generated cartridge addresses
+	CartridgeKeysDirName = "cartridge-keys"
+
+	// scrypt parameters for key files (N=2^15 takes ~100ms and 32MB)
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// EncryptedSecret is a secret encrypted with a passphrase (scrypt + AES-256-GCM)
+type EncryptedSecret struct {
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       string `json:"salt"`
+	Cipher     string `json:"cipher"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// encryptSecret encrypts plaintext with a key derived from passphrase
+func encryptSecret(plaintext []byte, passphrase string) (*EncryptedSecret, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return &EncryptedSecret{
+		KDF:        "scrypt",
+		N:          scryptN,
+		R:          scryptR,
+		P:          scryptP,
+		Salt:       hex.EncodeToString(salt),
+		Cipher:     "aes-256-gcm",
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil)),
+	}, nil
+}
+
+// decryptSecret reverses encryptSecret. A wrong passphrase fails authentication.
+func decryptSecret(secret *EncryptedSecret, passphrase string) ([]byte, error) {
+	if secret.KDF != "scrypt" || secret.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("unsupported encryption (kdf %q, cipher %q)", secret.KDF, secret.Cipher)
+	}
+
+	salt, err := hex.DecodeString(secret.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	nonce, err := hex.DecodeString(secret.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(secret.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, secret.N, secret.R, secret.P, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted file")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+// CartridgeKeyFile stores the key of a generated cartridge address. Address and
+// IDs are kept in the clear so key files can be listed without the passphrase.
+type CartridgeKeyFile struct {
+	Address     string           `json:"address"`
+	AppID       uint32           `json:"app_id"`
+	CartridgeID uint32           `json:"cartridge_id"`
+	Publisher   string           `json:"publisher"`
+	CreatedAt   string           `json:"created_at"`
+	Key         *EncryptedSecret `json:"key"` // encrypted AccountInfo JSON
+
+	path string
+}
+
+// GetCartridgeKeysDir returns the directory holding cartridge key files
+func GetCartridgeKeysDir() string {
+	return filepath.Join(GetConfigDir(), CartridgeKeysDirName)
+}
+
+// resolveKeyPassphrase returns the passphrase protecting cartridge key files:
+// the flag value, NIMIQ_KEY_PASSPHRASE, or the account passphrase (credentials
+// file, then NIMIQ_PASSPHRASE)
+func resolveKeyPassphrase(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if p := os.Getenv("NIMIQ_KEY_PASSPHRASE"); p != "" {
+		return p
+	}
+	if p := GetDefaultPassphrase(); p != "" {
+		return p
+	}
+	return os.Getenv("NIMIQ_PASSPHRASE")
+}
+
+// SaveCartridgeKey encrypts account and writes it to
+// <keys dir>/cartridge_<app_id>_<cartridge_id>_<address>.json (mode 0600)
+func SaveCartridgeKey(account *AccountInfo, appID, cartridgeID uint32, publisher, passphrase string) (string, error) {
+	if account.PrivateKey == "" {
+		return "", fmt.Errorf("node did not return a private key for %s", account.Address)
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("a passphrase is required to encrypt the cartridge key (--key-passphrase, NIMIQ_KEY_PASSPHRASE or passphrase in credentials.json)")
+	}
+
+	plaintext, err := json.Marshal(account)
+	if err != nil {
+		return "", err
+	}
+	secret, err := encryptSecret(plaintext, passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	keyFile := &CartridgeKeyFile{
+		Address:     account.Address,
+		AppID:       appID,
+		CartridgeID: cartridgeID,
+		Publisher:   publisher,
+		CreatedAt:   time.Now().Format(time.RFC3339),
+		Key:         secret,
+	}
+	data, err := json.MarshalIndent(keyFile, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	dir := GetCartridgeKeysDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("cartridge_%d_%d_%s.json", appID, cartridgeID, normalizeAddress(account.Address)))
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write cartridge key file: %w", err)
+	}
+	return path, nil
+}
+
+// LoadCartridgeKeyFiles reads all key files in dir, sorted by app-id and cartridge-id
+func LoadCartridgeKeyFiles(dir string) ([]*CartridgeKeyFile, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "cartridge_*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var keyFiles []*CartridgeKeyFile
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var keyFile CartridgeKeyFile
+		if err := json.Unmarshal(data, &keyFile); err != nil || keyFile.Key == nil {
+			fmt.Printf("Warning: skipping %s (not a cartridge key file)\n", path)
+			continue
+		}
+		keyFile.path = path
+		keyFiles = append(keyFiles, &keyFile)
+	}
+
+	sort.Slice(keyFiles, func(i, j int) bool {
+		if keyFiles[i].AppID != keyFiles[j].AppID {
+			return keyFiles[i].AppID < keyFiles[j].AppID
+		}
+		if keyFiles[i].CartridgeID != keyFiles[j].CartridgeID {
+			return keyFiles[i].CartridgeID < keyFiles[j].CartridgeID
+		}
+		return strings.Compare(keyFiles[i].Address, keyFiles[j].Address) < 0
+	})
+	return keyFiles, nil
+}
+
+// Decrypt returns the cartridge account stored in the key file
+func (k *CartridgeKeyFile) Decrypt(passphrase string) (*AccountInfo, error) {
+	plaintext, err := decryptSecret(k.Key, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", k.path, err)
+	}
+	var account AccountInfo
+	if err := json.Unmarshal(plaintext, &account); err != nil {
+		return nil, fmt.Errorf("%s: invalid key data: %w", k.path, err)
+	}
+	if normalizeAddress(account.Address) != normalizeAddress(k.Address) {
+		return nil, fmt.Errorf("%s: key belongs to %s, not %s", k.path, account.Address, k.Address)
+	}
+	return &account, nil
+}
+
+// SaveSigningKey encrypts account into a key file at path (mode 0600), for
+// signing transactions offline (see sign-bundle)
+func SaveSigningKey(path string, account *AccountInfo, passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("a passphrase is required to encrypt the key (--key-passphrase or NIMIQ_KEY_PASSPHRASE)")
+	}
+	plaintext, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	secret, err := encryptSecret(plaintext, passphrase)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(&CartridgeKeyFile{
+		Address:   account.Address,
+		Publisher: account.Address,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Key:       secret,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return nil
+}
+
+// LoadSigningKey decrypts a key file (signing key or cartridge key) into a key
+// that signs transactions in this process
+func LoadSigningKey(path, passphrase string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	keyFile := &CartridgeKeyFile{path: path}
+	if err := json.Unmarshal(data, keyFile); err != nil || keyFile.Key == nil {
+		return nil, fmt.Errorf("%s is not a key file", path)
+	}
+	account, err := keyFile.Decrypt(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := NewSigningKey(account)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
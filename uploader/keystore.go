@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// credentialSecrets is the part of Credentials that is encrypted into the keystore
+type credentialSecrets struct {
+	PrivateKey string `json:"private_key,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+var (
+	keystoreMu         sync.Mutex
+	keystorePassphrase string                                // resolved once per process
+	keystoreUnlocked   = make(map[string]*credentialSecrets) // keyed by keystore ciphertext
+	permissionsWarned  = make(map[string]bool)
+)
+
+// EncryptCredentials moves the private key and account passphrase of creds
+// into an encrypted keystore (scrypt + AES-256-GCM, like cartridge key files)
+func EncryptCredentials(creds *Credentials, passphrase string) error {
+	if creds.Keystore != nil {
+		return fmt.Errorf("credentials are already encrypted")
+	}
+	plaintext, err := json.Marshal(&credentialSecrets{PrivateKey: creds.PrivateKey, Passphrase: creds.Passphrase})
+	if err != nil {
+		return err
+	}
+	keystore, err := encryptSecret(plaintext, passphrase)
+	if err != nil {
+		return err
+	}
+	creds.Keystore = keystore
+	creds.PrivateKey = ""
+	creds.Passphrase = ""
+	return nil
+}
+
+// EncryptCredentialsFile encrypts the secrets of a JSON credentials file in place
+func EncryptCredentialsFile(path, passphrase string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := EncryptCredentials(&creds, passphrase); err != nil {
+		return err
+	}
+	return SaveCredentials(&creds, path)
+}
+
+// LoadCredentialSecrets loads credentials like LoadCredentials and, if the
+// secrets are in an encrypted keystore, unlocks it so PRIVATE_KEY and
+// PASSPHRASE are filled in (see resolveKeystorePassphrase)
+func LoadCredentialSecrets(filename string) (map[string]string, error) {
+	creds, err := LoadCredentials(filename)
+	if err != nil {
+		return nil, err
+	}
+	if creds["KEYSTORE"] == "" {
+		return creds, nil
+	}
+
+	keystoreMu.Lock()
+	defer keystoreMu.Unlock()
+	secrets, ok := keystoreUnlocked[creds["KEYSTORE"]]
+	if !ok {
+		var keystore EncryptedSecret
+		if err := json.Unmarshal([]byte(creds["KEYSTORE"]), &keystore); err != nil {
+			return nil, fmt.Errorf("invalid keystore: %w", err)
+		}
+		passphrase, err := resolveKeystorePassphraseLocked(false)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := decryptSecret(&keystore, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unlock credentials keystore: %w", err)
+		}
+		secrets = &credentialSecrets{}
+		if err := json.Unmarshal(plaintext, secrets); err != nil {
+			return nil, fmt.Errorf("invalid keystore contents: %w", err)
+		}
+		keystoreUnlocked[creds["KEYSTORE"]] = secrets
+	}
+
+	if secrets.PrivateKey != "" {
+		creds["PRIVATE_KEY"] = secrets.PrivateKey
+	}
+	if secrets.Passphrase != "" {
+		creds["PASSPHRASE"] = secrets.Passphrase
+	}
+	return creds, nil
+}
+
+// resolveKeystorePassphrase returns the passphrase of the credentials keystore from:
+// 1. NIMIQ_KEYSTORE_PASSPHRASE environment variable
+// 2. NIMIQ_KEYSTORE_PASSPHRASE_FD (first line read from that file descriptor)
+// 3. a prompt on the terminal (asked twice when confirm is set)
+func resolveKeystorePassphrase(confirm bool) (string, error) {
+	keystoreMu.Lock()
+	defer keystoreMu.Unlock()
+	return resolveKeystorePassphraseLocked(confirm)
+}
+
+func resolveKeystorePassphraseLocked(confirm bool) (string, error) {
+	if keystorePassphrase != "" {
+		return keystorePassphrase, nil
+	}
+
+	passphrase := os.Getenv("NIMIQ_KEYSTORE_PASSPHRASE")
+	if passphrase == "" {
+		if fdValue := os.Getenv("NIMIQ_KEYSTORE_PASSPHRASE_FD"); fdValue != "" {
+			fd, err := strconv.Atoi(fdValue)
+			if err != nil || fd < 0 {
+				return "", fmt.Errorf("invalid NIMIQ_KEYSTORE_PASSPHRASE_FD %q", fdValue)
+			}
+			line, err := bufio.NewReader(os.NewFile(uintptr(fd), "passphrase-fd")).ReadString('\n')
+			if err != nil && line == "" {
+				return "", fmt.Errorf("failed to read passphrase from fd %d: %w", fd, err)
+			}
+			passphrase = strings.TrimRight(line, "\r\n")
+		}
+	}
+
+	if passphrase == "" {
+		if !isTerminal(os.Stdin) {
+			return "", fmt.Errorf("credentials keystore passphrase required (set NIMIQ_KEYSTORE_PASSPHRASE or NIMIQ_KEYSTORE_PASSPHRASE_FD, or run in a terminal)")
+		}
+		var err error
+		passphrase, err = readPassphrase("Keystore passphrase: ")
+		if err != nil {
+			return "", fmt.Errorf("%w (set NIMIQ_KEYSTORE_PASSPHRASE or NIMIQ_KEYSTORE_PASSPHRASE_FD)", err)
+		}
+		if confirm {
+			again, err := readPassphrase("Repeat passphrase: ")
+			if err != nil {
+				return "", err
+			}
+			if again != passphrase {
+				return "", fmt.Errorf("passphrases do not match")
+			}
+		}
+	}
+
+	if passphrase == "" {
+		return "", fmt.Errorf("keystore passphrase must not be empty")
+	}
+	keystorePassphrase = passphrase
+	return passphrase, nil
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// warnIfExposed warns (once per file) when a credentials file can be read by
+// other users
+func warnIfExposed(path string) {
+	keystoreMu.Lock()
+	defer keystoreMu.Unlock()
+	if permissionsWarned[path] {
+		return
+	}
+	permissionsWarned[path] = true
+	if mode, exposed := filePermissionsExposed(path); exposed {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: %s is accessible by other users (mode %04o). Run: chmod 600 %s\n", path, mode, path)
+	}
+}
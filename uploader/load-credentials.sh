@@ -82,6 +82,12 @@ if [ -n "$RPC_URL" ]; then
     export NIMIQ_RPC_URL="$RPC_URL"
 fi
 
+if grep -q '"keystore"' "$CREDENTIALS_FILE"; then
+    echo "Error: the secrets in $CREDENTIALS_FILE are in an encrypted keystore"
+    echo "The uploader unlocks it itself (NIMIQ_KEYSTORE_PASSPHRASE or a prompt); no need to load it into the shell"
+    return 1 2>/dev/null || exit 1
+fi
+
 if [ -z "$ADDRESS" ] || [ -z "$PRIVATE_KEY" ] || [ -z "$PASSPHRASE" ]; then
     echo "Error: Invalid credentials file format"
     return 1 2>/dev/null || exit 1
@@ -0,0 +1,74 @@
+//go:build !unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// acquireFileLock takes an exclusive lock on path by creating it exclusively.
+// The file holds the PID of the owner: a lock left behind by a process that
+// is no longer running (or any lock, with --force-unlock) is taken over.
+func acquireFileLock(path string) (*os.File, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Sync()
+			return file, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		pid, running := lockOwner(path)
+		if running && !forceUnlockFlag {
+			return nil, fmt.Errorf("another upload is already running for this cartridge (lock held by PID %d on %s; use --force-unlock if it is not)", pid, path)
+		}
+		if attempt == 0 {
+			if running {
+				fmt.Fprintf(os.Stderr, "⚠️  Removing lock %s of PID %d (--force-unlock)\n", path, pid)
+			} else {
+				fmt.Fprintf(os.Stderr, "⚠️  Removing stale lock %s (PID %d is not running)\n", path, pid)
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove stale lock %s: %w", path, err)
+			}
+		}
+	}
+	return nil, fmt.Errorf("another upload is already running for this cartridge (lock held on %s)", path)
+}
+
+// lockOwner returns the PID recorded in a lock file and whether that process
+// still runs. A lock without a readable PID counts as held.
+func lockOwner(path string) (int, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, true
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, true
+	}
+	if pid == os.Getpid() {
+		return pid, true
+	}
+	// On Windows FindProcess fails for processes that do not exist
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return pid, false
+	}
+	process.Release()
+	return pid, true
+}
+
+// releaseFileLock closes and removes a lock taken by acquireFileLock, so the
+// next run can create it again
+func releaseFileLock(file *os.File) {
+	path := file.Name()
+	file.Close()
+	os.Remove(path)
+}
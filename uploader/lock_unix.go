@@ -0,0 +1,40 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// acquireFileLock takes an exclusive, non-blocking lock on path.
+// The lock is released by closing the returned file (or when the process dies).
+func acquireFileLock(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, fmt.Errorf("another upload is already running for this cartridge (lock held on %s)", path)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// Record who holds the lock to make stuck uploads easier to track down
+	file.Truncate(0)
+	fmt.Fprintf(file, "%d\n", os.Getpid())
+	file.Sync()
+
+	return file, nil
+}
+
+// releaseFileLock drops a lock taken by acquireFileLock. The file itself is
+// left in place: removing it would let a process that already opened it lock
+// an orphaned inode.
+func releaseFileLock(file *os.File) {
+	file.Close()
+}
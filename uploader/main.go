@@ -31,9 +31,28 @@ Legacy txt format is also supported:
   - ~/.config/nimiq-uploader/account_credentials.txt
 
 Use 'nimiq-uploader account create --global' to save credentials globally.
-Use 'nimiq-uploader migrate --global' to convert old txt to new JSON format.`,
+Use 'nimiq-uploader migrate --global' to convert old txt to new JSON format.
+
+Named profiles (RPC endpoint, account, catalog, fee, rate per network) are read
+from ~/.config/nimiq-uploader/profiles.yaml and selected with --profile or
+NIMIQ_PROFILE. Run 'nimiq-uploader config' to see the effective settings.`,
+		// Fill flags that were not given from the environment, profile or credentials
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := setupFaultInjection(); err != nil {
+				return err
+			}
+			// Resolve the profile once, now that --profile is parsed
+			resetSettings()
+			return applyProfileFlags(cmd)
+		},
 	}
 
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Profile from profiles.yaml to use (defaults to NIMIQ_PROFILE or default in profiles.yaml)")
+	rootCmd.PersistentFlags().StringVar(&injectFaultsFlag, "inject-faults", "", "Inject send faults for testing resume paths (see fault.go)")
+	rootCmd.PersistentFlags().MarkHidden("inject-faults")
+	rootCmd.PersistentFlags().BoolVar(&forceUnlockFlag, "force-unlock", false, "Take over upload lock files of other processes (non-unix systems, where a crashed run can leave its lock behind)")
+	rootCmd.PersistentFlags().StringVar(&signerFlag, "signer", "", "Transaction signer: node, key or exec:<command> (defaults to NIMIQ_SIGNER, signer in credentials, or node)")
+
 	// Add version command
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "version",
@@ -48,10 +67,13 @@ Use 'nimiq-uploader migrate --global' to convert old txt to new JSON format.`,
 	// Add config command
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "config",
-		Short: "Show configuration paths",
+		Short: "Show configuration paths and effective settings",
+		// Show the settings even when the selected profile is broken
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
 		Run: func(cmd *cobra.Command, args []string) {
 			fmt.Println("Configuration Paths:")
 			fmt.Printf("  Config directory: %s\n", GetConfigDir())
+			fmt.Printf("  Profiles file:    %s\n", GetProfilesPath())
 			fmt.Printf("  Credentials file: %s\n", GetCredentialsPath())
 			fmt.Println()
 
@@ -64,25 +86,52 @@ Use 'nimiq-uploader migrate --global' to convert old txt to new JSON format.`,
 				if rpcURL := creds["RPC_URL"]; rpcURL != "" {
 					fmt.Printf("  RPC URL: %s\n", rpcURL)
 				}
-				fmt.Printf("  RPC URL (effective): %s\n", GetDefaultRPCURL())
+				switch {
+				case creds["KEYSTORE"] != "":
+					fmt.Println("  Secrets: encrypted keystore")
+				case creds["PRIVATE_KEY"] != "" || creds["PASSPHRASE"] != "":
+					fmt.Println("  Secrets: plaintext (run 'nimiq-uploader migrate --encrypt')")
+				}
 			} else {
 				fmt.Printf("No credentials found. Run 'nimiq-uploader account create' to create an account.\n")
 			}
+			fmt.Println()
+
+			printSettings()
 		},
 	})
 
 	// Main commands
 	rootCmd.AddCommand(newUploadCartridgeCmd())
+	rootCmd.AddCommand(newUploadDirCmd())
 	rootCmd.AddCommand(newRetireAppCmd())
 	rootCmd.AddCommand(newAccountCmd())
+	rootCmd.AddCommand(newCartridgeCmd())
+	rootCmd.AddCommand(newEstimateCmd())
+	rootCmd.AddCommand(newPlanCmd())
+	rootCmd.AddCommand(newApplyCmd())
+	rootCmd.AddCommand(newExecuteCmd())
+	rootCmd.AddCommand(newSignBundleCmd())
+	rootCmd.AddCommand(newBroadcastCmd())
+	rootCmd.AddCommand(newOfflineKeyCmd())
+	rootCmd.AddCommand(newSignerStubCmd())
 	rootCmd.AddCommand(newPackageCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newDevnodeCmd())
+	rootCmd.AddCommand(newRPCRecordCmd())
+	rootCmd.AddCommand(newTestVectorsCmd())
 	rootCmd.AddCommand(newMigrateCmd()) // Migrate legacy txt to JSON
 
 	// Legacy commands (kept for backwards compatibility)
 	rootCmd.AddCommand(newUploadCmd())   // Legacy: uses old DOOM format
 	rootCmd.AddCommand(newManifestCmd()) // Legacy: generates old-style manifest
 
-	if err := rootCmd.Execute(); err != nil {
+	// Ctrl-C / SIGTERM cancel the command context so uploads can stop cleanly
+	ctx, stop := newSignalContext()
+	defer stop()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		stop()
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -45,12 +45,52 @@ func newManifestCmd() *cobra.Command {
 		progressFile string // Path to upload_progress_*.json file
 		title        string // Display title of the game
 		platform     string // Platform (e.g., "DOS", "Windows")
+		rpcURL       string // Node to check the network against (optional)
 	)
 
 	cmd := &cobra.Command{
 		Use:   "manifest",
 		Short: "Generate manifest.json for a file",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if progressFile == "" {
+				// Try default progress file name
+				progressFile = fmt.Sprintf("upload_progress_%d.json", gameID)
+			}
+
+			// The network comes from the upload (progress file) or the node
+			// rather than from --network alone
+			var networkID uint8
+			if data, err := os.ReadFile(progressFile); err == nil {
+				var progress UploadProgress
+				if err := json.Unmarshal(data, &progress); err == nil {
+					networkID = progress.NetworkID
+				}
+			}
+			if rpcURL != "" {
+				nodeID, err := checkNodeNetwork(NewNimiqRPC(rpcURL), "", network)
+				if err != nil {
+					return err
+				}
+				if err := checkProgressNetwork(progressFile, networkID, nodeID); err != nil {
+					return err
+				}
+				if networkID == 0 {
+					networkID = nodeID
+				}
+			}
+			if networkID != 0 {
+				if network != "" {
+					id, err := ParseNetworkID(network)
+					if err != nil {
+						return err
+					}
+					if id != networkID {
+						return fmt.Errorf("--network %s does not match the upload's %s", network, networkName(networkID))
+					}
+				}
+				network = manifestNetworkName(networkID)
+			}
+
 			if network == "" {
 				network = os.Getenv("NIMIQ_NETWORK")
 				if network == "" {
@@ -88,11 +128,6 @@ func newManifestCmd() *cobra.Command {
 			}
 
 			// Try to load transaction hashes from upload progress file
-			if progressFile == "" {
-				// Try default progress file name
-				progressFile = fmt.Sprintf("upload_progress_%d.json", gameID)
-			}
-
 			if progressData, err := os.ReadFile(progressFile); err == nil {
 				var progress struct {
 					Plan []struct {
@@ -146,7 +181,8 @@ func newManifestCmd() *cobra.Command {
 	cmd.Flags().StringVar(&filePath, "file", "", "Path to file (required)")
 	cmd.Flags().Uint32Var(&gameID, "game-id", 0, "Game ID (uint32) (required)")
 	cmd.Flags().StringVar(&sender, "sender", "", "Sender address (required)")
-	cmd.Flags().StringVar(&network, "network", "", "Network (mainnet/testnet) (or set NIMIQ_NETWORK)")
+	cmd.Flags().StringVar(&network, "network", "", "Network (mainnet/testnet) (or set NIMIQ_NETWORK; checked against the upload and --rpc-url)")
+	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Nimiq RPC URL to take the network from (optional)")
 	cmd.Flags().StringVar(&output, "output", "manifest.json", "Output manifest file")
 	cmd.Flags().StringVar(&progressFile, "progress-file", "", "Path to upload_progress_*.json file (defaults to upload_progress_{game-id}.json)")
 	cmd.Flags().StringVar(&title, "title", "", "Display title of the game (e.g., \"Digger Remastered\")")
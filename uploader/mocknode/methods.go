@@ -0,0 +1,337 @@
+package mocknode
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// methodParams lists the parameter names of each method, in positional order
+var methodParams = map[string][]string{
+	"getBlockNumber":                 nil,
+	"getLatestBlock":                 {"includeBody"},
+	"isConsensusEstablished":         nil,
+	"getAccountByAddress":            {"address"},
+	"getMinFeePerByte":               nil,
+	"mempool":                        nil,
+	"getTransactionByHash":           {"hash"},
+	"getTransactionsByAddress":       {"address", "max", "startAt"},
+	"getRawTransactionInfo":          {"rawTx"},
+	"sendRawTransaction":             {"rawTx"},
+	"createAccount":                  {"passphrase"},
+	"importRawKey":                   {"keyData", "passphrase"},
+	"isAccountImported":              {"address"},
+	"isAccountUnlocked":              {"address"},
+	"unlockAccount":                  {"address", "passphrase", "duration"},
+	"lockAccount":                    {"address"},
+	"createBasicTransaction":         {"wallet", "recipient", "value", "fee", "validityStartHeight"},
+	"createBasicTransactionWithData": {"wallet", "recipient", "data", "value", "fee", "validityStartHeight"},
+	"sendBasicTransaction":           {"wallet", "recipient", "value", "fee", "validityStartHeight"},
+	"sendBasicTransactionWithData":   {"wallet", "recipient", "data", "value", "fee", "validityStartHeight"},
+}
+
+// args holds the parameters of a call by name
+type args map[string]json.RawMessage
+
+// parseArgs accepts named (object) and positional (array) parameters
+func parseArgs(method string, params json.RawMessage) (args, error) {
+	a := make(args)
+	trimmed := strings.TrimSpace(string(params))
+	if trimmed == "" || trimmed == "null" {
+		return a, nil
+	}
+	if strings.HasPrefix(trimmed, "[") {
+		var positional []json.RawMessage
+		if err := json.Unmarshal(params, &positional); err != nil {
+			return nil, rpcError{Code: -32602, Message: "invalid params: " + err.Error()}
+		}
+		names := methodParams[method]
+		if len(positional) > len(names) {
+			return nil, rpcError{Code: -32602, Message: fmt.Sprintf("%s takes at most %d parameters", method, len(names))}
+		}
+		for i, value := range positional {
+			a[names[i]] = value
+		}
+		return a, nil
+	}
+	if err := json.Unmarshal(params, &a); err != nil {
+		return nil, rpcError{Code: -32602, Message: "invalid params: " + err.Error()}
+	}
+	return a, nil
+}
+
+func (a args) string(name string) (string, error) {
+	var s string
+	if raw, ok := a[name]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", rpcError{Code: -32602, Message: fmt.Sprintf("invalid %s: expected a string", name)}
+		}
+	}
+	return s, nil
+}
+
+func (a args) required(name string) (string, error) {
+	s, err := a.string(name)
+	if err == nil && s == "" {
+		err = rpcError{Code: -32602, Message: fmt.Sprintf("missing parameter %s", name)}
+	}
+	return s, err
+}
+
+func (a args) int(name string) (int64, error) {
+	var v int64
+	if raw, ok := a[name]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return 0, rpcError{Code: -32602, Message: fmt.Sprintf("invalid %s: expected an integer", name)}
+		}
+	}
+	return v, nil
+}
+
+// call runs one JSON-RPC method and returns its (unwrapped) result
+func (n *Node) call(method string, params json.RawMessage) (interface{}, error) {
+	if _, ok := methodParams[method]; !ok {
+		return nil, rpcError{Code: -32601, Message: fmt.Sprintf("method not found: %s", method)}
+	}
+	a, err := parseArgs(method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	switch method {
+	case "getBlockNumber":
+		return n.height, nil
+
+	case "getLatestBlock":
+		return map[string]interface{}{
+			"number":    n.height,
+			"hash":      fmt.Sprintf("%064x", n.height),
+			"timestamp": n.timestamp,
+			"network":   networkName(n.opts.NetworkID),
+			"type":      "micro",
+		}, nil
+
+	case "isConsensusEstablished":
+		return n.consensus, nil
+
+	case "getAccountByAddress":
+		address, err := a.required("address")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := parseAddress(address); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"address": address, "balance": n.balances[normalize(address)], "type": "basic"}, nil
+
+	case "getMinFeePerByte":
+		return n.opts.MinFeePerByte, nil
+
+	case "mempool":
+		return n.mempoolLocked(), nil
+
+	case "getTransactionByHash":
+		hash, err := a.required("hash")
+		if err != nil {
+			return nil, err
+		}
+		tx, ok := n.txs[strings.ToLower(hash)]
+		if !ok {
+			return nil, fmt.Errorf("transaction not found: %s", hash)
+		}
+		return tx.rpc(n.height), nil
+
+	case "getTransactionsByAddress":
+		address, err := a.required("address")
+		if err != nil {
+			return nil, err
+		}
+		max, err := a.int("max")
+		if err != nil {
+			return nil, err
+		}
+		startAt, err := a.string("startAt")
+		if err != nil {
+			return nil, err
+		}
+		return n.transactionsByAddressLocked(address, int(max), startAt), nil
+
+	case "getRawTransactionInfo":
+		raw, err := n.rawArg(a)
+		if err != nil {
+			return nil, err
+		}
+		tx, err := decodeTransaction(raw)
+		if err != nil {
+			return nil, err
+		}
+		return tx.rpc(n.height), nil
+
+	case "sendRawTransaction":
+		raw, err := n.rawArg(a)
+		if err != nil {
+			return nil, err
+		}
+		return n.submitLocked(raw)
+
+	case "createAccount":
+		// The key is only generated here; the caller imports it with
+		// importRawKey and its own passphrase (which also credits the
+		// initial balance)
+		key, err := newKey()
+		if err != nil {
+			return nil, err
+		}
+		publicKey := key.Public().(ed25519.PublicKey)
+		return map[string]interface{}{
+			"address":    addressOf(publicKey),
+			"publicKey":  hex.EncodeToString(publicKey),
+			"privateKey": hex.EncodeToString(key.Seed()),
+		}, nil
+
+	case "importRawKey":
+		keyData, err := a.required("keyData")
+		if err != nil {
+			return nil, err
+		}
+		passphrase, err := a.string("passphrase")
+		if err != nil {
+			return nil, err
+		}
+		seed, err := hex.DecodeString(strings.TrimSpace(keyData))
+		if err != nil || len(seed) != 32 {
+			return nil, fmt.Errorf("invalid private key")
+		}
+		return n.importKeyLocked(ed25519.NewKeyFromSeed(seed), passphrase), nil
+
+	case "isAccountImported":
+		address, err := a.required("address")
+		if err != nil {
+			return nil, err
+		}
+		_, ok := n.wallet[normalize(address)]
+		return ok, nil
+
+	case "isAccountUnlocked":
+		address, err := a.required("address")
+		if err != nil {
+			return nil, err
+		}
+		account, ok := n.wallet[normalize(address)]
+		return ok && account.isUnlocked(), nil
+
+	case "unlockAccount":
+		address, err := a.required("address")
+		if err != nil {
+			return nil, err
+		}
+		passphrase, err := a.string("passphrase")
+		if err != nil {
+			return nil, err
+		}
+		duration, err := a.int("duration")
+		if err != nil {
+			return nil, err
+		}
+		account, ok := n.wallet[normalize(address)]
+		if !ok {
+			return nil, fmt.Errorf("account %s is not imported", address)
+		}
+		if passphrase != account.passphrase {
+			return nil, fmt.Errorf("wrong passphrase for %s", address)
+		}
+		account.unlocked = true
+		account.unlockedUntil = time.Time{}
+		if duration > 0 {
+			account.unlockedUntil = time.Now().Add(time.Duration(duration) * time.Second)
+		}
+		return true, nil
+
+	case "lockAccount":
+		address, err := a.required("address")
+		if err != nil {
+			return nil, err
+		}
+		if account, ok := n.wallet[normalize(address)]; ok {
+			account.unlocked = false
+		}
+		return nil, nil
+
+	case "createBasicTransaction", "createBasicTransactionWithData", "sendBasicTransaction", "sendBasicTransactionWithData":
+		raw, err := n.walletTransaction(a)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(method, "create") {
+			return hex.EncodeToString(raw), nil
+		}
+		return n.submitLocked(raw)
+	}
+	return nil, rpcError{Code: -32601, Message: fmt.Sprintf("method not found: %s", method)}
+}
+
+func (n *Node) rawArg(a args) ([]byte, error) {
+	rawHex, err := a.required("rawTx")
+	if err != nil {
+		return nil, err
+	}
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(rawHex), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid rawTx: %w", err)
+	}
+	return raw, nil
+}
+
+// walletTransaction signs a transaction with an unlocked wallet key
+func (n *Node) walletTransaction(a args) ([]byte, error) {
+	wallet, err := a.required("wallet")
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := a.required("recipient")
+	if err != nil {
+		return nil, err
+	}
+	dataHex, err := a.string("data")
+	if err != nil {
+		return nil, err
+	}
+	data, err := hex.DecodeString(dataHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+	value, err := a.int("value")
+	if err != nil {
+		return nil, err
+	}
+	fee, err := a.int("fee")
+	if err != nil {
+		return nil, err
+	}
+	validityStartHeight, err := a.int("validityStartHeight")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := a["validityStartHeight"]; !ok {
+		validityStartHeight = n.height
+	}
+
+	account, ok := n.wallet[normalize(wallet)]
+	if !ok {
+		return nil, fmt.Errorf("account %s is not imported", wallet)
+	}
+	if !account.isUnlocked() {
+		return nil, fmt.Errorf("account %s is locked", wallet)
+	}
+	return signTransaction(account.key, recipient, data, value, fee, validityStartHeight, n.opts.NetworkID)
+}
+
+func (a *walletAccount) isUnlocked() bool {
+	return a.unlocked && (a.unlockedUntil.IsZero() || time.Now().Before(a.unlockedUntil))
+}
@@ -0,0 +1,391 @@
+// Package mocknode is an in-memory stand-in for a Nimiq Albatross node. It
+// serves the JSON-RPC methods nimiq-uploader and the web frontend use - wallet
+// accounts, balances, sending and looking up transactions with paging - and
+// produces blocks on a timer, optionally dropping mempool transactions.
+//
+// In Go tests, serve it with net/http/httptest and mine blocks by hand:
+//
+//	node := mocknode.New(mocknode.Options{})
+//	server := httptest.NewServer(node)
+//	defer server.Close()
+//	node.Fund("NQ...", 1000*mocknode.LunaPerNIM)
+//	// ... point the code under test at server.URL ...
+//	node.MineBlock()
+//
+// From the command line, `nimiq-uploader devnode` runs one with a block timer.
+package mocknode
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	mrand "math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// LunaPerNIM is the number of Luna in one NIM
+	LunaPerNIM = 100000
+
+	// ValidityWindow is how many blocks after its validity start height a
+	// transaction can be included
+	ValidityWindow = 7200
+
+	// NetworkIDTest is the Albatross testnet, the default network
+	NetworkIDTest = 5
+)
+
+// Options configure a mock node. The zero value is a testnet node at height 1
+// that only produces blocks when MineBlock is called.
+type Options struct {
+	NetworkID      uint8         // network ID (default 5, testnet); transactions for other networks are rejected
+	BlockInterval  time.Duration // time between blocks in Run (0 = only MineBlock produces blocks)
+	DropRate       float64       // probability (0..1) that a mempool transaction is dropped instead of included
+	MinFeePerByte  float64       // minimum fee per byte for mempool admission (Luna)
+	InitialBalance int64         // Luna credited to accounts imported into the wallet
+	StartHeight    int64         // height of the first block (default 1)
+	Seed           int64         // seed for DropRate (0 = random)
+
+	// Logf, if set, is called for blocks with transactions and for dropped transactions
+	Logf func(format string, args ...interface{})
+}
+
+// walletAccount is a key held by the node wallet
+type walletAccount struct {
+	key           ed25519.PrivateKey
+	passphrase    string
+	unlocked      bool
+	unlockedUntil time.Time // zero = until locked
+}
+
+// Node is an in-memory Albatross node. It implements http.Handler.
+type Node struct {
+	opts Options
+
+	mu        sync.Mutex
+	rng       *mrand.Rand
+	height    int64
+	timestamp int64 // of the latest block, milliseconds
+	consensus bool
+	balances  map[string]int64          // normalized address -> Luna
+	wallet    map[string]*walletAccount // normalized address -> key
+	mempool   []*Transaction
+	txs       map[string]*Transaction   // hash -> mempool and included transactions
+	history   map[string][]*Transaction // normalized address -> included transactions, oldest first
+	dropped   int
+}
+
+// New creates a node with consensus established
+func New(opts Options) *Node {
+	if opts.NetworkID == 0 {
+		opts.NetworkID = NetworkIDTest
+	}
+	if opts.StartHeight <= 0 {
+		opts.StartHeight = 1
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Node{
+		opts:      opts,
+		rng:       mrand.New(mrand.NewSource(seed)),
+		height:    opts.StartHeight,
+		timestamp: time.Now().UnixMilli(),
+		consensus: true,
+		balances:  make(map[string]int64),
+		wallet:    make(map[string]*walletAccount),
+		txs:       make(map[string]*Transaction),
+		history:   make(map[string][]*Transaction),
+	}
+}
+
+func (n *Node) logf(format string, args ...interface{}) {
+	if n.opts.Logf != nil {
+		n.opts.Logf(format, args...)
+	}
+}
+
+// Run produces a block every BlockInterval until ctx is done
+func (n *Node) Run(ctx context.Context) {
+	if n.opts.BlockInterval <= 0 {
+		<-ctx.Done()
+		return
+	}
+	ticker := time.NewTicker(n.opts.BlockInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.MineBlock()
+		}
+	}
+}
+
+// MineBlock produces the next block from the mempool and returns its number.
+// Each pending transaction is dropped with probability DropRate (its value and
+// fee are refunded), as are transactions whose validity window has passed.
+func (n *Node) MineBlock() int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.height++
+	n.timestamp = time.Now().UnixMilli()
+	included := 0
+	for _, tx := range n.mempool {
+		expired := n.height > tx.ValidityStartHeight+ValidityWindow
+		if expired || (n.opts.DropRate > 0 && n.rng.Float64() < n.opts.DropRate) {
+			n.balances[normalize(tx.From)] += tx.Value + tx.Fee
+			delete(n.txs, tx.Hash)
+			n.dropped++
+			n.logf("dropped %s (%s -> %s)", tx.Hash, tx.From, tx.To)
+			continue
+		}
+		tx.BlockNumber = n.height
+		tx.Timestamp = n.timestamp
+		n.balances[normalize(tx.To)] += tx.Value
+		n.history[normalize(tx.From)] = append(n.history[normalize(tx.From)], tx)
+		if normalize(tx.To) != normalize(tx.From) {
+			n.history[normalize(tx.To)] = append(n.history[normalize(tx.To)], tx)
+		}
+		included++
+	}
+	n.mempool = nil
+	if included > 0 {
+		n.logf("block %d: %d transactions", n.height, included)
+	}
+	return n.height
+}
+
+// Height returns the number of the latest block
+func (n *Node) Height() int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.height
+}
+
+// Fund credits luna to address
+func (n *Node) Fund(address string, luna int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.balances[normalize(address)] += luna
+}
+
+// Balance returns the balance of address in Luna (pending sends already deducted)
+func (n *Node) Balance(address string) int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.balances[normalize(address)]
+}
+
+// SetConsensus sets what isConsensusEstablished answers
+func (n *Node) SetConsensus(established bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.consensus = established
+}
+
+// Mempool returns the pending transactions
+func (n *Node) Mempool() []Transaction {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	txs := make([]Transaction, 0, len(n.mempool))
+	for _, tx := range n.mempool {
+		txs = append(txs, *tx)
+	}
+	return txs
+}
+
+// Transactions returns the included transactions of address, oldest first
+func (n *Node) Transactions(address string) []Transaction {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var txs []Transaction
+	for _, tx := range n.history[normalize(address)] {
+		txs = append(txs, *tx)
+	}
+	return txs
+}
+
+// Dropped returns how many transactions were dropped from the mempool
+func (n *Node) Dropped() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.dropped
+}
+
+// ImportKey adds an Ed25519 key to the wallet (locked, like importRawKey) and
+// returns its address
+func (n *Node) ImportKey(seed []byte, passphrase string) (string, error) {
+	if len(seed) != ed25519.SeedSize {
+		return "", fmt.Errorf("private key must be %d bytes", ed25519.SeedSize)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.importKeyLocked(ed25519.NewKeyFromSeed(seed), passphrase), nil
+}
+
+func (n *Node) importKeyLocked(key ed25519.PrivateKey, passphrase string) string {
+	address := addressOf(key.Public().(ed25519.PublicKey))
+	if _, ok := n.wallet[normalize(address)]; !ok {
+		n.wallet[normalize(address)] = &walletAccount{key: key, passphrase: passphrase}
+		n.balances[normalize(address)] += n.opts.InitialBalance
+	}
+	return address
+}
+
+// newKey generates a wallet key
+func newKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	return key, err
+}
+
+// submitLocked checks a signed transaction and adds it to the mempool
+func (n *Node) submitLocked(raw []byte) (string, error) {
+	tx, err := decodeTransaction(raw)
+	if err != nil {
+		return "", err
+	}
+	if err := n.checkLocked(tx); err != nil {
+		return "", err
+	}
+	if _, known := n.txs[tx.Hash]; known {
+		return "", fmt.Errorf("transaction %s is already known", tx.Hash)
+	}
+	n.balances[normalize(tx.From)] -= tx.Value + tx.Fee
+	n.mempool = append(n.mempool, tx)
+	n.txs[tx.Hash] = tx
+	return tx.Hash, nil
+}
+
+// checkLocked applies the mempool admission rules to a decoded transaction
+func (n *Node) checkLocked(tx *Transaction) error {
+	switch {
+	case tx.NetworkID != n.opts.NetworkID:
+		return fmt.Errorf("transaction is for network %d, this node is on network %d", tx.NetworkID, n.opts.NetworkID)
+	case tx.ValidityStartHeight > n.height+1 || tx.ValidityStartHeight+ValidityWindow < n.height+1:
+		return fmt.Errorf("invalid validity start height %d (current height %d)", tx.ValidityStartHeight, n.height)
+	case float64(tx.Fee) < n.opts.MinFeePerByte*float64(tx.Size):
+		return fmt.Errorf("fee %d is below the minimum of %.0f (%g Luna per byte)", tx.Fee, n.opts.MinFeePerByte*float64(tx.Size), n.opts.MinFeePerByte)
+	case n.balances[normalize(tx.From)] < tx.Value+tx.Fee:
+		return fmt.Errorf("insufficient funds: %s has %d Luna, needs %d", tx.From, n.balances[normalize(tx.From)], tx.Value+tx.Fee)
+	}
+	return nil
+}
+
+// transactionsByAddressLocked pages through the transactions of address,
+// newest first; startAt is exclusive
+func (n *Node) transactionsByAddressLocked(address string, max int, startAt string) []*rpcTransaction {
+	history := n.history[normalize(address)]
+	end := len(history)
+	if startAt != "" {
+		end = 0 // unknown startAt: nothing older
+		for i, tx := range history {
+			if tx.Hash == startAt {
+				end = i
+				break
+			}
+		}
+	}
+	txs := make([]*rpcTransaction, 0)
+	for i := end - 1; i >= 0 && (max <= 0 || len(txs) < max); i-- {
+		txs = append(txs, history[i].rpc(n.height))
+	}
+	return txs
+}
+
+// mempoolLocked summarizes the mempool by fee per byte, like the node's
+// mempool method
+func (n *Node) mempoolLocked() map[string]interface{} {
+	buckets := make(map[int64]int)
+	for _, tx := range n.mempool {
+		buckets[tx.Fee/int64(tx.Size)]++
+	}
+	var feesPerByte []int64
+	for fpb := range buckets {
+		feesPerByte = append(feesPerByte, fpb)
+	}
+	sort.Slice(feesPerByte, func(i, j int) bool { return feesPerByte[i] > feesPerByte[j] })
+
+	result := map[string]interface{}{"total": len(n.mempool), "buckets": feesPerByte}
+	for fpb, count := range buckets {
+		result[fmt.Sprintf("_%d", fpb)] = count
+	}
+	return result
+}
+
+// networkName is the node's name for a network ID (as in getLatestBlock)
+func networkName(id uint8) interface{} {
+	switch id {
+	case 24:
+		return "MainAlbatross"
+	case 5:
+		return "TestAlbatross"
+	case 6:
+		return "DevAlbatross"
+	case 7:
+		return "UnitAlbatross"
+	}
+	return id
+}
+
+// ServeHTTP answers JSON-RPC 2.0 requests (with CORS headers, so the web
+// frontend can use the node from its dev server)
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "JSON-RPC requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+	}
+	resp := map[string]interface{}{"jsonrpc": "2.0"}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp["id"] = nil
+		resp["error"] = rpcError{Code: -32700, Message: "parse error: " + err.Error()}
+	} else {
+		resp["id"] = req.ID
+		result, err := n.call(req.Method, req.Params)
+		if err != nil {
+			if e, ok := err.(rpcError); ok {
+				resp["error"] = e
+			} else {
+				resp["error"] = rpcError{Code: -32000, Message: err.Error()}
+			}
+		} else {
+			// Albatross wraps results as {"data": ..., "metadata": ...}
+			resp["result"] = map[string]interface{}{"data": result, "metadata": nil}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// rpcError is a JSON-RPC error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e rpcError) Error() string {
+	return e.Message
+}
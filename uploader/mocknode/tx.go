@@ -0,0 +1,291 @@
+package mocknode
+
+import (
+	"crypto/ed25519"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	addressBytes     = 20
+	txFormatExtended = 1
+	proofAlgEd25519  = 0
+)
+
+// nimiqBase32 is the Nimiq address alphabet (no I, O, W, Z)
+var nimiqBase32 = base32.NewEncoding("0123456789ABCDEFGHJKLMNPQRSTUVXY").WithPadding(base32.NoPadding)
+
+// Transaction is a basic transaction known to the node, in the mempool or in a block
+type Transaction struct {
+	Hash                string
+	From                string // user-friendly address
+	To                  string
+	Value               int64
+	Fee                 int64
+	Data                []byte // recipient data
+	ValidityStartHeight int64
+	NetworkID           uint8
+	Size                int   // serialized size in bytes
+	BlockNumber         int64 // 0 while in the mempool
+	Timestamp           int64 // of the block, milliseconds
+}
+
+// rpcTransaction is a transaction as the Albatross RPC describes it
+type rpcTransaction struct {
+	Hash                string `json:"hash"`
+	BlockNumber         int64  `json:"blockNumber,omitempty"`
+	Timestamp           int64  `json:"timestamp,omitempty"`
+	Confirmations       int64  `json:"confirmations,omitempty"`
+	From                string `json:"from"`
+	FromType            int    `json:"fromType"`
+	To                  string `json:"to"`
+	ToType              int    `json:"toType"`
+	Value               int64  `json:"value"`
+	Fee                 int64  `json:"fee"`
+	SenderData          string `json:"senderData"`
+	RecipientData       string `json:"recipientData"`
+	Flags               int    `json:"flags"`
+	ValidityStartHeight int64  `json:"validityStartHeight"`
+	NetworkID           uint8  `json:"networkId"`
+	Size                int    `json:"size"`
+}
+
+func (t *Transaction) rpc(height int64) *rpcTransaction {
+	tx := &rpcTransaction{
+		Hash:                t.Hash,
+		BlockNumber:         t.BlockNumber,
+		Timestamp:           t.Timestamp,
+		From:                t.From,
+		To:                  t.To,
+		Value:               t.Value,
+		Fee:                 t.Fee,
+		RecipientData:       hex.EncodeToString(t.Data),
+		ValidityStartHeight: t.ValidityStartHeight,
+		NetworkID:           t.NetworkID,
+		Size:                t.Size,
+	}
+	if t.BlockNumber > 0 {
+		tx.Confirmations = height - t.BlockNumber + 1
+	}
+	return tx
+}
+
+// normalize strips spaces and upper-cases an address, for use as a map key
+func normalize(address string) string {
+	return strings.ToUpper(strings.ReplaceAll(address, " ", ""))
+}
+
+// formatAddress encodes 20 address bytes as a user-friendly NQ address
+func formatAddress(addr [addressBytes]byte) string {
+	body := nimiqBase32.EncodeToString(addr[:])
+
+	// IBAN check digits: MOD-97-10 over body + "NQ00", letters as 10..35
+	remainder := 0
+	for _, c := range body + "NQ00" {
+		digits := string(c)
+		if c >= 'A' && c <= 'Z' {
+			digits = fmt.Sprintf("%d", c-'A'+10)
+		}
+		for _, d := range digits {
+			remainder = (remainder*10 + int(d-'0')) % 97
+		}
+	}
+	full := fmt.Sprintf("NQ%02d%s", 98-remainder, body)
+
+	groups := make([]string, 0, 9)
+	for i := 0; i < len(full); i += 4 {
+		groups = append(groups, full[i:i+4])
+	}
+	return strings.Join(groups, " ")
+}
+
+// parseAddress decodes a user-friendly NQ address and checks its check digits
+func parseAddress(address string) ([addressBytes]byte, error) {
+	var addr [addressBytes]byte
+	address = normalize(address)
+	if len(address) != 36 || !strings.HasPrefix(address, "NQ") {
+		return addr, fmt.Errorf("invalid address %q", address)
+	}
+	body, err := nimiqBase32.DecodeString(address[4:])
+	if err != nil || len(body) != addressBytes {
+		return addr, fmt.Errorf("invalid address %q", address)
+	}
+	copy(addr[:], body)
+	if normalize(formatAddress(addr)) != address {
+		return addr, fmt.Errorf("invalid address %q (check digits)", address)
+	}
+	return addr, nil
+}
+
+// addressOf derives the address of an Ed25519 public key
+func addressOf(publicKey ed25519.PublicKey) string {
+	hash := blake2b.Sum256(publicKey)
+	var addr [addressBytes]byte
+	copy(addr[:], hash[:addressBytes])
+	return formatAddress(addr)
+}
+
+// content is the signed part of a transaction (also what its hash covers)
+func content(from, to [addressBytes]byte, data []byte, value, fee uint64, validityStartHeight uint32, networkID uint8) []byte {
+	var b []byte
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	b = append(b, data...)
+	b = append(b, from[:]...)
+	b = append(b, 0) // basic account
+	b = append(b, to[:]...)
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint64(b, value)
+	b = binary.BigEndian.AppendUint64(b, fee)
+	b = binary.BigEndian.AppendUint32(b, validityStartHeight)
+	b = append(b, networkID)
+	b = append(b, 0)                        // flags
+	b = binary.BigEndian.AppendUint16(b, 0) // sender data
+	return b
+}
+
+// signTransaction builds and signs a transaction from a wallet key and
+// returns it serialized in the extended format
+func signTransaction(key ed25519.PrivateKey, to string, data []byte, value, fee, validityStartHeight int64, networkID uint8) ([]byte, error) {
+	publicKey := key.Public().(ed25519.PublicKey)
+	from, err := parseAddress(addressOf(publicKey))
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := parseAddress(to)
+	if err != nil {
+		return nil, err
+	}
+	if value < 0 || fee < 0 || validityStartHeight < 0 {
+		return nil, fmt.Errorf("value, fee and validity start height must not be negative")
+	}
+	signature := ed25519.Sign(key, content(from, recipient, data, uint64(value), uint64(fee), uint32(validityStartHeight), networkID))
+
+	var proof []byte
+	proof = append(proof, proofAlgEd25519)
+	proof = append(proof, publicKey...)
+	proof = append(proof, 0) // empty merkle path
+	proof = append(proof, signature...)
+
+	var b []byte
+	b = append(b, txFormatExtended)
+	b = append(b, from[:]...)
+	b = append(b, 0)
+	b = binary.AppendUvarint(b, 0) // sender data
+	b = append(b, recipient[:]...)
+	b = append(b, 0)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	b = append(b, data...)
+	b = binary.BigEndian.AppendUint64(b, uint64(value))
+	b = binary.BigEndian.AppendUint64(b, uint64(fee))
+	b = binary.BigEndian.AppendUint32(b, uint32(validityStartHeight))
+	b = append(b, networkID)
+	b = append(b, 0) // flags
+	b = binary.AppendUvarint(b, uint64(len(proof)))
+	b = append(b, proof...)
+	return b, nil
+}
+
+// decoder reads a serialized transaction
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.err = fmt.Errorf("transaction is truncated")
+		return nil
+	}
+	out := d.b[:n]
+	d.b = d.b[n:]
+	return out
+}
+
+func (d *decoder) uvarint() int {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 || v > uint64(len(d.b)) {
+		d.err = fmt.Errorf("invalid length in transaction")
+		return 0
+	}
+	d.b = d.b[n:]
+	return int(v)
+}
+
+func (d *decoder) address() (addr [addressBytes]byte) {
+	copy(addr[:], d.take(addressBytes))
+	if t := d.take(1); d.err == nil && t[0] != 0 {
+		d.err = fmt.Errorf("only basic accounts are supported")
+	}
+	return addr
+}
+
+// decodeTransaction parses and verifies an extended-format transaction with
+// an Ed25519 signature proof
+func decodeTransaction(raw []byte) (*Transaction, error) {
+	d := &decoder{b: raw}
+	if format := d.take(1); d.err == nil && format[0] != txFormatExtended {
+		return nil, fmt.Errorf("unsupported transaction format %d", format[0])
+	}
+	from := d.address()
+	if d.uvarint() != 0 && d.err == nil {
+		return nil, fmt.Errorf("sender data is not supported")
+	}
+	to := d.address()
+	data := d.take(d.uvarint())
+	valueBytes := d.take(8)
+	feeBytes := d.take(8)
+	heightBytes := d.take(4)
+	network := d.take(1)
+	flags := d.take(1)
+	proof := d.take(d.uvarint())
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(d.b) != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after transaction", len(d.b))
+	}
+	if flags[0] != 0 {
+		return nil, fmt.Errorf("transaction flags are not supported")
+	}
+	if len(proof) != 1+ed25519.PublicKeySize+1+ed25519.SignatureSize || proof[0] != proofAlgEd25519 || proof[1+ed25519.PublicKeySize] != 0 {
+		return nil, fmt.Errorf("invalid signature proof")
+	}
+
+	publicKey := ed25519.PublicKey(proof[1 : 1+ed25519.PublicKeySize])
+	signature := proof[2+ed25519.PublicKeySize:]
+	if normalize(addressOf(publicKey)) != normalize(formatAddress(from)) {
+		return nil, fmt.Errorf("proof public key does not belong to the sender")
+	}
+
+	value := binary.BigEndian.Uint64(valueBytes)
+	fee := binary.BigEndian.Uint64(feeBytes)
+	height := binary.BigEndian.Uint32(heightBytes)
+	signed := content(from, to, data, value, fee, height, network[0])
+	if !ed25519.Verify(publicKey, signed, signature) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	hash := blake2b.Sum256(signed)
+
+	return &Transaction{
+		Hash:                hex.EncodeToString(hash[:]),
+		From:                formatAddress(from),
+		To:                  formatAddress(to),
+		Value:               int64(value),
+		Fee:                 int64(fee),
+		Data:                append([]byte(nil), data...),
+		ValidityStartHeight: int64(height),
+		NetworkID:           network[0],
+		Size:                len(raw),
+	}, nil
+}
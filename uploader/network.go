@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// expectedNetwork returns the network the command is bound to, from the
+// catalog address (the main and test shortcuts belong to their network) and
+// the network setting (--network, NIMIQ_NETWORK, profile or credentials).
+// The ID is 0 when nothing binds the command to a network.
+func expectedNetwork(catalogAddr, network string) (uint8, string, error) {
+	var id uint8
+	var source string
+	if catalogAddr != "" {
+		if catalogID, ok := networkIDForCatalog(catalogAddr); ok {
+			id, source = catalogID, "catalog "+catalogAddr
+		}
+	}
+
+	networkSource := "--network"
+	if network == "" {
+		var err error
+		if network, networkSource, err = ResolveSetting("network"); err != nil {
+			return 0, "", err
+		}
+	}
+	if network == "" {
+		return id, source, nil
+	}
+	settingID, err := ParseNetworkID(network)
+	if err != nil {
+		return 0, "", fmt.Errorf("%w (from %s)", err, networkSource)
+	}
+	if id != 0 && settingID != id {
+		return 0, "", fmt.Errorf("%s belongs to %s, but the network is set to %s (%s)", source, networkName(id), networkName(settingID), networkSource)
+	}
+	return settingID, "network " + network + " from " + networkSource, nil
+}
+
+// checkNodeNetwork asks the node which network it is on and refuses to go on
+// if that is not the network the command is bound to (see expectedNetwork),
+// so mainnet catalogs are never written from a testnet node or the other way
+// round. Returns the node's network ID. If the node can't tell, that is an
+// error when a network is expected, and 0 with a warning otherwise.
+func checkNodeNetwork(rpc *NimiqRPC, catalogAddr, network string) (uint8, error) {
+	expected, source, err := expectedNetwork(catalogAddr, network)
+	if err != nil {
+		return 0, err
+	}
+
+	nodeID, err := rpc.GetNetworkID()
+	if err != nil && expected != 0 {
+		return 0, fmt.Errorf("could not determine the network of node %s, but %s expects %s - refusing to go on: %w", rpc.url, source, networkName(expected), err)
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Warning: could not determine the node's network (%v) - not checked\n", err)
+		return 0, nil
+	}
+	if expected != 0 && nodeID != expected {
+		return 0, fmt.Errorf("node %s is on %s, but %s expects %s", rpc.url, networkName(nodeID), source, networkName(expected))
+	}
+	fmt.Printf("Network: %s ✓\n", networkName(nodeID))
+	return nodeID, nil
+}
+
+// checkProgressNetwork makes a resume fail fast when a progress file was
+// written against a different network than the node's
+func checkProgressNetwork(progressFile string, recorded, current uint8) error {
+	if recorded == 0 || current == 0 || recorded == current {
+		return nil
+	}
+	return fmt.Errorf("progress file %s was written on %s, but the node is on %s - use a node on the same network or remove the progress file", progressFile, networkName(recorded), networkName(current))
+}
+
+// manifestNetworkName is the network name written to legacy manifests
+func manifestNetworkName(id uint8) string {
+	switch id {
+	case NetworkIDMain:
+		return "mainnet"
+	case NetworkIDTest:
+		return "testnet"
+	case NetworkIDDev:
+		return "devnet"
+	}
+	return strconv.Itoa(int(id))
+}
+
+// checkPlanNetwork refuses to send a plan or bundle made for another network
+// than the node's
+func checkPlanNetwork(what string, planID, nodeID uint8) error {
+	if planID == 0 || nodeID == 0 || planID == nodeID {
+		return nil
+	}
+	return fmt.Errorf("%s was made for %s, but the node is on %s - refusing to send", what, networkName(planID), networkName(nodeID))
+}
@@ -2,13 +2,20 @@ package main
 
 import (
 	"archive/zip"
+	"bytes"
+	"compress/flate"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -18,15 +25,48 @@ func newPackageCmd() *cobra.Command {
 		inputDir  string
 		outputZip string
 		gameExe   string
+		romFile   string
+		title     string
+		platform  string
+		filename  string
+		reproduce bool
+		optimize  bool
+		force     bool
+		feeFlag   string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "package",
-		Short: "Package DOS game files into a ZIP file ready for blockchain upload",
-		Long: `Package DOS game files into a ZIP file that can be uploaded to the blockchain
-and run directly in the browser using JS-DOS.
-
-The ZIP file will contain all game files with proper structure for DOS emulation.`,
+		Short: "Package game files into a ZIP file with run.json, ready for blockchain upload",
+		Long: `Package a game directory into a ZIP file that can be uploaded to the blockchain
+and run directly in the browser (JS-DOS for DOS games, the Game Boy and NES
+emulators for ROMs).
+
+A run.json (see RUN_JSON.md) is written to the root of the ZIP. The platform is
+detected unless --platform is given: a .gb/.gbc/.nes ROM (or a file with a Game
+Boy or iNES header) makes a GB, GBC or NES cartridge, otherwise the first .exe,
+.com or .bat file makes a DOS game. A run.json already in the directory is
+used for defaults; flags override it. The executable or ROM must be part of
+the package.
+
+With --reproducible the ZIP only depends on the file names and contents:
+entries are sorted, timestamps, permissions and the compression level are
+fixed, so packaging the same files again (on any machine, with the same
+uploader version) gives the same SHA256. An attestation listing the SHA256 of
+every input and of the output is printed.
+
+Every 51 bytes of the ZIP is one transaction, so --optimize makes the package as
+small as possible: each entry is stored or deflated at whichever compress/flate
+level gives the fewest bytes, and junk (.DS_Store, ._*, __MACOSX/, Thumbs.db,
+desktop.ini) and the patterns listed in the directory's .uploadignore are left
+out. The chunk count and estimated cost are printed next to those of the
+package without --optimize.
+
+Examples:
+  nimiq-uploader package --dir ./doom --title "Doom" --exe DOOM.EXE
+  nimiq-uploader package --dir ./tetris --title "Tetris" --platform GB
+  nimiq-uploader package --dir ./doom --title "Doom" --reproducible
+  nimiq-uploader package --dir ./doom --title "Doom" --optimize --fee min`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if inputDir == "" {
 				return fmt.Errorf("--dir is required")
@@ -42,99 +82,130 @@ The ZIP file will contain all game files with proper structure for DOS emulation
 			}
 
 			// Determine output filename
+			dirName := filepath.Base(filepath.Clean(inputDir))
+			if dirName == "." || dirName == "/" {
+				if abs, err := filepath.Abs(inputDir); err == nil {
+					dirName = filepath.Base(abs)
+				}
+			}
+			if dirName == "." || dirName == "/" {
+				dirName = "game"
+			}
 			outputFile := outputZip
 			if outputFile == "" {
-				dirName := filepath.Base(inputDir)
-				if dirName == "." || dirName == "/" {
-					dirName = "game"
-				}
 				outputFile = dirName + ".zip"
 			}
 
-			fmt.Printf("Packaging DOS game from directory: %s\n", inputDir)
-			fmt.Printf("Output file: %s\n", outputFile)
-
-			// Create ZIP file
-			zipFile, err := os.Create(outputFile)
-			if err != nil {
-				return fmt.Errorf("failed to create ZIP file: %w", err)
-			}
-			defer zipFile.Close()
-
-			zipWriter := zip.NewWriter(zipFile)
-			defer zipWriter.Close()
-
-			// Find game executable if not specified
-			gameExecutable := gameExe
-			if gameExecutable == "" {
-				gameExecutable = findGameExecutable(inputDir)
-				if gameExecutable == "" {
-					fmt.Printf("Warning: No game executable found (.exe, .com, or .bat). You may need to specify --exe\n")
-				} else {
-					fmt.Printf("Found game executable: %s\n", gameExecutable)
+			var ignore []string
+			var feePolicy FeePolicy
+			if optimize {
+				if feePolicy, err = ParseFeePolicy(feeFlag); err != nil {
+					return err
 				}
-			}
-
-			// Walk directory and add files to ZIP
-			var filesAdded int
-			var totalSize int64
-			err = filepath.Walk(inputDir, func(path string, info os.FileInfo, err error) error {
-				if err != nil {
+				if ignore, err = loadUploadIgnore(inputDir); err != nil {
 					return err
 				}
+			}
+			files, ignored, existing, err := collectPackageFiles(inputDir, outputFile, ignore)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				return fmt.Errorf("no files to package in %s", inputDir)
+			}
+			if reproduce {
+				sort.Strings(files)
+			}
 
-				// Skip directories
-				if info.IsDir() {
-					return nil
+			// run.json: an existing one in the directory, overridden by flags
+			config := &RunConfig{}
+			if existing != nil {
+				config = existing
+				fmt.Printf("Using run.json from %s\n", inputDir)
+			}
+			if title != "" {
+				config.Title = title
+			}
+			if filename != "" {
+				config.Filename = filename
+			}
+			if platform != "" {
+				config.Platform = platform
+			}
+			if gameExe != "" {
+				config.Executable, config.ROM = gameExe, ""
+				if platform == "" {
+					config.Platform = "DOS"
 				}
-
-				// Skip the output ZIP file if it's in the directory
-				if filepath.Base(path) == filepath.Base(outputFile) {
-					return nil
+			}
+			if romFile != "" {
+				config.ROM, config.Executable = romFile, ""
+				if platform == "" {
+					config.Platform = ""
 				}
-
-				// Get relative path from input directory
-				relPath, err := filepath.Rel(inputDir, path)
+			}
+			if err := detectPackageTarget(inputDir, files, config); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			if err := config.Validate(files); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			if config.ROM != "" {
+				rom, err := checkPackageROM(inputDir, config, force)
 				if err != nil {
+					cmd.SilenceUsage = true
 					return err
 				}
-
-				// Normalize path separators to forward slashes (DOS/Windows compatibility)
-				zipPath := strings.ReplaceAll(relPath, "\\", "/")
-
-				// Open source file
-				srcFile, err := os.Open(path)
-				if err != nil {
-					return fmt.Errorf("failed to open %s: %w", path, err)
-				}
-				defer srcFile.Close()
-
-				// Create file in ZIP
-				zipEntry, err := zipWriter.Create(zipPath)
-				if err != nil {
-					return fmt.Errorf("failed to create ZIP entry for %s: %w", zipPath, err)
+				if config.Title == "" && rom != nil && rom.Title != "" {
+					config.Title = rom.Title
+					fmt.Printf("Title from the ROM: %s\n", config.Title)
 				}
+			}
+			if config.Title == "" {
+				config.Title = dirName
+			}
+			if config.Filename == "" {
+				config.Filename = filepath.Base(outputFile)
+			}
+			if config.Platform == "DOS" && config.Executable == "" {
+				fmt.Printf("Warning: No game executable found (.exe, .com, or .bat). You may need to specify --exe\n")
+			}
 
-				// Copy file contents
-				written, err := io.Copy(zipEntry, srcFile)
-				if err != nil {
-					return fmt.Errorf("failed to write %s to ZIP: %w", zipPath, err)
-				}
+			runJSON, err := json.MarshalIndent(config, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode run.json: %w", err)
+			}
+			runJSON = append(runJSON, '\n')
 
-				filesAdded++
-				totalSize += written
-				fmt.Printf("  Added: %s (%d bytes)\n", zipPath, written)
+			fmt.Printf("Packaging %s game from directory: %s\n", config.Platform, inputDir)
+			fmt.Printf("Output file: %s\n", outputFile)
 
-				return nil
-			})
+			// Create ZIP file
+			zipFile, err := os.Create(outputFile)
+			if err != nil {
+				return fmt.Errorf("failed to create ZIP file: %w", err)
+			}
+			defer zipFile.Close()
 
+			zipOptions := packageZipOptions{reproducible: reproduce, optimize: optimize}
+			inputs, err := writePackageZip(zipFile, inputDir, files, runJSON, zipOptions)
 			if err != nil {
 				return fmt.Errorf("failed to package files: %w", err)
 			}
+			filesAdded := len(files)
+			var totalSize int64
+			for _, input := range inputs {
+				if input.path != "run.json" {
+					totalSize += input.size
+				}
+				fmt.Printf("  Added: %s (%s)\n", input.path, input.describe())
+			}
 
-			// Close ZIP writer before calculating hash
-			zipWriter.Close()
-			zipFile.Close()
+			if err := zipFile.Close(); err != nil {
+				return fmt.Errorf("failed to finish ZIP file: %w", err)
+			}
 
 			// Calculate SHA256 of the ZIP file
 			hash, err := calculateSHA256(outputFile)
@@ -142,18 +213,44 @@ The ZIP file will contain all game files with proper structure for DOS emulation
 				fmt.Printf("Warning: failed to calculate SHA256: %v\n", err)
 			} else {
 				fmt.Printf("\nSHA256: %s\n", hash)
+				if reproduce {
+					printPackageAttestation(inputs, hash)
+				}
+			}
+
+			if optimize {
+				// Baseline: the package without --optimize
+				baselineFiles := append(append([]string(nil), files...), ignored...)
+				baselineOptions := packageZipOptions{reproducible: reproduce}
+				baseline := &countingWriter{}
+				if _, err := writePackageZip(baseline, inputDir, baselineFiles, runJSON, baselineOptions); err != nil {
+					return fmt.Errorf("failed to build baseline package: %w", err)
+				}
+				fee := packageFee(cmd, feePolicy)
+				zipInfo, err := os.Stat(outputFile)
+				if err != nil {
+					return fmt.Errorf("failed to read ZIP file: %w", err)
+				}
+				printPackageOptimization(ignored, inputs, newPackageCost(baseline.n, fee), newPackageCost(zipInfo.Size(), fee), fee)
 			}
 
 			fmt.Printf("\n✓ Successfully created ZIP package:\n")
 			fmt.Printf("  File: %s\n", outputFile)
-			fmt.Printf("  Files: %d\n", filesAdded)
+			fmt.Printf("  Files: %d (+ run.json)\n", filesAdded)
 			fmt.Printf("  Total size: %d bytes (%.2f KB)\n", totalSize, float64(totalSize)/1024)
-			if gameExecutable != "" {
-				fmt.Printf("  Game executable: %s\n", gameExecutable)
-				fmt.Printf("\nThis ZIP file is ready to upload to the blockchain.\n")
-				fmt.Printf("After syncing and verifying, users can run it directly in the browser using JS-DOS.\n")
-			} else {
+			fmt.Printf("  Title: %s\n", config.Title)
+			fmt.Printf("  Platform: %s\n", config.Platform)
+			switch {
+			case config.Executable != "":
+				fmt.Printf("  Game executable: %s\n", config.Executable)
+			case config.ROM != "":
+				fmt.Printf("  ROM: %s\n", config.ROM)
+			}
+			if config.Platform == "DOS" && config.Executable == "" {
 				fmt.Printf("\nWarning: No game executable found. Make sure your ZIP contains a .exe, .com, or .bat file.\n")
+			} else {
+				fmt.Printf("\nThis ZIP file is ready to upload to the blockchain.\n")
+				fmt.Printf("After syncing and verifying, users can run it directly in the browser.\n")
 			}
 
 			return nil
@@ -162,39 +259,367 @@ The ZIP file will contain all game files with proper structure for DOS emulation
 
 	cmd.Flags().StringVar(&inputDir, "dir", "", "Directory containing game files to package")
 	cmd.Flags().StringVar(&outputZip, "output", "", "Output ZIP file path (default: <dirname>.zip)")
-	cmd.Flags().StringVar(&gameExe, "exe", "", "Main game executable (e.g., DOOM.EXE). If not specified, will try to find .exe, .com, or .bat files")
+	cmd.Flags().StringVar(&gameExe, "exe", "", "Main game executable of a DOS game (e.g., DOOM.EXE). If not specified, will try to find .exe, .com, or .bat files")
+	cmd.Flags().StringVar(&romFile, "rom", "", "ROM file of a GB, GBC or NES game (e.g., tetris.gb). If not specified, will try to find one")
+	cmd.Flags().StringVar(&title, "title", "", "Game title for run.json (default: directory name)")
+	cmd.Flags().StringVar(&platform, "platform", "", "Platform for run.json: DOS, GB, GBC or NES (default: detected)")
+	cmd.Flags().StringVar(&filename, "filename", "", "Filename for run.json (default: output ZIP name)")
+	cmd.Flags().BoolVar(&reproduce, "reproducible", false, "Build a byte-identical ZIP for the same files (sorted entries, fixed timestamps, permissions and compression) and print an attestation")
+	cmd.Flags().BoolVar(&optimize, "optimize", false, "Minimize the ZIP size: store or deflate each entry, whichever is smaller, and leave out junk and "+UploadIgnoreFile+" matches")
+	cmd.Flags().StringVar(&feeFlag, "fee", "0", "Transaction fee for the --optimize cost estimate: a fixed amount in Luna, or auto, min or fast")
+	cmd.Flags().BoolVar(&force, "force", false, "Package the ROM even if its header is broken or does not match --platform")
 	cmd.MarkFlagRequired("dir")
 
 	return cmd
 }
 
-func findGameExecutable(dir string) string {
-	var executables []string
+// collectPackageFiles lists the files of dir as ZIP paths, skipping the output
+// ZIP and a run.json at the root, which is parsed and returned instead. Files
+// matching an ignore pattern are returned separately.
+func collectPackageFiles(dir, outputFile string, ignore []string) ([]string, []string, *RunConfig, error) {
+	outputAbs, _ := filepath.Abs(outputFile)
+
+	var files, ignored []string
+	var existing *RunConfig
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
+		// Skip directories
+		if info.IsDir() {
 			return nil
 		}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		if ext == ".exe" || ext == ".com" || ext == ".bat" {
-			relPath, err := filepath.Rel(dir, path)
-			if err == nil {
-				executables = append(executables, strings.ReplaceAll(relPath, "\\", "/"))
+		// Skip the output ZIP file if it's in the directory
+		if abs, err := filepath.Abs(path); err == nil && abs == outputAbs {
+			return nil
+		}
+
+		// Get relative path from input directory
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		// Normalize path separators to forward slashes (DOS/Windows compatibility)
+		zipPath := strings.ReplaceAll(relPath, "\\", "/")
+
+		if zipPath == "run.json" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			existing, err = ParseRunConfig(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
 			}
+			return nil
+		}
+
+		if uploadIgnored(ignore, zipPath) {
+			ignored = append(ignored, zipPath)
+			return nil
 		}
+		files = append(files, zipPath)
 		return nil
 	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to package files: %w", err)
+	}
+	return files, ignored, existing, nil
+}
 
-	// Prefer .exe, then .com, then .bat
-	for _, ext := range []string{".exe", ".com", ".bat"} {
-		for _, exe := range executables {
-			if strings.HasSuffix(strings.ToLower(exe), ext) {
-				return exe
+// checkPackageROM parses the header of the ROM of config and checks it against
+// the platform; with force, problems are only printed
+func checkPackageROM(dir string, config *RunConfig, force bool) (*ROMInfo, error) {
+	rom, err := InspectROMFile(filepath.Join(dir, filepath.FromSlash(config.ROM)))
+	if err == nil && rom == nil {
+		err = fmt.Errorf("%s is not a GB, GBC or NES ROM", config.ROM)
+	}
+	if err == nil {
+		rom.Name = config.ROM
+		err = rom.CheckPlatform(config.Platform)
+	}
+	if err != nil {
+		if !force {
+			return nil, fmt.Errorf("%w (use --force to package it anyway)", err)
+		}
+		fmt.Printf("⚠️  Warning: %v\n", err)
+		return rom, nil
+	}
+
+	fmt.Printf("ROM header: %s", rom.Format)
+	if rom.Format == "iNES" || rom.Format == "NES 2.0" {
+		fmt.Printf(", mapper %d", rom.Mapper)
+	}
+	fmt.Printf(", title %q\n", rom.Title)
+	for _, warning := range rom.Warnings {
+		fmt.Printf("⚠️  Warning: %s: %s\n", rom.Name, warning)
+	}
+	return rom, nil
+}
+
+// packageFee returns the fee per transaction for cost estimates, or 0 if it
+// cannot be determined (package works without a node)
+func packageFee(cmd *cobra.Command, policy FeePolicy) int64 {
+	rpc := NewNimiqRPC(GetDefaultRPCURL()).WithContext(cmd.Context())
+	fee, err := NewFeeOracle(rpc, policy).Fee()
+	if err != nil {
+		fmt.Printf("Warning: failed to determine fee, assuming 0 (use --fee <luna> to set it): %v\n", err)
+		return 0
+	}
+	return fee
+}
+
+// detectPackageTarget fills in the platform and the executable or ROM of
+// config that were neither given nor in an existing run.json
+func detectPackageTarget(dir string, files []string, config *RunConfig) error {
+	if config.Executable != "" || config.ROM != "" {
+		if config.Platform == "" && config.ROM != "" {
+			platform, err := detectROMFile(dir, config.ROM)
+			if err != nil {
+				return err
+			}
+			if platform == "" {
+				return fmt.Errorf("cannot detect the platform of %s, use --platform GB, GBC or NES", config.ROM)
+			}
+			fmt.Printf("Detected %s ROM: %s\n", platform, config.ROM)
+			config.Platform = platform
+		}
+		return nil
+	}
+
+	platform := strings.ToUpper(strings.TrimSpace(config.Platform))
+	if platform != "DOS" {
+		roms, err := findROMs(dir, files)
+		if err != nil {
+			return err
+		}
+		var matching []packageROM
+		for _, rom := range roms {
+			if platform == "" || rom.platform == platform ||
+				(platform == "GBC" && rom.platform == "GB") { // a GB ROM with colour support
+				matching = append(matching, rom)
+			}
+		}
+		switch {
+		case len(matching) == 1:
+			if platform == "" {
+				platform = matching[0].platform
 			}
+			fmt.Printf("Found %s ROM: %s\n", platform, matching[0].path)
+			config.Platform, config.ROM = platform, matching[0].path
+			return nil
+		case len(matching) > 1:
+			names := make([]string, len(matching))
+			for i, rom := range matching {
+				names[i] = rom.path
+			}
+			return fmt.Errorf("found several ROMs (%s), choose one with --rom", strings.Join(names, ", "))
+		case platform != "":
+			return fmt.Errorf("no %s ROM found in %s, use --rom", platform, dir)
+		}
+	}
+
+	// DOS game
+	config.Platform = "DOS"
+	config.Executable = findGameExecutable(files)
+	if config.Executable != "" {
+		fmt.Printf("Found game executable: %s\n", config.Executable)
+	}
+	return nil
+}
+
+type packageROM struct {
+	path     string
+	platform string
+}
+
+// findROMs returns the files that are GB, GBC or NES ROMs by extension or header
+func findROMs(dir string, files []string) ([]packageROM, error) {
+	var roms []packageROM
+	for _, name := range files {
+		platform, err := detectROMFile(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		if platform != "" {
+			roms = append(roms, packageROM{path: name, platform: platform})
 		}
 	}
+	return roms, nil
+}
 
+// detectROMFile reads the header of a file of dir and returns its ROM platform
+func detectROMFile(dir, name string) (string, error) {
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer f.Close()
+
+	header := make([]byte, gbHeaderSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return DetectROMPlatform(name, header[:n]), nil
+}
+
+// Reproducible ZIP settings: every entry gets the same timestamp (the DOS
+// epoch, the earliest time a ZIP can store) and permissions, and is
+// compressed at a fixed level
+const (
+	reproducibleZipLevel = flate.BestCompression
+	reproducibleZipMode  = 0644
+)
+
+var reproducibleZipTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// packageInput is a file written to the ZIP, for the attestation
+type packageInput struct {
+	path       string
+	size       int64
+	sha256     string
+	compressed int64 // only known with --optimize
+	stored     bool
+}
+
+// describe returns the size (and encoding, if known) for the Added lines
+func (i packageInput) describe() string {
+	switch {
+	case i.compressed == 0 && !i.stored:
+		return fmt.Sprintf("%d bytes", i.size)
+	case i.stored:
+		return fmt.Sprintf("%d bytes, stored", i.size)
+	default:
+		return fmt.Sprintf("%d bytes, deflated to %d", i.size, i.compressed)
+	}
+}
+
+// packageZipOptions selects how writePackageZip builds the archive
+type packageZipOptions struct {
+	reproducible bool // fixed metadata, see reproducibleZipTime
+	optimize     bool // smallest encoding per entry, see writeSmallestEntry
+}
+
+// writePackageZip writes run.json and then the files of dir (ZIP paths) as a
+// ZIP archive to w and returns what it wrote, in order
+func writePackageZip(w io.Writer, dir string, files []string, runJSON []byte, opts packageZipOptions) ([]packageInput, error) {
+	zipWriter := zip.NewWriter(w)
+	if opts.reproducible {
+		zipWriter.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(w, reproducibleZipLevel)
+		})
+	}
+
+	// run.json goes first, so it can be read without scanning the archive
+	input, err := addZipEntry(zipWriter, "run.json", bytes.NewReader(runJSON), opts)
+	if err != nil {
+		return nil, err
+	}
+	inputs := []packageInput{input}
+
+	for _, zipPath := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(zipPath))
+		srcFile, err := os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", filePath, err)
+		}
+		input, err := addZipEntry(zipWriter, zipPath, srcFile, opts)
+		srcFile.Close()
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, input)
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish ZIP file: %w", err)
+	}
+	return inputs, nil
+}
+
+// zipEntryHeader returns the header of a deflated entry; in reproducible mode
+// its metadata only depends on the name
+func zipEntryHeader(zipPath string, reproducible bool) *zip.FileHeader {
+	header := &zip.FileHeader{
+		Name:   path.Clean(strings.TrimPrefix(zipPath, "./")),
+		Method: zip.Deflate,
+	}
+	if reproducible {
+		header.Modified = reproducibleZipTime
+		header.SetMode(reproducibleZipMode)
+	}
+	return header
+}
+
+// addZipEntry copies src into the ZIP and returns its size and hash
+func addZipEntry(zipWriter *zip.Writer, zipPath string, src io.Reader, opts packageZipOptions) (packageInput, error) {
+	input := packageInput{path: zipPath}
+	header := zipEntryHeader(zipPath, opts.reproducible)
+	hash := sha256.New()
+
+	if opts.optimize {
+		data, err := io.ReadAll(io.TeeReader(src, hash))
+		if err != nil {
+			return input, fmt.Errorf("failed to read %s: %w", zipPath, err)
+		}
+		if err := writeSmallestEntry(zipWriter, header, data); err != nil {
+			return input, fmt.Errorf("failed to write %s to ZIP: %w", zipPath, err)
+		}
+		input.size = int64(len(data))
+		input.compressed = int64(header.CompressedSize64)
+		input.stored = header.Method == zip.Store
+	} else {
+		zipEntry, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return input, fmt.Errorf("failed to create ZIP entry for %s: %w", zipPath, err)
+		}
+		written, err := io.Copy(io.MultiWriter(zipEntry, hash), src)
+		if err != nil {
+			return input, fmt.Errorf("failed to write %s to ZIP: %w", zipPath, err)
+		}
+		input.size = written
+	}
+
+	input.sha256 = hex.EncodeToString(hash.Sum(nil))
+	return input, nil
+}
+
+// printPackageAttestation prints the hash of every input in sha256sum format,
+// the hash of that list and the hash of the ZIP built from it
+func printPackageAttestation(inputs []packageInput, zipHash string) {
+	var list strings.Builder
+	var total int64
+	for _, input := range inputs {
+		fmt.Fprintf(&list, "%s  %s\n", input.sha256, input.path)
+		total += input.size
+	}
+	listHash := sha256.Sum256([]byte(list.String()))
+
+	fmt.Printf("\n📜 Reproducible build attestation:\n")
+	fmt.Printf("  Settings: sorted entries, mtime %s, mode %04o, deflate level %d, %s\n",
+		reproducibleZipTime.Format("2006-01-02"), reproducibleZipMode, reproducibleZipLevel, runtime.Version())
+	fmt.Printf("  Inputs: %d files, %d bytes\n", len(inputs), total)
+	for _, line := range strings.Split(strings.TrimSuffix(list.String(), "\n"), "\n") {
+		fmt.Printf("    %s\n", line)
+	}
+	fmt.Printf("  Inputs SHA256: %s\n", hex.EncodeToString(listHash[:]))
+	fmt.Printf("  Output SHA256: %s\n", zipHash)
+}
+
+// findGameExecutable picks the main executable among the files to package
+// (ZIP paths), preferring .exe, then .com, then .bat
+func findGameExecutable(files []string) string {
+	for _, ext := range []string{".exe", ".com", ".bat"} {
+		for _, name := range files {
+			if strings.ToLower(path.Ext(name)) == ext {
+				return name
+			}
+		}
+	}
 	return ""
 }
 
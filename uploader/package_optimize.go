@@ -0,0 +1,193 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// UploadIgnoreFile lists files that package --optimize leaves out, one
+// pattern per line (# starts a comment):
+//
+//	*.bak       any file or directory named like this, at any depth
+//	docs/       a directory and everything in it
+//	src/*.c     a path relative to the game directory
+const UploadIgnoreFile = ".uploadignore"
+
+// defaultUploadIgnore is junk left by archivers and file managers, ignored by
+// --optimize even without an .uploadignore
+var defaultUploadIgnore = []string{
+	UploadIgnoreFile,
+	".DS_Store",
+	"._*",
+	"__MACOSX/",
+	"Thumbs.db",
+	"desktop.ini",
+}
+
+// deflateLevels are the compress/flate levels --optimize tries per entry, in
+// order of preference when results are the same size
+var deflateLevels = []int{
+	flate.BestCompression, 8, 7, 6, 5, 4, 3, 2, flate.BestSpeed, flate.HuffmanOnly,
+}
+
+// loadUploadIgnore returns the default patterns plus those of dir/.uploadignore
+func loadUploadIgnore(dir string) ([]string, error) {
+	patterns := append([]string(nil), defaultUploadIgnore...)
+
+	f, err := os.Open(filepath.Join(dir, UploadIgnoreFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return patterns, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", UploadIgnoreFile, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		pattern := strings.TrimSpace(scanner.Text())
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		pattern = strings.TrimPrefix(strings.ReplaceAll(pattern, "\\", "/"), "/")
+		if _, err := path.Match(strings.TrimSuffix(pattern, "/"), ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern %q", UploadIgnoreFile, line, pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", UploadIgnoreFile, err)
+	}
+	return patterns, nil
+}
+
+// uploadIgnored reports whether a ZIP path matches one of the patterns
+func uploadIgnored(patterns []string, zipPath string) bool {
+	parts := strings.Split(zipPath, "/")
+	for _, pattern := range patterns {
+		dirOnly := strings.HasSuffix(pattern, "/")
+		pattern = strings.TrimSuffix(pattern, "/")
+
+		if strings.Contains(pattern, "/") {
+			// Anchored: the path or one of its directories
+			for i := len(parts); i > 0; i-- {
+				if dirOnly && i == len(parts) {
+					continue
+				}
+				if ok, _ := path.Match(pattern, strings.Join(parts[:i], "/")); ok {
+					return true
+				}
+			}
+			continue
+		}
+
+		// Any component (directories only for dir/ patterns)
+		for i, part := range parts {
+			if dirOnly && i == len(parts)-1 {
+				continue
+			}
+			if ok, _ := path.Match(pattern, part); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeSmallestEntry writes data with whichever is smaller: stored as is, or
+// deflated at the level of compress/flate that gives the smallest output.
+// The entry has no data descriptor, which saves 16 bytes more.
+func writeSmallestEntry(zipWriter *zip.Writer, header *zip.FileHeader, data []byte) error {
+	encoded, method := data, zip.Store
+	if deflated := smallestDeflate(data); len(deflated) < len(data) {
+		encoded, method = deflated, zip.Deflate
+	}
+
+	header.Method = method
+	header.CRC32 = crc32.ChecksumIEEE(data)
+	header.UncompressedSize64 = uint64(len(data))
+	header.CompressedSize64 = uint64(len(encoded))
+	header.CreatorVersion = header.CreatorVersion&0xff00 | 20
+	header.ReaderVersion = 20
+
+	entry, err := zipWriter.CreateRaw(header)
+	if err != nil {
+		return err
+	}
+	_, err = entry.Write(encoded)
+	return err
+}
+
+// smallestDeflate compresses data at every level of deflateLevels and returns
+// the smallest result
+func smallestDeflate(data []byte) []byte {
+	var best []byte
+	var buf bytes.Buffer
+	for _, level := range deflateLevels {
+		buf.Reset()
+		w, err := flate.NewWriter(&buf, level)
+		if err != nil {
+			continue
+		}
+		w.Write(data)
+		w.Close()
+		if best == nil || buf.Len() < len(best) {
+			best = append(best[:0], buf.Bytes()...)
+		}
+	}
+	return best
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct{ n int64 }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// packageCost is the size of a package and what uploading it costs
+type packageCost struct {
+	size   int64
+	chunks int64
+	luna   int64
+}
+
+func newPackageCost(size, fee int64) packageCost {
+	chunks := ChunkCount(size, ChunkSize)
+	estimate := EstimateUpload("publisher", nil, chunks, true, true, nil, fee)
+	return packageCost{size: size, chunks: chunks, luna: estimate.TotalLuna()}
+}
+
+// printPackageOptimization compares the optimized package with the baseline
+// (every file, deflated at the default level)
+func printPackageOptimization(ignored []string, inputs []packageInput, baseline, optimized packageCost, fee int64) {
+	fmt.Printf("\n🗜️  Size optimization:\n")
+	if len(ignored) > 0 {
+		fmt.Printf("  Ignored: %s\n", strings.Join(ignored, ", "))
+	}
+	stored := 0
+	for _, input := range inputs {
+		if input.stored {
+			stored++
+		}
+	}
+	fmt.Printf("  Entries: %d deflated, %d stored\n", len(inputs)-stored, stored)
+	fmt.Printf("  Fee: %d Luna per transaction\n", fee)
+	fmt.Printf("  Baseline:  %d bytes, %d chunks, %s\n", baseline.size, baseline.chunks, formatLuna(baseline.luna))
+	fmt.Printf("  Optimized: %d bytes, %d chunks, %s\n", optimized.size, optimized.chunks, formatLuna(optimized.luna))
+	if baseline.chunks > 0 {
+		saved := baseline.chunks - optimized.chunks
+		fmt.Printf("  Saved: %d bytes, %d transactions (%.1f%%)\n",
+			baseline.size-optimized.size, saved, float64(saved)*100/float64(baseline.chunks))
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The DOS executable is picked from the files that will be packaged, so
+// ignored junk and the output ZIP are never run
+func TestDetectPackageExecutable(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		optimize bool
+		want     string
+	}{
+		{name: "exe before com", files: []string{"GAME.COM", "GAME.EXE", "SETUP.BAT"}, want: "GAME.EXE"},
+		{name: "com before bat", files: []string{"GO.BAT", "GAME.COM"}, want: "GAME.COM"},
+		{name: "in a subdirectory", files: []string{"readme.txt", "bin/game.exe"}, want: "bin/game.exe"},
+		{name: "ignored junk", files: []string{"__MACOSX/._game.exe", "._setup.exe", "game.com"}, optimize: true, want: "game.com"},
+		{name: "junk without --optimize", files: []string{"__MACOSX/._game.exe"}, want: "__MACOSX/._game.exe"},
+		{name: "none", files: []string{"readme.txt"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("MZ"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// An output ZIP named like an executable is never picked
+			output := filepath.Join(dir, "output.exe")
+			if err := os.WriteFile(output, []byte("PK"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var ignore []string
+			if tt.optimize {
+				var err error
+				if ignore, err = loadUploadIgnore(dir); err != nil {
+					t.Fatal(err)
+				}
+			}
+			files, _, _, err := collectPackageFiles(dir, output, ignore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := &RunConfig{Platform: "DOS"}
+			if err := detectPackageTarget(dir, files, config); err != nil {
+				t.Fatalf("detectPackageTarget: %v", err)
+			}
+			if config.Executable != tt.want {
+				t.Errorf("executable %q, expected %q", config.Executable, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func loadPayloadVectors(t *testing.T) ([]byte, *PayloadVectorFile) {
+	t.Helper()
+	raw, err := os.ReadFile(DefaultVectorsFile)
+	if err != nil {
+		t.Fatalf("failed to read test vectors: %v", err)
+	}
+	var file PayloadVectorFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		t.Fatalf("failed to parse test vectors: %v", err)
+	}
+	if len(file.Vectors) == 0 {
+		t.Fatalf("%s has no vectors", DefaultVectorsFile)
+	}
+	return raw, &file
+}
+
+// Every checked-in vector decodes to its fields and encodes back to the same
+// record (or is rejected, for invalid vectors)
+func TestPayloadVectorsRoundTrip(t *testing.T) {
+	_, file := loadPayloadVectors(t)
+
+	types := make(map[string]bool)
+	for _, v := range file.Vectors {
+		v := v
+		types[v.Type] = true
+		t.Run(v.Name, func(t *testing.T) {
+			if err := verifyPayloadVector(v); err != nil {
+				t.Errorf("%s (%s): %v", v.Name, v.Type, err)
+			}
+		})
+	}
+	for _, typ := range []string{MagicCART, MagicDATA, MagicCENT, MagicCSGN, MagicDOOM} {
+		if !types[typ] {
+			t.Errorf("no %s vector in %s", typ, DefaultVectorsFile)
+		}
+	}
+}
+
+// The checked-in file is exactly what the Go encoders generate
+func TestPayloadVectorsUpToDate(t *testing.T) {
+	raw, _ := loadPayloadVectors(t)
+
+	generated, err := buildPayloadVectors()
+	if err != nil {
+		t.Fatalf("failed to generate test vectors: %v", err)
+	}
+	data, err := marshalVectorFile(generated)
+	if err != nil {
+		t.Fatalf("failed to marshal test vectors: %v", err)
+	}
+	if !bytes.Equal(raw, data) {
+		t.Errorf("%s differs from the generated vectors - regenerate it with: nimiq-uploader testvectors --out %s", DefaultVectorsFile, DefaultVectorsFile)
+	}
+}
+
+// A vector whose fields do not match its record must fail the check
+func TestPayloadVectorsDetectMismatch(t *testing.T) {
+	_, file := loadPayloadVectors(t)
+
+	for _, v := range file.Vectors {
+		if !v.Valid {
+			continue
+		}
+		broken := v
+		broken.Fields = make(map[string]interface{}, len(v.Fields))
+		for k, val := range v.Fields {
+			broken.Fields[k] = val
+		}
+		broken.Fields["unexpected"] = true
+		if err := verifyPayloadVector(broken); err == nil {
+			t.Errorf("%s: changed fields were not detected", v.Name)
+		}
+
+		flipped := v
+		flipped.Valid = false
+		if err := verifyPayloadVector(flipped); err == nil {
+			t.Errorf("%s: a decodable record passed as invalid", v.Name)
+		}
+	}
+}
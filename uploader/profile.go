@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// ProfilesFileName is the file (inside the config dir) with named profiles
+const ProfilesFileName = "profiles.yaml"
+
+// profileFlag is the root --profile flag (see activeProfile)
+var profileFlag string
+
+// profileFlagSource is the source of a profile selected with --profile
+const profileFlagSource = "--profile flag"
+
+// Profile is a named set of defaults, e.g. one per network:
+//
+//	default: main
+//	profiles:
+//	  main:
+//	    rpc_url: http://localhost:8648
+//	    network: main
+//	    catalog: main
+//	  test:
+//	    rpc_url: http://testnet-node:8648
+//	    network: test
+//	    credentials: test-credentials.json
+//	    catalog: test
+//	    fee: min
+type Profile struct {
+	RPCURL      string   `yaml:"rpc_url"`
+	Network     string   `yaml:"network"`
+	Credentials string   `yaml:"credentials"` // relative to the config dir
+	Address     string   `yaml:"address"`     // publisher, overrides the credentials address
+	Senders     []string `yaml:"senders"`
+	Catalog     string   `yaml:"catalog"`
+	Fee         string   `yaml:"fee"`
+	Rate        string   `yaml:"rate"`
+	Signer      string   `yaml:"signer"`
+}
+
+// ProfilesFile is the structure of profiles.yaml
+type ProfilesFile struct {
+	Default  string              `yaml:"default"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// GetProfilesPath returns the path of profiles.yaml
+func GetProfilesPath() string {
+	return filepath.Join(GetConfigDir(), ProfilesFileName)
+}
+
+// LoadProfiles reads profiles.yaml; a missing file means no profiles
+func LoadProfiles() (*ProfilesFile, error) {
+	data, err := os.ReadFile(GetProfilesPath())
+	if os.IsNotExist(err) {
+		return &ProfilesFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file ProfilesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", GetProfilesPath(), err)
+	}
+	return &file, nil
+}
+
+// Names returns the profile names, sorted
+func (f *ProfilesFile) Names() []string {
+	var names []string
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// settingsCache holds what settings are resolved from, read once per run: the
+// root PersistentPreRunE resolves the profile (see resetSettings), and
+// ResolveSetting reads the credentials file the first time it needs it
+var settingsCache struct {
+	mu            sync.Mutex
+	profileLoaded bool
+	profileName   string
+	profileSource string
+	profile       *Profile
+	profileErr    error
+	credsLoaded   bool
+	creds         map[string]string
+}
+
+// resetSettings drops the cached profile and credentials, so they are read
+// again (after flags were parsed, or the credentials file was written)
+func resetSettings() {
+	settingsCache.mu.Lock()
+	defer settingsCache.mu.Unlock()
+	settingsCache.profileLoaded = false
+	settingsCache.credsLoaded = false
+	settingsCache.profile, settingsCache.creds = nil, nil
+}
+
+// activeProfile returns the selected profile (see loadActiveProfile), read
+// from profiles.yaml only the first time
+func activeProfile() (string, string, *Profile, error) {
+	settingsCache.mu.Lock()
+	defer settingsCache.mu.Unlock()
+	if !settingsCache.profileLoaded {
+		settingsCache.profileName, settingsCache.profileSource, settingsCache.profile, settingsCache.profileErr = loadActiveProfile()
+		settingsCache.profileLoaded = true
+	}
+	return settingsCache.profileName, settingsCache.profileSource, settingsCache.profile, settingsCache.profileErr
+}
+
+// cachedCredentials returns the credentials file, read only the first time
+// (nil if there is none)
+func cachedCredentials() map[string]string {
+	settingsCache.mu.Lock()
+	if settingsCache.credsLoaded {
+		defer settingsCache.mu.Unlock()
+		return settingsCache.creds
+	}
+	settingsCache.mu.Unlock()
+
+	// Not under the lock: finding the file resolves the credentials setting
+	creds, err := LoadCredentials("")
+	if err != nil {
+		creds = nil
+	}
+	settingsCache.mu.Lock()
+	defer settingsCache.mu.Unlock()
+	settingsCache.creds, settingsCache.credsLoaded = creds, true
+	return creds
+}
+
+// loadActiveProfile returns the selected profile from (in order):
+// 1. --profile flag
+// 2. NIMIQ_PROFILE environment variable
+// 3. default in profiles.yaml
+// The profile is nil (and the name empty) when none is selected.
+func loadActiveProfile() (string, string, *Profile, error) {
+	name, source := profileFlag, profileFlagSource
+	if name == "" {
+		name, source = os.Getenv("NIMIQ_PROFILE"), "env NIMIQ_PROFILE"
+	}
+
+	file, err := LoadProfiles()
+	if err != nil {
+		return "", "", nil, err
+	}
+	if name == "" {
+		name, source = file.Default, "default in "+GetProfilesPath()
+	}
+	if name == "" {
+		return "", "", nil, nil
+	}
+
+	profile, ok := file.Profiles[name]
+	if !ok || profile == nil {
+		return "", "", nil, fmt.Errorf("profile %q not found in %s (%s)", name, GetProfilesPath(), source)
+	}
+	return name, source, profile, nil
+}
+
+// settingDef describes a value that can come from the environment, the active
+// profile or the credentials file
+type settingDef struct {
+	name    string // as in profiles.yaml
+	env     string
+	credKey string // key in the credentials map, if any
+	flag    string // command flag filled from this setting, if any
+	def     string
+	profile func(*Profile) string
+}
+
+var settingDefs = []settingDef{
+	{name: "rpc_url", env: "NIMIQ_RPC_URL", credKey: "RPC_URL", flag: "rpc-url", def: DefaultRPCURL,
+		profile: func(p *Profile) string { return p.RPCURL }},
+	{name: "network", env: "NIMIQ_NETWORK", credKey: "NETWORK", flag: "network",
+		profile: func(p *Profile) string { return p.Network }},
+	{name: "credentials", env: "NIMIQ_CREDENTIALS",
+		profile: func(p *Profile) string { return p.Credentials }},
+	{name: "address", env: "NIMIQ_ADDRESS", credKey: "ADDRESS",
+		profile: func(p *Profile) string { return p.Address }},
+	{name: "senders", env: "NIMIQ_SENDERS", credKey: "SENDERS",
+		profile: func(p *Profile) string { return strings.Join(p.Senders, ",") }},
+	{name: "catalog", env: "NIMIQ_CATALOG", flag: "catalog-addr",
+		profile: func(p *Profile) string { return p.Catalog }},
+	{name: "fee", env: "NIMIQ_FEE", flag: "fee",
+		profile: func(p *Profile) string { return p.Fee }},
+	{name: "rate", env: "NIMIQ_RATE", flag: "rate",
+		profile: func(p *Profile) string { return p.Rate }},
+	{name: "signer", env: "NIMIQ_SIGNER", credKey: "SIGNER", def: "node",
+		profile: func(p *Profile) string { return p.Signer }},
+}
+
+// ResolveSetting returns a setting and where it came from, in order:
+// 1. the profile selected with --profile
+// 2. environment variable
+// 3. the profile selected with NIMIQ_PROFILE or default in profiles.yaml
+// 4. credentials file
+// 5. built-in default (source "default", the value may be empty)
+// Command flags take precedence over all of these (see applyProfileFlags).
+// Profiles and credentials are read once per run (see settingsCache).
+func ResolveSetting(name string) (string, string, error) {
+	var def *settingDef
+	for i := range settingDefs {
+		if settingDefs[i].name == name {
+			def = &settingDefs[i]
+		}
+	}
+	if def == nil {
+		return "", "", fmt.Errorf("unknown setting %q", name)
+	}
+
+	profileName, profileSource, profile, err := activeProfile()
+	if err != nil {
+		profile = nil // reported by applyProfileFlags and config
+	}
+	var profileValue string
+	if profile != nil {
+		profileValue = def.profile(profile)
+	}
+
+	if profileValue != "" && profileSource == profileFlagSource {
+		return profileValue, "profile " + profileName, nil
+	}
+	if value := os.Getenv(def.env); value != "" {
+		return value, "env " + def.env, nil
+	}
+	if profileValue != "" {
+		return profileValue, "profile " + profileName, nil
+	}
+	if def.credKey != "" {
+		if creds := cachedCredentials(); creds[def.credKey] != "" {
+			return creds[def.credKey], "credentials " + GetCredentialsPath(), nil
+		}
+	}
+	return def.def, "default", nil
+}
+
+// applyProfileFlags fills the flags of cmd that were not given on the command
+// line from the environment, the active profile or the credentials file, so
+// every command resolves them through the same precedence
+func applyProfileFlags(cmd *cobra.Command) error {
+	if _, _, _, err := activeProfile(); err != nil {
+		return err
+	}
+	for _, def := range settingDefs {
+		if def.flag == "" {
+			continue
+		}
+		flag := cmd.Flags().Lookup(def.flag)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		value, source, err := ResolveSetting(def.name)
+		if err != nil {
+			return err
+		}
+		if source == "default" {
+			continue // keep the command's own default
+		}
+		if err := cmd.Flags().Set(def.flag, value); err != nil {
+			// e.g. fee "auto" for a command that only takes a fixed fee
+			if flag.Value.Type() != "string" {
+				continue
+			}
+			return fmt.Errorf("invalid --%s %q from %s: %w", def.flag, value, source, err)
+		}
+	}
+	return nil
+}
+
+// printSettings shows every setting with its effective value and source
+func printSettings() {
+	fmt.Println("Profile:")
+	if name, source, _, err := activeProfile(); err != nil {
+		fmt.Printf("  ⚠️  %v\n", err)
+	} else if name == "" {
+		fmt.Printf("  (none) - define profiles in %s\n", GetProfilesPath())
+	} else {
+		fmt.Printf("  %s (%s)\n", name, source)
+	}
+	if file, err := LoadProfiles(); err == nil && len(file.Profiles) > 0 {
+		fmt.Printf("  Available: %s\n", strings.Join(file.Names(), ", "))
+	}
+	fmt.Println()
+
+	fmt.Println("Effective settings:")
+	for _, def := range settingDefs {
+		value, source, _ := ResolveSetting(def.name)
+		if def.name == "signer" && signerFlag != "" {
+			value, source = signerFlag, "--signer flag"
+		}
+		if def.name == "credentials" {
+			value = GetCredentialsPath()
+		}
+		if value == "" {
+			value = "-"
+		}
+		fmt.Printf("  %-12s %s (%s)\n", def.name, value, source)
+	}
+}
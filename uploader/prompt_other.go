@@ -0,0 +1,27 @@
+//go:build !unix
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readPassphrase prompts on stderr and reads a line from the console. Unlike
+// the unix version it cannot turn off echo.
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// filePermissionsExposed always reports false: file modes don't describe
+// access on this platform
+func filePermissionsExposed(path string) (os.FileMode, bool) {
+	return 0, false
+}
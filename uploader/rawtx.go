@@ -0,0 +1,329 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// txValidityWindow is how many blocks after its validity start height a
+	// transaction can be included (Albatross: 7200 blocks, ~2 hours)
+	txValidityWindow = 7200
+
+	// Albatross network IDs (part of every signed transaction)
+	NetworkIDMain = 24
+	NetworkIDTest = 5
+	NetworkIDDev  = 6
+
+	txFormatExtended  = 1
+	accountTypeBasic  = 0
+	proofAlgEd25519   = 0
+	nimiqAddressBytes = 20
+)
+
+// ParseNetworkID accepts main, test, dev, the node's network names (e.g.
+// TestAlbatross) or a numeric network ID
+func ParseNetworkID(value string) (uint8, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "main", "mainnet", "mainalbatross":
+		return NetworkIDMain, nil
+	case "test", "testnet", "testalbatross":
+		return NetworkIDTest, nil
+	case "dev", "devnet", "devalbatross":
+		return NetworkIDDev, nil
+	}
+	id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid network %q (use main, test, dev or a numeric network ID)", value)
+	}
+	return uint8(id), nil
+}
+
+// networkName describes a network ID for messages, e.g. "testnet (5)"
+func networkName(id uint8) string {
+	switch id {
+	case NetworkIDMain:
+		return fmt.Sprintf("mainnet (%d)", id)
+	case NetworkIDTest:
+		return fmt.Sprintf("testnet (%d)", id)
+	case NetworkIDDev:
+		return fmt.Sprintf("devnet (%d)", id)
+	}
+	return fmt.Sprintf("network %d", id)
+}
+
+// networkIDForCatalog picks the network of a catalog address shortcut target
+func networkIDForCatalog(catalogAddr string) (uint8, bool) {
+	switch normalizeAddress(catalogAddr) {
+	case normalizeAddress(resolveCatalogAddress("main")):
+		return NetworkIDMain, true
+	case normalizeAddress(resolveCatalogAddress("test")):
+		return NetworkIDTest, true
+	}
+	return 0, false
+}
+
+// SigningKey is an Ed25519 key held by this process (never imported into a node)
+type SigningKey struct {
+	Address    string
+	PublicKey  ed25519.PublicKey
+	privateKey ed25519.PrivateKey
+}
+
+// addressFromPublicKey derives a Nimiq address: the first 20 bytes of the
+// Blake2b-256 hash of the public key
+func addressFromPublicKey(publicKey ed25519.PublicKey) string {
+	hash := blake2b.Sum256(publicKey)
+	var addr [nimiqAddressBytes]byte
+	copy(addr[:], hash[:nimiqAddressBytes])
+	return AddressBytesToNQ(addr)
+}
+
+// NewSigningKey loads a key from the hex private key (Ed25519 seed) of an account
+func NewSigningKey(account *AccountInfo) (*SigningKey, error) {
+	seed, err := hex.DecodeString(strings.TrimSpace(account.PrivateKey))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("private key must be %d bytes of hex", ed25519.SeedSize)
+	}
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	key := &SigningKey{PublicKey: privateKey.Public().(ed25519.PublicKey), privateKey: privateKey}
+	key.Address = addressFromPublicKey(key.PublicKey)
+	if account.Address != "" && normalizeAddress(account.Address) != normalizeAddress(key.Address) {
+		return nil, fmt.Errorf("private key belongs to %s, not %s", key.Address, account.Address)
+	}
+	return key, nil
+}
+
+// GenerateAccount creates a new key pair locally, without a node
+func GenerateAccount() (*AccountInfo, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return &AccountInfo{
+		Address:    addressFromPublicKey(publicKey),
+		PublicKey:  hex.EncodeToString(publicKey),
+		PrivateKey: hex.EncodeToString(privateKey.Seed()),
+	}, nil
+}
+
+// RawTransaction is a basic-account transaction with recipient data
+type RawTransaction struct {
+	Sender              [nimiqAddressBytes]byte
+	Recipient           [nimiqAddressBytes]byte
+	RecipientData       []byte
+	Value               uint64
+	Fee                 uint64
+	ValidityStartHeight uint32
+	NetworkID           uint8
+}
+
+// NewRawTransaction builds a transaction between two NQ addresses
+func NewRawTransaction(from, to string, data []byte, value, fee int64, validityStartHeight uint32, networkID uint8) (*RawTransaction, error) {
+	sender, err := AddressNQToBytes(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %s: %w", from, err)
+	}
+	recipient, err := AddressNQToBytes(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %s: %w", to, err)
+	}
+	if value < 0 || fee < 0 {
+		return nil, fmt.Errorf("value and fee must not be negative")
+	}
+	return &RawTransaction{
+		Sender:              sender,
+		Recipient:           recipient,
+		RecipientData:       data,
+		Value:               uint64(value),
+		Fee:                 uint64(fee),
+		ValidityStartHeight: validityStartHeight,
+		NetworkID:           networkID,
+	}, nil
+}
+
+// content is the serialized transaction content that is signed
+func (t *RawTransaction) content() []byte {
+	var b []byte
+	b = binary.BigEndian.AppendUint16(b, uint16(len(t.RecipientData)))
+	b = append(b, t.RecipientData...)
+	b = append(b, t.Sender[:]...)
+	b = append(b, accountTypeBasic)
+	b = append(b, t.Recipient[:]...)
+	b = append(b, accountTypeBasic)
+	b = binary.BigEndian.AppendUint64(b, t.Value)
+	b = binary.BigEndian.AppendUint64(b, t.Fee)
+	b = binary.BigEndian.AppendUint32(b, t.ValidityStartHeight)
+	b = append(b, t.NetworkID)
+	b = append(b, 0)                        // flags
+	b = binary.BigEndian.AppendUint16(b, 0) // sender data
+	return b
+}
+
+// Sign signs the transaction with key and returns the serialized transaction
+// (extended format with an Ed25519 signature proof) for sendRawTransaction
+func (t *RawTransaction) Sign(key *SigningKey) ([]byte, error) {
+	if addressFromPublicKey(key.PublicKey) != AddressBytesToNQ(t.Sender) {
+		return nil, fmt.Errorf("key %s cannot sign for %s", key.Address, AddressBytesToNQ(t.Sender))
+	}
+	return t.serialize(key.PublicKey, ed25519.Sign(key.privateKey, t.content())), nil
+}
+
+// Assemble checks a signature made elsewhere (e.g. by an external signer) over
+// the transaction content and returns the serialized transaction
+func (t *RawTransaction) Assemble(publicKey ed25519.PublicKey, signature []byte) ([]byte, error) {
+	if len(publicKey) != ed25519.PublicKeySize || len(signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("invalid public key or signature length")
+	}
+	if addressFromPublicKey(publicKey) != AddressBytesToNQ(t.Sender) {
+		return nil, fmt.Errorf("public key belongs to %s, not %s", addressFromPublicKey(publicKey), AddressBytesToNQ(t.Sender))
+	}
+	if !ed25519.Verify(publicKey, t.content(), signature) {
+		return nil, fmt.Errorf("signature does not match the transaction")
+	}
+	return t.serialize(publicKey, signature), nil
+}
+
+// serialize encodes the transaction in the extended format with an Ed25519 signature proof
+func (t *RawTransaction) serialize(publicKey ed25519.PublicKey, signature []byte) []byte {
+	var proof []byte
+	proof = append(proof, proofAlgEd25519)
+	proof = append(proof, publicKey...)
+	proof = append(proof, 0) // empty merkle path
+	proof = append(proof, signature...)
+
+	var b []byte
+	b = append(b, txFormatExtended)
+	b = append(b, t.Sender[:]...)
+	b = append(b, accountTypeBasic)
+	b = binary.AppendUvarint(b, 0) // sender data
+	b = append(b, t.Recipient[:]...)
+	b = append(b, accountTypeBasic)
+	b = binary.AppendUvarint(b, uint64(len(t.RecipientData)))
+	b = append(b, t.RecipientData...)
+	b = binary.BigEndian.AppendUint64(b, t.Value)
+	b = binary.BigEndian.AppendUint64(b, t.Fee)
+	b = binary.BigEndian.AppendUint32(b, t.ValidityStartHeight)
+	b = append(b, t.NetworkID)
+	b = append(b, 0) // flags
+	b = binary.AppendUvarint(b, uint64(len(proof)))
+	b = append(b, proof...)
+	return b
+}
+
+// ParseRawTransaction decodes a serialized transaction in the format written
+// by serialize (extended, basic accounts, Ed25519 signature proof). It returns
+// the transaction and its proof; the signature is not checked (see Assemble).
+func ParseRawTransaction(raw []byte) (*RawTransaction, ed25519.PublicKey, []byte, error) {
+	r := &rawTxReader{b: raw}
+	if format := r.take(1); r.err == nil && format[0] != txFormatExtended {
+		return nil, nil, nil, fmt.Errorf("unsupported transaction format %d", format[0])
+	}
+	t := &RawTransaction{}
+	t.Sender = r.address()
+	if r.uvarint() != 0 && r.err == nil {
+		return nil, nil, nil, fmt.Errorf("sender data is not supported")
+	}
+	t.Recipient = r.address()
+	t.RecipientData = append([]byte(nil), r.take(r.uvarint())...)
+	value := r.take(8)
+	fee := r.take(8)
+	height := r.take(4)
+	network := r.take(1)
+	flags := r.take(1)
+	proof := r.take(r.uvarint())
+	if r.err != nil {
+		return nil, nil, nil, r.err
+	}
+	if len(r.b) != 0 {
+		return nil, nil, nil, fmt.Errorf("%d trailing bytes after the transaction", len(r.b))
+	}
+	if flags[0] != 0 {
+		return nil, nil, nil, fmt.Errorf("transaction flags are not supported")
+	}
+	if len(proof) != 1+ed25519.PublicKeySize+1+ed25519.SignatureSize || proof[0] != proofAlgEd25519 || proof[1+ed25519.PublicKeySize] != 0 {
+		return nil, nil, nil, fmt.Errorf("unsupported signature proof")
+	}
+	t.Value = binary.BigEndian.Uint64(value)
+	t.Fee = binary.BigEndian.Uint64(fee)
+	t.ValidityStartHeight = binary.BigEndian.Uint32(height)
+	t.NetworkID = network[0]
+
+	publicKey := ed25519.PublicKey(append([]byte(nil), proof[1:1+ed25519.PublicKeySize]...))
+	signature := append([]byte(nil), proof[2+ed25519.PublicKeySize:]...)
+	return t, publicKey, signature, nil
+}
+
+// Diff lists the fields in which other differs from t (nil if none)
+func (t *RawTransaction) Diff(other *RawTransaction) []string {
+	var diffs []string
+	if t.Sender != other.Sender {
+		diffs = append(diffs, fmt.Sprintf("from %s, not %s", AddressBytesToNQ(other.Sender), AddressBytesToNQ(t.Sender)))
+	}
+	if t.Recipient != other.Recipient {
+		diffs = append(diffs, fmt.Sprintf("to %s, not %s", AddressBytesToNQ(other.Recipient), AddressBytesToNQ(t.Recipient)))
+	}
+	if t.Value != other.Value {
+		diffs = append(diffs, fmt.Sprintf("value %d, not %d", other.Value, t.Value))
+	}
+	if t.Fee != other.Fee {
+		diffs = append(diffs, fmt.Sprintf("fee %d, not %d", other.Fee, t.Fee))
+	}
+	if string(t.RecipientData) != string(other.RecipientData) {
+		diffs = append(diffs, fmt.Sprintf("data %s, not %s", hex.EncodeToString(other.RecipientData), hex.EncodeToString(t.RecipientData)))
+	}
+	if t.ValidityStartHeight != other.ValidityStartHeight {
+		diffs = append(diffs, fmt.Sprintf("validity start height %d, not %d", other.ValidityStartHeight, t.ValidityStartHeight))
+	}
+	if t.NetworkID != other.NetworkID {
+		diffs = append(diffs, fmt.Sprintf("network %d, not %d", other.NetworkID, t.NetworkID))
+	}
+	return diffs
+}
+
+// rawTxReader reads the fields of a serialized transaction
+type rawTxReader struct {
+	b   []byte
+	err error
+}
+
+func (r *rawTxReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = fmt.Errorf("transaction is truncated")
+		return nil
+	}
+	out := r.b[:n]
+	r.b = r.b[n:]
+	return out
+}
+
+func (r *rawTxReader) uvarint() int {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 || v > uint64(len(r.b)) {
+		r.err = fmt.Errorf("invalid length in transaction")
+		return 0
+	}
+	r.b = r.b[n:]
+	return int(v)
+}
+
+func (r *rawTxReader) address() (addr [nimiqAddressBytes]byte) {
+	copy(addr[:], r.take(nimiqAddressBytes))
+	if t := r.take(1); r.err == nil && t[0] != accountTypeBasic {
+		r.err = fmt.Errorf("only basic accounts are supported")
+	}
+	return addr
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// capturedCreateTx is a createBasicTransaction(WithData) call of a captured
+// fixture set: the node signed it with an account of its wallet
+type capturedCreateTx struct {
+	wallet, recipient   string
+	data                []byte
+	value, fee          int64
+	validityStartHeight uint32
+	raw                 []byte // what the node returned
+}
+
+// parseCapturedCreateTx reads the params (named or positional) and result of
+// a create* fixture; ok is false for calls that failed
+func parseCapturedCreateTx(fixture RPCFixture) (tx capturedCreateTx, ok bool, err error) {
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(fixture.Response, &response); err != nil || len(response.Error) > 0 || len(response.Result) == 0 {
+		return tx, false, nil
+	}
+	rawHex, err := parseRawTransaction(response.Result)
+	if err != nil {
+		return tx, false, err
+	}
+	if tx.raw, err = hex.DecodeString(rawHex); err != nil {
+		return tx, false, fmt.Errorf("result is not hex: %w", err)
+	}
+
+	withData := fixture.Method == "createBasicTransactionWithData"
+	var named struct {
+		Wallet              string `json:"wallet"`
+		Recipient           string `json:"recipient"`
+		Data                string `json:"data"`
+		Value               int64  `json:"value"`
+		Fee                 int64  `json:"fee"`
+		ValidityStartHeight uint32 `json:"validityStartHeight"`
+	}
+	if json.Unmarshal(fixture.Params, &named) != nil {
+		var positional []json.RawMessage
+		if err := json.Unmarshal(fixture.Params, &positional); err != nil {
+			return tx, false, fmt.Errorf("unreadable params: %w", err)
+		}
+		fields := []interface{}{&named.Wallet, &named.Recipient, &named.Data, &named.Value, &named.Fee, &named.ValidityStartHeight}
+		if !withData {
+			fields = append(fields[:2], fields[3:]...)
+		}
+		if len(positional) != len(fields) {
+			return tx, false, fmt.Errorf("%d positional params, expected %d", len(positional), len(fields))
+		}
+		for i, field := range fields {
+			if err := json.Unmarshal(positional[i], field); err != nil {
+				return tx, false, fmt.Errorf("param %d: %w", i, err)
+			}
+		}
+	}
+	tx.wallet, tx.recipient = named.Wallet, named.Recipient
+	tx.value, tx.fee, tx.validityStartHeight = named.Value, named.Fee, named.ValidityStartHeight
+	if tx.data, err = hex.DecodeString(named.Data); err != nil {
+		return tx, false, fmt.Errorf("data is not hex: %w", err)
+	}
+	return tx, true, nil
+}
+
+// Transactions built and serialized here are byte for byte what a real node
+// makes of the same fields, for every network a capture covers. The node's
+// signature has to verify over our content, so the signed bytes match too.
+func TestRawTransactionMatchesCapturedNodes(t *testing.T) {
+	networks := make(map[uint8][]string) // network ID -> sets covering it
+	for set, fixtures := range loadFixtureSets(t) {
+		if strings.HasPrefix(set, syntheticFixturePrefix) {
+			continue
+		}
+		for _, fixture := range fixtures {
+			if fixture.Method != "createBasicTransaction" && fixture.Method != "createBasicTransactionWithData" {
+				continue
+			}
+			captured, ok, err := parseCapturedCreateTx(fixture)
+			if err != nil {
+				t.Errorf("%s #%d: %v", set, fixture.Seq, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			nodeTx, publicKey, signature, err := ParseRawTransaction(captured.raw)
+			if err != nil {
+				t.Errorf("%s #%d: node transaction not understood: %v", set, fixture.Seq, err)
+				continue
+			}
+			ours, err := NewRawTransaction(captured.wallet, captured.recipient, captured.data, captured.value, captured.fee,
+				captured.validityStartHeight, nodeTx.NetworkID)
+			if err != nil {
+				t.Errorf("%s #%d: %v", set, fixture.Seq, err)
+				continue
+			}
+			serialized, err := ours.Assemble(publicKey, signature)
+			if err != nil {
+				t.Errorf("%s #%d: the node's signature does not verify over our content: %v", set, fixture.Seq, err)
+				continue
+			}
+			if !bytes.Equal(serialized, captured.raw) {
+				t.Errorf("%s #%d: serialized differently\n  node: %x\n  ours: %x", set, fixture.Seq, captured.raw, serialized)
+			}
+			networks[nodeTx.NetworkID] = append(networks[nodeTx.NetworkID], set)
+		}
+	}
+
+	if len(networks) == 0 {
+		t.Skipf("no createBasicTransaction captures from a real node in %s (see the README on recording one per network)", rpcFixturesDir)
+	}
+	var ids []int
+	for id := range networks {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		t.Logf("%s: %d transactions compared", networkName(uint8(id)), len(networks[uint8(id)]))
+	}
+	for _, id := range []uint8{NetworkIDMain, NetworkIDTest} {
+		if len(networks[id]) == 0 {
+			t.Logf("no capture from %s yet", networkName(id))
+		}
+	}
+}
+
+// The network ID is signed: a transaction for one network neither verifies
+// nor serializes the same for the other
+func TestRawTransactionNetworks(t *testing.T) {
+	key, err := NewSigningKey(&AccountInfo{PrivateKey: strings.Repeat("07", 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := testCartridgeAddr(1)
+	data := []byte("DATA chunk")
+
+	signed := make(map[uint8][]byte)
+	for _, network := range []uint8{NetworkIDMain, NetworkIDTest} {
+		tx, err := NewRawTransaction(key.Address, to, data, 1, 233, 1000, network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := tx.Sign(key)
+		if err != nil {
+			t.Fatalf("%s: %v", networkName(network), err)
+		}
+		parsed, publicKey, signature, err := ParseRawTransaction(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", networkName(network), err)
+		}
+		if parsed.NetworkID != network || !bytes.Equal(parsed.RecipientData, data) || parsed.Value != 1 || parsed.Fee != 233 ||
+			parsed.ValidityStartHeight != 1000 || AddressBytesToNQ(parsed.Sender) != key.Address || AddressBytesToNQ(parsed.Recipient) != to {
+			t.Errorf("%s: parsed back as %+v", networkName(network), parsed)
+		}
+		if again, err := parsed.Assemble(publicKey, signature); err != nil || !bytes.Equal(again, raw) {
+			t.Errorf("%s: reassembled %x, %v; expected %x", networkName(network), again, err, raw)
+		}
+		signed[network] = raw
+	}
+
+	main, test := signed[NetworkIDMain], signed[NetworkIDTest]
+	if bytes.Equal(main, test) {
+		t.Fatal("mainnet and testnet transactions are identical")
+	}
+	parsed, publicKey, signature, err := ParseRawTransaction(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed.NetworkID = NetworkIDTest
+	if _, err := parsed.Assemble(publicKey, signature); err == nil {
+		t.Error("mainnet signature verifies for testnet")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newCartridgeCmd() *cobra.Command {
+	cartridgeCmd := &cobra.Command{
+		Use:   "cartridge",
+		Short: "Manage cartridge addresses",
+	}
+
+	cartridgeCmd.AddCommand(newCartridgeReclaimCmd())
+
+	return cartridgeCmd
+}
+
+func newCartridgeReclaimCmd() *cobra.Command {
+	var (
+		rpcURL        string
+		to            string
+		keysDir       string
+		keyPassphrase string
+		fee           int64
+		dryRun        bool
+		noWait        bool
+		timeout       time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reclaim",
+		Short: "Sweep the luna accumulated on generated cartridge addresses back to the publisher",
+		Long: `Every CART and DATA transaction sends 1 luna to the cartridge address.
+For addresses created with --generate-cartridge-addr the key is kept in an
+encrypted key file, so that value can be moved back.
+
+This decrypts each cartridge key, signs a transfer of the balance minus fee to
+the publisher address in-process and broadcasts it with sendRawTransaction.
+The keys never reach the node wallet.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get RPC URL from env, credentials file, or default
+			if rpcURL == "" {
+				rpcURL = GetDefaultRPCURL()
+			}
+
+			// Try to get address from credentials file if not provided
+			if to == "" {
+				to = GetDefaultAddress()
+			}
+			if to == "" {
+				return fmt.Errorf("publisher address is required (--to or set in credentials.json)")
+			}
+
+			if keysDir == "" {
+				keysDir = GetCartridgeKeysDir()
+			}
+			keyFiles, err := LoadCartridgeKeyFiles(keysDir)
+			if err != nil {
+				return fmt.Errorf("failed to load cartridge keys: %w", err)
+			}
+			if len(keyFiles) == 0 {
+				fmt.Printf("No cartridge key files found in %s\n", keysDir)
+				return nil
+			}
+
+			rpc := NewNimiqRPC(rpcURL).WithContext(cmd.Context())
+
+			// Check consensus first
+			consensus, err := rpc.IsConsensusEstablished()
+			if err != nil {
+				return fmt.Errorf("failed to check consensus: %w", err)
+			}
+			if !consensus {
+				return fmt.Errorf("node does not have consensus with the network - wait for sync")
+			}
+
+			// The sweeps are signed here, for the network the node is on
+			networkID, err := checkNodeNetwork(rpc, "", "")
+			if err != nil {
+				return err
+			}
+			if networkID == 0 {
+				if networkID, err = defaultNetworkID(); err != nil {
+					return fmt.Errorf("cannot tell which network to sign the transfers for: %w", err)
+				}
+			}
+
+			publisherBalance, err := rpc.GetBalance(to)
+			if err != nil {
+				return fmt.Errorf("failed to get balance: %w", err)
+			}
+
+			// Find the cartridge addresses with something to reclaim
+			var reclaimable []*CartridgeKeyFile
+			var transfers []transfer
+			var total int64
+			fmt.Printf("Publisher: %s\n", to)
+			fmt.Printf("Cartridge keys: %d (%s)\n\n", len(keyFiles), keysDir)
+			for _, keyFile := range keyFiles {
+				balance, err := rpc.GetBalance(keyFile.Address)
+				if err != nil {
+					fmt.Printf("  app %d / cartridge %d  %s: failed to get balance: %v\n", keyFile.AppID, keyFile.CartridgeID, keyFile.Address, err)
+					continue
+				}
+				amount := balance - fee
+				if amount <= 0 {
+					fmt.Printf("  app %d / cartridge %d  %s: %s (nothing to reclaim)\n", keyFile.AppID, keyFile.CartridgeID, keyFile.Address, formatLuna(balance))
+					continue
+				}
+				fmt.Printf("  app %d / cartridge %d  %s: %s\n", keyFile.AppID, keyFile.CartridgeID, keyFile.Address, formatLuna(balance))
+				reclaimable = append(reclaimable, keyFile)
+				transfers = append(transfers, transfer{from: keyFile.Address, to: to, amount: amount})
+				total += amount
+			}
+			fmt.Printf("\nFees:            %s\n", formatLuna(fee*int64(len(transfers))))
+			fmt.Printf("Total reclaimed: %s\n", formatLuna(total))
+
+			if len(transfers) == 0 {
+				fmt.Println("\nNothing to reclaim")
+				return nil
+			}
+			if dryRun {
+				fmt.Println("\n[DRY RUN] No transactions sent")
+				return nil
+			}
+
+			passphrase := resolveKeyPassphrase(keyPassphrase)
+			if passphrase == "" {
+				return fmt.Errorf("passphrase for the cartridge keys is required (--key-passphrase, NIMIQ_KEY_PASSPHRASE or passphrase in credentials.json)")
+			}
+
+			// Sign with the cartridge keys in-process; they never go to the node
+			for i, keyFile := range reclaimable {
+				account, err := keyFile.Decrypt(passphrase)
+				if err != nil {
+					return err
+				}
+				key, err := NewSigningKey(account)
+				if err != nil {
+					return fmt.Errorf("cartridge key %s: %w", keyFile.Address, err)
+				}
+				transfers[i].signer = &KeySigner{key: key, networkID: networkID}
+			}
+			fmt.Println()
+
+			if err := executeTransfers(cmd.Context(), rpcURL, fee, transfers); err != nil {
+				return err
+			}
+
+			if noWait {
+				return nil
+			}
+			return waitForBalances(cmd.Context(), rpc, map[string]int64{to: publisherBalance + total}, timeout)
+		},
+	}
+
+	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
+	cmd.Flags().StringVar(&to, "to", "", "Publisher address to send the luna to (defaults to address from credentials.json)")
+	cmd.Flags().StringVar(&keysDir, "keys-dir", "", "Directory with cartridge key files (default: ~/.config/nimiq-uploader/cartridge-keys)")
+	cmd.Flags().StringVar(&keyPassphrase, "key-passphrase", "", "Passphrase of the cartridge key files (defaults to NIMIQ_KEY_PASSPHRASE or the account passphrase)")
+	cmd.Flags().Int64Var(&fee, "fee", 0, "Transaction fee in Luna per transfer (default: 0, minimum)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show reclaimable balances without sending")
+	cmd.Flags().BoolVar(&noWait, "no-wait", false, "Return right after sending instead of waiting for confirmation")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "How long to wait for the transfers to be confirmed")
+
+	return cmd
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/maestroi/nimiq-doom/uploader/mocknode"
+)
+
+// reclaim signs the sweeps with the cartridge keys itself: the node accepts
+// them for its network without the keys ever entering its wallet
+func TestReclaimSignsInProcess(t *testing.T) {
+	isolateSettings(t)
+	node := mocknode.New(mocknode.Options{})
+	rpcURL := startTestNode(t, node)
+	rpc := NewNimiqRPC(rpcURL)
+	publisher := importTestAccount(t, node, rpc, 1)
+
+	const fee = 2
+	balances := []int64{500, fee, 37}
+	var cartridges []string
+	for i, balance := range balances {
+		account := &AccountInfo{PrivateKey: hex.EncodeToString(bytes.Repeat([]byte{byte(10 + i)}, 32))}
+		key, err := NewSigningKey(account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		account.Address, account.PublicKey = key.Address, hex.EncodeToString(key.PublicKey)
+		if _, err := SaveCartridgeKey(account, 1, uint32(i+1), publisher, "secret"); err != nil {
+			t.Fatalf("failed to save cartridge key: %v", err)
+		}
+		node.Fund(account.Address, balance)
+		cartridges = append(cartridges, account.Address)
+	}
+	before := node.Balance(publisher)
+
+	cmd := newCartridgeReclaimCmd()
+	cmd.SetArgs([]string{"--rpc-url", rpcURL, "--to", publisher, "--key-passphrase", "secret", "--fee", "2", "--no-wait"})
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+	node.MineBlock()
+
+	var want int64
+	for i, addr := range cartridges {
+		imported, err := rpc.IsAccountImported(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if imported {
+			t.Errorf("cartridge key %s was imported into the node wallet", addr)
+		}
+		left := int64(0)
+		if balances[i] <= fee {
+			left = balances[i] // nothing to reclaim
+		} else {
+			want += balances[i] - fee
+		}
+		if got := node.Balance(addr); got != left {
+			t.Errorf("%s: %d Luna left, expected %d", addr, got, left)
+		}
+	}
+	if got := node.Balance(publisher) - before; got != want {
+		t.Errorf("publisher received %d Luna, expected %d", got, want)
+	}
+}
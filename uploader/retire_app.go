@@ -59,6 +59,9 @@ The command will:
 
 			// Initialize RPC
 			rpc := NewNimiqRPC(rpcURL)
+			if _, err := checkNodeNetwork(rpc, catalogAddr, ""); err != nil {
+				return err
+			}
 
 			// Find the latest version of this app
 			normalizedPublisher := normalizeAddress(sender)
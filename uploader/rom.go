@@ -0,0 +1,277 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Game Boy cartridge header fields (https://gbdev.io/pandocs/The_Cartridge_Header.html)
+const (
+	gbTitleOffset        = 0x134
+	gbManufacturerOffset = 0x13F // 4-byte manufacturer code ending the title of CGB ROMs
+	gbROMSizeOffset      = 0x148
+	gbChecksumOffset     = 0x14D
+)
+
+// iNES header fields (https://www.nesdev.org/wiki/INES, /wiki/NES_2.0)
+const (
+	nesHeaderSize  = 16
+	nesTrainerSize = 512
+	nesPRGUnit     = 16 * 1024
+	nesCHRUnit     = 8 * 1024
+)
+
+// ROMInfo is what the header of a GB, GBC or NES ROM says about it
+type ROMInfo struct {
+	Name      string   // file name (inside the ZIP, for packages)
+	Format    string   // "Game Boy", "Game Boy Color", "iNES" or "NES 2.0"
+	Platform  string   // suggested run.json platform
+	Platforms []string // every platform the ROM runs on
+	Title     string   // from the header (GB) or the file name (NES), max 16 characters
+	Mapper    int      // NES only
+	Warnings  []string // oddities that don't stop the ROM from running
+}
+
+// ParseROMHeader parses the header of a ROM of size bytes, given its name and
+// first bytes (at least gbHeaderSize). It returns nil if the file is not a
+// ROM, and an error if it has a ROM extension or magic but a broken header.
+func ParseROMHeader(name string, header []byte, size int64) (*ROMInfo, error) {
+	platform := DetectROMPlatform(name, header)
+	if platform == "" {
+		return nil, nil
+	}
+	if platform == "NES" {
+		return parseNESHeader(name, header, size)
+	}
+	return parseGBHeader(name, header, size)
+}
+
+func parseGBHeader(name string, header []byte, size int64) (*ROMInfo, error) {
+	if len(header) < gbHeaderSize || !hasGBLogo(header) {
+		return nil, fmt.Errorf("%s: no Game Boy cartridge header (Nintendo logo missing at 0x104)", name)
+	}
+
+	var checksum byte
+	for _, b := range header[gbTitleOffset:gbChecksumOffset] {
+		checksum = checksum - b - 1
+	}
+	if checksum != header[gbChecksumOffset] {
+		return nil, fmt.Errorf("%s: Game Boy header checksum is 0x%02X, expected 0x%02X (the ROM would not boot)",
+			name, header[gbChecksumOffset], checksum)
+	}
+
+	// The platform comes from the CGB flag, never from the extension
+	info := &ROMInfo{Name: name, Format: "Game Boy", Platforms: gbCGBPlatforms(header[gbCGBFlagOffset])}
+	info.Platform = info.Platforms[len(info.Platforms)-1]
+	titleEnd := gbCGBFlagOffset + 1
+	if info.Platform == "GBC" {
+		info.Format = "Game Boy Color"
+		titleEnd = gbManufacturerOffset
+	}
+	info.Title = cleanROMTitle(string(header[gbTitleOffset:titleEnd]))
+	if info.Title == "" {
+		info.Title = cleanROMTitle(titleFromFilename(name))
+	}
+
+	if code := header[gbROMSizeOffset]; code <= 8 {
+		if want := int64(32*1024) << code; size < want {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("header declares %d KiB of ROM, file has %d bytes", want/1024, size))
+		}
+	}
+	return info, nil
+}
+
+func parseNESHeader(name string, header []byte, size int64) (*ROMInfo, error) {
+	if len(header) < nesHeaderSize || string(header[:len(nesMagic)]) != string(nesMagic) {
+		return nil, fmt.Errorf("%s: no iNES header (NES<EOF> magic missing)", name)
+	}
+
+	info := &ROMInfo{Name: name, Format: "iNES", Platform: "NES", Platforms: []string{"NES"}}
+	prg := int64(header[4]) * nesPRGUnit
+	chr := int64(header[5]) * nesCHRUnit
+	info.Mapper = int(header[6]>>4) | int(header[7]&0xF0)
+
+	if header[7]&0x0C == 0x08 {
+		info.Format = "NES 2.0"
+		info.Mapper |= int(header[8]&0x0F) << 8
+		prg = nes2ROMSize(header[4], header[9]&0x0F, nesPRGUnit)
+		chr = nes2ROMSize(header[5], header[9]>>4, nesCHRUnit)
+	} else if header[12] != 0 || header[13] != 0 || header[14] != 0 || header[15] != 0 {
+		// Old dumping tools wrote their name ("DiskDude!") over bytes 7-15
+		info.Mapper &= 0x0F
+		info.Warnings = append(info.Warnings, "garbage in header bytes 7-15, mapper may be wrong")
+	}
+
+	if prg == 0 {
+		return nil, fmt.Errorf("%s: iNES header declares no PRG ROM", name)
+	}
+	want := nesHeaderSize + prg + chr
+	if header[6]&0x04 != 0 {
+		want += nesTrainerSize
+	}
+	if size < want {
+		return nil, fmt.Errorf("%s: truncated NES ROM (header declares %d bytes, file has %d)", name, want, size)
+	}
+
+	info.Title = cleanROMTitle(titleFromFilename(name))
+	return info, nil
+}
+
+// nes2ROMSize decodes a NES 2.0 PRG/CHR size from its LSB and MSB nibble
+func nes2ROMSize(lsb, msb byte, unit int64) int64 {
+	if msb == 0x0F {
+		// Exponent-multiplier notation: 2^E * (MM*2+1) bytes
+		return (int64(1) << (lsb >> 2)) * int64(lsb&0x03*2+1)
+	}
+	return (int64(msb)<<8 | int64(lsb)) * unit
+}
+
+// cleanROMTitle keeps the printable ASCII of a header title (up to the first
+// NUL) or file name, without dump tags like "(U) [!]", as a short title
+func cleanROMTitle(title string) string {
+	if i := strings.IndexByte(title, 0); i >= 0 {
+		title = title[:i]
+	}
+	if i := strings.IndexAny(title, "(["); i > 0 {
+		title = title[:i]
+	}
+	title = strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7E {
+			return -1
+		}
+		return r
+	}, title)
+	title = strings.Join(strings.Fields(title), " ")
+	if len(title) > 16 {
+		title = strings.TrimSpace(title[:16])
+	}
+	return title
+}
+
+// CheckPlatform returns an error if the ROM does not run on platform
+func (r *ROMInfo) CheckPlatform(platform string) error {
+	platform = strings.ToUpper(strings.TrimSpace(platform))
+	for _, p := range r.Platforms {
+		if p == platform {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is a %s ROM, but the platform is %s (use %s)",
+		r.Name, r.Format, platform, strings.Join(r.Platforms, " or "))
+}
+
+// InspectROMFile parses the ROM header of a file, or of the ROM inside a
+// ZIP (the rom of its run.json, else the only ROM in it). It returns nil if
+// there is no ROM or run.json says it is a DOS game.
+func InspectROMFile(filePath string) (*ROMInfo, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, gbHeaderSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	header = header[:n]
+
+	if !strings.HasPrefix(string(header), "PK\x03\x04") {
+		return ParseROMHeader(filepath.Base(filePath), header, stat.Size())
+	}
+
+	archive, err := zip.NewReader(f, stat.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP: %w", err)
+	}
+	return inspectZipROM(archive)
+}
+
+// inspectZipROM finds and parses the ROM of a cartridge ZIP
+func inspectZipROM(archive *zip.Reader) (*ROMInfo, error) {
+	entries := make(map[string]*zip.File)
+	var names []string
+	var config *RunConfig
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if f.Name == "run.json" {
+			data, err := readZipEntry(f, 64*1024)
+			if err != nil {
+				return nil, err
+			}
+			if config, err = ParseRunConfig(data); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		entries[f.Name] = f
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+
+	if config != nil && strings.EqualFold(config.Platform, "DOS") {
+		return nil, nil
+	}
+	if config != nil && config.ROM != "" {
+		f := entries[config.ROM]
+		if f == nil {
+			return nil, fmt.Errorf("run.json: rom %q is not in the ZIP", config.ROM)
+		}
+		info, err := parseZipROM(f)
+		if err == nil && info == nil {
+			err = fmt.Errorf("run.json: rom %q is not a GB, GBC or NES ROM", config.ROM)
+		}
+		return info, err
+	}
+
+	var found *ROMInfo
+	for _, name := range names {
+		info, err := parseZipROM(entries[name])
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("ZIP contains several ROMs (%s, %s), set rom in run.json", found.Name, info.Name)
+		}
+		found = info
+	}
+	return found, nil
+}
+
+// parseZipROM parses the header of a ZIP entry
+func parseZipROM(f *zip.File) (*ROMInfo, error) {
+	header, err := readZipEntry(f, gbHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+	return ParseROMHeader(f.Name, header, int64(f.UncompressedSize64))
+}
+
+// readZipEntry reads up to limit bytes of a ZIP entry
+func readZipEntry(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gbROM returns the first bytes of a Game Boy ROM with a valid header
+func gbROM(title string, cgb, romSizeCode byte) []byte {
+	header := make([]byte, gbHeaderSize)
+	copy(header[gbLogoOffset:], gbLogo)
+	copy(header[gbTitleOffset:gbCGBFlagOffset+1], title)
+	if cgb != 0 { // 16-character titles of GB ROMs run into the flag byte
+		header[gbCGBFlagOffset] = cgb
+	}
+	header[gbROMSizeOffset] = romSizeCode
+	var checksum byte
+	for _, b := range header[gbTitleOffset:gbChecksumOffset] {
+		checksum = checksum - b - 1
+	}
+	header[gbChecksumOffset] = checksum
+	return header
+}
+
+// nesROM returns an iNES header with bytes 4-15 set from fields
+func nesROM(fields ...byte) []byte {
+	header := make([]byte, nesHeaderSize)
+	copy(header, nesMagic)
+	copy(header[4:], fields)
+	return header
+}
+
+// nesROMWithTail returns an iNES header whose bytes 4-6 are set from fields
+// and bytes 7-15 from tail
+func nesROMWithTail(tail string, fields ...byte) []byte {
+	header := nesROM(fields...)
+	copy(header[7:], tail)
+	return header
+}
+
+func TestParseGBHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		header    []byte
+		size      int64
+		format    string
+		platform  string
+		platforms []string
+		title     string
+		warnings  int
+	}{
+		{name: "GB", file: "tetris.gb", header: gbROM("TETRIS", 0x00, 0), size: 32 * 1024,
+			format: "Game Boy", platform: "GB", platforms: []string{"GB"}, title: "TETRIS"},
+		{name: "GB named .gbc", file: "tetris.gbc", header: gbROM("TETRIS", 0x00, 0), size: 32 * 1024,
+			format: "Game Boy", platform: "GB", platforms: []string{"GB"}, title: "TETRIS"},
+		{name: "16-character GB title", file: "game.gb", header: gbROM("ABCDEFGHIJKLMNOP", 0x00, 0), size: 32 * 1024,
+			format: "Game Boy", platform: "GB", platforms: []string{"GB"}, title: "ABCDEFGHIJKLMNOP"},
+		{name: "dual mode named .gb", file: "silver.gb", header: gbROM("POKEMON_SLVAAXE", 0x80, 6), size: 2 * 1024 * 1024,
+			format: "Game Boy Color", platform: "GBC", platforms: []string{"GB", "GBC"}, title: "POKEMON_SLV"},
+		{name: "GBC only", file: "crystal.gbc", header: gbROM("PM_CRYSTAL\x00BYTE", 0xC0, 6), size: 2 * 1024 * 1024,
+			format: "Game Boy Color", platform: "GBC", platforms: []string{"GBC"}, title: "PM_CRYSTAL"},
+		{name: "unusual extension", file: "game.bin", header: gbROM("HOMEBREW", 0x00, 0), size: 32 * 1024,
+			format: "Game Boy", platform: "GB", platforms: []string{"GB"}, title: "HOMEBREW"},
+		{name: "title from the file name", file: "Cool Game (U) [!].gb", header: gbROM("", 0x00, 0), size: 32 * 1024,
+			format: "Game Boy", platform: "GB", platforms: []string{"GB"}, title: "Cool Game"},
+		{name: "shorter than declared", file: "game.gb", header: gbROM("GAME", 0x00, 1), size: 32 * 1024,
+			format: "Game Boy", platform: "GB", platforms: []string{"GB"}, title: "GAME", warnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ParseROMHeader(tt.file, tt.header, tt.size)
+			if err != nil {
+				t.Fatalf("ParseROMHeader: %v", err)
+			}
+			if info == nil {
+				t.Fatal("not recognized as a ROM")
+			}
+			if info.Format != tt.format || info.Platform != tt.platform || !reflect.DeepEqual(info.Platforms, tt.platforms) ||
+				info.Title != tt.title || len(info.Warnings) != tt.warnings {
+				t.Errorf("got %s, %s %v, title %q, warnings %v; expected %s, %s %v, title %q, %d warnings",
+					info.Format, info.Platform, info.Platforms, info.Title, info.Warnings,
+					tt.format, tt.platform, tt.platforms, tt.title, tt.warnings)
+			}
+			// The suggested platform always passes the ROM's own check
+			if err := info.CheckPlatform(info.Platform); err != nil {
+				t.Errorf("suggested platform rejected: %v", err)
+			}
+			if got := DetectROMPlatform(tt.file, tt.header); got != tt.platform {
+				t.Errorf("DetectROMPlatform = %q, expected %q", got, tt.platform)
+			}
+		})
+	}
+}
+
+func TestParseGBHeaderErrors(t *testing.T) {
+	badChecksum := gbROM("TETRIS", 0x00, 0)
+	badChecksum[gbChecksumOffset]++
+	tests := []struct {
+		name   string
+		file   string
+		header []byte
+		err    string
+	}{
+		{name: "bad checksum", file: "tetris.gb", header: badChecksum, err: "header checksum"},
+		{name: "no logo", file: "tetris.gb", header: make([]byte, gbHeaderSize), err: "Nintendo logo missing"},
+		{name: "too short", file: "tetris.gbc", header: []byte("GB"), err: "Nintendo logo missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseROMHeader(tt.file, tt.header, int64(len(tt.header)))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error %v, expected one containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseNESHeader(t *testing.T) {
+	const kib = 1024
+	tests := []struct {
+		name     string
+		header   []byte
+		size     int64
+		format   string
+		mapper   int
+		warnings int
+		err      string
+	}{
+		{name: "NROM", header: nesROM(2, 1, 0x01, 0x00), size: 16 + 32*kib + 8*kib, format: "iNES", mapper: 0},
+		{name: "MMC1", header: nesROM(8, 0, 0x10, 0x00), size: 16 + 128*kib, format: "iNES", mapper: 1},
+		{name: "mapper high nibble", header: nesROM(8, 0, 0x40, 0x10), size: 16 + 128*kib, format: "iNES", mapper: 0x14},
+		{name: "trainer", header: nesROM(1, 1, 0x04, 0x00), size: 16 + 512 + 16*kib + 8*kib, format: "iNES"},
+		{name: "truncated trainer", header: nesROM(1, 1, 0x04, 0x00), size: 16 + 16*kib + 8*kib, err: "truncated NES ROM"},
+		{name: "DiskDude garbage", header: nesROMWithTail("DiskDude!", 1, 1, 0x10),
+			size: 16 + 16*kib + 8*kib, format: "iNES", mapper: 1, warnings: 1},
+		{name: "no PRG ROM", header: nesROM(0, 1, 0x00, 0x00), size: 16 + 8*kib, err: "no PRG ROM"},
+		{name: "truncated", header: nesROM(2, 1, 0x00, 0x00), size: 16 + 32*kib, err: "truncated NES ROM"},
+		// NES 2.0: mapper 0x105 (MSB nibble in byte 8), sizes with MSB nibbles in byte 9
+		{name: "NES 2.0", header: nesROM(0x00, 0x00, 0x50, 0x08, 0x01, 0x11), size: 16 + 256*16*kib + 256*8*kib,
+			format: "NES 2.0", mapper: 0x105},
+		{name: "NES 2.0 truncated", header: nesROM(0x00, 0x00, 0x50, 0x08, 0x01, 0x11), size: 16 + 256*16*kib, err: "truncated NES ROM"},
+		// Exponent-multiplier PRG size: 2^12 * (1*2+1) = 12 KiB
+		{name: "NES 2.0 exponent size", header: nesROM(12<<2|1, 0, 0x00, 0x08, 0x00, 0x0F), size: 16 + 12*kib, format: "NES 2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ParseROMHeader("Super Game (U).nes", tt.header, tt.size)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error %v, expected one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseROMHeader: %v", err)
+			}
+			if info.Format != tt.format || info.Mapper != tt.mapper || len(info.Warnings) != tt.warnings ||
+				info.Platform != "NES" || info.Title != "Super Game" {
+				t.Errorf("got %s mapper %d, %s, title %q, warnings %v; expected %s mapper %d, %d warnings",
+					info.Format, info.Mapper, info.Platform, info.Title, info.Warnings, tt.format, tt.mapper, tt.warnings)
+			}
+		})
+	}
+}
+
+// Files without a header are only recognized by their extension
+func TestDetectROMPlatformByExtension(t *testing.T) {
+	for file, want := range map[string]string{
+		"game.gb":   "GB",
+		"game.GBC":  "GBC",
+		"game.nes":  "NES",
+		"game.exe":  "",
+		"notes.txt": "",
+	} {
+		if got := DetectROMPlatform(file, []byte("not a ROM")); got != want {
+			t.Errorf("%s: %q, expected %q", file, got, want)
+		}
+	}
+	if got := DetectROMPlatform("game.txt", nesROM(1, 1)); got != "NES" {
+		t.Errorf("iNES magic: %q, expected NES", got)
+	}
+}
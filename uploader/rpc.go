@@ -2,26 +2,46 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ErrOutcomeUnknown marks errors where the request may have reached the node but
+// no answer came back (timeout, dropped connection, cancellation mid-request).
+// For sends this means the transaction may or may not have been broadcast.
+var ErrOutcomeUnknown = errors.New("outcome unknown")
+
 // NimiqRPC is a client for Nimiq JSON-RPC endpoints (uploader version)
 type NimiqRPC struct {
 	url    string
 	client *http.Client
+	ctx    context.Context // optional, cancels in-flight requests
 }
 
 func NewNimiqRPC(url string) *NimiqRPC {
-	return &NimiqRPC{
+	rpc := &NimiqRPC{
 		url: url,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
+	// replay:DIR answers from fixtures recorded with rpc-record
+	if dir, ok := strings.CutPrefix(url, ReplayURLPrefix); ok {
+		rpc.client.Transport = replayTransportFor(dir)
+	}
+	// --inject-faults (see fault.go)
+	if activeFaults != nil && activeFaults.cfg.Layer == FaultLayerRPC {
+		rpc.client.Transport = NewFaultTransport(rpc.client.Transport, activeFaults)
+	}
+	return rpc
 }
 
 type JSONRPCRequest struct {
@@ -43,8 +63,34 @@ type JSONRPCError struct {
 	Message string `json:"message"`
 }
 
+// WithContext returns a copy of the client whose requests are cancelled with ctx
+func (rpc *NimiqRPC) WithContext(ctx context.Context) *NimiqRPC {
+	c := *rpc
+	c.ctx = ctx
+	return &c
+}
+
+// context returns the context for requests (background if none was set)
+func (rpc *NimiqRPC) context() context.Context {
+	if rpc.ctx == nil {
+		return context.Background()
+	}
+	return rpc.ctx
+}
+
 // Call performs a JSON-RPC call with object params
 func (rpc *NimiqRPC) Call(method string, params map[string]interface{}) (json.RawMessage, error) {
+	return rpc.call(method, params)
+}
+
+// CallPositional performs a JSON-RPC call with array params
+// (some RPC implementations expect positional params)
+func (rpc *NimiqRPC) CallPositional(method string, params []interface{}) (json.RawMessage, error) {
+	return rpc.call(method, params)
+}
+
+// call sends a JSON-RPC request and returns the raw result
+func (rpc *NimiqRPC) call(method string, params interface{}) (json.RawMessage, error) {
 	req := JSONRPCRequest{
 		JSONRPC: "2.0",
 		ID:      1,
@@ -57,7 +103,7 @@ func (rpc *NimiqRPC) Call(method string, params map[string]interface{}) (json.Ra
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", rpc.url, bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(rpc.context(), "POST", rpc.url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -65,13 +111,16 @@ func (rpc *NimiqRPC) Call(method string, params map[string]interface{}) (json.Ra
 
 	resp, err := rpc.client.Do(httpReq)
 	if err != nil {
+		if isOutcomeUnknown(err) {
+			return nil, fmt.Errorf("failed to send request: %w (%w)", err, ErrOutcomeUnknown)
+		}
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w (%w)", err, ErrOutcomeUnknown)
 	}
 
 	var jsonResp JSONRPCResponse
@@ -86,6 +135,20 @@ func (rpc *NimiqRPC) Call(method string, params map[string]interface{}) (json.Ra
 	return jsonResp.Result, nil
 }
 
+// isOutcomeUnknown reports whether a transport error may have happened after the
+// node received the request. Only failures to connect are known to be harmless.
+func isOutcomeUnknown(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return false
+	}
+	return true
+}
+
 // IsAccountImported checks if an account has been imported
 func (rpc *NimiqRPC) IsAccountImported(address string) (bool, error) {
 	result, err := rpc.Call("isAccountImported", map[string]interface{}{
@@ -221,48 +284,15 @@ func (rpc *NimiqRPC) CreateAccount() (*AccountInfo, error) {
 
 // ImportRawKey imports an account by its private key
 func (rpc *NimiqRPC) ImportRawKey(keyData string, passphrase string) (string, error) {
-	// Create request with array params (some RPC implementations expect positional params)
-	req := JSONRPCRequest{
-		JSONRPC: "2.0",
-		ID:      1,
-		Method:  "importRawKey",
-		Params:  []interface{}{keyData, passphrase},
-	}
-
-	body, err := json.Marshal(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	httpReq, err := http.NewRequest("POST", rpc.url, bytes.NewReader(body))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := rpc.client.Do(httpReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
+	// Use array params (some RPC implementations expect positional params)
+	result, err := rpc.CallPositional("importRawKey", []interface{}{keyData, passphrase})
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	var jsonResp JSONRPCResponse
-	if err := json.Unmarshal(respBody, &jsonResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	if jsonResp.Error != nil {
-		return "", fmt.Errorf("RPC error: %s (code %d)", jsonResp.Error.Message, jsonResp.Error.Code)
+		return "", err
 	}
 
 	// Try parsing response - may be direct string, nested object, or object with Address field
 	var directAddress string
-	if err := json.Unmarshal(jsonResp.Result, &directAddress); err == nil && directAddress != "" {
+	if err := json.Unmarshal(result, &directAddress); err == nil && directAddress != "" {
 		return directAddress, nil
 	}
 
@@ -271,7 +301,7 @@ func (rpc *NimiqRPC) ImportRawKey(keyData string, passphrase string) (string, er
 		Address string `json:"Address"`
 		Data    interface{} `json:"data"`
 	}
-	if err := json.Unmarshal(jsonResp.Result, &response); err == nil {
+	if err := json.Unmarshal(result, &response); err == nil {
 		if response.Address != "" {
 			return response.Address, nil
 		}
@@ -287,7 +317,7 @@ func (rpc *NimiqRPC) ImportRawKey(keyData string, passphrase string) (string, er
 		}
 	}
 
-	return "", fmt.Errorf("no address found in response: %s", string(jsonResp.Result))
+	return "", fmt.Errorf("no address found in response: %s", string(result))
 }
 
 type AccountInfo struct {
@@ -413,61 +443,8 @@ func (rpc *NimiqRPC) GetBlockNumber() (int64, error) {
 	return 0, fmt.Errorf("failed to parse block number: unexpected format: %s", string(result))
 }
 
-// SendBasicTransactionWithData sends a transaction with data field
-func (rpc *NimiqRPC) SendBasicTransactionWithData(wallet, recipient, data string, value, fee, validityStartHeight int64) (string, error) {
-	// Try with object params first
-	result, err := rpc.Call("sendBasicTransactionWithData", map[string]interface{}{
-		"wallet":             wallet,
-		"recipient":           recipient,
-		"data":                data,
-		"value":               value,
-		"fee":                 fee,
-		"validityStartHeight": validityStartHeight,
-	})
-	if err != nil {
-		// Try with array params (some RPC implementations expect positional parameters)
-		req := JSONRPCRequest{
-			JSONRPC: "2.0",
-			ID:      1,
-			Method:  "sendBasicTransactionWithData",
-			Params:  []interface{}{wallet, recipient, data, value, fee, validityStartHeight},
-		}
-
-		body, err2 := json.Marshal(req)
-		if err2 != nil {
-			return "", fmt.Errorf("failed to marshal request: %w", err)
-		}
-
-		httpReq, err2 := http.NewRequest("POST", rpc.url, bytes.NewReader(body))
-		if err2 != nil {
-			return "", fmt.Errorf("failed to create request: %w", err)
-		}
-		httpReq.Header.Set("Content-Type", "application/json")
-
-		resp, err2 := rpc.client.Do(httpReq)
-		if err2 != nil {
-			return "", fmt.Errorf("failed to send request: %w", err)
-		}
-		defer resp.Body.Close()
-
-		respBody, err2 := io.ReadAll(resp.Body)
-		if err2 != nil {
-			return "", fmt.Errorf("failed to read response: %w", err)
-		}
-
-		var jsonResp JSONRPCResponse
-		if err2 := json.Unmarshal(respBody, &jsonResp); err2 != nil {
-			return "", fmt.Errorf("failed to unmarshal response: %w", err2)
-		}
-
-		if jsonResp.Error != nil {
-			// Include full response for debugging
-			return "", fmt.Errorf("RPC error: %s (code %d). Full response: %s", jsonResp.Error.Message, jsonResp.Error.Code, string(respBody))
-		}
-
-		result = jsonResp.Result
-	}
-
+// parseTransactionHash extracts the transaction hash from a send* response
+func parseTransactionHash(result json.RawMessage) (string, error) {
 	// Try parsing response - may be direct string, nested object, or object with Blake2bHash field
 	var directHash string
 	if err := json.Unmarshal(result, &directHash); err == nil && directHash != "" {
@@ -498,6 +475,76 @@ func (rpc *NimiqRPC) SendBasicTransactionWithData(wallet, recipient, data string
 	return "", fmt.Errorf("no transaction hash found in response: %s", string(result))
 }
 
+// GetMinFeePerByte returns the node's minimum fee per byte for mempool admission (Luna)
+func (rpc *NimiqRPC) GetMinFeePerByte() (float64, error) {
+	result, err := rpc.Call("getMinFeePerByte", map[string]interface{}{})
+	if err != nil {
+		return 0, err
+	}
+
+	// Try parsing as direct number first
+	var fee float64
+	if err := json.Unmarshal(result, &fee); err == nil {
+		return fee, nil
+	}
+
+	// Try parsing as nested object with "data" field
+	var response struct {
+		Data *float64 `json:"data"`
+	}
+	if err := json.Unmarshal(result, &response); err == nil && response.Data != nil {
+		return *response.Data, nil
+	}
+
+	return 0, fmt.Errorf("failed to parse min fee per byte: unexpected format: %s", string(result))
+}
+
+// MempoolInfo summarizes the mempool: the number of transactions paying at
+// least a given fee per byte (bucket) and the total
+type MempoolInfo struct {
+	Total   int
+	Buckets map[float64]int // fee per byte -> number of transactions in that bucket
+}
+
+// GetMempoolInfo returns the fee buckets of the node's mempool
+func (rpc *NimiqRPC) GetMempoolInfo() (*MempoolInfo, error) {
+	result, err := rpc.Call("mempool", map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Response is {"_0": n, "_1": n, ..., "total": n, "buckets": [...]}, possibly inside "data"
+	var responseObj map[string]interface{}
+	if err := json.Unmarshal(result, &responseObj); err != nil {
+		return nil, fmt.Errorf("failed to parse mempool response: %w", err)
+	}
+	if data, ok := responseObj["data"].(map[string]interface{}); ok {
+		responseObj = data
+	}
+
+	info := &MempoolInfo{Buckets: make(map[float64]int)}
+	for key, value := range responseObj {
+		count, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		if key == "total" {
+			info.Total = int(count)
+			continue
+		}
+		if strings.HasPrefix(key, "_") {
+			if feePerByte, err := strconv.ParseFloat(key[1:], 64); err == nil {
+				info.Buckets[feePerByte] = int(count)
+			}
+		}
+	}
+	if _, ok := responseObj["total"]; !ok {
+		return nil, fmt.Errorf("failed to parse mempool response: unexpected format: %s", string(result))
+	}
+
+	return info, nil
+}
+
 // parseHexInt64 parses a hex string to int64
 func parseHexInt64(hexStr string) (int64, error) {
 	// Remove 0x prefix if present
@@ -509,3 +556,185 @@ func parseHexInt64(hexStr string) (int64, error) {
 	_, err := fmt.Sscanf(hexStr, "%x", &result)
 	return result, err
 }
+
+// RPCTransaction is a transaction as described by the node
+// (getTransactionByHash, getRawTransactionInfo)
+type RPCTransaction struct {
+	Hash                string `json:"hash"`
+	BlockNumber         int64  `json:"blockNumber"`
+	From                string `json:"from"`
+	To                  string `json:"to"`
+	Value               int64  `json:"value"`
+	Fee                 int64  `json:"fee"`
+	RecipientData       string `json:"recipientData"`
+	ValidityStartHeight int64  `json:"validityStartHeight"`
+}
+
+// unwrapData returns the "data" member of a {"data", "metadata"} result, or
+// the result itself. Bare objects with a data field of their own (transactions
+// of older nodes) are not unwrapped.
+func unwrapData(result json.RawMessage) json.RawMessage {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(result, &response); err != nil {
+		return result
+	}
+	data, ok := response["data"]
+	if !ok || string(data) == "null" {
+		return result
+	}
+	for key := range response {
+		if key != "data" && key != "metadata" {
+			return result
+		}
+	}
+	return data
+}
+
+// SendRawTransaction broadcasts a signed, serialized transaction (hex)
+func (rpc *NimiqRPC) SendRawTransaction(rawTx string) (string, error) {
+	result, err := rpc.Call("sendRawTransaction", map[string]interface{}{
+		"rawTx": rawTx,
+	})
+	if err != nil {
+		return "", err
+	}
+	return parseTransactionHash(result)
+}
+
+// GetRawTransactionInfo asks the node to decode a serialized transaction without sending it
+func (rpc *NimiqRPC) GetRawTransactionInfo(rawTx string) (*RPCTransaction, error) {
+	result, err := rpc.Call("getRawTransactionInfo", map[string]interface{}{
+		"rawTx": rawTx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var tx RPCTransaction
+	if err := json.Unmarshal(unwrapData(result), &tx); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction info: %w", err)
+	}
+	return &tx, nil
+}
+
+// GetTransactionByHash returns a transaction that was included in a block
+func (rpc *NimiqRPC) GetTransactionByHash(hash string) (*RPCTransaction, error) {
+	result, err := rpc.Call("getTransactionByHash", map[string]interface{}{
+		"hash": hash,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var tx RPCTransaction
+	if err := json.Unmarshal(unwrapData(result), &tx); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	return &tx, nil
+}
+
+// CreateBasicTransactionWithData asks the node wallet to sign a transaction
+// with data without sending it, and returns the serialized transaction (hex)
+func (rpc *NimiqRPC) CreateBasicTransactionWithData(wallet, recipient, data string, value, fee, validityStartHeight int64) (string, error) {
+	result, err := rpc.Call("createBasicTransactionWithData", map[string]interface{}{
+		"wallet":              wallet,
+		"recipient":           recipient,
+		"data":                data,
+		"value":               value,
+		"fee":                 fee,
+		"validityStartHeight": validityStartHeight,
+	})
+	if err != nil {
+		// Creating a transaction has no side effects, so positional params are always safe to try
+		result, err = rpc.CallPositional("createBasicTransactionWithData",
+			[]interface{}{wallet, recipient, data, value, fee, validityStartHeight})
+		if err != nil {
+			return "", err
+		}
+	}
+	return parseRawTransaction(result)
+}
+
+// CreateBasicTransaction asks the node wallet to sign a plain value transfer
+// without sending it, and returns the serialized transaction (hex)
+func (rpc *NimiqRPC) CreateBasicTransaction(wallet, recipient string, value, fee, validityStartHeight int64) (string, error) {
+	result, err := rpc.Call("createBasicTransaction", map[string]interface{}{
+		"wallet":              wallet,
+		"recipient":           recipient,
+		"value":               value,
+		"fee":                 fee,
+		"validityStartHeight": validityStartHeight,
+	})
+	if err != nil {
+		result, err = rpc.CallPositional("createBasicTransaction",
+			[]interface{}{wallet, recipient, value, fee, validityStartHeight})
+		if err != nil {
+			return "", err
+		}
+	}
+	return parseRawTransaction(result)
+}
+
+// parseRawTransaction extracts the serialized transaction from a create* response
+func parseRawTransaction(result json.RawMessage) (string, error) {
+	var rawTx string
+	if err := json.Unmarshal(unwrapData(result), &rawTx); err != nil || rawTx == "" {
+		return "", fmt.Errorf("no raw transaction found in response: %s", string(result))
+	}
+	return rawTx, nil
+}
+
+// LatestBlock is the head of the node's chain, as far as the uploader needs it
+type LatestBlock struct {
+	Number    int64
+	Timestamp int64 // milliseconds since the epoch
+	Network   uint8 // 0 if the node did not say
+}
+
+// GetLatestBlock returns the node's latest block (without body)
+func (rpc *NimiqRPC) GetLatestBlock() (*LatestBlock, error) {
+	result, err := rpc.Call("getLatestBlock", map[string]interface{}{
+		"includeBody": false,
+	})
+	if err != nil {
+		result, err = rpc.CallPositional("getLatestBlock", []interface{}{false})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var block struct {
+		Number    int64           `json:"number"`
+		Timestamp int64           `json:"timestamp"`
+		Network   json.RawMessage `json:"network"`
+	}
+	if err := json.Unmarshal(unwrapData(result), &block); err != nil {
+		return nil, fmt.Errorf("invalid latest block: %s", string(result))
+	}
+	latest := &LatestBlock{Number: block.Number, Timestamp: block.Timestamp}
+	if len(block.Network) == 0 {
+		return latest, nil
+	}
+	if err := json.Unmarshal(block.Network, &latest.Network); err == nil {
+		return latest, nil
+	}
+	var name string
+	if err := json.Unmarshal(block.Network, &name); err != nil {
+		return nil, fmt.Errorf("invalid network in latest block: %s", string(block.Network))
+	}
+	if latest.Network, err = ParseNetworkID(name); err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// GetNetworkID asks the node which network it is on (from the network field of
+// its latest block, e.g. "TestAlbatross" or a numeric ID)
+func (rpc *NimiqRPC) GetNetworkID() (uint8, error) {
+	block, err := rpc.GetLatestBlock()
+	if err != nil {
+		return 0, err
+	}
+	if block.Network == 0 {
+		return 0, fmt.Errorf("no network found in latest block")
+	}
+	return block.Network, nil
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// RPCFixture is one recorded JSON-RPC exchange, as written by rpc-record and
+// served by ReplayTransport. Passphrases and private keys are redacted.
+type RPCFixture struct {
+	Seq          int             `json:"seq"`
+	Method       string          `json:"method"`
+	Params       json.RawMessage `json:"params,omitempty"`
+	Status       int             `json:"status"`
+	Response     json.RawMessage `json:"response,omitempty"`
+	ResponseText string          `json:"response_text,omitempty"` // body that was not JSON (proxy errors)
+	Node         string          `json:"node,omitempty"`          // --label, e.g. the node version
+	RecordedAt   string          `json:"recorded_at"`
+	DurationMs   int64           `json:"duration_ms"`
+}
+
+// rpcSecretFields are redacted wherever they appear in params or results
+var rpcSecretFields = map[string]bool{"passphrase": true, "keyData": true, "privateKey": true}
+
+// rpcSecretPositions are the secret positional params of wallet methods
+var rpcSecretPositions = map[string][]int{
+	"createAccount": {0},
+	"importRawKey":  {0, 1},
+	"unlockAccount": {1},
+}
+
+const rpcRedacted = "<redacted>"
+
+// redactRPCParams removes passphrases and keys from request params
+func redactRPCParams(method string, params json.RawMessage) json.RawMessage {
+	v, ok := decodeRPCJSON(params)
+	if !ok {
+		return params
+	}
+	changed := redactRPCValue(v)
+	if list, isList := v.([]interface{}); isList {
+		for _, i := range rpcSecretPositions[method] {
+			if i < len(list) && list[i] != nil {
+				list[i] = rpcRedacted
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return params
+	}
+	out, err := marshalRPCJSON(v, "")
+	if err != nil {
+		return params
+	}
+	return out
+}
+
+// redactRPCResponse removes private keys from a response (createAccount)
+func redactRPCResponse(body json.RawMessage) json.RawMessage {
+	v, ok := decodeRPCJSON(body)
+	if !ok || !redactRPCValue(v) {
+		return body
+	}
+	out, err := marshalRPCJSON(v, "")
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// decodeRPCJSON decodes JSON keeping numbers exact (Luna amounts, heights)
+func decodeRPCJSON(raw json.RawMessage) (interface{}, bool) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// marshalRPCJSON encodes without HTML escaping, so fixtures stay readable
+func marshalRPCJSON(v interface{}, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// redactRPCValue replaces secret fields in place and reports whether any were found
+func redactRPCValue(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if rpcSecretFields[key] {
+				if field != nil && field != "" {
+					v[key] = rpcRedacted
+					changed = true
+				}
+				continue
+			}
+			if redactRPCValue(field) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if redactRPCValue(item) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// rpcRecorder forwards requests to the upstream node and writes every
+// JSON-RPC exchange to a fixture file
+type rpcRecorder struct {
+	upstream string
+	outDir   string
+	label    string
+	client   *http.Client
+
+	mu  sync.Mutex
+	seq int
+}
+
+func (r *rpcRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+
+	upstreamReq, err := http.NewRequestWithContext(req.Context(), req.Method, r.upstream, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, header := range []string{"Content-Type", "Accept", "Authorization", "Origin"} {
+		if value := req.Header.Get(header); value != "" {
+			upstreamReq.Header.Set(header, value)
+		}
+	}
+
+	start := time.Now()
+	resp, err := r.client.Do(upstreamReq)
+	if err != nil {
+		log.Printf("upstream error: %v", err)
+		http.Error(w, fmt.Sprintf("upstream error: %v", err), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("upstream error: %v", err)
+		http.Error(w, fmt.Sprintf("upstream error: %v", err), http.StatusBadGateway)
+		return
+	}
+	elapsed := time.Since(start)
+
+	for key, values := range resp.Header {
+		if key == "Content-Length" {
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(respBody)
+
+	// Only JSON-RPC calls are recorded (not CORS preflights)
+	var rpcReq struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if req.Method != http.MethodPost || json.Unmarshal(body, &rpcReq) != nil || rpcReq.Method == "" {
+		return
+	}
+	if err := r.record(rpcReq.Method, rpcReq.Params, resp.StatusCode, respBody, elapsed); err != nil {
+		log.Printf("failed to record %s: %v", rpcReq.Method, err)
+	}
+}
+
+func (r *rpcRecorder) record(method string, params json.RawMessage, status int, respBody []byte, elapsed time.Duration) error {
+	fixture := &RPCFixture{
+		Method:     method,
+		Status:     status,
+		Node:       r.label,
+		RecordedAt: time.Now().UTC().Format(time.RFC3339),
+		DurationMs: elapsed.Milliseconds(),
+	}
+	if len(params) > 0 && string(params) != "null" {
+		fixture.Params = redactRPCParams(method, params)
+	}
+	if json.Valid(respBody) {
+		fixture.Response = redactRPCResponse(respBody)
+	} else {
+		fixture.ResponseText = string(respBody)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	fixture.Seq = r.seq
+
+	data, err := marshalRPCJSON(fixture, "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%04d-%s.json", fixture.Seq, method)
+	if err := os.WriteFile(filepath.Join(r.outDir, name), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	log.Printf("%s -> %s (%d, %s)", method, name, status, elapsed.Round(time.Millisecond))
+	return nil
+}
+
+// lastFixtureSeq returns the highest sequence number of the fixtures in dir,
+// so a new session appends instead of overwriting
+func lastFixtureSeq(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	last := 0
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "-")
+		if !ok || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if seq, err := strconv.Atoi(prefix); err == nil && seq > last {
+			last = seq
+		}
+	}
+	return last, nil
+}
+
+func newRPCRecordCmd() *cobra.Command {
+	var (
+		upstream string
+		outDir   string
+		host     string
+		port     int
+		label    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rpc-record",
+		Short: "Proxy to a node and record JSON-RPC requests and responses as fixtures",
+		Long: `Run a JSON-RPC proxy in front of a real node and write every request/response
+pair to --out, one JSON file per call (0001-getBlockNumber.json, ...). Point the
+uploader or the web frontend at the proxy and use them as usual.
+
+Passphrases and private keys are redacted before anything is written, but the
+fixtures still contain your addresses and transactions - review them before
+committing them.
+
+Recorded fixtures are served back by --rpc-url replay:DIR, so commands (and the
+response parsers in rpc.go) can be run against the response shapes of several
+node versions without a node:
+
+  nimiq-uploader rpc-record --upstream http://localhost:8648 --out fixtures/albatross-1.1 --label albatross-1.1
+  NIMIQ_RPC_URL=http://127.0.0.1:8649 nimiq-uploader doctor
+  nimiq-uploader doctor --rpc-url replay:fixtures/albatross-1.1`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if upstream == "" {
+				return fmt.Errorf("--upstream is required")
+			}
+			if err := os.MkdirAll(outDir, 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			seq, err := lastFixtureSeq(outDir)
+			if err != nil {
+				return fmt.Errorf("failed to read output directory: %w", err)
+			}
+
+			recorder := &rpcRecorder{
+				upstream: upstream,
+				outDir:   outDir,
+				label:    label,
+				client:   &http.Client{Timeout: 60 * time.Second},
+				seq:      seq,
+			}
+
+			listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+			if err != nil {
+				return fmt.Errorf("failed to listen: %w", err)
+			}
+			server := &http.Server{Handler: recorder}
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+				defer done()
+				server.Shutdown(shutdownCtx)
+			}()
+
+			url := "http://" + listener.Addr().String()
+			fmt.Printf("📼 Recording %s -> %s\n", url, upstream)
+			fmt.Printf("   Fixtures: %s", outDir)
+			if seq > 0 {
+				fmt.Printf(" (appending after %04d)", seq)
+			}
+			fmt.Println()
+			fmt.Printf("   Use it with: export NIMIQ_RPC_URL=%s\n", url)
+			fmt.Printf("   Replay with: --rpc-url %s%s\n", ReplayURLPrefix, outDir)
+			fmt.Println("   Press Ctrl-C to stop")
+
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&upstream, "upstream", "", "RPC URL of the node to record (required)")
+	cmd.Flags().StringVar(&outDir, "out", "fixtures", "Directory to write the fixtures to")
+	cmd.Flags().StringVar(&host, "host", "127.0.0.1", "Address to listen on")
+	cmd.Flags().IntVar(&port, "port", 8649, "Port to listen on")
+	cmd.Flags().StringVar(&label, "label", "", "Note stored with every fixture, e.g. the node version")
+
+	return cmd
+}
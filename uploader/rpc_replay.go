@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ReplayURLPrefix selects recorded fixtures instead of a node as RPC URL:
+// replay:DIR serves the files written by rpc-record to DIR
+const ReplayURLPrefix = "replay:"
+
+// LoadRPCFixtures reads the fixtures in dir, in recording order
+func LoadRPCFixtures(dir string) ([]*RPCFixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	fixtures := make([]*RPCFixture, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fixture RPCFixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+		}
+		if fixture.Method == "" {
+			return nil, fmt.Errorf("invalid fixture %s: no method", path)
+		}
+		fixtures = append(fixtures, &fixture)
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("no fixtures in %s", dir)
+	}
+	sort.SliceStable(fixtures, func(i, j int) bool { return fixtures[i].Seq < fixtures[j].Seq })
+	return fixtures, nil
+}
+
+// ReplayTransport answers JSON-RPC requests from recorded fixtures.
+//
+// A request is answered by the first unused fixture of the same method with
+// the same params, else by the first unused one of the same method. Once all
+// fixtures of a method are used, the last one is repeated (polling loops keep
+// getting the final state).
+type ReplayTransport struct {
+	mu       sync.Mutex
+	fixtures []*RPCFixture
+	used     []bool
+}
+
+// NewReplayTransport loads the fixtures in dir
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	fixtures, err := LoadRPCFixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayTransport{fixtures: fixtures, used: make([]bool, len(fixtures))}, nil
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var rpcReq struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(body, &rpcReq); err != nil || rpcReq.Method == "" {
+		return nil, fmt.Errorf("replay: not a JSON-RPC request")
+	}
+
+	fixture := t.match(rpcReq.Method, rpcReq.Params)
+	if fixture == nil {
+		return nil, replayError(fmt.Errorf("no recorded response for %s", rpcReq.Method))
+	}
+
+	respBody := []byte(fixture.ResponseText)
+	if len(fixture.Response) > 0 {
+		respBody = withRPCID(fixture.Response, rpcReq.ID)
+	}
+	status := fixture.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+// match picks the fixture for a call and marks it used
+func (t *ReplayTransport) match(method string, params json.RawMessage) *RPCFixture {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	want := canonicalRPCParams(redactRPCParams(method, params))
+	first, last := -1, -1
+	for i, fixture := range t.fixtures {
+		if fixture.Method != method {
+			continue
+		}
+		last = i
+		if t.used[i] {
+			continue
+		}
+		if canonicalRPCParams(fixture.Params) == want {
+			t.used[i] = true
+			return fixture
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	if first >= 0 {
+		t.used[first] = true
+		return t.fixtures[first]
+	}
+	if last >= 0 {
+		return t.fixtures[last]
+	}
+	return nil
+}
+
+// canonicalRPCParams re-encodes params with sorted keys, so params recorded
+// from other clients compare equal
+func canonicalRPCParams(params json.RawMessage) string {
+	v, ok := decodeRPCJSON(params)
+	if !ok || v == nil {
+		return ""
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return string(params)
+	}
+	return string(out)
+}
+
+// withRPCID sets the id of a recorded response to the id of the request
+func withRPCID(response, id json.RawMessage) []byte {
+	var fields map[string]json.RawMessage
+	if len(id) == 0 || json.Unmarshal(response, &fields) != nil {
+		return response
+	}
+	fields["id"] = id
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return response
+	}
+	return out
+}
+
+// replayTransports shares one transport per directory within a process, so
+// every client of a command consumes the same recording
+var (
+	replayTransportsMu sync.Mutex
+	replayTransports   = map[string]http.RoundTripper{}
+)
+
+// replayTransportFor returns the transport for a replay:DIR RPC URL; a
+// directory that cannot be loaded fails every request with the load error
+func replayTransportFor(dir string) http.RoundTripper {
+	dir = filepath.Clean(strings.TrimSpace(dir))
+	replayTransportsMu.Lock()
+	defer replayTransportsMu.Unlock()
+	if transport, ok := replayTransports[dir]; ok {
+		return transport
+	}
+	var transport http.RoundTripper
+	if replay, err := NewReplayTransport(dir); err != nil {
+		transport = failingTransport{replayError(err)}
+	} else {
+		transport = replay
+	}
+	replayTransports[dir] = transport
+	return transport
+}
+
+// replayError marks a request the replay could not answer as never sent, so
+// callers don't treat it as a send with unknown outcome
+func replayError(err error) error {
+	return &net.OpError{Op: "dial", Net: "replay", Err: err}
+}
+
+// failingTransport fails every request with err
+type failingTransport struct{ err error }
+
+func (t failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, t.err
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// RunConfig is the run.json metadata inside a cartridge ZIP (see RUN_JSON.md)
+type RunConfig struct {
+	Title      string `json:"title,omitempty"`
+	Filename   string `json:"filename,omitempty"`
+	Executable string `json:"executable,omitempty"`
+	ROM        string `json:"rom,omitempty"`
+	Platform   string `json:"platform,omitempty"`
+}
+
+// platformCodes maps run.json platform names to CART/CENT platform codes
+var platformCodes = map[string]uint8{
+	"DOS": 0,
+	"GB":  1,
+	"GBC": 2,
+	"NES": 3,
+}
+
+// PlatformCode returns the platform code for a run.json platform name
+func PlatformCode(name string) (uint8, error) {
+	code, ok := platformCodes[strings.ToUpper(strings.TrimSpace(name))]
+	if !ok {
+		return 0, fmt.Errorf("unknown platform %q (use DOS, GB, GBC or NES)", name)
+	}
+	return code, nil
+}
+
+// PlatformName returns the run.json platform name for a platform code, or ""
+func PlatformName(code uint8) string {
+	for name, c := range platformCodes {
+		if c == code {
+			return name
+		}
+	}
+	return ""
+}
+
+// ReadRunConfig reads run.json from the root of a ZIP file. It returns nil
+// (and no error) if the ZIP has no run.json.
+func ReadRunConfig(zipPath string) (*RunConfig, error) {
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP: %w", err)
+	}
+	defer archive.Close()
+
+	for _, f := range archive.File {
+		if f.Name != "run.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open run.json: %w", err)
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(io.LimitReader(rc, 64*1024))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read run.json: %w", err)
+		}
+		return ParseRunConfig(data)
+	}
+	return nil, nil
+}
+
+// ParseRunConfig parses the contents of a run.json file
+func ParseRunConfig(data []byte) (*RunConfig, error) {
+	var config RunConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid run.json: %w", err)
+	}
+	return &config, nil
+}
+
+// dosExecutableExts are the file types JS-DOS can start
+var dosExecutableExts = []string{".exe", ".com", ".bat"}
+
+// romPlatforms maps ROM file extensions to run.json platform names
+var romPlatforms = map[string]string{
+	".gb":  "GB",
+	".sgb": "GB",
+	".gbc": "GBC",
+	".nes": "NES",
+}
+
+// gbLogo is the Nintendo logo every Game Boy ROM carries at 0x104
+var gbLogo = []byte{
+	0xCE, 0xED, 0x66, 0x66, 0xCC, 0x0D, 0x00, 0x0B, 0x03, 0x73, 0x00, 0x83,
+	0x00, 0x0C, 0x00, 0x0D, 0x00, 0x08, 0x11, 0x1F, 0x88, 0x89, 0x00, 0x0E,
+	0xDC, 0xCC, 0x6E, 0xE6, 0xDD, 0xDD, 0xD9, 0x99, 0xBB, 0xBB, 0x67, 0x63,
+	0x6E, 0x0E, 0xEC, 0xCC, 0xDD, 0xDC, 0x99, 0x9F, 0xBB, 0xB9, 0x33, 0x3E,
+}
+
+const (
+	gbLogoOffset    = 0x104
+	gbCGBFlagOffset = 0x143 // 0x80: also runs on a Game Boy, 0xC0: Game Boy Color only
+	gbHeaderSize    = 0x150
+)
+
+// gbCGBPlatforms returns the platforms a Game Boy ROM runs on given its CGB flag
+func gbCGBPlatforms(flag byte) []string {
+	switch flag {
+	case 0xC0:
+		return []string{"GBC"}
+	case 0x80:
+		return []string{"GB", "GBC"}
+	}
+	return []string{"GB"}
+}
+
+// nesMagic starts every iNES ROM
+var nesMagic = []byte("NES\x1a")
+
+// DetectROMPlatform returns the platform of a ROM file from its name and its
+// first bytes (at least gbHeaderSize for Game Boy ROMs), or "" if it is not a
+// ROM. The header wins over the extension: a Game Boy ROM is GBC if its CGB
+// flag says it runs on a Game Boy Color (GB otherwise, also as .gbc), and a
+// ROM with an unusual extension is still found. The extension only decides
+// for files without a header.
+func DetectROMPlatform(name string, header []byte) string {
+	if bytes.HasPrefix(header, nesMagic) {
+		return "NES"
+	}
+	if len(header) > gbCGBFlagOffset && hasGBLogo(header) {
+		platforms := gbCGBPlatforms(header[gbCGBFlagOffset])
+		return platforms[len(platforms)-1]
+	}
+	return romPlatforms[strings.ToLower(path.Ext(name))]
+}
+
+// hasGBLogo reports whether header carries the Nintendo logo of Game Boy ROMs
+func hasGBLogo(header []byte) bool {
+	return len(header) >= gbLogoOffset+len(gbLogo) &&
+		bytes.Equal(header[gbLogoOffset:gbLogoOffset+len(gbLogo)], gbLogo)
+}
+
+// Validate checks the config against the files of the archive (paths
+// relative to the ZIP root, with forward slashes). The platform is required,
+// and the executable or ROM must be in the archive; a path that only differs
+// in case is corrected to the archive's spelling.
+func (c *RunConfig) Validate(files []string) error {
+	if c.Platform == "" {
+		return fmt.Errorf("run.json: platform is required")
+	}
+	if _, err := PlatformCode(c.Platform); err != nil {
+		return fmt.Errorf("run.json: %w", err)
+	}
+	c.Platform = strings.ToUpper(strings.TrimSpace(c.Platform))
+
+	find := func(field, name string) (string, error) {
+		name = strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "./")
+		if strings.HasPrefix(name, "/") || strings.Contains("/"+name+"/", "/../") {
+			return "", fmt.Errorf("run.json: %s %q must be a path inside the ZIP", field, name)
+		}
+		for _, f := range files {
+			if f == name {
+				return f, nil
+			}
+		}
+		for _, f := range files {
+			if strings.EqualFold(f, name) {
+				return f, nil
+			}
+		}
+		return "", fmt.Errorf("run.json: %s %q is not in the ZIP", field, name)
+	}
+
+	if c.Platform == "DOS" {
+		if c.ROM != "" {
+			return fmt.Errorf("run.json: DOS games use executable, not rom")
+		}
+		if c.Executable != "" {
+			exe, err := find("executable", c.Executable)
+			if err != nil {
+				return err
+			}
+			ext := strings.ToLower(path.Ext(exe))
+			if !containsString(dosExecutableExts, ext) {
+				return fmt.Errorf("run.json: executable %q is not a .exe, .com or .bat file", exe)
+			}
+			c.Executable = exe
+		}
+		return nil
+	}
+
+	if c.Executable != "" {
+		return fmt.Errorf("run.json: %s games use rom, not executable", c.Platform)
+	}
+	if c.ROM != "" {
+		rom, err := find("rom", c.ROM)
+		if err != nil {
+			return err
+		}
+		c.ROM = rom
+	}
+	return nil
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
-	"encoding/hex"
+	"context"
 	"fmt"
+	"strings"
+	"sync/atomic"
 )
 
 // TxSender interface for sending transactions
@@ -11,6 +13,14 @@ type TxSender interface {
 	SendTransaction(payload []byte) (string, error) // Returns transaction hash
 }
 
+// Resender is implemented by senders that can escalate the fee when a
+// transaction is sent again after earlier attempts failed or expired. It also
+// returns the validity start height of the transaction, so callers can tell
+// when it has expired (0 if unknown).
+type Resender interface {
+	ResendTransaction(payload []byte, attempt int) (string, int64, error)
+}
+
 // DryRunSender implements TxSender but doesn't actually send transactions
 type DryRunSender struct{}
 
@@ -19,86 +29,274 @@ func (d *DryRunSender) SendTransaction(payload []byte) (string, error) {
 	return "", nil
 }
 
-// RPCSender implements TxSender using Nimiq RPC
+// RPCSender implements TxSender by having signer sign each transaction and
+// broadcaster send it (both backed by the Nimiq RPC node by default)
 type RPCSender struct {
 	rpc             *NimiqRPC
+	signer          Signer
+	broadcaster     Broadcaster
 	senderAddress   string
 	receiverAddress string
 	fee             int64
+	feeOracle       *FeeOracle      // optional, overrides fee (see --fee auto|min|fast)
+	ctx             context.Context // optional, stops sends that haven't started yet
 }
 
-// NewRPCSender creates a new RPC sender and verifies account status
+// NewRPCSender creates a new RPC sender using the default signer (see
+// GetDefaultSigner) and verifies the signer can sign for senderAddress
 func NewRPCSender(rpcURL, senderAddress, receiverAddress string, fee int64) (*RPCSender, error) {
 	rpc := NewNimiqRPC(rpcURL)
+	signer, err := NewSigner(GetDefaultSigner(), rpc, senderAddress)
+	if err != nil {
+		return nil, err
+	}
+	return NewRPCSenderWithSigner(rpc, signer, receiverAddress, fee), nil
+}
 
+// NewRPCSenderWithSigner creates a sender that signs with signer and
+// broadcasts through rpc
+func NewRPCSenderWithSigner(rpc *NimiqRPC, signer Signer, receiverAddress string, fee int64) *RPCSender {
 	// Default receiver address if not provided
 	if receiverAddress == "" {
 		receiverAddress = "NQ27 21G6 9BG1 JBHJ NUFA YVJS 1R6C D2X0 QAES"
 	}
 
-	sender := &RPCSender{
+	return &RPCSender{
 		rpc:             rpc,
-		senderAddress:   senderAddress,
+		signer:          signer,
+		broadcaster:     rpc,
+		senderAddress:   signer.Address(),
 		receiverAddress: receiverAddress,
 		fee:             fee,
 	}
+}
+
+// WithContext makes the sender refuse new transactions once ctx is cancelled.
+// A transaction that is already being broadcast is allowed to finish, so its
+// hash can still be recorded.
+func (r *RPCSender) WithContext(ctx context.Context) *RPCSender {
+	r.ctx = ctx
+	return r
+}
+
+// prepareSend checks consensus and returns the validityStartHeight for the next
+// transaction. It fails with the context error once the sender was cancelled.
+func (r *RPCSender) prepareSend() (int64, error) {
+	checkRPC := r.rpc
+	if r.ctx != nil {
+		checkRPC = r.rpc.WithContext(r.ctx)
+	}
 
-	// Check if account is imported
-	imported, err := rpc.IsAccountImported(senderAddress)
+	// Check consensus before sending transaction
+	consensus, err := checkRPC.IsConsensusEstablished()
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if account is imported: %w", err)
+		return 0, fmt.Errorf("failed to check consensus: %w", err)
 	}
-	if !imported {
-		return nil, fmt.Errorf("account %s is not imported. Use 'account import' command first", senderAddress)
+	if !consensus {
+		return 0, fmt.Errorf("node does not have consensus with the network - cannot send transaction")
 	}
 
-	// Check if account is unlocked
-	unlocked, err := rpc.IsAccountUnlocked(senderAddress)
+	// Get current block height for validityStartHeight
+	blockHeight, err := checkRPC.GetBlockNumber()
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if account is unlocked: %w", err)
+		return 0, fmt.Errorf("failed to get block height: %w", err)
 	}
-	if !unlocked {
-		return nil, fmt.Errorf("account %s is locked. Please unlock it first", senderAddress)
+
+	// Last chance to back out before the transaction leaves this process
+	if r.ctx != nil && r.ctx.Err() != nil {
+		return 0, r.ctx.Err()
 	}
 
-	return sender, nil
+	return blockHeight, nil
 }
 
-func (r *RPCSender) SendTransaction(payload []byte) (string, error) {
-	// Check consensus before sending transaction
-	consensus, err := r.rpc.IsConsensusEstablished()
-	if err != nil {
-		return "", fmt.Errorf("failed to check consensus: %w", err)
+// WithFeeOracle makes the sender ask oracle for the fee of every transaction
+func (r *RPCSender) WithFeeOracle(oracle *FeeOracle) *RPCSender {
+	r.feeOracle = oracle
+	return r
+}
+
+// currentFee returns the fee for a transaction sent for the attempt-th time
+// (0 = first send)
+func (r *RPCSender) currentFee(attempt int) (int64, error) {
+	if r.feeOracle == nil {
+		return r.fee, nil
 	}
-	if !consensus {
-		return "", fmt.Errorf("node does not have consensus with the network - cannot send transaction")
+	fee, err := r.feeOracle.FeeForAttempt(attempt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine fee: %w", err)
 	}
+	return fee, nil
+}
 
-	// Get current block height for validityStartHeight
-	blockHeight, err := r.rpc.GetBlockNumber()
+func (r *RPCSender) SendTransaction(payload []byte) (string, error) {
+	txHash, _, err := r.ResendTransaction(payload, 0)
+	return txHash, err
+}
+
+// ResendTransaction sends payload again after attempt earlier sends failed or
+// expired; the fee oracle (if any) escalates the fee accordingly
+func (r *RPCSender) ResendTransaction(payload []byte, attempt int) (string, int64, error) {
+	fee, err := r.currentFee(attempt)
 	if err != nil {
-		return "", fmt.Errorf("failed to get block height: %w", err)
+		return "", 0, err
 	}
 
-	// Encode payload as hex string
-	dataHex := hex.EncodeToString(payload)
+	blockHeight, err := r.prepareSend()
+	if err != nil {
+		return "", 0, err
+	}
 
-	// Send transaction with data
 	// Value must be > 0 for transactions with data (RPC requirement: "value must be zero for signaling transactions and cannot be zero for others")
 	// Use 1 Luna (smallest unit) as the value
-	txHash, err := r.rpc.SendBasicTransactionWithData(
-		r.senderAddress,   // wallet (sender)
-		r.receiverAddress, // recipient (receiver address)
-		dataHex,           // data (hex-encoded payload)
-		1,                 // value (1 Luna - minimum required for data transactions)
-		r.fee,             // fee (configurable)
-		blockHeight,       // validityStartHeight
-	)
+	txHash, err := r.signAndBroadcast(&UnsignedTx{
+		From:                r.senderAddress,
+		To:                  r.receiverAddress,
+		Value:               txValue,
+		Fee:                 fee,
+		Data:                payload,
+		ValidityStartHeight: blockHeight,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to send transaction: %w", err)
+		return "", 0, err
 	}
 
 	// Transaction sent successfully
 	fmt.Printf("Transaction sent to %s: %s\n", r.receiverAddress, txHash)
+	return txHash, blockHeight, nil
+}
+
+// SendValue transfers value Luna (no data) to the receiver address
+func (r *RPCSender) SendValue(value int64) (string, error) {
+	fee, err := r.currentFee(0)
+	if err != nil {
+		return "", err
+	}
+
+	blockHeight, err := r.prepareSend()
+	if err != nil {
+		return "", err
+	}
+
+	return r.signAndBroadcast(&UnsignedTx{
+		From:                r.senderAddress,
+		To:                  r.receiverAddress,
+		Value:               value,
+		Fee:                 fee,
+		ValidityStartHeight: blockHeight,
+	})
+}
+
+// signAndBroadcast signs tx with the sender's signer and broadcasts it
+func (r *RPCSender) signAndBroadcast(tx *UnsignedTx) (string, error) {
+	rawTx, err := r.signer.SignTransaction(tx)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	txHash, err := r.broadcaster.SendRawTransaction(rawTx)
+	if err != nil {
+		return "", fmt.Errorf("failed to send transaction: %w", err)
+	}
 	return txHash, nil
 }
+
+// SenderPool spreads transactions round-robin over several sender accounts.
+// Each account is its own RPCSender, so their signers are checked up front and their nonces never compete.
+type SenderPool struct {
+	senders []*RPCSender
+	next    uint64
+	faults  *FaultInjector // optional, see WithFaults
+}
+
+// NewSenderPool creates one RPC sender per address, all sending to receiverAddress
+func NewSenderPool(rpcURL string, senderAddresses []string, receiverAddress string, fee int64) (*SenderPool, error) {
+	pool := &SenderPool{}
+	for _, addr := range dedupeAddresses(senderAddresses) {
+		sender, err := NewRPCSender(rpcURL, addr, receiverAddress, fee)
+		if err != nil {
+			return nil, fmt.Errorf("sender %s: %w", addr, err)
+		}
+		pool.senders = append(pool.senders, sender)
+	}
+	if len(pool.senders) == 0 {
+		return nil, fmt.Errorf("sender pool is empty")
+	}
+	return pool, nil
+}
+
+// WithContext applies ctx to every sender in the pool (see RPCSender.WithContext)
+func (p *SenderPool) WithContext(ctx context.Context) *SenderPool {
+	for _, sender := range p.senders {
+		sender.WithContext(ctx)
+	}
+	return p
+}
+
+// WithFeeOracle applies oracle to every sender in the pool (see RPCSender.WithFeeOracle)
+func (p *SenderPool) WithFeeOracle(oracle *FeeOracle) *SenderPool {
+	for _, sender := range p.senders {
+		sender.WithFeeOracle(oracle)
+	}
+	return p
+}
+
+// WithFaults injects the faults of f into every send from the pool
+func (p *SenderPool) WithFaults(f *FaultInjector) *SenderPool {
+	p.faults = f
+	return p
+}
+
+// Addresses returns the sender addresses in the pool
+func (p *SenderPool) Addresses() []string {
+	addresses := make([]string, len(p.senders))
+	for i, sender := range p.senders {
+		addresses[i] = sender.senderAddress
+	}
+	return addresses
+}
+
+// SendFrom sends payload from the next account in the pool and returns the
+// address it was sent from along with the transaction hash and its validity
+// start height. attempt is the number of earlier failed sends of this payload
+// (see RPCSender.ResendTransaction).
+func (p *SenderPool) SendFrom(payload []byte, attempt int) (string, string, int64, error) {
+	sender := p.senders[(atomic.AddUint64(&p.next, 1)-1)%uint64(len(p.senders))]
+	if p.faults != nil {
+		txHash, validFrom, err := NewFaultSender(sender, p.faults).ResendTransaction(payload, attempt)
+		return sender.senderAddress, txHash, validFrom, err
+	}
+	txHash, validFrom, err := sender.ResendTransaction(payload, attempt)
+	return sender.senderAddress, txHash, validFrom, err
+}
+
+func (p *SenderPool) SendTransaction(payload []byte) (string, error) {
+	_, txHash, _, err := p.SendFrom(payload, 0)
+	return txHash, err
+}
+
+// dedupeAddresses drops empty and repeated addresses (ignoring spacing and case)
+func dedupeAddresses(addresses []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, addr := range addresses {
+		key := normalizeAddress(addr)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, addr)
+	}
+	return result
+}
+
+// parseAddressList splits a comma-separated list of addresses (as used by
+// --senders and the senders config entry), ignoring empty entries
+func parseAddressList(list string) []string {
+	var addresses []string
+	for _, addr := range strings.Split(list, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addresses = append(addresses, addr)
+		}
+	}
+	return addresses
+}
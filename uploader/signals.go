@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// newSignalContext returns a context that is cancelled on the first SIGINT or
+// SIGTERM. Long-running commands watch it to stop picking up new work, let
+// in-flight transactions finish and flush their progress. A second signal
+// exits immediately.
+func newSignalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			fmt.Fprintf(os.Stderr, "\n⚠️  Received %s - finishing in-flight transactions and saving progress...\n", sig)
+			fmt.Fprintf(os.Stderr, "   Press Ctrl-C again to force quit.\n")
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+
+		sig := <-signals
+		fmt.Fprintf(os.Stderr, "\n❌ Received %s again - forcing exit. Progress recorded so far is kept.\n", sig)
+		os.Exit(130)
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// resumeCommand rebuilds the current command line for resuming an interrupted
+// run. Flags listed in pinned are replaced by (or added with) the given values,
+// and boolean flags listed in drop are removed, so values that were generated during
+// this run (app-id, cartridge address, ...) are reused instead of regenerated.
+func resumeCommand(args []string, pinned map[string]string, drop ...string) string {
+	skip := make(map[string]bool)
+	for name := range pinned {
+		skip["--"+name] = true
+	}
+	for _, name := range drop {
+		skip["--"+name] = true
+	}
+
+	parts := []string{"nimiq-uploader"}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name := arg
+		hasValue := false
+		if eq := strings.Index(arg, "="); eq >= 0 {
+			name = arg[:eq]
+			hasValue = true
+		}
+		if skip[name] {
+			// Pinned flags take a value; skip it too if it was passed separately
+			if _, isPinned := pinned[strings.TrimPrefix(name, "--")]; isPinned && !hasValue && i+1 < len(args) {
+				i++
+			}
+			continue
+		}
+		parts = append(parts, shellQuote(arg))
+	}
+
+	// Sort pinned flags to keep the output stable
+	names := make([]string, 0, len(pinned))
+	for name := range pinned {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, "--"+name, shellQuote(pinned[name]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quotes s for a POSIX shell if it contains anything unsafe
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=,@+", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sleepContext sleeps for d, returning early with ctx.Err() if ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
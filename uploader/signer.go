@@ -0,0 +1,495 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// externalSignerTimeout bounds how long an external signer may take to answer
+// (long enough for a confirmation on a hardware device)
+const externalSignerTimeout = 2 * time.Minute
+
+// signerFlag is the root --signer flag (see GetDefaultSigner)
+var signerFlag string
+
+// UnsignedTx is a basic-account transaction waiting for a signature
+type UnsignedTx struct {
+	From                string
+	To                  string
+	Value               int64
+	Fee                 int64
+	Data                []byte // recipient data, empty for plain transfers
+	ValidityStartHeight int64
+}
+
+// Signer turns unsigned transactions into serialized, signed transactions
+// (hex) ready for sendRawTransaction
+type Signer interface {
+	Address() string
+	SignTransaction(tx *UnsignedTx) (string, error)
+}
+
+// Broadcaster sends signed transactions to the network. *NimiqRPC is one.
+type Broadcaster interface {
+	SendRawTransaction(rawTx string) (string, error)
+}
+
+// GetDefaultSigner returns the signer spec from --signer, or else the signer
+// setting (see ResolveSetting: --profile, NIMIQ_SIGNER, the active profile,
+// the credentials file), or node (the node wallet)
+func GetDefaultSigner() string {
+	if signerFlag != "" {
+		return signerFlag
+	}
+	spec, _, _ := ResolveSetting("signer")
+	return spec
+}
+
+// defaultNetworkID returns the network signers outside the node sign for, from
+// NIMIQ_NETWORK, the active profile or network in the credentials file
+func defaultNetworkID() (uint8, error) {
+	network, _, _ := ResolveSetting("network")
+	if network == "" {
+		return 0, fmt.Errorf("network is not set (set NIMIQ_NETWORK, or network in the profile or credentials, to main or test)")
+	}
+	return ParseNetworkID(network)
+}
+
+// NewSigner creates the signer for address described by spec:
+//
+//	node          the node wallet signs (account must be imported and unlocked)
+//	key           the private key from the credentials file, signed in-process
+//	exec:<cmd>    an external command speaking the JSON signer protocol
+func NewSigner(spec string, rpc *NimiqRPC, address string) (Signer, error) {
+	switch {
+	case spec == "" || spec == "node":
+		return NewNodeWalletSigner(rpc, address)
+	case spec == "key":
+		return NewKeySigner(address)
+	case strings.HasPrefix(spec, "exec:"):
+		return NewExternalSigner(strings.TrimSpace(strings.TrimPrefix(spec, "exec:")), address)
+	}
+	return nil, fmt.Errorf("unknown signer %q (use node, key or exec:<command>)", spec)
+}
+
+// NodeWalletSigner signs with an account imported into the node's wallet
+type NodeWalletSigner struct {
+	rpc     *NimiqRPC
+	address string
+}
+
+// NewNodeWalletSigner verifies the account is imported and unlocked in the node
+func NewNodeWalletSigner(rpc *NimiqRPC, address string) (*NodeWalletSigner, error) {
+	// Check if account is imported
+	imported, err := rpc.IsAccountImported(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if account is imported: %w", err)
+	}
+	if !imported {
+		return nil, fmt.Errorf("account %s is not imported. Use 'account import' command first", address)
+	}
+
+	// Check if account is unlocked
+	unlocked, err := rpc.IsAccountUnlocked(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if account is unlocked: %w", err)
+	}
+	if !unlocked {
+		return nil, fmt.Errorf("account %s is locked. Please unlock it first", address)
+	}
+
+	return &NodeWalletSigner{rpc: rpc, address: address}, nil
+}
+
+func (s *NodeWalletSigner) Address() string {
+	return s.address
+}
+
+func (s *NodeWalletSigner) SignTransaction(tx *UnsignedTx) (string, error) {
+	if len(tx.Data) == 0 {
+		return s.rpc.CreateBasicTransaction(tx.From, tx.To, tx.Value, tx.Fee, tx.ValidityStartHeight)
+	}
+	return s.rpc.CreateBasicTransactionWithData(tx.From, tx.To, hex.EncodeToString(tx.Data), tx.Value, tx.Fee, tx.ValidityStartHeight)
+}
+
+// KeySigner signs in-process with a private key (for --signer key, the one
+// from the credentials file)
+type KeySigner struct {
+	key       *SigningKey
+	networkID uint8
+}
+
+// NewKeySigner loads the credentials private key, which must belong to address
+func NewKeySigner(address string) (*KeySigner, error) {
+	creds, err := LoadCredentialSecrets("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credentials: %w", err)
+	}
+	if creds["PRIVATE_KEY"] == "" {
+		return nil, fmt.Errorf("no private key in credentials (needed by --signer key)")
+	}
+	key, err := NewSigningKey(&AccountInfo{Address: address, PrivateKey: creds["PRIVATE_KEY"]})
+	if err != nil {
+		return nil, err
+	}
+	networkID, err := defaultNetworkID()
+	if err != nil {
+		return nil, err
+	}
+	return &KeySigner{key: key, networkID: networkID}, nil
+}
+
+func (s *KeySigner) Address() string {
+	return s.key.Address
+}
+
+func (s *KeySigner) SignTransaction(tx *UnsignedTx) (string, error) {
+	raw, err := NewRawTransaction(tx.From, tx.To, tx.Data, tx.Value, tx.Fee, uint32(tx.ValidityStartHeight), s.networkID)
+	if err != nil {
+		return "", err
+	}
+	signed, err := raw.Sign(s.key)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(signed), nil
+}
+
+// ExternalSignerRequest is one line written to the external signer's stdin
+type ExternalSignerRequest struct {
+	ID         int               `json:"id"`
+	Method     string            `json:"method"` // "check" or "sign"
+	Address    string            `json:"address,omitempty"`
+	Tx         *ExternalSignerTx `json:"tx,omitempty"`
+	ContentHex string            `json:"content_hex,omitempty"` // the exact bytes to sign (Ed25519)
+}
+
+// ExternalSignerTx describes the transaction in a sign request
+type ExternalSignerTx struct {
+	From                string `json:"from"`
+	To                  string `json:"to"`
+	Value               int64  `json:"value"`
+	Fee                 int64  `json:"fee"`
+	DataHex             string `json:"data_hex"`
+	ValidityStartHeight int64  `json:"validity_start_height"`
+	NetworkID           uint8  `json:"network_id"`
+}
+
+// ExternalSignerResponse is one line read from the external signer's stdout.
+// A sign response carries either raw_hex (the serialized transaction) or
+// public_key + signature, from which the transaction is assembled here.
+type ExternalSignerResponse struct {
+	ID        int    `json:"id"`
+	Address   string `json:"address,omitempty"`
+	RawHex    string `json:"raw_hex,omitempty"`
+	PublicKey string `json:"public_key,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// signerProcess is a running external signer, shared by every address it signs for
+type signerProcess struct {
+	command string
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	lines   chan string
+	nextID  int
+	broken  error
+}
+
+var (
+	signerProcessesMu sync.Mutex
+	signerProcesses   = make(map[string]*signerProcess)
+)
+
+// startSignerProcess starts command (via sh -c) once and reuses it afterwards
+func startSignerProcess(command string) (*signerProcess, error) {
+	signerProcessesMu.Lock()
+	defer signerProcessesMu.Unlock()
+	if p, ok := signerProcesses[command]; ok && p.broken == nil {
+		return p, nil
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr // prompts and diagnostics of the signer
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start external signer: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start external signer: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start external signer: %w", err)
+	}
+
+	p := &signerProcess{command: command, cmd: cmd, stdin: stdin, lines: make(chan string)}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			p.lines <- scanner.Text()
+		}
+		close(p.lines)
+	}()
+	signerProcesses[command] = p
+	return p, nil
+}
+
+// request sends one request and waits for the response with the same id
+func (p *signerProcess) request(req *ExternalSignerRequest) (*ExternalSignerResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.broken != nil {
+		return nil, p.broken
+	}
+
+	p.nextID++
+	req.ID = p.nextID
+	line, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		return nil, p.fail(fmt.Errorf("external signer is not accepting requests: %w", err))
+	}
+
+	timeout := time.After(externalSignerTimeout)
+	for {
+		select {
+		case out, ok := <-p.lines:
+			if !ok {
+				return nil, p.fail(fmt.Errorf("external signer exited"))
+			}
+			var resp ExternalSignerResponse
+			if err := json.Unmarshal([]byte(out), &resp); err != nil {
+				return nil, p.fail(fmt.Errorf("external signer sent invalid JSON: %w", err))
+			}
+			if resp.ID != req.ID {
+				continue // stale answer to a request that timed out
+			}
+			if resp.Error != "" {
+				return nil, fmt.Errorf("external signer: %s", resp.Error)
+			}
+			return &resp, nil
+		case <-timeout:
+			return nil, p.fail(fmt.Errorf("external signer did not answer within %s", externalSignerTimeout))
+		}
+	}
+}
+
+// fail stops a misbehaving signer; the next NewExternalSigner starts a fresh one
+func (p *signerProcess) fail(err error) error {
+	p.broken = err
+	p.stdin.Close()
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+	go p.cmd.Wait()
+	return err
+}
+
+// ExternalSigner delegates signing to an external command (hardware wallet
+// bridge, remote signing service, ...). See README "External signer protocol".
+type ExternalSigner struct {
+	proc      *signerProcess
+	address   string
+	networkID uint8
+}
+
+// NewExternalSigner starts (or reuses) command and asks it whether it can sign for address
+func NewExternalSigner(command, address string) (*ExternalSigner, error) {
+	if command == "" {
+		return nil, fmt.Errorf("exec signer needs a command (exec:<command>)")
+	}
+	networkID, err := defaultNetworkID()
+	if err != nil {
+		return nil, err
+	}
+	proc, err := startSignerProcess(command)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := proc.request(&ExternalSignerRequest{Method: "check", Address: address})
+	if err != nil {
+		return nil, fmt.Errorf("account %s: %w", address, err)
+	}
+	if normalizeAddress(resp.Address) != normalizeAddress(address) {
+		return nil, fmt.Errorf("external signer answered for %s, not %s", resp.Address, address)
+	}
+	return &ExternalSigner{proc: proc, address: address, networkID: networkID}, nil
+}
+
+func (s *ExternalSigner) Address() string {
+	return s.address
+}
+
+func (s *ExternalSigner) SignTransaction(tx *UnsignedTx) (string, error) {
+	raw, err := NewRawTransaction(tx.From, tx.To, tx.Data, tx.Value, tx.Fee, uint32(tx.ValidityStartHeight), s.networkID)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.proc.request(&ExternalSignerRequest{
+		Method: "sign",
+		Tx: &ExternalSignerTx{
+			From:                tx.From,
+			To:                  tx.To,
+			Value:               tx.Value,
+			Fee:                 tx.Fee,
+			DataHex:             hex.EncodeToString(tx.Data),
+			ValidityStartHeight: tx.ValidityStartHeight,
+			NetworkID:           s.networkID,
+		},
+		ContentHex: hex.EncodeToString(raw.content()),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if resp.RawHex != "" {
+		return checkSignedRawHex(raw, resp.RawHex)
+	}
+	publicKey, err := hex.DecodeString(resp.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("external signer sent an invalid public key: %w", err)
+	}
+	signature, err := hex.DecodeString(resp.Signature)
+	if err != nil {
+		return "", fmt.Errorf("external signer sent an invalid signature: %w", err)
+	}
+	signed, err := raw.Assemble(ed25519.PublicKey(publicKey), signature)
+	if err != nil {
+		return "", fmt.Errorf("external signer: %w", err)
+	}
+	return hex.EncodeToString(signed), nil
+}
+
+// checkSignedRawHex decodes a transaction an external signer serialized
+// itself and refuses it unless it is exactly the requested one, validly signed
+// by the sender
+func checkSignedRawHex(requested *RawTransaction, rawHex string) (string, error) {
+	rawBytes, err := hex.DecodeString(strings.TrimPrefix(rawHex, "0x"))
+	if err != nil {
+		return "", fmt.Errorf("external signer sent invalid raw_hex: %w", err)
+	}
+	signed, publicKey, signature, err := ParseRawTransaction(rawBytes)
+	if err != nil {
+		return "", fmt.Errorf("external signer sent an undecodable transaction: %w", err)
+	}
+	if diffs := requested.Diff(signed); len(diffs) > 0 {
+		return "", fmt.Errorf("external signer signed a different transaction (%s)", strings.Join(diffs, ", "))
+	}
+	serialized, err := requested.Assemble(publicKey, signature)
+	if err != nil {
+		return "", fmt.Errorf("external signer: %w", err)
+	}
+	return hex.EncodeToString(serialized), nil
+}
+
+// newSignerStubCmd is a reference implementation of the external signer
+// protocol backed by a local key file, for tests and as a template
+func newSignerStubCmd() *cobra.Command {
+	var (
+		keyFile       string
+		keyPassphrase string
+		rawOutput     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "signer-stub",
+		Short: "External signer backed by a local key file (for --signer exec:...)",
+		Long: `Speaks the external signer protocol on stdin/stdout, signing with a key file
+created by 'offline-key create'. Use it to test --signer exec:<command> setups:
+
+  nimiq-uploader --signer "exec:nimiq-uploader signer-stub --key-file key.json" upload-cartridge ...
+
+By default it answers with public_key + signature (like a hardware wallet);
+--raw makes it answer with the full serialized transaction instead.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := LoadSigningKey(keyFile, resolveKeyPassphrase(keyPassphrase))
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			scanner := bufio.NewScanner(os.Stdin)
+			scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				var req ExternalSignerRequest
+				if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+					encoder.Encode(&ExternalSignerResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+					continue
+				}
+				encoder.Encode(stubAnswer(key, &req, rawOutput))
+			}
+			return scanner.Err()
+		},
+	}
+
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "Key file to sign with (see 'offline-key create')")
+	cmd.Flags().StringVar(&keyPassphrase, "key-passphrase", "", "Passphrase of the key file (defaults to NIMIQ_KEY_PASSPHRASE or the account passphrase)")
+	cmd.Flags().BoolVar(&rawOutput, "raw", false, "Answer with raw_hex instead of public_key + signature")
+	cmd.MarkFlagRequired("key-file")
+
+	return cmd
+}
+
+// stubAnswer handles one signer-stub request
+func stubAnswer(key *SigningKey, req *ExternalSignerRequest, rawOutput bool) *ExternalSignerResponse {
+	resp := &ExternalSignerResponse{ID: req.ID}
+	switch req.Method {
+	case "check":
+		if normalizeAddress(req.Address) != normalizeAddress(key.Address) {
+			resp.Error = fmt.Sprintf("no key for %s", req.Address)
+			return resp
+		}
+		resp.Address = key.Address
+	case "sign":
+		if req.Tx == nil {
+			resp.Error = "sign request without tx"
+			return resp
+		}
+		data, err := hex.DecodeString(req.Tx.DataHex)
+		if err != nil {
+			resp.Error = fmt.Sprintf("invalid data_hex: %v", err)
+			return resp
+		}
+		raw, err := NewRawTransaction(req.Tx.From, req.Tx.To, data, req.Tx.Value, req.Tx.Fee, uint32(req.Tx.ValidityStartHeight), req.Tx.NetworkID)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		// Sign what the transaction says, never blindly what content_hex says
+		if hex.EncodeToString(raw.content()) != strings.ToLower(req.ContentHex) {
+			resp.Error = "content_hex does not match tx"
+			return resp
+		}
+		signed, err := raw.Sign(key)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		if rawOutput {
+			resp.RawHex = hex.EncodeToString(signed)
+		} else {
+			resp.PublicKey = hex.EncodeToString(key.PublicKey)
+			resp.Signature = hex.EncodeToString(signed[len(signed)-ed25519.SignatureSize:])
+		}
+	default:
+		resp.Error = fmt.Sprintf("unknown method %q", req.Method)
+	}
+	return resp
+}
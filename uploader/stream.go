@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultMaxFileSize is the default upload size limit (--max-size)
+	DefaultMaxFileSize = 6 * 1024 * 1024 // 6MB
+
+	// softMaxChunks is the chunk count above which we warn that the upload
+	// (and reconstructing it in the browser) will take a long time
+	softMaxChunks = 1_000_000
+)
+
+// ChunkSource is the input of an upload: anything chunks can be read from at
+// arbitrary offsets, so the whole file never has to be held in memory
+type ChunkSource struct {
+	io.ReaderAt
+	Name string
+	Size int64
+
+	closer func() error
+}
+
+// OpenChunkSource opens path for chunking. "-" reads from stdin, which is
+// spooled to a temporary file first so it can be resumed and re-read like a file.
+// Inputs larger than maxSize are refused (maxSize <= 0 means no limit).
+func OpenChunkSource(path string, maxSize int64) (*ChunkSource, error) {
+	if path == "-" {
+		return spoolStdin(maxSize)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if maxSize > 0 && info.Size() > maxSize {
+		file.Close()
+		return nil, fmt.Errorf("file size (%d bytes) exceeds maximum allowed size of %s (%d bytes); raise it with --max-size", info.Size(), formatByteSize(maxSize), maxSize)
+	}
+
+	return &ChunkSource{ReaderAt: file, Name: path, Size: info.Size(), closer: file.Close}, nil
+}
+
+// spoolStdin copies stdin to a temporary file (which is removed on Close)
+func spoolStdin(maxSize int64) (*ChunkSource, error) {
+	tmp, err := os.CreateTemp("", "nimiq-uploader-stdin-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file for stdin: %w", err)
+	}
+	cleanup := func() error {
+		tmp.Close()
+		return os.Remove(tmp.Name())
+	}
+
+	var reader io.Reader = os.Stdin
+	if maxSize > 0 {
+		// Read one byte past the limit so oversized input can be detected
+		reader = io.LimitReader(os.Stdin, maxSize+1)
+	}
+	size, err := io.Copy(tmp, reader)
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if maxSize > 0 && size > maxSize {
+		cleanup()
+		return nil, fmt.Errorf("stdin exceeds maximum allowed size of %s (%d bytes); raise it with --max-size", formatByteSize(maxSize), maxSize)
+	}
+
+	return &ChunkSource{ReaderAt: tmp, Name: "<stdin>", Size: size, closer: cleanup}, nil
+}
+
+// Close releases the source (and removes the stdin spool file, if any)
+func (s *ChunkSource) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer()
+}
+
+// ChunkCount returns the number of chunks of chunkSize bytes needed for size bytes
+func ChunkCount(size int64, chunkSize uint8) int64 {
+	if size <= 0 || chunkSize == 0 {
+		return 0
+	}
+	return (size + int64(chunkSize) - 1) / int64(chunkSize)
+}
+
+// ValidateChunkLayout checks that size can be represented in the CART/DATA
+// fields with the given chunk size and returns the chunk count. It returns a
+// non-empty warning for uploads that are valid but unusually large.
+func ValidateChunkLayout(size int64, chunkSize uint8) (int64, string, error) {
+	if chunkSize == 0 || chunkSize > ChunkSize {
+		return 0, "", fmt.Errorf("chunk size must be between 1 and %d bytes (got %d)", ChunkSize, chunkSize)
+	}
+	if size == 0 {
+		return 0, "", fmt.Errorf("file is empty")
+	}
+
+	chunks := ChunkCount(size, chunkSize)
+	// chunk_index is a u32, so indices 0..MaxUint32 are available
+	if chunks > math.MaxUint32+1 {
+		return 0, "", fmt.Errorf("file needs %d chunks, more than the %d a cartridge can index (u32 chunk_index)", chunks, int64(math.MaxUint32)+1)
+	}
+
+	warning := ""
+	if chunks > softMaxChunks {
+		warning = fmt.Sprintf("upload needs %d transactions - this will take a long time to send and to reconstruct in the browser", chunks)
+	} else if size > DefaultMaxFileSize {
+		warning = fmt.Sprintf("file is larger than %s (%d transactions)", formatByteSize(DefaultMaxFileSize), chunks)
+	}
+	return chunks, warning, nil
+}
+
+// StreamChunker reads a ChunkSource front to back, handing out chunks and
+// hashing the content in the same pass
+type StreamChunker struct {
+	reader    *bufio.Reader
+	chunkSize int
+	next      uint32
+	remaining int64
+	hash      hash.Hash
+}
+
+// NewStreamChunker creates a chunker over src
+func NewStreamChunker(src *ChunkSource, chunkSize uint8) *StreamChunker {
+	return &StreamChunker{
+		reader:    bufio.NewReaderSize(io.NewSectionReader(src, 0, src.Size), 64*1024),
+		chunkSize: int(chunkSize),
+		remaining: src.Size,
+		hash:      sha256.New(),
+	}
+}
+
+// Next returns the next chunk index and its data, or io.EOF after the last chunk
+func (c *StreamChunker) Next() (uint32, []byte, error) {
+	if c.remaining <= 0 {
+		return 0, nil, io.EOF
+	}
+
+	n := c.chunkSize
+	if int64(n) > c.remaining {
+		n = int(c.remaining)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(c.reader, data); err != nil {
+		return 0, nil, fmt.Errorf("failed to read chunk %d: %w", c.next, err)
+	}
+	c.hash.Write(data)
+	c.remaining -= int64(n)
+
+	idx := c.next
+	c.next++
+	return idx, data, nil
+}
+
+// Sum returns the SHA256 of everything read so far (the whole file once Next returned io.EOF)
+func (c *StreamChunker) Sum() [32]byte {
+	var sum [32]byte
+	copy(sum[:], c.hash.Sum(nil))
+	return sum
+}
+
+// checkSentChunk compares the data read for an already-sent chunk with the
+// payload it was sent with and returns an error if the file is not the one
+// it was read from
+func checkSentChunk(sent UploadPlan, cartridgeID uint32, data []byte) error {
+	if sent.Payload == "" {
+		return nil // journaled before payloads were recorded
+	}
+	encoded, err := EncodeDATA(DATAPayload{CartridgeID: cartridgeID, ChunkIndex: sent.Index, Length: uint8(len(data)), Data: data})
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(encoded) != sent.Payload {
+		return fmt.Errorf("file content changed since the previous run (chunk %d differs from the one sent) - use a new --cartridge-id", sent.Index)
+	}
+	return nil
+}
+
+// parseByteSize parses sizes like "6MB", "512KB", "1.5GB" or a plain byte count
+// (binary units: 1KB = 1024 bytes). "0" means no limit.
+func parseByteSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		factor int64
+	}{
+		{"GB", 1024 * 1024 * 1024},
+		{"MB", 1024 * 1024},
+		{"KB", 1024},
+		{"G", 1024 * 1024 * 1024},
+		{"M", 1024 * 1024},
+		{"K", 1024},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(str, unit.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, unit.suffix))
+			multiplier = unit.factor
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size: %q (use e.g. 6MB, 512KB or a byte count)", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// formatByteSize formats a byte count with a binary unit
+func formatByteSize(n int64) string {
+	switch {
+	case n >= 1024*1024*1024:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1024*1024*1024))
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1fKB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
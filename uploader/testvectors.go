@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// DefaultVectorsFile is where the shared payload test vectors are kept
+// (relative to the uploader directory)
+const DefaultVectorsFile = "testdata/payload-vectors.json"
+
+// PayloadVectorFile is the canonical set of payload test vectors shared by
+// the Go codec, web/src/utils/payloads.js and scripts/generate-payloads.js
+type PayloadVectorFile struct {
+	Comment string          `json:"comment"`
+	Version int             `json:"version"`
+	Vectors []PayloadVector `json:"vectors"`
+}
+
+// PayloadVector is one 64-byte record with its decoded fields. Field names
+// match the objects returned by the JS parsers (parseCART, parseDATA, ...);
+// byte strings are hex and addresses are NQ... without spaces.
+type PayloadVector struct {
+	Name   string                 `json:"name"`
+	Type   string                 `json:"type"` // record magic: CART, DATA, CENT, CSGN or DOOM
+	Hex    string                 `json:"hex"`
+	Valid  bool                   `json:"valid"`            // false: every decoder must reject it
+	Fields map[string]interface{} `json:"fields,omitempty"` // valid vectors only
+	Note   string                 `json:"note,omitempty"`
+}
+
+// vectorAddress formats an address the way the JS decoders return it
+func vectorAddress(addr [20]byte) string {
+	return strings.ReplaceAll(AddressBytesToNQ(addr), " ", "")
+}
+
+// decodeVector decodes payload as a record of type typ and returns its
+// fields along with the payload encoded again from the decoded record
+func decodeVector(typ string, payload []byte) (map[string]interface{}, []byte, error) {
+	switch typ {
+	case MagicCART:
+		header, err := DecodeCART(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		encoded, err := EncodeCART(header)
+		return map[string]interface{}{
+			"schema":      header.Schema,
+			"platform":    header.Platform,
+			"chunkSize":   header.ChunkSize,
+			"flags":       header.Flags,
+			"cartridgeId": header.CartridgeID,
+			"totalSize":   header.TotalSize,
+			"sha256":      hex.EncodeToString(header.SHA256[:]),
+		}, encoded, err
+
+	case MagicDATA:
+		chunk, err := DecodeDATA(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		encoded, err := EncodeDATA(chunk)
+		return map[string]interface{}{
+			"cartridgeId": chunk.CartridgeID,
+			"chunkIndex":  chunk.ChunkIndex,
+			"len":         chunk.Length,
+			"data":        hex.EncodeToString(chunk.Data),
+		}, encoded, err
+
+	case MagicCENT:
+		entry, err := DecodeCENT(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		encoded, err := EncodeCENT(entry)
+		return map[string]interface{}{
+			"schema":           entry.Schema,
+			"platform":         entry.Platform,
+			"flags":            entry.Flags,
+			"appId":            entry.AppID,
+			"semver":           fmt.Sprintf("%d.%d.%d", entry.Semver[0], entry.Semver[1], entry.Semver[2]),
+			"cartridgeAddress": vectorAddress(entry.CartridgeAddr),
+			"title":            entry.TitleShort,
+		}, encoded, err
+
+	case MagicCSGN:
+		record, err := DecodeCSGN(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		encoded, err := EncodeCSGN(record)
+		coSigners := make([]string, len(record.CoSigners))
+		for i, addr := range record.CoSigners {
+			coSigners[i] = vectorAddress(addr)
+		}
+		return map[string]interface{}{
+			"cartridgeId": record.CartridgeID,
+			"coSigners":   coSigners,
+		}, encoded, err
+
+	case MagicDOOM:
+		chunk, err := DecodePayload(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		encoded, err := EncodePayload(chunk)
+		return map[string]interface{}{
+			"gameId": chunk.GameID,
+			"index":  chunk.Index,
+			"len":    chunk.Length,
+			"data":   hex.EncodeToString(chunk.Data),
+		}, encoded, err
+	}
+	return nil, nil, fmt.Errorf("unknown record type %q", typ)
+}
+
+// buildPayloadVectors generates the canonical vectors from the Go encoders
+func buildPayloadVectors() (*PayloadVectorFile, error) {
+	sha := sha256.Sum256([]byte("nimiq-doom test vector"))
+	addrA, err := AddressNQToBytes("NQ27 21G6 9BG1 JBHJ NUFA YVJS 1R6C D2X0 QAES")
+	if err != nil {
+		return nil, err
+	}
+	var addrB [20]byte
+	for i := range addrB {
+		addrB[i] = 0xff - byte(i)
+	}
+	sequence := make([]byte, 51)
+	for i := range sequence {
+		sequence[i] = byte(i)
+	}
+
+	type vectorCase struct {
+		name, typ, note string
+		payload         []byte
+		err             error
+	}
+	var records []vectorCase
+	// add(...)(EncodeX(...)) records the result of an encoder
+	add := func(name, typ, note string) func([]byte, error) {
+		return func(payload []byte, err error) {
+			records = append(records, vectorCase{name: name, typ: typ, note: note, payload: payload, err: err})
+		}
+	}
+
+	add("cart-dos", MagicCART, "")(EncodeCART(CARTHeader{Schema: 1, Platform: 0, ChunkSize: ChunkSize, CartridgeID: 1, TotalSize: 2500, SHA256: sha}))
+	add("cart-max-ids", MagicCART, "largest cartridge-id; total size above 32 bits")(EncodeCART(CARTHeader{Schema: 1, Platform: 2, ChunkSize: ChunkSize, Flags: 0x80, CartridgeID: 0xffffffff, TotalSize: 1<<40 + 7, SHA256: sha}))
+	add("data-full", MagicDATA, "")(EncodeDATA(DATAPayload{CartridgeID: 7, ChunkIndex: 0, Length: 51, Data: sequence}))
+	add("data-last-partial", MagicDATA, "last chunk of a file, rest of the record is zero")(EncodeDATA(DATAPayload{CartridgeID: 7, ChunkIndex: 1234567, Length: 3, Data: []byte("end")}))
+	add("data-empty", MagicDATA, "")(EncodeDATA(DATAPayload{CartridgeID: 0xffffffff, ChunkIndex: 0xffffffff}))
+	add("cent-basic", MagicCENT, "")(EncodeCENT(CENTEntry{Schema: 1, Platform: 0, AppID: 1, Semver: [3]uint8{1, 0, 0}, CartridgeAddr: addrA, TitleShort: "Doom"}))
+	add("cent-retired-yanked", MagicCENT, "both flags, largest app-id and semver")(EncodeCENT(CENTEntry{Schema: 1, Platform: 1, Flags: FlagRetired | FlagYanked, AppID: 0xffffffff, Semver: [3]uint8{255, 255, 255}, CartridgeAddr: addrB, TitleShort: "Tetris"}))
+	add("cent-title-max", MagicCENT, "titles are cut to 15 bytes so the 16th stays NUL")(EncodeCENT(CENTEntry{Schema: 1, Platform: 2, AppID: 42, Semver: [3]uint8{0, 9, 12}, CartridgeAddr: addrA, TitleShort: "ABCDEFGHIJKLMNOP"}))
+	add("csgn-one", MagicCSGN, "")(EncodeCSGN(CSGNRecord{CartridgeID: 3, CoSigners: [][20]byte{addrA}}))
+	add("csgn-two", MagicCSGN, "")(EncodeCSGN(CSGNRecord{CartridgeID: 3, CoSigners: [][20]byte{addrA, addrB}}))
+	add("doom-chunk", MagicDOOM, "legacy format (upload command)")(EncodePayload(ChunkPayload{GameID: 42, Index: 3, Length: 10, Data: []byte("DOOM1.WAD\x00")}))
+
+	// Invalid records, derived from valid ones
+	invalid := func(name, typ, note, base string, mutate func(p []byte) []byte) {
+		for _, r := range records {
+			if r.name == base {
+				p := append([]byte(nil), r.payload...)
+				records = append(records, vectorCase{name: name, typ: typ, note: note, payload: mutate(p)})
+				return
+			}
+		}
+	}
+	invalid("cart-short", MagicCART, "records are 64 bytes", "cart-dos", func(p []byte) []byte { return p[:63] })
+	invalid("cart-wrong-magic", MagicCART, "a DATA record is not a CART header", "data-full", func(p []byte) []byte { return p })
+	invalid("data-len-over-51", MagicDATA, "length byte larger than a chunk", "data-full", func(p []byte) []byte { p[12] = 52; return p })
+	invalid("csgn-no-cosigners", MagicCSGN, "count must be 1 or 2", "csgn-one", func(p []byte) []byte { p[8] = 0; return p })
+	invalid("csgn-three-cosigners", MagicCSGN, "count must be 1 or 2", "csgn-two", func(p []byte) []byte { p[8] = 3; return p })
+
+	file := &PayloadVectorFile{
+		Comment: "Generated by 'nimiq-uploader testvectors'. Checked by 'nimiq-uploader testvectors --verify' and 'node scripts/generate-payloads.js --vectors'.",
+		Version: 1,
+	}
+	for _, r := range records {
+		if r.err != nil {
+			return nil, fmt.Errorf("vector %s: %w", r.name, r.err)
+		}
+		vector := PayloadVector{Name: r.name, Type: r.typ, Hex: hex.EncodeToString(r.payload), Note: r.note}
+		fields, _, err := decodeVector(r.typ, r.payload)
+		if err == nil {
+			vector.Valid = true
+			vector.Fields = normalizeVectorFields(fields)
+		}
+		file.Vectors = append(file.Vectors, vector)
+	}
+	return file, nil
+}
+
+// normalizeVectorFields converts fields to their JSON form, so freshly
+// decoded fields compare equal to ones read from a file
+func normalizeVectorFields(fields map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return fields
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return fields
+	}
+	return normalized
+}
+
+// verifyPayloadVector checks one vector against the Go codec
+func verifyPayloadVector(v PayloadVector) error {
+	payload, err := hex.DecodeString(v.Hex)
+	if err != nil {
+		return fmt.Errorf("invalid hex: %w", err)
+	}
+	fields, reencoded, err := decodeVector(v.Type, payload)
+	if !v.Valid {
+		if err == nil {
+			return fmt.Errorf("decoded, but the vector is invalid")
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode: %w", err)
+	}
+	got, _ := json.Marshal(normalizeVectorFields(fields))
+	want, _ := json.Marshal(normalizeVectorFields(v.Fields))
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("decoded fields %s, expected %s", got, want)
+	}
+	if !bytes.Equal(reencoded, payload) {
+		return fmt.Errorf("encodes back to %x", reencoded)
+	}
+	return nil
+}
+
+func marshalVectorFile(file *PayloadVectorFile) ([]byte, error) {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func newTestVectorsCmd() *cobra.Command {
+	var (
+		outFile    string
+		verifyFile string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "testvectors",
+		Short: "Write or verify the payload test vectors shared with the web frontend",
+		Long: `Write the canonical payload test vectors (CART, DATA, CENT, CSGN and the legacy
+DOOM records, valid and invalid) generated by the Go encoders, or verify a
+vectors file against the Go codec.
+
+The same file is checked against the JS encoders and the web decoders by
+scripts/generate-payloads.js, so a layout change in either language fails the
+other's check:
+
+  nimiq-uploader testvectors --out testdata/payload-vectors.json
+  nimiq-uploader testvectors --verify testdata/payload-vectors.json
+  node ../scripts/generate-payloads.js --vectors testdata/payload-vectors.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			generated, err := buildPayloadVectors()
+			if err != nil {
+				return fmt.Errorf("failed to generate test vectors: %w", err)
+			}
+			data, err := marshalVectorFile(generated)
+			if err != nil {
+				return err
+			}
+
+			if verifyFile == "" {
+				if outFile == "" {
+					_, err := os.Stdout.Write(data)
+					return err
+				}
+				if err := os.WriteFile(outFile, data, 0644); err != nil {
+					return fmt.Errorf("failed to write test vectors: %w", err)
+				}
+				fmt.Printf("✅ Wrote %d test vectors to %s\n", len(generated.Vectors), outFile)
+				return nil
+			}
+
+			raw, err := os.ReadFile(verifyFile)
+			if err != nil {
+				return fmt.Errorf("failed to read test vectors: %w", err)
+			}
+			var file PayloadVectorFile
+			if err := json.Unmarshal(raw, &file); err != nil {
+				return fmt.Errorf("failed to parse test vectors: %w", err)
+			}
+
+			failed := 0
+			for _, v := range file.Vectors {
+				if err := verifyPayloadVector(v); err != nil {
+					fmt.Printf("❌ %s (%s): %v\n", v.Name, v.Type, err)
+					failed++
+				}
+			}
+			if !bytes.Equal(raw, data) {
+				fmt.Printf("❌ %s differs from the vectors the Go encoders generate - regenerate it with: nimiq-uploader testvectors --out %s\n", verifyFile, verifyFile)
+				failed++
+			}
+			if failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d test vector check(s) failed", failed)
+			}
+			fmt.Printf("✅ %d test vectors match the Go codec\n", len(file.Vectors))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&outFile, "out", "", "Write the vectors to this file instead of stdout")
+	cmd.Flags().StringVar(&verifyFile, "verify", "", "Verify a vectors file against the Go codec (e.g. "+DefaultVectorsFile+")")
+
+	return cmd
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// lunaPerNIM is the number of Luna in one NIM
+const lunaPerNIM = 100000
+
+// nimToLuna converts a NIM amount to Luna (rounded to the nearest Luna)
+func nimToLuna(nim float64) int64 {
+	return int64(math.Round(nim * lunaPerNIM))
+}
+
+// formatLuna formats an amount the way the balance commands print it
+func formatLuna(luna int64) string {
+	return fmt.Sprintf("%d Luna (%.5f NIM)", luna, float64(luna)/lunaPerNIM)
+}
+
+// transfer is one planned value transfer between our own accounts
+type transfer struct {
+	from   string
+	to     string
+	amount int64  // Luna, excluding the fee
+	signer Signer // optional, overrides the default signer (see GetDefaultSigner)
+}
+
+// workerAddresses returns the worker accounts from --workers, falling back to
+// the sender pool in the credentials file. The main account is never a worker.
+func workerAddresses(workers, main string) ([]string, error) {
+	list := parseAddressList(workers)
+	if workers == "" {
+		list = GetDefaultSenders()
+	}
+
+	var result []string
+	seen := map[string]bool{normalizeAddress(main): true}
+	for _, addr := range list {
+		if seen[normalizeAddress(addr)] {
+			continue
+		}
+		seen[normalizeAddress(addr)] = true
+		result = append(result, addr)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no worker accounts (--workers NQa,NQb or senders in the credentials file)")
+	}
+	return result, nil
+}
+
+// executeTransfers sends the planned transfers, one sender per source account
+func executeTransfers(ctx context.Context, rpcURL string, fee int64, transfers []transfer) error {
+	for i, t := range transfers {
+		var sender *RPCSender
+		var err error
+		if t.signer != nil {
+			sender = NewRPCSenderWithSigner(NewNimiqRPC(rpcURL), t.signer, t.to, fee)
+		} else {
+			sender, err = NewRPCSender(rpcURL, t.from, t.to, fee)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to initialize sender %s: %w", t.from, err)
+		}
+
+		txHash, err := sender.WithContext(ctx).SendValue(t.amount)
+		if err != nil {
+			return fmt.Errorf("transfer %d/%d (%s -> %s) failed: %w", i+1, len(transfers), t.from, t.to, err)
+		}
+		fmt.Printf("✓ [%d/%d] %s -> %s: %s\n", i+1, len(transfers), t.from, t.to, txHash)
+	}
+	return nil
+}
+
+// waitForBalances polls until every address has at least the expected balance
+// (i.e. the transfers were included in a block) or the timeout expires
+func waitForBalances(ctx context.Context, rpc *NimiqRPC, expected map[string]int64, timeout time.Duration) error {
+	fmt.Printf("\nWaiting for %d transfers to be confirmed (timeout %s)...\n", len(expected), timeout)
+	deadline := time.Now().Add(timeout)
+	for {
+		pending := 0
+		for addr, want := range expected {
+			balance, err := rpc.GetBalance(addr)
+			if err != nil || balance < want {
+				pending++
+			}
+		}
+		if pending == 0 {
+			fmt.Println("✅ All transfers confirmed")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d of %d transfers not confirmed after %s (they may still be pending, check with 'account balance')", pending, len(expected), timeout)
+		}
+		fmt.Printf("[%s] ⏳ %d/%d confirmed\n", time.Now().Format("15:04:05"), len(expected)-pending, len(expected))
+		if err := sleepContext(ctx, 5*time.Second); err != nil {
+			return err
+		}
+	}
+}
+
+func newAccountFundWorkersCmd() *cobra.Command {
+	var (
+		rpcURL    string
+		from      string
+		workers   string
+		amountNIM float64
+		keepNIM   float64
+		fee       int64
+		dryRun    bool
+		noWait    bool
+		timeout   time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "fund-workers",
+		Short: "Split the main account's balance across worker accounts",
+		Long: `Send NIM from the main account to each worker account (the sender pool
+used by upload-cartridge --senders).
+
+By default the available balance (minus --keep-nim and fees) is split evenly;
+use --amount-nim to send a fixed amount to each worker instead.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get RPC URL from env, credentials file, or default
+			if rpcURL == "" {
+				rpcURL = GetDefaultRPCURL()
+			}
+
+			// Try to get address from credentials file if not provided
+			if from == "" {
+				from = GetDefaultAddress()
+			}
+			if from == "" {
+				return fmt.Errorf("main address is required (--from or set in credentials.json)")
+			}
+
+			workerList, err := workerAddresses(workers, from)
+			if err != nil {
+				return err
+			}
+
+			rpc := NewNimiqRPC(rpcURL).WithContext(cmd.Context())
+
+			// Check consensus first
+			consensus, err := rpc.IsConsensusEstablished()
+			if err != nil {
+				return fmt.Errorf("failed to check consensus: %w", err)
+			}
+			if !consensus {
+				return fmt.Errorf("node does not have consensus with the network - wait for sync")
+			}
+
+			balance, err := rpc.GetBalance(from)
+			if err != nil {
+				return fmt.Errorf("failed to get balance: %w", err)
+			}
+
+			// Work out how much each worker gets
+			n := int64(len(workerList))
+			fees := fee * n
+			perWorker := nimToLuna(amountNIM)
+			if amountNIM <= 0 {
+				perWorker = (balance - nimToLuna(keepNIM) - fees) / n
+			}
+			if perWorker <= 0 {
+				return fmt.Errorf("nothing to distribute: balance %s is not enough for %d workers (keep %.5f NIM, fees %d Luna)", formatLuna(balance), n, keepNIM, fees)
+			}
+			total := perWorker*n + fees
+
+			fmt.Printf("Main account: %s\n", from)
+			fmt.Printf("Balance:      %s\n", formatLuna(balance))
+			fmt.Printf("Workers:      %d\n", n)
+			for _, w := range workerList {
+				fmt.Printf("  %s <- %s\n", w, formatLuna(perWorker))
+			}
+			fmt.Printf("Fees:         %s\n", formatLuna(fees))
+			fmt.Printf("Total debit:  %s\n", formatLuna(total))
+			fmt.Printf("Remaining:    %s\n", formatLuna(balance-total))
+
+			if total > balance {
+				return fmt.Errorf("insufficient balance: need %s, have %s", formatLuna(total), formatLuna(balance))
+			}
+			if dryRun {
+				fmt.Println("\n[DRY RUN] No transactions sent")
+				return nil
+			}
+
+			// Remember worker balances so we can tell when the transfers landed
+			expected := make(map[string]int64, n)
+			transfers := make([]transfer, 0, n)
+			for _, w := range workerList {
+				current, err := rpc.GetBalance(w)
+				if err != nil {
+					return fmt.Errorf("failed to get balance of %s: %w", w, err)
+				}
+				expected[w] = current + perWorker
+				transfers = append(transfers, transfer{from: from, to: w, amount: perWorker})
+			}
+
+			fmt.Println()
+			if err := executeTransfers(cmd.Context(), rpcURL, fee, transfers); err != nil {
+				return err
+			}
+
+			if noWait {
+				return nil
+			}
+			return waitForBalances(cmd.Context(), rpc, expected, timeout)
+		},
+	}
+
+	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
+	cmd.Flags().StringVar(&from, "from", "", "Main account to fund from (defaults to address from credentials.json)")
+	cmd.Flags().StringVar(&workers, "workers", "", "Comma-separated worker addresses (defaults to senders from credentials)")
+	cmd.Flags().Float64Var(&amountNIM, "amount-nim", 0, "NIM to send to each worker (default: split the available balance evenly)")
+	cmd.Flags().Float64Var(&keepNIM, "keep-nim", 0, "NIM to keep on the main account when splitting the balance")
+	cmd.Flags().Int64Var(&fee, "fee", 0, "Transaction fee in Luna per transfer (default: 0, minimum)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the transfers and totals without sending")
+	cmd.Flags().BoolVar(&noWait, "no-wait", false, "Return right after sending instead of waiting for confirmation")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "How long to wait for the transfers to be confirmed")
+
+	return cmd
+}
+
+func newAccountSweepCmd() *cobra.Command {
+	var (
+		rpcURL  string
+		to      string
+		workers string
+		keepNIM float64
+		fee     int64
+		dryRun  bool
+		noWait  bool
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sweep",
+		Short: "Move the remaining balance of worker accounts back to the main account",
+		Long: `Send everything (minus fees and --keep-nim) from each worker account back
+to the main account. Worker accounts must be imported and unlocked on the node.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get RPC URL from env, credentials file, or default
+			if rpcURL == "" {
+				rpcURL = GetDefaultRPCURL()
+			}
+
+			// Try to get address from credentials file if not provided
+			if to == "" {
+				to = GetDefaultAddress()
+			}
+			if to == "" {
+				return fmt.Errorf("main address is required (--to or set in credentials.json)")
+			}
+
+			workerList, err := workerAddresses(workers, to)
+			if err != nil {
+				return err
+			}
+
+			rpc := NewNimiqRPC(rpcURL).WithContext(cmd.Context())
+
+			// Check consensus first
+			consensus, err := rpc.IsConsensusEstablished()
+			if err != nil {
+				return fmt.Errorf("failed to check consensus: %w", err)
+			}
+			if !consensus {
+				return fmt.Errorf("node does not have consensus with the network - wait for sync")
+			}
+
+			mainBalance, err := rpc.GetBalance(to)
+			if err != nil {
+				return fmt.Errorf("failed to get balance: %w", err)
+			}
+
+			// Plan one transfer per worker with something left to sweep
+			keep := nimToLuna(keepNIM)
+			var transfers []transfer
+			var total, fees int64
+			fmt.Printf("Main account: %s\n", to)
+			fmt.Printf("Workers:      %d\n", len(workerList))
+			for _, w := range workerList {
+				balance, err := rpc.GetBalance(w)
+				if err != nil {
+					return fmt.Errorf("failed to get balance of %s: %w", w, err)
+				}
+				amount := balance - keep - fee
+				if amount <= 0 {
+					fmt.Printf("  %s: %s (nothing to sweep)\n", w, formatLuna(balance))
+					continue
+				}
+				fmt.Printf("  %s: %s -> %s\n", w, formatLuna(balance), formatLuna(amount))
+				transfers = append(transfers, transfer{from: w, to: to, amount: amount})
+				total += amount
+				fees += fee
+			}
+			fmt.Printf("Fees:         %s\n", formatLuna(fees))
+			fmt.Printf("Total swept:  %s\n", formatLuna(total))
+
+			if len(transfers) == 0 {
+				fmt.Println("\nNothing to sweep")
+				return nil
+			}
+			if dryRun {
+				fmt.Println("\n[DRY RUN] No transactions sent")
+				return nil
+			}
+
+			fmt.Println()
+			if err := executeTransfers(cmd.Context(), rpcURL, fee, transfers); err != nil {
+				return err
+			}
+
+			if noWait {
+				return nil
+			}
+			return waitForBalances(cmd.Context(), rpc, map[string]int64{to: mainBalance + total}, timeout)
+		},
+	}
+
+	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
+	cmd.Flags().StringVar(&to, "to", "", "Main account to sweep into (defaults to address from credentials.json)")
+	cmd.Flags().StringVar(&workers, "workers", "", "Comma-separated worker addresses (defaults to senders from credentials)")
+	cmd.Flags().Float64Var(&keepNIM, "keep-nim", 0, "NIM to leave on each worker account")
+	cmd.Flags().Int64Var(&fee, "fee", 0, "Transaction fee in Luna per transfer (default: 0, minimum)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the transfers and totals without sending")
+	cmd.Flags().BoolVar(&noWait, "no-wait", false, "Return right after sending instead of waiting for confirmation")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "How long to wait for the transfers to be confirmed")
+
+	return cmd
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transaction plan step kinds, in the order an upload sends them
+const (
+	StepDATA = "DATA"
+	StepCSGN = "CSGN"
+	StepCART = "CART"
+	StepCENT = "CENT"
+
+	txPlanVersion = 1
+)
+
+// TxPlanHeader is the first line of a plan file (upload-cartridge --plan-out).
+// PlanHash covers the header (with an empty PlanHash) and every step line.
+type TxPlanHeader struct {
+	Type          string `json:"type"` // "header"
+	Version       int    `json:"version"`
+	CreatedAt     string `json:"created_at"`
+	File          string `json:"file"` // as given to --file ("-" = stdin)
+	Size          int64  `json:"size"`
+	SHA256        string `json:"sha256"`
+	AppID         uint32 `json:"app_id"`
+	CartridgeID   uint32 `json:"cartridge_id"`
+	CartridgeAddr string `json:"cartridge_addr"`
+	CatalogAddr   string `json:"catalog_addr"`
+	NetworkID     uint8  `json:"network_id,omitempty"` // network of the planning node (0 = unknown)
+	Publisher     string `json:"publisher"`
+	Title         string `json:"title"`
+	Semver        string `json:"semver"`
+	Platform      uint8  `json:"platform"`
+	Schema        uint8  `json:"schema"`
+	ChunkSize     uint8  `json:"chunk_size"`
+	Steps         int    `json:"steps"`
+	CatalogState  string `json:"catalog_state"` // see catalogStateHash
+	PlanHash      string `json:"plan_hash"`
+}
+
+// TxPlanStep is one transaction of a plan, sent exactly as written
+type TxPlanStep struct {
+	Type    string  `json:"type"` // "step"
+	Step    int     `json:"step"`
+	Kind    string  `json:"kind"`
+	Chunk   *uint32 `json:"chunk,omitempty"` // DATA only
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Value   int64   `json:"value"`
+	Fee     int64   `json:"fee"`
+	Payload string  `json:"payload_hex"`
+}
+
+// TxPlan is the complete, ordered list of transactions of an upload
+type TxPlan struct {
+	Header TxPlanHeader
+	Steps  []TxPlanStep
+}
+
+func (p *TxPlan) add(kind, from, to string, fee int64, payload []byte) *TxPlanStep {
+	p.Steps = append(p.Steps, TxPlanStep{
+		Type:    "step",
+		Step:    len(p.Steps),
+		Kind:    kind,
+		From:    from,
+		To:      to,
+		Value:   txValue,
+		Fee:     fee,
+		Payload: hex.EncodeToString(payload),
+	})
+	return &p.Steps[len(p.Steps)-1]
+}
+
+// lines returns the JSON lines of the plan (header first)
+func (p *TxPlan) lines() ([][]byte, error) {
+	var lines [][]byte
+	header, err := json.Marshal(p.Header)
+	if err != nil {
+		return nil, err
+	}
+	lines = append(lines, header)
+	for _, step := range p.Steps {
+		line, err := json.Marshal(step)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// Hash computes the plan hash over the header (without its hash) and all steps
+func (p *TxPlan) Hash() (string, error) {
+	unhashed := *p
+	unhashed.Header.PlanHash = ""
+	lines, err := unhashed.lines()
+	if err != nil {
+		return "", err
+	}
+	return jsonLinesHash(lines), nil
+}
+
+// Seal fills in the step count and plan hash
+func (p *TxPlan) Seal() error {
+	p.Header.Steps = len(p.Steps)
+	hash, err := p.Hash()
+	if err != nil {
+		return fmt.Errorf("failed to hash plan: %w", err)
+	}
+	p.Header.PlanHash = hash
+	return nil
+}
+
+// Write stores the sealed plan as JSON lines
+func (p *TxPlan) Write(path string) error {
+	lines, err := p.lines()
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := writeJSONLines(path, lines); err != nil {
+		return fmt.Errorf("failed to write plan %s: %w", path, err)
+	}
+	return nil
+}
+
+// jsonLinesHash hashes newline-terminated JSON lines (plan and bundle hashes)
+func jsonLinesHash(lines [][]byte) string {
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write(line)
+		h.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeJSONLines(path string, lines [][]byte) error {
+	var data []byte
+	for _, line := range lines {
+		data = append(append(data, line...), '\n')
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// readJSONLines returns the non-empty lines of a JSONL file with their line numbers
+func readJSONLines(path string) ([]string, []int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	var numbers []int
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		lines = append(lines, scanner.Text())
+		numbers = append(numbers, n)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil, fmt.Errorf("%s is empty", path)
+	}
+	return lines, numbers, nil
+}
+
+// decodeStrict decodes one JSON line, rejecting unknown fields
+func decodeStrict(line string, v interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// Count returns the number of steps of a kind
+func (p *TxPlan) Count(kind string) int {
+	n := 0
+	for _, step := range p.Steps {
+		if step.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// ReadTxPlan reads a plan file and refuses it if it was edited after it was written
+func ReadTxPlan(path string) (*TxPlan, error) {
+	lines, numbers, err := readJSONLines(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	plan := &TxPlan{}
+	if err := decodeStrict(lines[0], &plan.Header); err != nil || plan.Header.Type != "header" {
+		return nil, fmt.Errorf("%s is not a transaction plan (the first line must be the header)", path)
+	}
+	for i, line := range lines[1:] {
+		var step TxPlanStep
+		if err := decodeStrict(line, &step); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, numbers[i+1], err)
+		}
+		if step.Type != "step" || step.Step != len(plan.Steps) {
+			return nil, fmt.Errorf("%s line %d: expected step %d", path, numbers[i+1], len(plan.Steps))
+		}
+		if payload, err := hex.DecodeString(step.Payload); err != nil || len(payload) != 64 {
+			return nil, fmt.Errorf("%s line %d: payload must be 64 bytes of hex", path, numbers[i+1])
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	if plan.Header.Version != txPlanVersion {
+		return nil, fmt.Errorf("unsupported plan version %d (expected %d)", plan.Header.Version, txPlanVersion)
+	}
+	if plan.Header.Steps != len(plan.Steps) {
+		return nil, fmt.Errorf("plan is truncated: header lists %d steps, file has %d", plan.Header.Steps, len(plan.Steps))
+	}
+	hash, err := plan.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if hash != plan.Header.PlanHash {
+		return nil, fmt.Errorf("plan hash mismatch (header %s, content %s) - the plan was modified after it was made", shortHash(plan.Header.PlanHash), shortHash(hash))
+	}
+	return plan, nil
+}
+
+// catalogStateHash fingerprints the publisher's transactions to the catalog.
+// Any CENT sent after a plan was made changes it, which could invalidate the
+// plan's app-id, cartridge-id or title lookup.
+func catalogStateHash(rpc *NimiqRPC, catalogAddr, publisher string) (string, error) {
+	transactions, err := GetAllTransactionsByAddress(rpc, normalizeAddress(catalogAddr), 500)
+	if err != nil {
+		return "", fmt.Errorf("failed to query catalog: %w", err)
+	}
+	var hashes []string
+	for _, tx := range transactions {
+		if normalizeAddress(tx.To) == normalizeAddress(catalogAddr) && normalizeAddress(tx.From) == normalizeAddress(publisher) {
+			hashes = append(hashes, tx.Hash)
+		}
+	}
+	sort.Strings(hashes)
+	h := sha256.Sum256([]byte(strings.Join(hashes, "\n")))
+	return hex.EncodeToString(h[:]), nil
+}
+
+// BuildUploadTxPlan encodes every transaction of a cartridge upload: the DATA
+// chunks (spread round-robin over pool, like SenderPool), the CSGN co-signer
+// declarations, the CART header and the CENT catalog entry
+func BuildUploadTxPlan(header TxPlanHeader, source *ChunkSource, pool []string, fee int64, semver [3]uint8) (*TxPlan, error) {
+	plan := &TxPlan{Header: header}
+	plan.Header.Type = "header"
+	plan.Header.Version = txPlanVersion
+	plan.Header.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	plan.Header.Size = source.Size
+
+	pool = dedupeAddresses(pool)
+	coSigners := make(map[string]string)
+	chunker := NewStreamChunker(source, header.ChunkSize)
+	for {
+		idx, data, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", source.Name, err)
+		}
+		payload, err := EncodeDATA(DATAPayload{
+			CartridgeID: header.CartridgeID,
+			ChunkIndex:  idx,
+			Length:      uint8(len(data)),
+			Data:        data,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode chunk %d: %w", idx, err)
+		}
+		from := header.Publisher
+		if len(pool) > 0 {
+			from = pool[int(idx)%len(pool)]
+			if normalizeAddress(from) != normalizeAddress(header.Publisher) {
+				coSigners[normalizeAddress(from)] = from
+			}
+		}
+		chunk := idx
+		plan.add(StepDATA, from, header.CartridgeAddr, fee, payload).Chunk = &chunk
+	}
+	sum := chunker.Sum()
+	plan.Header.SHA256 = hex.EncodeToString(sum[:])
+
+	if len(coSigners) > 0 {
+		var declared []string
+		for _, addr := range coSigners {
+			declared = append(declared, addr)
+		}
+		sort.Strings(declared)
+		records, err := EncodeCSGNRecords(header.CartridgeID, declared)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode co-signer declaration: %w", err)
+		}
+		for _, payload := range records {
+			plan.add(StepCSGN, header.Publisher, header.CartridgeAddr, fee, payload)
+		}
+	}
+
+	cartPayload, err := EncodeCART(CARTHeader{
+		Schema:      header.Schema,
+		Platform:    header.Platform,
+		ChunkSize:   header.ChunkSize,
+		CartridgeID: header.CartridgeID,
+		TotalSize:   uint64(source.Size),
+		SHA256:      sum,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CART header: %w", err)
+	}
+	plan.add(StepCART, header.Publisher, header.CartridgeAddr, fee, cartPayload)
+
+	cartAddrBytes, err := AddressNQToBytes(header.CartridgeAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert cartridge address: %w", err)
+	}
+	centPayload, err := EncodeCENT(CENTEntry{
+		Schema:        header.Schema,
+		Platform:      header.Platform,
+		AppID:         header.AppID,
+		Semver:        semver,
+		CartridgeAddr: cartAddrBytes,
+		TitleShort:    header.Title,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CENT entry: %w", err)
+	}
+	plan.add(StepCENT, header.Publisher, header.CatalogAddr, fee, centPayload)
+
+	if err := plan.Seal(); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// TxPlanProgress records which steps of a plan were sent (<plan>.progress.json)
+type TxPlanProgress struct {
+	PlanHash  string         `json:"plan_hash"`            // bundle hash for broadcast
+	NetworkID uint8          `json:"network_id,omitempty"` // network of the node the steps were sent to
+	Sent      map[int]string `json:"sent"`                 // step -> transaction hash
+	Confirmed map[int]int64  `json:"confirmed,omitempty"`  // step -> block number (broadcast)
+	UpdatedAt string         `json:"updated_at"`
+
+	mu   sync.Mutex
+	path string
+}
+
+func loadTxPlanProgress(path, planHash string) (*TxPlanProgress, error) {
+	progress := &TxPlanProgress{PlanHash: planHash, Sent: make(map[int]string), Confirmed: make(map[int]int64), path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return progress, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, progress); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if progress.PlanHash != planHash {
+		return nil, fmt.Errorf("%s belongs to plan %s, not %s", path, shortHash(progress.PlanHash), shortHash(planHash))
+	}
+	if progress.Sent == nil {
+		progress.Sent = make(map[int]string)
+	}
+	if progress.Confirmed == nil {
+		progress.Confirmed = make(map[int]int64)
+	}
+	return progress, nil
+}
+
+// bindNetwork fails if the progress was recorded on another network than
+// networkID, and otherwise remembers networkID for later resumes
+func (p *TxPlanProgress) bindNetwork(networkID uint8) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := checkProgressNetwork(p.path, p.NetworkID, networkID); err != nil {
+		return err
+	}
+	if p.NetworkID == 0 {
+		p.NetworkID = networkID
+	}
+	return nil
+}
+
+// record stores the hash of a sent step and saves the progress file
+func (p *TxPlanProgress) record(step int, txHash string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Sent[step] = txHash
+	return p.saveLocked()
+}
+
+// confirm stores the block a sent step was included in and saves the progress file
+func (p *TxPlanProgress) confirm(step int, blockNumber int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Confirmed[step] = blockNumber
+	return p.saveLocked()
+}
+
+func (p *TxPlanProgress) saveLocked() error {
+	p.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(p.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save %s: %w", p.path, err)
+	}
+	return nil
+}
+
+// verifyPlanFile re-hashes the file a plan was made from
+func verifyPlanFile(header TxPlanHeader) error {
+	if header.File == "-" {
+		fmt.Println("⚠️  Plan was made from stdin; the source file cannot be re-checked")
+		return nil
+	}
+	source, err := OpenChunkSource(header.File, 0)
+	if err != nil {
+		return fmt.Errorf("cannot verify the planned file: %w", err)
+	}
+	defer source.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(source, 0, source.Size)); err != nil {
+		return fmt.Errorf("failed to hash %s: %w", header.File, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); source.Size != header.Size || sum != header.SHA256 {
+		return fmt.Errorf("%s changed since the plan was made (planned %d bytes, SHA256 %s; now %d bytes, SHA256 %s) - make a new plan", header.File, header.Size, shortHash(header.SHA256), source.Size, shortHash(sum))
+	}
+	return nil
+}
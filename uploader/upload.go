@@ -26,6 +26,11 @@ type UploadPlan struct {
 	Index   uint32 `json:"idx"`
 	Payload string `json:"payload_hex"`
 	TxHash  string `json:"tx_hash,omitempty"` // Transaction hash where this chunk was sent
+	Sender  string `json:"sender,omitempty"`  // Sender address, if not the publisher (sender pools)
+	// ValidFrom is the validity start height of the transaction (0 if unknown);
+	// it can no longer be mined after ValidFrom+txValidityWindow
+	ValidFrom int64 `json:"validity_start_height,omitempty"`
+	Attempt   int   `json:"attempt,omitempty"` // Earlier failed or expired sends of this chunk
 }
 
 type UploadProgress struct {
@@ -33,6 +38,7 @@ type UploadProgress struct {
 	TotalChunks  int          `json:"total_chunks"`
 	SentChunks   int          `json:"sent_chunks"`
 	FailedChunks []int        `json:"failed_chunks,omitempty"`
+	NetworkID    uint8        `json:"network_id,omitempty"` // Network of the node the chunks were sent to
 	Plan         []UploadPlan `json:"plan"`
 }
 
@@ -51,6 +57,7 @@ func newUploadCmd() *cobra.Command {
 		network          string
 		title            string // Display title of the game
 		platform         string // Platform (e.g., "DOS", "Windows")
+		maxSize          string
 	)
 
 	cmd := &cobra.Command{
@@ -76,14 +83,20 @@ func newUploadCmd() *cobra.Command {
 				receiver = "NQ27 21G6 9BG1 JBHJ NUFA YVJS 1R6C D2X0 QAES"
 			}
 
-			// Check file size limit (6MB)
-			const maxFileSize = 6 * 1024 * 1024 // 6MB
+			// Check file size limit (--max-size)
+			maxFileSize, err := parseByteSize(maxSize)
+			if err != nil {
+				return fmt.Errorf("invalid --max-size: %w", err)
+			}
 			fileInfo, err := os.Stat(filePath)
 			if err != nil {
 				return fmt.Errorf("failed to get file info: %w", err)
 			}
-			if fileInfo.Size() > maxFileSize {
-				return fmt.Errorf("file size (%d bytes) exceeds maximum allowed size of 6MB (%d bytes)", fileInfo.Size(), maxFileSize)
+			if maxFileSize > 0 && fileInfo.Size() > maxFileSize {
+				return fmt.Errorf("file size (%d bytes) exceeds maximum allowed size of %s (%d bytes); raise it with --max-size", fileInfo.Size(), formatByteSize(maxFileSize), maxFileSize)
+			}
+			if _, _, err := ValidateChunkLayout(fileInfo.Size(), ChunkSize); err != nil {
+				return err
 			}
 
 			chunks, err := ChunkFile(filePath, gameID)
@@ -120,6 +133,21 @@ func newUploadCmd() *cobra.Command {
 					return fmt.Errorf("node does not have consensus with the network - cannot upload. Wait for sync or use --dry-run")
 				}
 
+				// The manifest names the network, so it has to be the node's
+				networkID, err := checkNodeNetwork(rpc, "", network)
+				if err != nil {
+					return err
+				}
+				if err := checkProgressNetwork(progressFile, progress.NetworkID, networkID); err != nil {
+					return err
+				}
+				if progress.NetworkID == 0 {
+					progress.NetworkID = networkID
+				}
+				if network == "" && networkID != 0 {
+					network = manifestNetworkName(networkID)
+				}
+
 				// Create RPC sender (will check account status)
 				fmt.Printf("Sending transactions from %s to %s\n", sender, receiver)
 				rpcSender, err := NewRPCSender(rpcURL, sender, receiver, fee)
@@ -149,8 +177,9 @@ func newUploadCmd() *cobra.Command {
 					continue
 				}
 
-				// Rate limit
+				// Rate limit (returns early when interrupted - keep what was sent so far)
 				if err := limiter.Wait(cmd.Context()); err != nil {
+					saveProgress(progressFile, progress)
 					return err
 				}
 
@@ -235,6 +264,7 @@ func newUploadCmd() *cobra.Command {
 	cmd.Flags().StringVar(&network, "network", "", "Network for manifest (mainnet/testnet) (or set NIMIQ_NETWORK)")
 	cmd.Flags().StringVar(&title, "title", "", "Display title of the game (e.g., \"Digger Remastered\")")
 	cmd.Flags().StringVar(&platform, "platform", "", "Platform (e.g., \"DOS\", \"Windows\", \"Linux\")")
+	cmd.Flags().StringVar(&maxSize, "max-size", "6MB", "Maximum file size to upload (e.g. 6MB, 100MB, 0 = no limit)")
 
 	cmd.MarkFlagRequired("file")
 	cmd.MarkFlagRequired("game-id")
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,37 +20,69 @@ import (
 )
 
 type CartridgeUploadProgress struct {
-	AppID         uint32       `json:"app_id"`
-	CartridgeID   uint32       `json:"cartridge_id"`
-	CartridgeAddr string       `json:"cartridge_addr"`
-	TotalChunks   int          `json:"total_chunks"`
-	SentChunks    int          `json:"sent_chunks"`
-	FailedChunks  []int        `json:"failed_chunks,omitempty"`
-	CARTTxHash    string       `json:"cart_tx_hash,omitempty"`
-	CENTTxHash    string       `json:"cent_tx_hash,omitempty"`
-	Plan          []UploadPlan `json:"plan"`
+	AppID          uint32         `json:"app_id"`
+	CartridgeID    uint32         `json:"cartridge_id"`
+	CartridgeAddr  string         `json:"cartridge_addr"`
+	TotalChunks    int            `json:"total_chunks"`
+	SentChunks     int            `json:"sent_chunks"`
+	FailedChunks   []int          `json:"failed_chunks,omitempty"`
+	UnknownChunks  []int          `json:"unknown_chunks,omitempty"` // Sends interrupted with unknown outcome (re-sent on resume)
+	CARTTxHash     string         `json:"cart_tx_hash,omitempty"`
+	CENTTxHash     string         `json:"cent_tx_hash,omitempty"`
+	CSGNTxHashes   []string       `json:"csgn_tx_hashes,omitempty"`  // Co-signer declarations (sender pools only)
+	SHA256         string         `json:"sha256,omitempty"`          // Content hash, recorded once the file was fully read
+	ResendAttempts map[uint32]int `json:"resend_attempts,omitempty"` // Failed/unknown sends per chunk (escalates the fee on resend)
+	NetworkID      uint8          `json:"network_id,omitempty"`      // Network of the node the upload was sent to
+	Plan           []UploadPlan   `json:"plan"`
+	// Validity start heights of the CART, CENT and CSGN transactions by hash (unmined ones expire)
+	HeaderValidFrom map[string]int64 `json:"header_validity_start_heights,omitempty"`
+}
+
+// dryRunCartridgeAddr stands in for --generate-cartridge-addr in dry-runs
+const dryRunCartridgeAddr = "NQ07 0000 0000 0000 0000 0000 0000 0000 0000"
+
+// cartridgeUpload is a single upload-cartridge run. Zero IDs and an empty
+// cartridge address are filled in from the catalog/node while it runs.
+type cartridgeUpload struct {
+	filePath         string
+	appID            uint32
+	cartridgeID      uint32
+	title            string
+	semver           string
+	platform         uint8
+	cartridgeAddr    string
+	catalogAddr      string
+	sender           string
+	senders          string
+	dryRun           bool
+	planOut          string
+	offline          bool
+	rateLimit        float64
+	rpcURL           string
+	feeFlag          string
+	maxSpend         float64
+	generateCartAddr bool
+	keyPassphrase    string
+	waitForFundsFlag bool
+	skipPreflight    bool
+	schema           uint8
+	chunkSize        uint8
+	concurrency      int
+	maxSize          string
+	detectPlatform   bool // platform was not given, take it from the ROM header
+	force            bool // upload even if the ROM header does not match the platform
+
+	limiter      *rate.Limiter // shared rate budget (upload-dir); nil = own limiter from rateLimit
+	noResumeHint bool          // don't print an upload-cartridge resume command when interrupted
+	networkID    uint8         // network of the node (0 = unknown), recorded in progress and plans
+
+	// onIDs is called (if set) once the IDs and cartridge address are fixed
+	// and a generated key is saved, before anything is sent
+	onIDs func(appID, cartridgeID uint32, cartridgeAddr string) error
 }
 
 func newUploadCartridgeCmd() *cobra.Command {
-	var (
-		filePath         string
-		appID            uint32
-		cartridgeID      uint32
-		title            string
-		semver           string
-		platform         uint8
-		cartridgeAddr    string
-		catalogAddr      string
-		sender           string
-		dryRun           bool
-		rateLimit        float64
-		rpcURL           string
-		fee              int64
-		generateCartAddr bool
-		schema           uint8
-		chunkSize        uint8
-		concurrency      int
-	)
+	u := &cartridgeUpload{}
 
 	cmd := &cobra.Command{
 		Use:   "upload-cartridge",
@@ -58,542 +93,1046 @@ func newUploadCartridgeCmd() *cobra.Command {
 - Uploads DATA chunk transactions
 - Registers cartridge in catalog with CENT entry`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Get RPC URL from env, credentials file, or default
-			if rpcURL == "" {
-				rpcURL = GetDefaultRPCURL()
-			}
+			u.detectPlatform = !cmd.Flags().Changed("platform")
 
-			// Try to get sender from credentials file if not provided
-			if sender == "" {
-				sender = GetDefaultAddress()
-			}
+			// Cancelled on SIGINT/SIGTERM: stop starting new sends, keep in-flight ones
+			_, err := u.run(cmd.Context())
+			return err
+		},
+	}
 
-			if sender == "" {
-				return fmt.Errorf("sender address is required (--sender or set in account_credentials.txt)")
-			}
+	cmd.Flags().StringVar(&u.filePath, "file", "", "Path to file to upload, or - for stdin (required)")
+	cmd.Flags().Uint32Var(&u.appID, "app-id", 0, "App ID (uint32, auto-generated if not provided)")
+	cmd.Flags().Uint32Var(&u.cartridgeID, "cartridge-id", 0, "Cartridge ID (uint32, auto-generated if not provided)")
+	cmd.Flags().StringVar(&u.title, "title", "", "Short title (max 16 chars, required unless the ROM header has one)")
+	cmd.Flags().StringVar(&u.semver, "semver", "", "Semantic version (e.g., 1.0.0, required)")
+	cmd.Flags().Uint8Var(&u.platform, "platform", 0, "Platform code: 0=DOS, 1=GB, 2=GBC, 3=NES (default: from the ROM header, else 0)")
+	cmd.Flags().StringVar(&u.cartridgeAddr, "cartridge-addr", "", "Cartridge address (NQ..., or use --generate-cartridge-addr)")
+	cmd.Flags().BoolVar(&u.generateCartAddr, "generate-cartridge-addr", false, "Generate a new cartridge address")
+	cmd.Flags().StringVar(&u.keyPassphrase, "key-passphrase", "", "Passphrase to encrypt the generated cartridge key (defaults to NIMIQ_KEY_PASSPHRASE or the account passphrase)")
+	cmd.Flags().StringVar(&u.catalogAddr, "catalog-addr", "", "Catalog address (NQ..., 'main', 'test', required)")
+	cmd.Flags().StringVar(&u.sender, "sender", "", "Sender address (defaults to ADDRESS from account_credentials.txt)")
+	cmd.Flags().StringVar(&u.senders, "senders", "", "Comma-separated sender pool for DATA chunks (defaults to senders from credentials; CART/CENT still come from --sender)")
+	cmd.Flags().BoolVar(&u.dryRun, "dry-run", false, "Dry-run mode (show what would be sent, nothing is sent or saved)")
+	cmd.Flags().BoolVar(&u.offline, "offline", false, "Build the --plan-out plan without a node (needs --app-id, --cartridge-id and a fixed --fee; sign it with sign-bundle)")
+	cmd.Flags().StringVar(&u.planOut, "plan-out", "", "Write every transaction to a reviewable plan file (JSONL) instead of sending; send it with 'execute --plan'")
+	cmd.Flags().BoolVar(&u.waitForFundsFlag, "wait-for-funds", false, "Wait for the sender account(s) to be funded instead of refusing to start")
+	cmd.Flags().BoolVar(&u.skipPreflight, "skip-preflight", false, "Skip the cost estimate and balance check")
+	cmd.Flags().Float64Var(&u.rateLimit, "rate", 25.0, "Transaction rate limit (tx/s, default: 25)")
+	cmd.Flags().StringVar(&u.rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
+	cmd.Flags().StringVar(&u.feeFlag, "fee", "0", "Transaction fee: a fixed amount in Luna per transaction, or auto, min or fast")
+	cmd.Flags().Float64Var(&u.maxSpend, "max-spend", 0, "Maximum NIM to spend on this upload (value + fees); caps the fee per transaction (0 = no cap)")
+	cmd.Flags().Uint8Var(&u.schema, "schema", 1, "Schema version (default: 1)")
+	cmd.Flags().Uint8Var(&u.chunkSize, "chunk-size", 51, "Chunk size in bytes (default: 51)")
+	cmd.Flags().IntVar(&u.concurrency, "concurrency", 1, "Number of parallel upload workers (default: 1, max: 10)")
+	cmd.Flags().StringVar(&u.maxSize, "max-size", "6MB", "Maximum file size to upload (e.g. 6MB, 100MB, 0 = no limit)")
+	cmd.Flags().BoolVar(&u.force, "force", false, "Upload even if the ROM header is broken or does not match --platform")
 
-			if catalogAddr == "" {
-				return fmt.Errorf("catalog address is required (--catalog-addr)")
-			}
+	cmd.MarkFlagRequired("file")
+	cmd.MarkFlagRequired("semver")
+	cmd.MarkFlagRequired("catalog-addr")
 
-			// Resolve catalog address shortcuts
-			catalogAddr = resolveCatalogAddress(catalogAddr)
-
-			// Initialize RPC for catalog queries
-			rpc := NewNimiqRPC(rpcURL)
-
-			// Auto-generate app-id if not provided
-			// Note: Even in dry-run, we query the catalog to get correct IDs
-			if appID == 0 {
-				publisherAddr := sender // Use sender as publisher for filtering
-				// Try to find existing app-id by title first (for new versions)
-				if title != "" {
-					foundAppID, err := FindAppIDByTitle(rpc, catalogAddr, publisherAddr, title)
-					if err != nil {
-						fmt.Printf("Warning: failed to search for existing app-id by title: %v\n", err)
-					} else if foundAppID > 0 {
-						appID = foundAppID
-						if dryRun {
-							fmt.Printf("Found existing app-id %d for title \"%s\" (new version, dry-run)\n", appID, title)
-						} else {
-							fmt.Printf("Found existing app-id %d for title \"%s\" (new version)\n", appID, title)
-							logCartridgeUpload(fmt.Sprintf("Found existing app-id %d for title \"%s\"", appID, title))
-						}
-					} else {
-						fmt.Printf("No existing app-id found for title \"%s\" (will create new game)\n", title)
-					}
-				}
+	return cmd
+}
 
-				// If not found by title, generate new app-id
-				if appID == 0 {
-					fmt.Println("Auto-generating new app-id...")
-					var err error
-					appID, err = GetMaxAppID(rpc, catalogAddr, publisherAddr)
-					if err != nil {
-						return fmt.Errorf("failed to auto-generate app-id: %w", err)
-					}
-					if dryRun {
-						fmt.Printf("Auto-generated app-id: %d (new game, dry-run)\n", appID)
-					} else {
-						fmt.Printf("Auto-generated app-id: %d (new game)\n", appID)
-					}
-				}
-			} else {
-				fmt.Printf("Using provided app-id: %d\n", appID)
-			}
+// checkROM parses the ROM header of the file, fills in the platform and title
+// if they were not given, and refuses a platform the ROM does not run on
+func (u *cartridgeUpload) checkROM() error {
+	if u.filePath == "-" {
+		return nil
+	}
+	rom, err := InspectROMFile(u.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		if !u.force {
+			return fmt.Errorf("invalid ROM: %w (use --force to upload anyway)", err)
+		}
+		fmt.Printf("⚠️  Warning: invalid ROM: %v\n", err)
+		return nil
+	}
+	if rom == nil {
+		return nil
+	}
 
-			// Auto-generate cartridge-id if not provided
-			// Note: Even in dry-run, we query the catalog to get correct IDs
-			if cartridgeID == 0 {
-				fmt.Println("Auto-generating cartridge-id...")
-				publisherAddr := sender // Use sender as publisher for filtering
-				var err error
-				cartridgeID, err = GetMaxCartridgeID(rpc, catalogAddr, publisherAddr, appID)
-				if err != nil {
-					return fmt.Errorf("failed to auto-generate cartridge-id: %w", err)
-				}
-				if dryRun {
-					fmt.Printf("Auto-generated cartridge-id: %d (dry-run)\n", cartridgeID)
-				} else {
-					fmt.Printf("Auto-generated cartridge-id: %d\n", cartridgeID)
-				}
-			}
+	fmt.Printf("ROM header: %s (%s), title %q\n", rom.Format, rom.Name, rom.Title)
+	for _, warning := range rom.Warnings {
+		fmt.Printf("⚠️  Warning: %s: %s\n", rom.Name, warning)
+	}
+	if u.detectPlatform {
+		code, _ := PlatformCode(rom.Platform)
+		u.platform = code
+		fmt.Printf("Platform from the ROM header: %d (%s)\n", code, rom.Platform)
+	} else if err := rom.CheckPlatform(PlatformName(u.platform)); err != nil {
+		if !u.force {
+			return fmt.Errorf("platform %d does not match the ROM: %w (use --force to upload anyway)", u.platform, err)
+		}
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+	if u.title == "" && rom.Title != "" {
+		u.title = rom.Title
+		fmt.Printf("Title from the ROM: %s\n", u.title)
+	}
+	return nil
+}
 
-			// Validate semver format
-			semverParts := strings.Split(semver, ".")
-			if len(semverParts) != 3 {
-				return fmt.Errorf("semver must be in format major.minor.patch (e.g., 1.0.0)")
-			}
-			var semverBytes [3]uint8
-			for i, part := range semverParts {
-				val, err := strconv.ParseUint(part, 10, 8)
-				if err != nil || val > 255 {
-					return fmt.Errorf("invalid semver component: %s (must be 0-255)", part)
-				}
-				semverBytes[i] = uint8(val)
-			}
+// run uploads the DATA chunks, CSGN declarations, CART header and CENT entry,
+// resuming from the progress journal, and returns the final progress
+func (u *cartridgeUpload) run(ctx context.Context) (*CartridgeUploadProgress, error) {
+	// GB/GBC/NES ROMs (also inside ZIPs) must match the platform
+	if err := u.checkROM(); err != nil {
+		return nil, err
+	}
+	if u.title == "" {
+		return nil, fmt.Errorf("title is required (--title)")
+	}
 
-			// Validate title length
-			if len(title) > 16 {
-				return fmt.Errorf("title must be <= 16 characters (got %d)", len(title))
-			}
+	// Get RPC URL from env, credentials file, or default
+	if u.rpcURL == "" {
+		u.rpcURL = GetDefaultRPCURL()
+	}
 
-			// Defaults
-			if schema == 0 {
-				schema = 1
-			}
-			if chunkSize == 0 {
-				chunkSize = 51
-			}
+	// Try to get sender from credentials file if not provided
+	if u.sender == "" {
+		u.sender = GetDefaultAddress()
+	}
 
-			// Generate or use cartridge address
-			if generateCartAddr {
-				fmt.Println("Generating new cartridge address...")
-				account, err := rpc.CreateAccount()
-				if err != nil {
-					return fmt.Errorf("failed to create cartridge account: %w", err)
-				}
-				cartridgeAddr = account.Address
-				fmt.Printf("Generated cartridge address: %s\n", cartridgeAddr)
-				logCartridgeUpload(fmt.Sprintf("Generated new cartridge address: %s", cartridgeAddr))
-			}
+	if u.sender == "" {
+		return nil, fmt.Errorf("sender address is required (--sender or set in account_credentials.txt)")
+	}
 
-			if cartridgeAddr == "" {
-				return fmt.Errorf("cartridge address is required (--cartridge-addr or --generate-cartridge-addr)")
-			}
+	feePolicy, err := ParseFeePolicy(u.feeFlag)
+	if err != nil {
+		return nil, err
+	}
 
-			// Validate cartridge address format
-			if !strings.HasPrefix(cartridgeAddr, "NQ") || len(cartridgeAddr) < 42 {
-				return fmt.Errorf("invalid cartridge address format: %s", cartridgeAddr)
-			}
+	// Optional sender pool for DATA chunks (CART/CENT always come from the publisher)
+	senderPool := parseAddressList(u.senders)
+	if u.senders == "" {
+		senderPool = GetDefaultSenders()
+	}
 
-			// Check file size limit (6MB)
-			const maxFileSize = 6 * 1024 * 1024 // 6MB
-			fileInfo, err := os.Stat(filePath)
-			if err != nil {
-				return fmt.Errorf("failed to get file info: %w", err)
-			}
-			if fileInfo.Size() > maxFileSize {
-				return fmt.Errorf("file size (%d bytes) exceeds maximum allowed size of 6MB (%d bytes)", fileInfo.Size(), maxFileSize)
-			}
+	if u.catalogAddr == "" {
+		return nil, fmt.Errorf("catalog address is required (--catalog-addr)")
+	}
+
+	// Offline plans can't look anything up: IDs and fee have to be given
+	if u.offline {
+		if u.planOut == "" {
+			return nil, fmt.Errorf("--offline only builds a plan, use it with --plan-out")
+		}
+		if u.appID == 0 || u.cartridgeID == 0 {
+			return nil, fmt.Errorf("--offline needs --app-id and --cartridge-id")
+		}
+		if feePolicy.Mode != FeeFixed {
+			return nil, fmt.Errorf("--offline needs a fixed --fee in Luna")
+		}
+	}
 
-			// Read file and calculate SHA256
-			fileData, err := os.ReadFile(filePath)
+	// Resolve catalog address shortcuts
+	u.catalogAddr = resolveCatalogAddress(u.catalogAddr)
+
+	// Initialize RPC for catalog queries
+	rpc := NewNimiqRPC(u.rpcURL).WithContext(ctx)
+
+	// Make sure the node is on the network the catalog belongs to
+	if u.offline {
+		u.networkID, _, err = expectedNetwork(u.catalogAddr, "")
+	} else {
+		u.networkID, err = checkNodeNetwork(rpc, u.catalogAddr, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Auto-generate app-id if not provided
+	// Note: Even in dry-run, we query the catalog to get correct IDs
+	if u.appID == 0 {
+		publisherAddr := u.sender // Use sender as publisher for filtering
+		// Try to find existing app-id by title first (for new versions)
+		if u.title != "" {
+			foundAppID, err := FindAppIDByTitle(rpc, u.catalogAddr, publisherAddr, u.title)
 			if err != nil {
-				return fmt.Errorf("failed to read file: %w", err)
+				fmt.Printf("Warning: failed to search for existing app-id by title: %v\n", err)
+			} else if foundAppID > 0 {
+				u.appID = foundAppID
+				if u.dryRun {
+					fmt.Printf("Found existing app-id %d for title \"%s\" (new version, dry-run)\n", u.appID, u.title)
+				} else {
+					fmt.Printf("Found existing app-id %d for title \"%s\" (new version)\n", u.appID, u.title)
+					logCartridgeUpload(fmt.Sprintf("Found existing app-id %d for title \"%s\"", u.appID, u.title))
+				}
+			} else {
+				fmt.Printf("No existing app-id found for title \"%s\" (will create new game)\n", u.title)
 			}
+		}
 
-			sha256Hash, err := CalculateFileSHA256(filePath)
+		// If not found by title, generate new app-id
+		if u.appID == 0 {
+			fmt.Println("Auto-generating new app-id...")
+			var err error
+			u.appID, err = GetMaxAppID(rpc, u.catalogAddr, publisherAddr)
 			if err != nil {
-				return fmt.Errorf("failed to calculate SHA256: %w", err)
+				return nil, fmt.Errorf("failed to auto-generate app-id: %w", err)
 			}
-
-			totalSize := uint64(len(fileData))
-			expectedChunks := int((totalSize + uint64(chunkSize) - 1) / uint64(chunkSize))
-
-			fmt.Printf("\n=== Upload Configuration ===\n")
-			fmt.Printf("File: %s\n", filePath)
-			fmt.Printf("Size: %d bytes\n", totalSize)
-			fmt.Printf("SHA256: %s\n", hex.EncodeToString(sha256Hash[:]))
-			fmt.Printf("Expected chunks: %d\n", expectedChunks)
-			fmt.Printf("App ID: %d\n", appID)
-			fmt.Printf("Cartridge ID: %d\n", cartridgeID)
-			fmt.Printf("Cartridge Address: %s\n", cartridgeAddr)
-			fmt.Printf("Catalog Address: %s\n", catalogAddr)
-			fmt.Printf("===========================\n\n")
-
-			// Log upload start
-			logCartridgeUpload("=== Upload Started ===")
-			logCartridgeUpload("File: " + filePath)
-			logCartridgeUpload(fmt.Sprintf("Size: %d bytes", totalSize))
-			logCartridgeUpload(fmt.Sprintf("SHA256: %s", hex.EncodeToString(sha256Hash[:])))
-			logCartridgeUpload(fmt.Sprintf("App ID: %d", appID))
-			logCartridgeUpload(fmt.Sprintf("Cartridge ID: %d", cartridgeID))
-			logCartridgeUpload(fmt.Sprintf("Title: %s", title))
-			logCartridgeUpload(fmt.Sprintf("Semver: %s", semver))
-			logCartridgeUpload(fmt.Sprintf("Platform: %d", platform))
-			logCartridgeUpload(fmt.Sprintf("Cartridge Address: %s", cartridgeAddr))
-			logCartridgeUpload(fmt.Sprintf("Catalog Address: %s", catalogAddr))
-			logCartridgeUpload(fmt.Sprintf("Sender: %s", sender))
-			logCartridgeUpload(fmt.Sprintf("RPC URL: %s", rpcURL))
-			logCartridgeUpload(fmt.Sprintf("Expected chunks: %d", expectedChunks))
-
-			// Load or create progress (include app-id in filename to avoid conflicts)
-			progressFile := fmt.Sprintf("upload_cartridge_%d_%d.json", appID, cartridgeID)
-			progress := &CartridgeUploadProgress{
-				AppID:         appID,
-				CartridgeID:   cartridgeID,
-				CartridgeAddr: cartridgeAddr,
-				TotalChunks:   expectedChunks,
-				SentChunks:    0,
-				Plan:          make([]UploadPlan, 0, expectedChunks),
+			if u.dryRun {
+				fmt.Printf("Auto-generated app-id: %d (new game, dry-run)\n", u.appID)
+			} else {
+				fmt.Printf("Auto-generated app-id: %d (new game)\n", u.appID)
 			}
+		}
+	} else {
+		fmt.Printf("Using provided app-id: %d\n", u.appID)
+	}
 
-			// Try to load existing progress, but validate it matches current upload
-			if data, err := os.ReadFile(progressFile); err == nil {
-				var loadedProgress CartridgeUploadProgress
-				if err := json.Unmarshal(data, &loadedProgress); err == nil {
-					// Only use loaded progress if it matches current upload
-					if loadedProgress.AppID == appID && loadedProgress.CartridgeID == cartridgeID &&
-						loadedProgress.CartridgeAddr == cartridgeAddr && loadedProgress.TotalChunks == expectedChunks {
-						progress = &loadedProgress
-						fmt.Printf("Resuming from progress file: %s\n", progressFile)
-					} else {
-						fmt.Printf("Progress file exists but doesn't match current upload. Starting fresh.\n")
-					}
-				}
-			}
+	// Auto-generate cartridge-id if not provided
+	// Note: Even in dry-run, we query the catalog to get correct IDs
+	if u.cartridgeID == 0 {
+		fmt.Println("Auto-generating cartridge-id...")
+		publisherAddr := u.sender // Use sender as publisher for filtering
+		var err error
+		u.cartridgeID, err = GetMaxCartridgeID(rpc, u.catalogAddr, publisherAddr, u.appID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to auto-generate cartridge-id: %w", err)
+		}
+		if u.dryRun {
+			fmt.Printf("Auto-generated cartridge-id: %d (dry-run)\n", u.cartridgeID)
+		} else {
+			fmt.Printf("Auto-generated cartridge-id: %d\n", u.cartridgeID)
+		}
+	}
 
-			var txSender TxSender
-			if dryRun {
-				txSender = &DryRunSender{}
-			} else {
-				// Check consensus before proceeding
-				consensus, err := rpc.IsConsensusEstablished()
-				if err != nil {
-					return fmt.Errorf("failed to check consensus: %w", err)
-				}
-				if !consensus {
-					return fmt.Errorf("node does not have consensus with the network - cannot upload. Wait for sync or use --dry-run")
-				}
+	// Validate semver format
+	semverBytes, err := parseSemver(u.semver)
+	if err != nil {
+		return nil, err
+	}
 
-				// Create RPC sender for cartridge address (will be used for CART and DATA)
-				fmt.Printf("Sending transactions from %s\n", sender)
-				rpcSender, err := NewRPCSender(rpcURL, sender, cartridgeAddr, fee)
-				if err != nil {
-					return fmt.Errorf("failed to initialize RPC sender: %w", err)
-				}
-				txSender = rpcSender
-			}
+	// Validate title length
+	if len(u.title) > 16 {
+		return nil, fmt.Errorf("title must be <= 16 bytes (got %d)", len(u.title))
+	}
 
-			// Validate and cap concurrency
-			if concurrency < 1 {
-				concurrency = 1
-			}
-			if concurrency > 10 {
-				concurrency = 10
-			}
+	// Defaults
+	if u.schema == 0 {
+		u.schema = 1
+	}
+	if u.chunkSize == 0 {
+		u.chunkSize = 51
+	}
 
-			// Use burst size equal to concurrency for smoother parallel uploads
-			limiter := rate.NewLimiter(rate.Limit(rateLimit), concurrency)
+	// Generate or use cartridge address. A plain dry-run sends and saves
+	// nothing, so it doesn't create a key either.
+	if u.generateCartAddr && u.dryRun && u.planOut == "" {
+		u.cartridgeAddr = dryRunCartridgeAddr
+		fmt.Printf("Cartridge address: %s (placeholder, a new address is generated for the real upload)\n", u.cartridgeAddr)
+	} else if u.generateCartAddr {
+		// Every CART/DATA transaction sends 1 luna to the cartridge address, so keep its
+		// key (encrypted) to be able to reclaim that value later
+		passphrase := resolveKeyPassphrase(u.keyPassphrase)
+		if passphrase == "" {
+			return nil, fmt.Errorf("a passphrase is required to store the generated cartridge key (--key-passphrase, NIMIQ_KEY_PASSPHRASE or passphrase in credentials.json)")
+		}
+
+		fmt.Println("Generating new cartridge address...")
+		var account *AccountInfo
+		if u.offline {
+			account, err = GenerateAccount()
+		} else {
+			account, err = rpc.CreateAccount()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cartridge account: %w", err)
+		}
+		u.cartridgeAddr = account.Address
+		fmt.Printf("Generated cartridge address: %s\n", u.cartridgeAddr)
+		logCartridgeUpload(fmt.Sprintf("Generated new cartridge address: %s", u.cartridgeAddr))
+
+		keyPath, err := SaveCartridgeKey(account, u.appID, u.cartridgeID, u.sender, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save cartridge key (nothing was sent): %w", err)
+		}
+		fmt.Printf("Cartridge key saved to %s (reclaim its luna later with 'cartridge reclaim')\n", keyPath)
+		logCartridgeUpload(fmt.Sprintf("Cartridge key saved to %s", keyPath))
+	}
 
-			// Step 1: Send DATA chunks FIRST
-			// (CART header is sent AFTER all chunks so it appears in newest transactions for faster loading)
-			fmt.Printf("\n=== Step 1: Uploading DATA chunks (concurrency: %d) ===\n", concurrency)
+	if u.cartridgeAddr == "" {
+		return nil, fmt.Errorf("cartridge address is required (--cartridge-addr or --generate-cartridge-addr)")
+	}
 
-			// fileData was already read earlier for SHA256 calculation - reuse it
-			// Build list of chunks to upload (skip already sent)
-			type chunkWork struct {
-				index uint32
-				data  []byte
-			}
-			var chunksToUpload []chunkWork
-			sentHashes := make(map[uint32]string) // index -> txHash for already sent
+	// Validate cartridge address format
+	if !strings.HasPrefix(u.cartridgeAddr, "NQ") || len(u.cartridgeAddr) < 42 {
+		return nil, fmt.Errorf("invalid cartridge address format: %s", u.cartridgeAddr)
+	}
+	if u.onIDs != nil {
+		if err := u.onIDs(u.appID, u.cartridgeID, u.cartridgeAddr); err != nil {
+			return nil, err
+		}
+	}
 
-			for _, plan := range progress.Plan {
-				if plan.TxHash != "" {
-					sentHashes[plan.Index] = plan.TxHash
-				}
-			}
+	// Open the input without reading it into memory ("-" = stdin)
+	maxFileSize, err := parseByteSize(u.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --max-size: %w", err)
+	}
+	source, err := OpenChunkSource(u.filePath, maxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
 
-			for i := 0; i < len(fileData); i += int(chunkSize) {
-				end := i + int(chunkSize)
-				if end > len(fileData) {
-					end = len(fileData)
-				}
-				chunkIdx := uint32(i / int(chunkSize))
+	// Make sure the chunk count fits the CART/DATA fields
+	chunkCount, sizeWarning, err := ValidateChunkLayout(source.Size, u.chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	if sizeWarning != "" {
+		fmt.Printf("⚠️  Warning: %s\n", sizeWarning)
+	}
 
-				if txHash, ok := sentHashes[chunkIdx]; ok {
-					fmt.Printf("Skipping chunk %d (already sent: %s)\n", chunkIdx, txHash[:16])
-					continue
-				}
+	totalSize := uint64(source.Size)
+	expectedChunks := int(chunkCount)
+
+	fmt.Printf("\n=== Upload Configuration ===\n")
+	fmt.Printf("File: %s\n", source.Name)
+	fmt.Printf("Size: %d bytes\n", totalSize)
+	fmt.Printf("Expected chunks: %d\n", expectedChunks)
+	fmt.Printf("App ID: %d\n", u.appID)
+	fmt.Printf("Cartridge ID: %d\n", u.cartridgeID)
+	fmt.Printf("Cartridge Address: %s\n", u.cartridgeAddr)
+	fmt.Printf("Catalog Address: %s\n", u.catalogAddr)
+	fmt.Printf("===========================\n\n")
+
+	if u.dryRun || u.planOut != "" {
+		return u.plan(rpc, feePolicy, source, chunkCount, senderPool, semverBytes)
+	}
 
-				chunkData := make([]byte, end-i)
-				copy(chunkData, fileData[i:end])
-				chunksToUpload = append(chunksToUpload, chunkWork{index: chunkIdx, data: chunkData})
-			}
+	// Log upload start
+	logCartridgeUpload("=== Upload Started ===")
+	logCartridgeUpload("File: " + source.Name)
+	logCartridgeUpload(fmt.Sprintf("Size: %d bytes", totalSize))
+	logCartridgeUpload(fmt.Sprintf("App ID: %d", u.appID))
+	logCartridgeUpload(fmt.Sprintf("Cartridge ID: %d", u.cartridgeID))
+	logCartridgeUpload(fmt.Sprintf("Title: %s", u.title))
+	logCartridgeUpload(fmt.Sprintf("Semver: %s", u.semver))
+	logCartridgeUpload(fmt.Sprintf("Platform: %d", u.platform))
+	logCartridgeUpload(fmt.Sprintf("Cartridge Address: %s", u.cartridgeAddr))
+	logCartridgeUpload(fmt.Sprintf("Catalog Address: %s", u.catalogAddr))
+	logCartridgeUpload(fmt.Sprintf("Sender: %s", u.sender))
+	if len(senderPool) > 0 {
+		logCartridgeUpload(fmt.Sprintf("Sender pool: %s", strings.Join(senderPool, ", ")))
+	}
+	logCartridgeUpload(fmt.Sprintf("RPC URL: %s", u.rpcURL))
+	logCartridgeUpload(fmt.Sprintf("Expected chunks: %d", expectedChunks))
+
+	// Open the progress journal (include app-id in filename to avoid conflicts).
+	// Existing upload_cartridge_*.json files are imported as the starting snapshot.
+	progressBase := fmt.Sprintf("upload_cartridge_%d_%d", u.appID, u.cartridgeID)
+	journal, err := OpenProgressJournal(progressBase, &CartridgeUploadProgress{
+		AppID:         u.appID,
+		CartridgeID:   u.cartridgeID,
+		CartridgeAddr: u.cartridgeAddr,
+		TotalChunks:   expectedChunks,
+		SentChunks:    0,
+		NetworkID:     u.networkID,
+		Plan:          make([]UploadPlan, 0, expectedChunks),
+	}, func(loaded *CartridgeUploadProgress) bool {
+		// Only use loaded progress if it matches current upload
+		return loaded.AppID == u.appID && loaded.CartridgeID == u.cartridgeID &&
+			loaded.CartridgeAddr == u.cartridgeAddr && loaded.TotalChunks == expectedChunks
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open progress journal: %w", err)
+	}
+	defer journal.Close()
+	progressFile := journal.SnapshotPath()
+
+	// If we get interrupted, tell the user how to pick up where we left off.
+	// Generated IDs and addresses are pinned so the resume matches this progress file.
+	defer func() {
+		if ctx.Err() == nil || u.noResumeHint {
+			return
+		}
+		p := journal.Progress()
+		fmt.Printf("\n⏸  Upload interrupted: %d/%d chunks sent, progress saved to %s\n", p.SentChunks, p.TotalChunks, progressFile)
+		if len(p.UnknownChunks) > 0 {
+			fmt.Printf("   %d chunks were in flight with unknown outcome and will be re-sent: %v\n", len(p.UnknownChunks), p.UnknownChunks)
+		}
+		fmt.Printf("   Resume with:\n     %s\n", resumeCommand(os.Args[1:], map[string]string{
+			"app-id":         strconv.FormatUint(uint64(u.appID), 10),
+			"cartridge-id":   strconv.FormatUint(uint64(u.cartridgeID), 10),
+			"cartridge-addr": u.cartridgeAddr,
+		}, "generate-cartridge-addr"))
+		logCartridgeUpload(fmt.Sprintf("Upload interrupted at %d/%d chunks", p.SentChunks, p.TotalChunks))
+	}()
+
+	// A resume against a node on another network would mix up two catalogs
+	if err := checkProgressNetwork(progressFile, journal.Progress().NetworkID, u.networkID); err != nil {
+		return nil, err
+	}
+	if journal.Progress().NetworkID == 0 && u.networkID != 0 {
+		if err := journal.RecordNetworkID(u.networkID); err != nil {
+			return nil, err
+		}
+	}
 
-			fmt.Printf("Chunks to upload: %d (already sent: %d)\n", len(chunksToUpload), len(sentHashes))
+	if p := journal.Progress(); len(p.UnknownChunks) > 0 {
+		fmt.Printf("Note: %d chunks from a previous run have an unknown outcome and will be re-sent (duplicates are ignored by the reconstructor)\n", len(p.UnknownChunks))
+	}
+	if expired, headers, err := expireUnminedTransactions(rpc, journal); err != nil {
+		return nil, err
+	} else if expired > 0 || headers > 0 {
+		fmt.Printf("Note: %d chunks and %d CART/CENT/CSGN transactions from a previous run expired without being mined and will be re-sent\n", expired, headers)
+	}
 
-			if len(chunksToUpload) > 0 {
-				// Create worker pool for parallel uploads
-				var wg sync.WaitGroup
-				var mu sync.Mutex
-				var sentCount int64
-				var failedCount int64
-				startTime := time.Now()
+	// Pick the fee from the node's fee information (--fee auto|min|fast) or use the fixed one
+	feeOracle := NewFeeOracle(rpc, feePolicy)
+	fee, err := feeOracle.Fee()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine fee (use --fee <luna> to set it explicitly): %w", err)
+	}
 
-				// Create work channel
-				workChan := make(chan chunkWork, len(chunksToUpload))
-				for _, chunk := range chunksToUpload {
-					workChan <- chunk
-				}
-				close(workChan)
-
-				// Start workers
-				for w := 0; w < concurrency; w++ {
-					wg.Add(1)
-					go func(workerID int) {
-						defer wg.Done()
-
-						for chunk := range workChan {
-							// Rate limit
-							if err := limiter.Wait(cmd.Context()); err != nil {
-								return
-							}
-
-							dataPayload := DATAPayload{
-								CartridgeID: cartridgeID,
-								ChunkIndex:  chunk.index,
-								Length:      uint8(len(chunk.data)),
-								Data:        chunk.data,
-							}
-
-							encoded, err := EncodeDATA(dataPayload)
-							if err != nil {
-								fmt.Printf("[W%d] Failed to encode chunk %d: %v\n", workerID, chunk.index, err)
-								atomic.AddInt64(&failedCount, 1)
-								mu.Lock()
-								progress.FailedChunks = append(progress.FailedChunks, int(chunk.index))
-								mu.Unlock()
-								continue
-							}
-
-							txHash, err := txSender.SendTransaction(encoded)
-							if err != nil {
-								fmt.Printf("[W%d] Failed to send chunk %d: %v\n", workerID, chunk.index, err)
-								atomic.AddInt64(&failedCount, 1)
-								mu.Lock()
-								progress.FailedChunks = append(progress.FailedChunks, int(chunk.index))
-								mu.Unlock()
-								continue
-							}
-
-							// Update progress (thread-safe)
-							mu.Lock()
-							progress.Plan = append(progress.Plan, UploadPlan{
-								Index:   chunk.index,
-								Payload: hex.EncodeToString(encoded),
-								TxHash:  txHash,
-							})
-							progress.SentChunks++
-							currentSent := progress.SentChunks
-							mu.Unlock()
-
-							sent := atomic.AddInt64(&sentCount, 1)
-							elapsed := time.Since(startTime).Seconds()
-							rate := float64(sent) / elapsed
-							remaining := float64(len(chunksToUpload)-int(sent)) / rate
-
-							fmt.Printf("[W%d] Sent chunk %d/%d (%.1f tx/s, ETA: %.0fs)\n",
-								workerID, currentSent, expectedChunks, rate, remaining)
-
-							// Save progress periodically (every 10 successful sends across all workers)
-							if sent%10 == 0 {
-								mu.Lock()
-								saveCartridgeProgress(progressFile, progress)
-								mu.Unlock()
-							}
-
-							// Log every 100 chunks
-							if sent%100 == 0 {
-								logCartridgeUpload(fmt.Sprintf("Progress: %d/%d chunks sent (%.1f tx/s)", currentSent, expectedChunks, rate))
-							}
-						}
-					}(w)
-				}
+	p := journal.Progress()
+	var coSigners []string
+	if len(senderPool) > 0 {
+		coSigners = uploadCoSigners(p.Plan)
+	}
+	fee, estimate, err := u.capFee(feeOracle, fee, func(fee int64) *UploadEstimate {
+		return EstimateUpload(u.sender, senderPool, int64(p.TotalChunks-p.SentChunks), p.CARTTxHash == "", p.CENTTxHash == "", coSigners, fee)
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Fee: %s -> %d Luna per transaction\n", feeOracle.Policy(), fee)
+	logCartridgeUpload(fmt.Sprintf("Fee: %s -> %d Luna per transaction", feeOracle.Policy(), fee))
+
+	// Preflight: make sure the remaining transactions can be paid for before sending any
+	if !u.skipPreflight {
+		fmt.Println("\n=== Cost estimate ===")
+		estimate.Print()
+		if err := preflightBalances(ctx, rpc, estimate, u.waitForFundsFlag); err != nil {
+			return nil, err
+		}
+	}
 
-				// Wait for all workers to complete
-				wg.Wait()
+	// Check consensus before proceeding
+	consensus, err := rpc.IsConsensusEstablished()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check consensus: %w", err)
+	}
+	if !consensus {
+		return nil, fmt.Errorf("node does not have consensus with the network - cannot upload. Wait for sync or use --dry-run")
+	}
 
-				elapsed := time.Since(startTime).Seconds()
-				finalRate := float64(sentCount) / elapsed
-				fmt.Printf("\n✓ Uploaded %d chunks in %.1fs (%.1f tx/s avg)\n", sentCount, elapsed, finalRate)
+	// Create RPC sender for cartridge address (will be used for CART and DATA)
+	fmt.Printf("Sending transactions from %s\n", u.sender)
+	rpcSender, err := NewRPCSender(u.rpcURL, u.sender, u.cartridgeAddr, fee)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize RPC sender: %w", err)
+	}
+	var txSender TxSender = rpcSender.WithContext(ctx).WithFeeOracle(feeOracle)
+	senderFaults := activeFaults != nil && activeFaults.cfg.Layer == FaultLayerSender
+	if senderFaults {
+		txSender = NewFaultSender(txSender, activeFaults)
+	}
 
-				if failedCount > 0 {
-					fmt.Printf("⚠️  %d chunks failed - run again to retry\n", failedCount)
-				}
-			}
+	var dataPool *SenderPool // DATA chunks are spread over this pool if set
+	if len(senderPool) > 0 {
+		pool, err := NewSenderPool(u.rpcURL, senderPool, u.cartridgeAddr, fee)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize sender pool: %w", err)
+		}
+		dataPool = pool.WithContext(ctx).WithFeeOracle(feeOracle)
+		if senderFaults {
+			dataPool.WithFaults(activeFaults)
+		}
+		fmt.Printf("Spreading DATA chunks over %d senders: %s\n", len(dataPool.Addresses()), strings.Join(dataPool.Addresses(), ", "))
+	}
 
-			// Final save
-			saveCartridgeProgress(progressFile, progress)
-
-			// Step 2: Send CART header AFTER all chunks (so it's in newest transactions for faster loading)
-			if progress.SentChunks == progress.TotalChunks && progress.CARTTxHash == "" {
-				fmt.Println("\n=== Step 2: Uploading CART header ===")
-				cartHeader := CARTHeader{
-					Schema:      schema,
-					Platform:    platform,
-					ChunkSize:   chunkSize,
-					Flags:       0,
-					CartridgeID: cartridgeID,
-					TotalSize:   totalSize,
-					SHA256:      sha256Hash,
-				}
+	// Validate and cap concurrency
+	if u.concurrency < 1 {
+		u.concurrency = 1
+	}
+	if u.concurrency > 10 {
+		u.concurrency = 10
+	}
 
-				cartPayload, err := EncodeCART(cartHeader)
-				if err != nil {
-					return fmt.Errorf("failed to encode CART header: %w", err)
-				}
+	// Use burst size equal to concurrency for smoother parallel uploads
+	limiter := u.limiter
+	if limiter == nil {
+		limiter = rate.NewLimiter(rate.Limit(u.rateLimit), u.concurrency)
+	}
 
-				if err := limiter.Wait(cmd.Context()); err != nil {
-					return err
-				}
+	// Step 1: Send DATA chunks FIRST
+	// (CART header is sent AFTER all chunks so it appears in newest transactions for faster loading)
+	fmt.Printf("\n=== Step 1: Uploading DATA chunks (concurrency: %d) ===\n", u.concurrency)
 
-				txHash, err := txSender.SendTransaction(cartPayload)
-				if err != nil {
-					return fmt.Errorf("failed to send CART header: %w", err)
-				}
+	// Chunks are read and hashed in a single streaming pass; already-sent
+	// chunks are hashed and compared with their journaled payload, but skipped
+	type chunkWork struct {
+		index uint32
+		data  []byte
+	}
+	alreadySent := 0
+	for _, plan := range journal.Progress().Plan {
+		if plan.TxHash != "" {
+			alreadySent++
+		}
+	}
+	toUpload := expectedChunks - alreadySent
+
+	fmt.Printf("Chunks to upload: %d (already sent: %d)\n", toUpload, alreadySent)
+
+	// Produce chunks for the workers while hashing the file
+	chunker := NewStreamChunker(source, u.chunkSize)
+	workChan := make(chan chunkWork, u.concurrency*4)
+	var readErr error
+	var readFailed int32 // set once readErr is, so queued chunks are not sent
+	var readWG sync.WaitGroup
+	readWG.Add(1)
+	go func() {
+		defer readWG.Done()
+		defer close(workChan)
+		for {
+			idx, data, err := chunker.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				readErr = fmt.Errorf("failed to read %s: %w", source.Name, err)
+				atomic.StoreInt32(&readFailed, 1)
+				return
+			}
 
-				progress.CARTTxHash = txHash
-				fmt.Printf("✓ CART header sent: %s\n", txHash)
-				saveCartridgeProgress(progressFile, progress)
-				logCartridgeUpload(fmt.Sprintf("CART header sent: %s", txHash))
-			} else if progress.CARTTxHash != "" {
-				fmt.Printf("CART header already sent: %s\n", progress.CARTTxHash)
+			// A resume must not put chunks of another file under this
+			// cartridge-id: stop at the first sent chunk that differs
+			if sent, ok := journal.SentChunk(idx); ok && sent.TxHash != "" {
+				if err := checkSentChunk(sent, u.cartridgeID, data); err != nil {
+					readErr = err
+					atomic.StoreInt32(&readFailed, 1)
+					return
+				}
+				fmt.Printf("Skipping chunk %d (already sent: %s)\n", idx, shortHash(sent.TxHash))
+				continue
 			}
 
-			// Step 3: Send CENT entry to catalog if all chunks AND CART header are uploaded
-			if progress.SentChunks == progress.TotalChunks && progress.CARTTxHash != "" && progress.CENTTxHash == "" {
-				fmt.Println("\n=== Step 3: Registering cartridge in catalog (CENT) ===")
+			select {
+			case workChan <- chunkWork{index: idx, data: data}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Create worker pool for parallel uploads
+	var wg sync.WaitGroup
+	var sentCount int64
+	var failedCount int64
+	startTime := time.Now()
+
+	// Start workers
+	for w := 0; w < u.concurrency; w++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+
+			for chunk := range workChan {
+				// Stop picking up new chunks once interrupted or the file turned out to have changed
+				if ctx.Err() != nil || atomic.LoadInt32(&readFailed) != 0 {
+					return
+				}
 
-				// Convert cartridge address to bytes
-				cartAddrBytes, err := AddressNQToBytes(cartridgeAddr)
-				if err != nil {
-					return fmt.Errorf("failed to convert cartridge address: %w", err)
+				// Rate limit
+				if err := limiter.Wait(ctx); err != nil {
+					return
 				}
 
-				centEntry := CENTEntry{
-					Schema:        schema,
-					Platform:      platform,
-					Flags:         0,
-					AppID:         appID,
-					Semver:        semverBytes,
-					CartridgeAddr: cartAddrBytes,
-					TitleShort:    title,
+				dataPayload := DATAPayload{
+					CartridgeID: u.cartridgeID,
+					ChunkIndex:  chunk.index,
+					Length:      uint8(len(chunk.data)),
+					Data:        chunk.data,
 				}
 
-				centPayload, err := EncodeCENT(centEntry)
+				encoded, err := EncodeDATA(dataPayload)
 				if err != nil {
-					return fmt.Errorf("failed to encode CENT entry: %w", err)
+					fmt.Printf("[W%d] Failed to encode chunk %d: %v\n", workerID, chunk.index, err)
+					atomic.AddInt64(&failedCount, 1)
+					if jerr := journal.RecordChunkFailed(chunk.index, err); jerr != nil {
+						fmt.Printf("Warning: %v\n", jerr)
+					}
+					continue
 				}
 
-				// Create sender for catalog address
-				var catalogSender TxSender
-				if dryRun {
-					catalogSender = &DryRunSender{}
+				// Chunks whose earlier sends failed or expired go out with an escalated fee
+				attempt := journal.Attempts(chunk.index)
+				var txHash, from string
+				var validFrom int64
+				if dataPool != nil {
+					from, txHash, validFrom, err = dataPool.SendFrom(encoded, attempt)
+				} else if resender, ok := txSender.(Resender); ok {
+					txHash, validFrom, err = resender.ResendTransaction(encoded, attempt)
 				} else {
-					if err := limiter.Wait(cmd.Context()); err != nil {
-						return err
+					txHash, err = txSender.SendTransaction(encoded)
+				}
+				if normalizeAddress(from) == normalizeAddress(u.sender) {
+					from = "" // sent by the publisher itself
+				}
+				if err != nil && errors.Is(err, context.Canceled) {
+					// Interrupted before the transaction left this process
+					return
+				}
+				if err != nil && errors.Is(err, ErrOutcomeUnknown) {
+					fmt.Printf("[W%d] Chunk %d outcome unknown: %v\n", workerID, chunk.index, err)
+					atomic.AddInt64(&failedCount, 1)
+					if jerr := journal.RecordChunkUnknown(chunk.index, err); jerr != nil {
+						fmt.Printf("Warning: %v\n", jerr)
 					}
-
-					catalogRpcSender, err := NewRPCSender(rpcURL, sender, catalogAddr, fee)
-					if err != nil {
-						return fmt.Errorf("failed to initialize catalog RPC sender: %w", err)
+					continue
+				}
+				if err != nil {
+					fmt.Printf("[W%d] Failed to send chunk %d: %v\n", workerID, chunk.index, err)
+					atomic.AddInt64(&failedCount, 1)
+					if jerr := journal.RecordChunkFailed(chunk.index, err); jerr != nil {
+						fmt.Printf("Warning: %v\n", jerr)
 					}
-					catalogSender = catalogRpcSender
+					continue
 				}
 
-				txHash, err := catalogSender.SendTransaction(centPayload)
-				if err != nil {
-					return fmt.Errorf("failed to send CENT entry: %w", err)
+				// Record the hash durably before moving on (the journal is thread-safe)
+				currentSent, jerr := journal.RecordChunkSent(chunk.index, hex.EncodeToString(encoded), txHash, from, validFrom, attempt)
+				if jerr != nil {
+					fmt.Printf("Warning: %v\n", jerr)
 				}
 
-				progress.CENTTxHash = txHash
-				fmt.Printf("✓ CENT entry sent to catalog: %s\n", txHash)
-				saveCartridgeProgress(progressFile, progress)
-				logCartridgeUpload(fmt.Sprintf("CENT entry sent to catalog: %s", txHash))
-			} else if progress.CENTTxHash != "" {
-				fmt.Printf("CENT entry already sent: %s\n", progress.CENTTxHash)
-			} else {
-				fmt.Printf("\n⚠️  Not all chunks uploaded yet (%d/%d). CENT entry will be sent when complete.\n", progress.SentChunks, progress.TotalChunks)
-			}
+				sent := atomic.AddInt64(&sentCount, 1)
+				elapsed := time.Since(startTime).Seconds()
+				rate := float64(sent) / elapsed
+				remaining := float64(toUpload-int(sent)) / rate
 
-			if dryRun {
-				fmt.Printf("\nDry-run complete. Upload plan saved to %s\n", progressFile)
-			} else {
-				fmt.Printf("\n✓ Upload complete!\n")
-				fmt.Printf("  CART header: %s\n", progress.CARTTxHash)
-				fmt.Printf("  DATA chunks: %d/%d\n", progress.SentChunks, progress.TotalChunks)
-				if progress.CENTTxHash != "" {
-					fmt.Printf("  CENT entry: %s\n", progress.CENTTxHash)
-				}
-				if len(progress.FailedChunks) > 0 {
-					fmt.Printf("  Failed chunks: %v\n", progress.FailedChunks)
-				}
+				fmt.Printf("[W%d] Sent chunk %d/%d (%.1f tx/s, ETA: %.0fs)\n",
+					workerID, currentSent, expectedChunks, rate, remaining)
 
-				// Log upload completion
-				logCartridgeUpload("=== Upload Complete ===")
-				logCartridgeUpload("CART header: " + progress.CARTTxHash)
-				logCartridgeUpload(fmt.Sprintf("DATA chunks: %d/%d", progress.SentChunks, progress.TotalChunks))
-				if progress.CENTTxHash != "" {
-					logCartridgeUpload(fmt.Sprintf("CENT entry: %s", progress.CENTTxHash))
-				}
-				if len(progress.FailedChunks) > 0 {
-					logCartridgeUpload(fmt.Sprintf("Failed chunks: %v", progress.FailedChunks))
+				// Log every 100 chunks
+				if sent%100 == 0 {
+					logCartridgeUpload(fmt.Sprintf("Progress: %d/%d chunks sent (%.1f tx/s)", currentSent, expectedChunks, rate))
 				}
-				logCartridgeUpload("") // Empty line for readability
 			}
+		}(w)
+	}
 
-			return nil
-		},
+	// Wait for all workers to complete
+	wg.Wait()
+	readWG.Wait()
+
+	if toUpload > 0 {
+		elapsed := time.Since(startTime).Seconds()
+		finalRate := float64(sentCount) / elapsed
+		fmt.Printf("\n✓ Uploaded %d chunks in %.1fs (%.1f tx/s avg)\n", sentCount, elapsed, finalRate)
 	}
 
-	cmd.Flags().StringVar(&filePath, "file", "", "Path to file to upload (required)")
-	cmd.Flags().Uint32Var(&appID, "app-id", 0, "App ID (uint32, auto-generated if not provided)")
-	cmd.Flags().Uint32Var(&cartridgeID, "cartridge-id", 0, "Cartridge ID (uint32, auto-generated if not provided)")
-	cmd.Flags().StringVar(&title, "title", "", "Short title (max 16 chars, required)")
-	cmd.Flags().StringVar(&semver, "semver", "", "Semantic version (e.g., 1.0.0, required)")
-	cmd.Flags().Uint8Var(&platform, "platform", 0, "Platform code: 0=DOS, 1=GB, 2=GBC, 3=NES (default: 0)")
-	cmd.Flags().StringVar(&cartridgeAddr, "cartridge-addr", "", "Cartridge address (NQ..., or use --generate-cartridge-addr)")
-	cmd.Flags().BoolVar(&generateCartAddr, "generate-cartridge-addr", false, "Generate a new cartridge address")
-	cmd.Flags().StringVar(&catalogAddr, "catalog-addr", "", "Catalog address (NQ..., 'main', 'test', required)")
-	cmd.Flags().StringVar(&sender, "sender", "", "Sender address (defaults to ADDRESS from account_credentials.txt)")
-	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Dry-run mode (output plan file only)")
-	cmd.Flags().Float64Var(&rateLimit, "rate", 25.0, "Transaction rate limit (tx/s, default: 25)")
-	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
-	cmd.Flags().Int64Var(&fee, "fee", 0, "Transaction fee in Luna (default: 0, minimum)")
-	cmd.Flags().Uint8Var(&schema, "schema", 1, "Schema version (default: 1)")
-	cmd.Flags().Uint8Var(&chunkSize, "chunk-size", 51, "Chunk size in bytes (default: 51)")
-	cmd.Flags().IntVar(&concurrency, "concurrency", 1, "Number of parallel upload workers (default: 1, max: 10)")
+	if failedCount > 0 {
+		fmt.Printf("⚠️  %d chunks failed - run again to retry\n", failedCount)
+	}
 
-	cmd.MarkFlagRequired("file")
-	cmd.MarkFlagRequired("title")
-	cmd.MarkFlagRequired("semver")
-	cmd.MarkFlagRequired("catalog-addr")
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("upload interrupted")
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
 
-	return cmd
+	// The hash is complete now that the chunker has read the whole file.
+	// Refuse to register a cartridge whose file changed since an earlier run
+	// read it to the end.
+	sha256Hash := chunker.Sum()
+	sha256Hex := hex.EncodeToString(sha256Hash[:])
+	fmt.Printf("SHA256: %s\n", sha256Hex)
+	if previous := journal.Progress().SHA256; previous == "" {
+		if err := journal.RecordSHA256(sha256Hex); err != nil {
+			return nil, err
+		}
+	} else if previous != sha256Hex {
+		return nil, fmt.Errorf("file content changed since the previous run (SHA256 %s, now %s) - chunks already sent belong to the old file; use a new --cartridge-id", previous, sha256Hex)
+	}
+	logCartridgeUpload(fmt.Sprintf("SHA256: %s", sha256Hex))
+
+	// Fold the journal into the snapshot
+	if err := journal.Compact(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	progress := journal.Progress()
+
+	// Declare co-signers before the CART header, so readers that find the CART
+	// (newest first) see right away which other senders to accept DATA from
+	// (re-sent on their own if they expired after the CART got in)
+	if coSigners := uploadCoSigners(progress.Plan); progress.SentChunks == progress.TotalChunks && len(coSigners) > 0 {
+		csgnPayloads, err := EncodeCSGNRecords(u.cartridgeID, coSigners)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode co-signer declaration: %w", err)
+		}
+		if len(progress.CSGNTxHashes) < len(csgnPayloads) {
+			fmt.Printf("\n=== Declaring %d co-signers (CSGN) ===\n", len(coSigners))
+		}
+		for i, csgnPayload := range csgnPayloads {
+			if i < len(progress.CSGNTxHashes) {
+				continue // already sent
+			}
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+			txHash, validFrom, err := sendHeader(txSender, csgnPayload)
+			if err != nil {
+				return nil, fmt.Errorf("failed to send co-signer declaration: %w", err)
+			}
+			progress.CSGNTxHashes = append(progress.CSGNTxHashes, txHash)
+			fmt.Printf("✓ CSGN %d/%d sent: %s\n", i+1, len(csgnPayloads), txHash)
+			if err := journal.RecordCSGN(i, txHash, validFrom); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+			logCartridgeUpload(fmt.Sprintf("CSGN sent: %s", txHash))
+		}
+	}
+
+	// Step 2: Send CART header AFTER all chunks (so it's in newest transactions for faster loading)
+	if progress.SentChunks == progress.TotalChunks && progress.CARTTxHash == "" {
+		fmt.Println("\n=== Step 2: Uploading CART header ===")
+		cartHeader := CARTHeader{
+			Schema:      u.schema,
+			Platform:    u.platform,
+			ChunkSize:   u.chunkSize,
+			Flags:       0,
+			CartridgeID: u.cartridgeID,
+			TotalSize:   totalSize,
+			SHA256:      sha256Hash,
+		}
+
+		cartPayload, err := EncodeCART(cartHeader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode CART header: %w", err)
+		}
+
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		txHash, validFrom, err := sendHeader(txSender, cartPayload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send CART header: %w", err)
+		}
+
+		progress.CARTTxHash = txHash
+		fmt.Printf("✓ CART header sent: %s\n", txHash)
+		if err := journal.RecordCART(txHash, validFrom); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		logCartridgeUpload(fmt.Sprintf("CART header sent: %s", txHash))
+	} else if progress.CARTTxHash != "" {
+		fmt.Printf("CART header already sent: %s\n", progress.CARTTxHash)
+	}
+
+	// Step 3: Send CENT entry to catalog if all chunks AND CART header are uploaded
+	if progress.SentChunks == progress.TotalChunks && progress.CARTTxHash != "" && progress.CENTTxHash == "" {
+		fmt.Println("\n=== Step 3: Registering cartridge in catalog (CENT) ===")
+
+		// Convert cartridge address to bytes
+		cartAddrBytes, err := AddressNQToBytes(u.cartridgeAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert cartridge address: %w", err)
+		}
+
+		centEntry := CENTEntry{
+			Schema:        u.schema,
+			Platform:      u.platform,
+			Flags:         0,
+			AppID:         u.appID,
+			Semver:        semverBytes,
+			CartridgeAddr: cartAddrBytes,
+			TitleShort:    u.title,
+		}
+
+		centPayload, err := EncodeCENT(centEntry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode CENT entry: %w", err)
+		}
+
+		// Create sender for catalog address
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		catalogRpcSender, err := NewRPCSender(u.rpcURL, u.sender, u.catalogAddr, fee)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize catalog RPC sender: %w", err)
+		}
+		var catalogSender TxSender = catalogRpcSender.WithContext(ctx).WithFeeOracle(feeOracle)
+		if senderFaults {
+			catalogSender = NewFaultSender(catalogSender, activeFaults)
+		}
+
+		txHash, validFrom, err := sendHeader(catalogSender, centPayload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send CENT entry: %w", err)
+		}
+
+		progress.CENTTxHash = txHash
+		fmt.Printf("✓ CENT entry sent to catalog: %s\n", txHash)
+		if err := journal.RecordCENT(txHash, validFrom); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		logCartridgeUpload(fmt.Sprintf("CENT entry sent to catalog: %s", txHash))
+	} else if progress.CENTTxHash != "" {
+		fmt.Printf("CENT entry already sent: %s\n", progress.CENTTxHash)
+	} else {
+		fmt.Printf("\n⚠️  Not all chunks uploaded yet (%d/%d). CENT entry will be sent when complete.\n", progress.SentChunks, progress.TotalChunks)
+	}
+
+	fmt.Printf("\n✓ Upload complete!\n")
+	fmt.Printf("  CART header: %s\n", progress.CARTTxHash)
+	fmt.Printf("  DATA chunks: %d/%d\n", progress.SentChunks, progress.TotalChunks)
+	if progress.CENTTxHash != "" {
+		fmt.Printf("  CENT entry: %s\n", progress.CENTTxHash)
+	}
+	if len(progress.FailedChunks) > 0 {
+		fmt.Printf("  Failed chunks: %v\n", progress.FailedChunks)
+	}
+
+	// Log upload completion
+	logCartridgeUpload("=== Upload Complete ===")
+	logCartridgeUpload("CART header: " + progress.CARTTxHash)
+	logCartridgeUpload(fmt.Sprintf("DATA chunks: %d/%d", progress.SentChunks, progress.TotalChunks))
+	if progress.CENTTxHash != "" {
+		logCartridgeUpload(fmt.Sprintf("CENT entry: %s", progress.CENTTxHash))
+	}
+	if len(progress.FailedChunks) > 0 {
+		logCartridgeUpload(fmt.Sprintf("Failed chunks: %v", progress.FailedChunks))
+	}
+	logCartridgeUpload("") // Empty line for readability
+
+	return &progress, nil
+}
+
+// capFee applies --max-spend: the fee per transaction is capped so that the
+// whole upload (including escalated resends) stays within budget. estimateAt
+// computes the estimate for a fee; the capped fee and its estimate are returned.
+func (u *cartridgeUpload) capFee(feeOracle *FeeOracle, fee int64, estimateAt func(fee int64) *UploadEstimate) (int64, *UploadEstimate, error) {
+	estimate := estimateAt(fee)
+	if txs := estimate.Transactions(); u.maxSpend > 0 && txs > 0 {
+		maxFee := nimToLuna(u.maxSpend)/txs - txValue
+		if maxFee < 0 {
+			return 0, nil, fmt.Errorf("--max-spend %.5f NIM cannot pay for %d transactions (at least %s needed)", u.maxSpend, txs, formatLuna(txs*txValue))
+		}
+		feeOracle.SetMaxFee(maxFee)
+		if fee > maxFee {
+			fmt.Printf("⚠️  Fee capped at %d Luna by --max-spend (wanted %d Luna); transactions may confirm slowly\n", maxFee, fee)
+			fee = maxFee
+			estimate = estimateAt(fee)
+		}
+	}
+	return fee, estimate, nil
 }
 
-func saveCartridgeProgress(filename string, progress *CartridgeUploadProgress) {
-	data, err := json.MarshalIndent(progress, "", "  ")
+// plan encodes every transaction of the upload without sending anything or
+// touching the progress journal. With --plan-out the transactions are written
+// to a plan file for 'execute --plan'; a plain --dry-run only prints them.
+func (u *cartridgeUpload) plan(rpc *NimiqRPC, feePolicy FeePolicy, source *ChunkSource, chunks int64, senderPool []string, semver [3]uint8) (*CartridgeUploadProgress, error) {
+	feeOracle := NewFeeOracle(rpc, feePolicy)
+	fee, err := feeOracle.Fee()
 	if err != nil {
-		fmt.Printf("Warning: failed to marshal progress: %v\n", err)
-		return
+		if u.planOut != "" {
+			return nil, fmt.Errorf("failed to determine fee (use --fee <luna> to set it explicitly): %w", err)
+		}
+		fmt.Printf("Warning: failed to determine fee, assuming 0 for the dry-run: %v\n", err)
+		feeOracle = FixedFeeOracle(0)
+		fee = 0
+	}
+	fee, estimate, err := u.capFee(feeOracle, fee, func(fee int64) *UploadEstimate {
+		return EstimateUpload(u.sender, senderPool, chunks, true, true, nil, fee)
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Fee: %s -> %d Luna per transaction\n", feeOracle.Policy(), fee)
+
+	if !u.skipPreflight {
+		fmt.Println("\n=== Cost estimate ===")
+		estimate.Print()
+		if u.offline {
+			fmt.Println("Balances not checked (offline)")
+		} else if _, err := estimate.CheckBalances(rpc); err != nil {
+			fmt.Printf("Warning: could not check balances: %v\n", err)
+		}
+	}
+
+	header := TxPlanHeader{
+		File:          u.filePath,
+		AppID:         u.appID,
+		CartridgeID:   u.cartridgeID,
+		CartridgeAddr: u.cartridgeAddr,
+		CatalogAddr:   u.catalogAddr,
+		NetworkID:     u.networkID,
+		Publisher:     u.sender,
+		Title:         u.title,
+		Semver:        formatSemver(semver),
+		Platform:      u.platform,
+		Schema:        u.schema,
+		ChunkSize:     u.chunkSize,
+	}
+	if u.planOut != "" && !u.offline {
+		// execute refuses the plan if the catalog changes in the meantime
+		header.CatalogState, err = catalogStateHash(rpc, u.catalogAddr, u.sender)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan, err := BuildUploadTxPlan(header, source, senderPool, fee, semver)
+	if err != nil {
+		return nil, err
 	}
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		fmt.Printf("Warning: failed to save progress: %v\n", err)
+	fmt.Printf("\nSHA256: %s\n", plan.Header.SHA256)
+	fmt.Printf("Planned %d transactions: %d DATA, %d CSGN, %d CART, %d CENT\n",
+		len(plan.Steps), plan.Count(StepDATA), plan.Count(StepCSGN), plan.Count(StepCART), plan.Count(StepCENT))
+
+	progress := &CartridgeUploadProgress{
+		AppID:         u.appID,
+		CartridgeID:   u.cartridgeID,
+		CartridgeAddr: u.cartridgeAddr,
+		TotalChunks:   int(chunks),
+		SHA256:        plan.Header.SHA256,
+	}
+	if u.planOut == "" {
+		fmt.Printf("\nDry-run complete. Nothing was sent or saved (use --plan-out to write the transactions to a plan file)\n")
+		return progress, nil
+	}
+
+	if err := plan.Write(u.planOut); err != nil {
+		return nil, err
+	}
+	fmt.Printf("\n✓ Plan written to %s\n", u.planOut)
+	fmt.Printf("  Plan hash: %s\n", plan.Header.PlanHash)
+	if u.offline {
+		fmt.Printf("  Review it, then sign it with:\n    nimiq-uploader sign-bundle --plan %s --key-file publisher.key --validity-start-height <height>\n", u.planOut)
+	} else {
+		fmt.Printf("  Review it, then send exactly these transactions with:\n    nimiq-uploader execute --plan %s\n", u.planOut)
+	}
+	logCartridgeUpload(fmt.Sprintf("Plan written to %s (app-id %d, cartridge-id %d, %d transactions, hash %s)", u.planOut, u.appID, u.cartridgeID, len(plan.Steps), plan.Header.PlanHash))
+	return progress, nil
+}
+
+// uploadCoSigners returns the distinct co-signers (non-publisher senders) that
+// sent chunks of an upload, sorted for a stable CSGN encoding
+func uploadCoSigners(plan []UploadPlan) []string {
+	seen := make(map[string]bool)
+	var coSigners []string
+	for _, p := range plan {
+		if p.Sender == "" || seen[normalizeAddress(p.Sender)] {
+			continue
+		}
+		seen[normalizeAddress(p.Sender)] = true
+		coSigners = append(coSigners, p.Sender)
+	}
+	sort.Strings(coSigners)
+	return coSigners
+}
+
+// sendHeader sends a CART, CENT or CSGN payload and returns the validity start
+// height of the transaction if the sender reports it (0 otherwise)
+func sendHeader(sender TxSender, payload []byte) (string, int64, error) {
+	if resender, ok := sender.(Resender); ok {
+		return resender.ResendTransaction(payload, 0)
+	}
+	txHash, err := sender.SendTransaction(payload)
+	return txHash, 0, err
+}
+
+// expireUnminedTransactions finds sent chunks and CART, CENT and CSGN
+// transactions that were not mined and can no longer be (the chain is past
+// validity_start_height+txValidityWindow) and records them as expired, so
+// they are sent again; chunks with one more attempt. Transactions sent before
+// validity heights were journaled are never expired. Returns the number of
+// expired chunks and other transactions.
+func expireUnminedTransactions(rpc *NimiqRPC, journal *ProgressJournal) (int, int, error) {
+	progress := journal.Progress()
+	var candidates []UploadPlan
+	for _, p := range progress.Plan {
+		if p.ValidFrom > 0 {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 && len(progress.HeaderValidFrom) == 0 {
+		return 0, 0, nil
+	}
+
+	height, err := rpc.GetBlockNumber()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get block height: %w", err)
+	}
+	// Nodes answer with an error for transactions they don't know; a
+	// duplicate of a chunk that did get in is ignored by the reconstructor
+	unmined := func(txHash string, validFrom int64) bool {
+		if height <= validFrom+txValidityWindow {
+			return false
+		}
+		tx, err := rpc.GetTransactionByHash(txHash)
+		return err != nil || tx.BlockNumber == 0
+	}
+
+	expired := 0
+	for _, p := range candidates {
+		if !unmined(p.TxHash, p.ValidFrom) {
+			continue
+		}
+		fmt.Printf("Chunk %d expired unmined (%s, valid from block %d, now %d)\n", p.Index, shortHash(p.TxHash), p.ValidFrom, height)
+		if err := journal.RecordChunkExpired(p.Index); err != nil {
+			return expired, 0, err
+		}
+		expired++
+	}
+
+	headers := []struct{ name, txHash string }{{"CART header", progress.CARTTxHash}, {"CENT entry", progress.CENTTxHash}}
+	for i, txHash := range progress.CSGNTxHashes {
+		headers = append(headers, struct{ name, txHash string }{fmt.Sprintf("CSGN %d", i+1), txHash})
+	}
+	expiredHeaders := 0
+	for _, h := range headers {
+		validFrom, ok := progress.HeaderValidFrom[h.txHash]
+		if h.txHash == "" || !ok || !unmined(h.txHash, validFrom) {
+			continue
+		}
+		fmt.Printf("%s expired unmined (%s, valid from block %d, now %d)\n", h.name, shortHash(h.txHash), validFrom, height)
+		if err := journal.RecordHeaderExpired(h.txHash); err != nil {
+			return expired, expiredHeaders, err
+		}
+		expiredHeaders++
+	}
+	return expired, expiredHeaders, nil
+}
+
+// parseSemver parses major.minor.patch with components of 0-255
+func parseSemver(semver string) ([3]uint8, error) {
+	var semverBytes [3]uint8
+	semverParts := strings.Split(semver, ".")
+	if len(semverParts) != 3 {
+		return semverBytes, fmt.Errorf("semver must be in format major.minor.patch (e.g., 1.0.0)")
+	}
+	for i, part := range semverParts {
+		val, err := strconv.ParseUint(part, 10, 8)
+		if err != nil || val > 255 {
+			return semverBytes, fmt.Errorf("invalid semver component: %s (must be 0-255)", part)
+		}
+		semverBytes[i] = uint8(val)
+	}
+	return semverBytes, nil
+}
+
+// shortHash shortens a transaction hash for log output
+func shortHash(hash string) string {
+	if len(hash) > 16 {
+		return hash[:16]
 	}
+	return hash
 }
 
 // logCartridgeUpload writes upload information to upload_cartridge.log
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maestroi/nimiq-doom/uploader/mocknode"
+)
+
+// A resume with a file that differs from the chunks already sent stops
+// without sending anything, and one that differs elsewhere never gets a CART
+func TestResumeRefusesChangedFile(t *testing.T) {
+	isolateSettings(t)
+	node := mocknode.New(mocknode.Options{Seed: 5})
+	rpcURL := startTestNode(t, node)
+	rpc := NewNimiqRPC(rpcURL)
+	publisher := importTestAccount(t, node, rpc, 1)
+
+	content := testFile(20*ChunkSize + 3)
+	file := filepath.Join(t.TempDir(), "game.zip")
+	upload := func(content []byte) error {
+		t.Helper()
+		if err := os.WriteFile(file, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		u := &cartridgeUpload{
+			filePath:      file,
+			appID:         1,
+			cartridgeID:   1,
+			title:         "Resume Test",
+			semver:        "1.0.0",
+			cartridgeAddr: testCartridgeAddr(3),
+			catalogAddr:   "test",
+			sender:        publisher,
+			rpcURL:        rpcURL,
+			feeFlag:       "0",
+			rateLimit:     1000,
+			skipPreflight: true,
+			chunkSize:     ChunkSize,
+			concurrency:   1,
+			maxSize:       "1MB",
+			noResumeHint:  true,
+		}
+		_, err := u.run(context.Background())
+		return err
+	}
+	// A first run that sends only some of the chunks
+	activeFaults = NewFaultInjector(FaultConfig{Layer: FaultLayerSender, FailRate: 0.5, Seed: 9})
+	err := upload(content)
+	activeFaults = nil
+	if err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	progress := readTestProgress(t)
+	if progress.SentChunks == 0 || progress.SentChunks == progress.TotalChunks {
+		t.Fatalf("first run sent %d of %d chunks - the seed no longer leaves a partial upload", progress.SentChunks, progress.TotalChunks)
+	}
+	if progress.SHA256 == "" {
+		t.Fatal("first run read the whole file but recorded no SHA256")
+	}
+	firstChunk := func(p CartridgeUploadProgress, sent bool) int {
+		for idx := 0; idx < p.TotalChunks; idx++ {
+			if _, ok := findPlanEntry(p.Plan, uint32(idx)); ok == sent {
+				return idx
+			}
+		}
+		t.Fatalf("no chunk with sent=%v", sent)
+		return 0
+	}
+	changeChunk := func(idx int) []byte {
+		changed := append([]byte(nil), content...)
+		changed[idx*ChunkSize]++
+		return changed
+	}
+
+	// A change in a chunk that was sent stops the run there: the changed
+	// chunk is not sent, and neither is anything read after it
+	sentIdx := firstChunk(progress, true)
+	err = upload(changeChunk(sentIdx))
+	if want := fmt.Sprintf("chunk %d differs from the one sent", sentIdx); err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("sent chunk changed: error %v, expected one containing %q", err, want)
+	}
+	before := progress
+	progress = readTestProgress(t)
+	for _, entry := range progress.Plan {
+		if _, ok := findPlanEntry(before.Plan, entry.Index); !ok && int(entry.Index) > sentIdx {
+			t.Errorf("sent chunk changed: chunk %d was sent after it", entry.Index)
+		}
+		if entry.Payload != testChunkPayload(t, content, entry.Index) {
+			t.Errorf("sent chunk changed: chunk %d was sent with other content", entry.Index)
+		}
+	}
+
+	// A change in a chunk that was not sent yet is caught by the file hash
+	// before the CART registers the cartridge
+	err = upload(changeChunk(firstChunk(progress, false)))
+	if want := "SHA256"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("unsent chunk changed: error %v, expected one containing %q", err, want)
+	}
+	if p := readTestProgress(t); p.CARTTxHash != "" || p.SHA256 != progress.SHA256 {
+		t.Errorf("unsent chunk changed: CART %q sent, SHA256 %s (was %s)", p.CARTTxHash, p.SHA256, progress.SHA256)
+	}
+}
+
+// testChunkPayload returns the hex DATA payload of chunk idx of content
+func testChunkPayload(t *testing.T, content []byte, idx uint32) string {
+	t.Helper()
+	data := content[int(idx)*ChunkSize:]
+	if len(data) > ChunkSize {
+		data = data[:ChunkSize]
+	}
+	payload, err := EncodeDATA(DATAPayload{CartridgeID: 1, ChunkIndex: idx, Length: uint8(len(data)), Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(payload)
+}
+
+func readTestProgress(t *testing.T) CartridgeUploadProgress {
+	t.Helper()
+	data, err := os.ReadFile("upload_cartridge_1_1.json")
+	if err != nil {
+		t.Fatalf("failed to read progress: %v", err)
+	}
+	var p CartridgeUploadProgress
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatalf("failed to parse progress: %v", err)
+	}
+	return p
+}
+
+func findPlanEntry(plan []UploadPlan, idx uint32) (UploadPlan, bool) {
+	for _, p := range plan {
+		if p.Index == idx && p.TxHash != "" {
+			return p, true
+		}
+	}
+	return UploadPlan{}, false
+}
+
+// Generated IDs and addresses are handed out before the first send, so
+// callers can save them where a crashed run would look for them
+func TestIDsReportedBeforeSending(t *testing.T) {
+	isolateSettings(t)
+	node := mocknode.New(mocknode.Options{Seed: 6})
+	rpcURL := startTestNode(t, node)
+	rpc := NewNimiqRPC(rpcURL)
+	publisher := importTestAccount(t, node, rpc, 1)
+
+	file := filepath.Join(t.TempDir(), "game.zip")
+	if err := os.WriteFile(file, testFile(4*ChunkSize), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var reported []string
+	u := &cartridgeUpload{
+		filePath:         file,
+		title:            "ID Test",
+		semver:           "1.0.0",
+		catalogAddr:      "test",
+		sender:           publisher,
+		rpcURL:           rpcURL,
+		feeFlag:          "0",
+		rateLimit:        1000,
+		generateCartAddr: true,
+		keyPassphrase:    "test",
+		skipPreflight:    true,
+		concurrency:      1,
+		maxSize:          "1MB",
+		noResumeHint:     true,
+		onIDs: func(appID, cartridgeID uint32, cartridgeAddr string) error {
+			mempool, err := rpc.GetMempoolInfo()
+			if err != nil {
+				t.Fatalf("failed to get mempool: %v", err)
+			}
+			if mempool.Total != 0 {
+				t.Errorf("%d transactions sent before the IDs were reported", mempool.Total)
+			}
+			reported = append(reported, fmt.Sprintf("%d/%d %s", appID, cartridgeID, cartridgeAddr))
+			return nil
+		},
+	}
+	if _, err := u.run(context.Background()); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	want := fmt.Sprintf("%d/%d %s", u.appID, u.cartridgeID, u.cartridgeAddr)
+	if len(reported) != 1 || reported[0] != want || u.appID == 0 || u.cartridgeID == 0 {
+		t.Errorf("reported %v, expected [%s]", reported, want)
+	}
+}
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+// Upload statuses in the upload-dir report
+const (
+	UploadDirPending  = "pending"
+	UploadDirUploaded = "uploaded"
+	UploadDirSkipped  = "skipped"
+	UploadDirFailed   = "failed"
+	UploadDirDryRun   = "dry-run"
+)
+
+// UploadDirEntry is the outcome for one ZIP of an upload-dir run. Generated
+// IDs and cartridge addresses are kept so a rerun resumes the same upload.
+type UploadDirEntry struct {
+	File          string  `json:"file"`
+	Title         string  `json:"title"`
+	Platform      uint8   `json:"platform"`
+	Size          int64   `json:"size"`
+	Status        string  `json:"status"`
+	Reason        string  `json:"reason,omitempty"`
+	AppID         uint32  `json:"app_id,omitempty"`
+	CartridgeID   uint32  `json:"cartridge_id,omitempty"`
+	CartridgeAddr string  `json:"cartridge_addr,omitempty"`
+	Chunks        string  `json:"chunks,omitempty"` // sent/total
+	CARTTxHash    string  `json:"cart_tx_hash,omitempty"`
+	CENTTxHash    string  `json:"cent_tx_hash,omitempty"`
+	Seconds       float64 `json:"seconds,omitempty"`
+}
+
+// UploadDirReport is the summary report written by upload-dir
+type UploadDirReport struct {
+	Dir         string            `json:"dir"`
+	CatalogAddr string            `json:"catalog_addr"`
+	Publisher   string            `json:"publisher"`
+	StartedAt   string            `json:"started_at"`
+	FinishedAt  string            `json:"finished_at,omitempty"`
+	Entries     []*UploadDirEntry `json:"entries"`
+
+	mu   sync.Mutex
+	path string
+}
+
+// loadUploadDirReport reads a previous report for the same directory and
+// catalog, or returns nil
+func loadUploadDirReport(path, dir, catalogAddr string) *UploadDirReport {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var report UploadDirReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		fmt.Printf("Warning: ignoring unreadable report %s: %v\n", path, err)
+		return nil
+	}
+	if filepath.Clean(report.Dir) != filepath.Clean(dir) || normalizeAddress(report.CatalogAddr) != normalizeAddress(catalogAddr) {
+		return nil
+	}
+	return &report
+}
+
+// entry returns the report entry for a ZIP file name (nil-safe)
+func (r *UploadDirReport) entry(file string) *UploadDirEntry {
+	if r == nil {
+		return nil
+	}
+	for _, e := range r.Entries {
+		if e.File == file {
+			return e
+		}
+	}
+	return nil
+}
+
+// save writes the report (safe for concurrent use)
+func (r *UploadDirReport) save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(r.path, data, 0644)
+}
+
+// count returns how many entries have status
+func (r *UploadDirReport) count(status string) int {
+	n := 0
+	for _, e := range r.Entries {
+		if e.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// titleFromFilename derives a catalog title from a ZIP name when run.json has none
+func titleFromFilename(name string) string {
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	words := strings.Fields(strings.NewReplacer("-", " ", "_", " ").Replace(base))
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(r)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func newUploadDirCmd() *cobra.Command {
+	var (
+		dir              string
+		catalogAddr      string
+		sender           string
+		senders          string
+		semver           string
+		platform         string
+		rpcURL           string
+		feeFlag          string
+		keyPassphrase    string
+		reportPath       string
+		parallel         int
+		concurrency      int
+		rateLimit        float64
+		chunkSize        uint8
+		maxSize          string
+		dryRun           bool
+		waitForFundsFlag bool
+		skipPreflight    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upload-dir",
+		Short: "Upload every packaged ZIP in a directory as a new cartridge",
+		Long: `Upload all *.zip files in a directory, each as a cartridge with a generated
+cartridge address (like upload-cartridge --generate-cartridge-addr).
+
+Title and platform come from the run.json inside each ZIP (the title falls back
+to the file name, the platform to the ROM header of a GB, GBC or NES ROM inside,
+then to --platform). ZIPs whose ROM does not match their platform fail. Titles
+the publisher already has in the catalog are skipped.
+
+Several cartridges are uploaded at once (--parallel); --rate is the budget for
+all of them together. The outcome of every ZIP is written to a report file; when
+run again, finished ZIPs are skipped and interrupted ones resume with the same
+app-id, cartridge-id and cartridge address.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get RPC URL from env, credentials file, or default
+			if rpcURL == "" {
+				rpcURL = GetDefaultRPCURL()
+			}
+
+			// Try to get sender from credentials file if not provided
+			if sender == "" {
+				sender = GetDefaultAddress()
+			}
+			if sender == "" {
+				return fmt.Errorf("sender address is required (--sender or set in credentials.json)")
+			}
+
+			feePolicy, err := ParseFeePolicy(feeFlag)
+			if err != nil {
+				return err
+			}
+			defaultPlatform, err := PlatformCode(platform)
+			if err != nil {
+				return fmt.Errorf("invalid --platform: %w", err)
+			}
+			maxFileSize, err := parseByteSize(maxSize)
+			if err != nil {
+				return fmt.Errorf("invalid --max-size: %w", err)
+			}
+			if parallel < 1 {
+				parallel = 1
+			}
+			if chunkSize == 0 {
+				chunkSize = 51
+			}
+
+			catalogAddr = resolveCatalogAddress(catalogAddr)
+
+			zips, err := filepath.Glob(filepath.Join(dir, "*.zip"))
+			if err != nil {
+				return err
+			}
+			sort.Strings(zips)
+			if len(zips) == 0 {
+				fmt.Printf("No ZIP files found in %s\n", dir)
+				return nil
+			}
+
+			ctx := cmd.Context()
+			rpc := NewNimiqRPC(rpcURL).WithContext(ctx)
+
+			if !dryRun {
+				// Check consensus first
+				consensus, err := rpc.IsConsensusEstablished()
+				if err != nil {
+					return fmt.Errorf("failed to check consensus: %w", err)
+				}
+				if !consensus {
+					return fmt.Errorf("node does not have consensus with the network - wait for sync or use --dry-run")
+				}
+			}
+
+			// Start from the previous report so reruns pick up where they left off
+			previous := loadUploadDirReport(reportPath, dir, catalogAddr)
+			report := &UploadDirReport{
+				Dir:         dir,
+				CatalogAddr: catalogAddr,
+				Publisher:   sender,
+				StartedAt:   time.Now().Format(time.RFC3339),
+				path:        reportPath,
+			}
+
+			fmt.Printf("Directory: %s (%d ZIP files)\n", dir, len(zips))
+			fmt.Printf("Catalog: %s\n", catalogAddr)
+			fmt.Printf("Publisher: %s\n\n", sender)
+
+			var pending []*UploadDirEntry
+			titles := make(map[string]string) // lowercase title -> ZIP
+			for _, zipPath := range zips {
+				name := filepath.Base(zipPath)
+				entry := &UploadDirEntry{File: name, Status: UploadDirPending, Platform: defaultPlatform}
+				report.Entries = append(report.Entries, entry)
+
+				if prev := previous.entry(name); prev != nil {
+					if prev.Status == UploadDirUploaded || prev.Status == UploadDirSkipped {
+						*entry = *prev
+						fmt.Printf("  %-32s %s in previous run\n", name, prev.Status)
+						continue
+					}
+					// Resume with the same IDs and cartridge address (dry-runs sent nothing)
+					if prev.Status != UploadDirDryRun {
+						entry.AppID, entry.CartridgeID, entry.CartridgeAddr = prev.AppID, prev.CartridgeID, prev.CartridgeAddr
+					}
+				}
+
+				info, err := os.Stat(zipPath)
+				if err != nil {
+					entry.Status, entry.Reason = UploadDirFailed, err.Error()
+					continue
+				}
+				entry.Size = info.Size()
+				if maxFileSize > 0 && entry.Size > maxFileSize {
+					entry.Status, entry.Reason = UploadDirFailed, fmt.Sprintf("larger than --max-size (%d bytes)", maxFileSize)
+					fmt.Printf("  %-32s ✗ %s\n", name, entry.Reason)
+					continue
+				}
+
+				config, err := ReadRunConfig(zipPath)
+				if err != nil {
+					entry.Status, entry.Reason = UploadDirFailed, err.Error()
+					fmt.Printf("  %-32s ✗ %v\n", name, err)
+					continue
+				}
+				if config != nil && config.Title != "" {
+					entry.Title = config.Title
+				} else {
+					entry.Title = titleFromFilename(name)
+				}
+				if len(entry.Title) > 16 {
+					fmt.Printf("  %-32s ⚠️  title %q truncated to 16 bytes\n", name, entry.Title)
+					entry.Title = strings.TrimSpace(truncateUTF8(entry.Title, 16))
+				}
+				if config != nil && config.Platform != "" {
+					code, err := PlatformCode(config.Platform)
+					if err != nil {
+						entry.Status, entry.Reason = UploadDirFailed, "run.json: "+err.Error()
+						fmt.Printf("  %-32s ✗ %s\n", name, entry.Reason)
+						continue
+					}
+					entry.Platform = code
+				} else if rom, err := InspectROMFile(zipPath); err == nil && rom != nil {
+					// No platform in run.json: a ROM inside decides (checked again on upload)
+					entry.Platform, _ = PlatformCode(rom.Platform)
+				}
+
+				// Titles the publisher already has in the catalog are not uploaded again
+				if entry.CartridgeAddr == "" {
+					appID, err := FindAppIDByTitle(rpc, catalogAddr, sender, entry.Title)
+					if err != nil {
+						return fmt.Errorf("failed to look up %q in the catalog: %w", entry.Title, err)
+					}
+					if appID > 0 {
+						entry.Status, entry.AppID = UploadDirSkipped, appID
+						entry.Reason = fmt.Sprintf("title already in catalog (app-id %d)", appID)
+						fmt.Printf("  %-32s skipped: %s\n", name, entry.Reason)
+						continue
+					}
+				}
+
+				if other := titles[strings.ToLower(entry.Title)]; other != "" {
+					entry.Status, entry.Reason = UploadDirFailed, fmt.Sprintf("same title as %s", other)
+					fmt.Printf("  %-32s ✗ %s\n", name, entry.Reason)
+					continue
+				}
+				titles[strings.ToLower(entry.Title)] = name
+
+				fmt.Printf("  %-32s %q (platform %d, %d bytes)\n", name, entry.Title, entry.Platform, entry.Size)
+				pending = append(pending, entry)
+			}
+
+			// Parallel uploads would all pick the same next app-id, so hand them out here
+			var nextAppID uint32
+			for _, entry := range pending {
+				if entry.AppID != 0 {
+					continue
+				}
+				if nextAppID == 0 {
+					nextAppID, err = GetMaxAppID(rpc, catalogAddr, sender)
+					if err != nil {
+						return fmt.Errorf("failed to auto-generate app-id: %w", err)
+					}
+				}
+				entry.AppID = nextAppID
+				nextAppID++
+			}
+
+			if len(pending) == 0 {
+				fmt.Println("\nNothing to upload")
+				report.FinishedAt = time.Now().Format(time.RFC3339)
+				return report.save()
+			}
+
+			senderPool := parseAddressList(senders)
+			if senders == "" {
+				senderPool = GetDefaultSenders()
+			}
+
+			// One preflight for the whole batch, so parallel uploads cannot each
+			// see the same balance (an upper bound for resumed uploads)
+			if !skipPreflight {
+				fee, err := NewFeeOracle(rpc, feePolicy).Fee()
+				if err != nil {
+					if !dryRun {
+						return fmt.Errorf("failed to determine fee (use --fee <luna> to set it explicitly): %w", err)
+					}
+					fmt.Printf("Warning: failed to determine fee, assuming 0 for the dry-run: %v\n", err)
+				}
+				total := EstimateUpload(sender, nil, 0, false, false, nil, fee)
+				for _, entry := range pending {
+					chunks, _, err := ValidateChunkLayout(entry.Size, chunkSize)
+					if err != nil {
+						entry.Status, entry.Reason = UploadDirFailed, err.Error()
+						continue
+					}
+					total.Add(EstimateUpload(sender, senderPool, chunks, true, true, nil, fee))
+				}
+				fmt.Printf("\n=== Cost estimate (%d uploads) ===\n", len(pending))
+				total.Print()
+				if dryRun {
+					if _, err := total.CheckBalances(rpc); err != nil {
+						fmt.Printf("Warning: could not check balances: %v\n", err)
+					}
+				} else if err := preflightBalances(ctx, rpc, total, waitForFundsFlag); err != nil {
+					return err
+				}
+			}
+
+			if err := report.save(); err != nil {
+				return fmt.Errorf("failed to write report: %w", err)
+			}
+
+			// All cartridges share one rate budget
+			if concurrency < 1 {
+				concurrency = 1
+			}
+			limiter := rate.NewLimiter(rate.Limit(rateLimit), concurrency*parallel)
+
+			fmt.Printf("\n=== Uploading %d cartridges (%d at a time, %.1f tx/s total) ===\n", len(pending), parallel, rateLimit)
+			var wg sync.WaitGroup
+			slots := make(chan struct{}, parallel)
+			for i, entry := range pending {
+				if entry.Status != UploadDirPending {
+					continue
+				}
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+				}
+				if ctx.Err() != nil {
+					break
+				}
+
+				wg.Add(1)
+				go func(n int, entry *UploadDirEntry) {
+					defer wg.Done()
+					defer func() { <-slots }()
+
+					fmt.Printf("\n[%d/%d] %s: uploading %q\n", n, len(pending), entry.File, entry.Title)
+					u := &cartridgeUpload{
+						filePath:         filepath.Join(dir, entry.File),
+						appID:            entry.AppID,
+						cartridgeID:      entry.CartridgeID,
+						title:            entry.Title,
+						semver:           semver,
+						platform:         entry.Platform,
+						cartridgeAddr:    entry.CartridgeAddr,
+						catalogAddr:      catalogAddr,
+						sender:           sender,
+						senders:          senders,
+						dryRun:           dryRun,
+						rateLimit:        rateLimit,
+						rpcURL:           rpcURL,
+						feeFlag:          feeFlag,
+						generateCartAddr: entry.CartridgeAddr == "",
+						keyPassphrase:    keyPassphrase,
+						skipPreflight:    true,
+						schema:           1,
+						chunkSize:        chunkSize,
+						concurrency:      concurrency,
+						maxSize:          maxSize,
+						limiter:          limiter,
+						noResumeHint:     true,
+					}
+					// Keep the IDs and generated address in the report before the
+					// first send, so a crashed run resumes with them
+					u.onIDs = func(appID, cartridgeID uint32, cartridgeAddr string) error {
+						report.mu.Lock()
+						entry.AppID, entry.CartridgeID = appID, cartridgeID
+						if cartridgeAddr != dryRunCartridgeAddr {
+							entry.CartridgeAddr = cartridgeAddr
+						}
+						report.mu.Unlock()
+						if err := report.save(); err != nil {
+							return fmt.Errorf("failed to write report (nothing was sent): %w", err)
+						}
+						return nil
+					}
+
+					start := time.Now()
+					progress, err := u.run(ctx)
+
+					report.mu.Lock()
+					entry.Seconds = time.Since(start).Round(time.Second).Seconds()
+					if progress != nil {
+						entry.Chunks = fmt.Sprintf("%d/%d", progress.SentChunks, progress.TotalChunks)
+						entry.CARTTxHash, entry.CENTTxHash = progress.CARTTxHash, progress.CENTTxHash
+					}
+					switch {
+					case err != nil && ctx.Err() != nil:
+						entry.Status, entry.Reason = UploadDirPending, "interrupted"
+					case err != nil:
+						entry.Status, entry.Reason = UploadDirFailed, err.Error()
+					case dryRun:
+						entry.Status, entry.Reason = UploadDirDryRun, ""
+					case progress.CENTTxHash == "":
+						entry.Status, entry.Reason = UploadDirFailed, "not all chunks were sent (rerun to retry)"
+					default:
+						entry.Status, entry.Reason = UploadDirUploaded, ""
+					}
+					report.mu.Unlock()
+
+					if err := report.save(); err != nil {
+						fmt.Printf("Warning: failed to write report: %v\n", err)
+					}
+					fmt.Printf("\n[%d/%d] %s: %s %s\n", n, len(pending), entry.File, entry.Status, entry.Reason)
+				}(i+1, entry)
+			}
+			wg.Wait()
+
+			report.FinishedAt = time.Now().Format(time.RFC3339)
+			if err := report.save(); err != nil {
+				return fmt.Errorf("failed to write report: %w", err)
+			}
+
+			fmt.Printf("\n=== Upload Summary ===\n")
+			for _, entry := range report.Entries {
+				line := fmt.Sprintf("  %-32s %-8s %q", entry.File, entry.Status, entry.Title)
+				if entry.AppID > 0 {
+					line += fmt.Sprintf(" app %d / cartridge %d", entry.AppID, entry.CartridgeID)
+				}
+				if entry.Reason != "" {
+					line += " - " + entry.Reason
+				}
+				fmt.Println(line)
+			}
+			fmt.Printf("\nUploaded: %d, skipped: %d, failed: %d, pending: %d\n",
+				report.count(UploadDirUploaded), report.count(UploadDirSkipped), report.count(UploadDirFailed), report.count(UploadDirPending))
+			if dryRun {
+				fmt.Printf("Dry-run: %d\n", report.count(UploadDirDryRun))
+			}
+			fmt.Printf("Report written to %s\n", reportPath)
+
+			if ctx.Err() != nil {
+				return fmt.Errorf("upload interrupted - run the same command again to resume")
+			}
+			if failed := report.count(UploadDirFailed); failed > 0 {
+				return fmt.Errorf("%d upload(s) failed - run the same command again to retry", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory with packaged ZIP files (required)")
+	cmd.Flags().StringVar(&catalogAddr, "catalog-addr", "", "Catalog address (NQ..., 'main', 'test', required)")
+	cmd.Flags().StringVar(&sender, "sender", "", "Publisher address (defaults to address from credentials.json)")
+	cmd.Flags().StringVar(&senders, "senders", "", "Comma-separated sender pool for DATA chunks (defaults to senders from credentials)")
+	cmd.Flags().StringVar(&semver, "semver", "1.0.0", "Semantic version for all cartridges")
+	cmd.Flags().StringVar(&platform, "platform", "DOS", "Platform for ZIPs whose run.json has none (DOS, GB, GBC, NES)")
+	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Nimiq RPC URL (default: from credentials or localhost:8648)")
+	cmd.Flags().StringVar(&feeFlag, "fee", "0", "Transaction fee: a fixed amount in Luna per transaction, or auto, min or fast")
+	cmd.Flags().StringVar(&keyPassphrase, "key-passphrase", "", "Passphrase to encrypt the generated cartridge keys (defaults to NIMIQ_KEY_PASSPHRASE or the account passphrase)")
+	cmd.Flags().StringVar(&reportPath, "report", "upload_dir_report.json", "Summary report file (also used to resume)")
+	cmd.Flags().IntVar(&parallel, "parallel", 2, "Number of cartridges uploaded at once")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 5, "Parallel upload workers per cartridge (max: 10)")
+	cmd.Flags().Float64Var(&rateLimit, "rate", 25.0, "Transaction rate limit for all cartridges together (tx/s)")
+	cmd.Flags().Uint8Var(&chunkSize, "chunk-size", 51, "Chunk size in bytes (default: 51)")
+	cmd.Flags().StringVar(&maxSize, "max-size", "6MB", "Maximum size per ZIP (e.g. 6MB, 100MB, 0 = no limit)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Dry-run mode (no transactions are sent)")
+	cmd.Flags().BoolVar(&waitForFundsFlag, "wait-for-funds", false, "Wait for the sender account(s) to be funded instead of refusing to start")
+	cmd.Flags().BoolVar(&skipPreflight, "skip-preflight", false, "Skip the cost estimate and balance check")
+
+	cmd.MarkFlagRequired("dir")
+	cmd.MarkFlagRequired("catalog-addr")
+
+	return cmd
+}
@@ -1,5 +1,5 @@
 import { ref, computed } from 'vue'
-import { parseCART, parseDATA, computeExpectedChunks, verifySHA256, hexToBytes, normalizeAddress, isDataMagicHex } from '../utils/payloads.js'
+import { parseCART, parseDATA, parseCSGN, computeExpectedChunks, verifySHA256, hexToBytes, normalizeAddress, isDataMagicHex, isCsgnMagicHex } from '../utils/payloads.js'
 import { useCache } from './useCache.js'
 
 /**
@@ -388,6 +388,18 @@ export function useCartridge(rpcClient, cartridgeAddress, publisherAddress) {
       const chunks = new Map()
       let lastYieldTime = Date.now()
       
+      // Uploads may spread DATA over several accounts. The publisher declares them
+      // in CSGN records; DATA from other senders is held back until it is vouched for.
+      const allowedSenders = new Set(normalizedPublisher ? [normalizedPublisher] : [])
+      const pendingBySender = new Map() // sender -> Map(chunkIndex -> chunk)
+      
+      const addChunk = (dataChunk) => {
+        // Only keep the first occurrence of each chunk index (in case of duplicates)
+        if (chunks.has(dataChunk.chunkIndex)) return 0
+        chunks.set(dataChunk.chunkIndex, dataChunk)
+        return 1
+      }
+      
       // Stream transactions and process in batches as they arrive
       await rpcClient.value.streamTransactionsParallel(
         cartridgeAddress.value,
@@ -402,14 +414,34 @@ export function useCartridge(rpcClient, cartridgeAddress, publisherAddress) {
           let batchChunksFound = 0
           
           for (const tx of batchTxs) {
-            // Filter by publisher if specified
-            if (normalizedPublisher && normalizeAddress(tx.from) !== normalizedPublisher) {
-              continue
-            }
-            
             const txData = tx.recipientData || tx.data || ''
             if (!txData) continue
             
+            const from = normalizeAddress(tx.from)
+            
+            // Co-signer declarations only count when they come from the publisher
+            if (normalizedPublisher && from === normalizedPublisher && isCsgnMagicHex(txData)) {
+              try {
+                const csgn = parseCSGN(hexToBytes(txData))
+                if (csgn && csgn.cartridgeId === cartData.cartridgeId) {
+                  for (const coSigner of csgn.coSigners.map(normalizeAddress)) {
+                    allowedSenders.add(coSigner)
+                    // Accept chunks this co-signer sent before we saw the declaration
+                    const pending = pendingBySender.get(coSigner)
+                    if (pending) {
+                      for (const chunk of pending.values()) {
+                        batchChunksFound += addChunk(chunk)
+                      }
+                      pendingBySender.delete(coSigner)
+                    }
+                  }
+                }
+              } catch (err) {
+                // Not a valid CSGN record, continue
+              }
+              continue
+            }
+            
             // OPTIMIZATION: Quick magic byte check - skip non-DATA transactions without full parsing
             if (!isDataMagicHex(txData)) {
               continue
@@ -420,14 +452,17 @@ export function useCartridge(rpcClient, cartridgeAddress, publisherAddress) {
               const dataChunk = parseDATA(data)
               
               if (dataChunk && dataChunk.cartridgeId === cartData.cartridgeId) {
-                // Only keep the first occurrence of each chunk index (in case of duplicates)
-                if (!chunks.has(dataChunk.chunkIndex)) {
-                  chunks.set(dataChunk.chunkIndex, {
-                    ...dataChunk,
-                    txHash: tx.hash
-                  })
-                  batchChunksFound++
+                const chunk = { ...dataChunk, txHash: tx.hash }
+                
+                // Filter by publisher (and declared co-signers) if specified
+                if (normalizedPublisher && !allowedSenders.has(from)) {
+                  if (!pendingBySender.has(from)) pendingBySender.set(from, new Map())
+                  const pending = pendingBySender.get(from)
+                  if (!pending.has(chunk.chunkIndex)) pending.set(chunk.chunkIndex, chunk)
+                  continue
                 }
+                
+                batchChunksFound += addChunk(chunk)
               }
             } catch (err) {
               // Not a valid DATA chunk, continue
@@ -71,8 +71,9 @@ export function useCatalog(rpcClient, catalogAddress, publisherAddress, showReti
       rawEntries.value = entries
       console.log(`Parsed ${entries.length} CENT entries`)
 
-      // Flag constant for retired apps (matches Go: FlagRetired = 0x01)
+      // Flag constants (match Go: FlagRetired = 0x01, FlagYanked = 0x02)
       const FLAG_RETIRED = 0x01
+      const FLAG_YANKED = 0x02
       
       // First, identify which app-ids are retired (if ANY version has retired flag, entire app is retired)
       const retiredAppIds = new Set()
@@ -83,11 +84,26 @@ export function useCatalog(rpcClient, catalogAddress, publisherAddress, showReti
         }
       }
       
+      // The latest CENT entry per app-id/semver wins (yank/retire re-send the entry)
+      const latestVersions = new Map()
+      for (const entry of entries) {
+        const key = `${entry.appId}:${entry.semver.major}.${entry.semver.minor}.${entry.semver.patch}`
+        const existing = latestVersions.get(key)
+        if (!existing || (entry.height || 0) >= (existing.height || 0)) {
+          latestVersions.set(key, entry)
+        }
+      }
+
       // Group by app_id and sort versions (optionally excluding retired apps)
       const gamesMap = new Map()
       const shouldShowRetired = showRetiredGames?.value ?? false
       
-      for (const entry of entries) {
+      for (const entry of latestVersions.values()) {
+        // Yanked versions are withdrawn
+        if (entry.flags & FLAG_YANKED) {
+          continue
+        }
+
         // Skip entire app if it's retired (unless showRetiredGames is enabled)
         if (retiredAppIds.has(entry.appId) && !shouldShowRetired) {
           continue
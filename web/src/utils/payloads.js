@@ -1,4 +1,4 @@
-// Payload parsing utilities for CART, DATA, and CENT formats
+// Payload parsing utilities for CART, DATA, CENT and CSGN formats
 
 /**
  * Parse CART header payload (64 bytes)
@@ -113,6 +113,37 @@ export function parseCENT(data) {
   }
 }
 
+/**
+ * Parse CSGN co-signer declaration payload (64 bytes)
+ * Format: MAGIC "CSGN"(4), cartridge_id(u32), count(1), co-signer addresses(count x 20 bytes, max 2), reserved
+ * Sent by the publisher to the cartridge address to vouch for other DATA senders.
+ */
+export function parseCSGN(data) {
+  if (!data || data.length < 64) return null
+  
+  // Check magic
+  const magic = String.fromCharCode(data[0], data[1], data[2], data[3])
+  if (magic !== 'CSGN') return null
+  
+  const view = new DataView(data.buffer, data.byteOffset, data.byteLength)
+  
+  const cartridgeId = view.getUint32(4, true) // little-endian
+  const count = data[8]
+  if (count < 1 || count > 2) return null
+  
+  const coSigners = []
+  for (let i = 0; i < count; i++) {
+    coSigners.push(addressBytesToNQ(data.slice(9 + i * 20, 29 + i * 20)))
+  }
+  
+  return {
+    magic,
+    cartridgeId,
+    coSigners,
+    raw: data
+  }
+}
+
 /**
  * Convert Nimiq address string (NQ...) to 20-byte binary
  */
@@ -282,3 +313,12 @@ export function isCartMagicHex(hexString) {
   return hex.length >= 8 && hex.slice(0, 8).toUpperCase() === '43415254'
 }
 
+
+/**
+ * OPTIMIZATION: Quick check if hex data starts with CSGN magic
+ * "CSGN" in hex = "4353474E"
+ */
+export function isCsgnMagicHex(hexString) {
+  const hex = hexString.startsWith('0x') ? hexString.slice(2) : hexString
+  return hex.length >= 8 && hex.slice(0, 8).toUpperCase() === '4353474E'
+}